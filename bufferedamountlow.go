@@ -0,0 +1,180 @@
+package webrtc
+
+import (
+	"sync"
+)
+
+// bufferedAmountLowMux shares the single bufferedAmountLow threshold of a
+// data channel between the OnBufferedAmountLow handler and the
+// SendWithBackpressure calls waiting for the bufferedAmount to go down to
+// their limit. While there are waiters the threshold is set to the highest
+// pending one, and it's moved down each time it's reached.
+type bufferedAmountLowMux struct {
+	mu sync.Mutex
+
+	// threshold and handler are the ones set by the application
+	threshold uint64
+	handler   func()
+	// handlerPending is set when the bufferedAmount went over threshold
+	// while the threshold of the data channel was used by the waiters
+	handlerPending bool
+
+	waiters []*bufferedAmountLowWaiter
+	closed  bool
+
+	// the data channel accessors, set when the data channel is open
+	attached            bool
+	bufferedAmount      func() uint64
+	setChannelThreshold func(uint64)
+}
+
+type bufferedAmountLowWaiter struct {
+	limit uint64
+	ready chan struct{}
+}
+
+// attach starts using the threshold of the open data channel, its
+// bufferedAmountLow event must call onBufferedAmountLow
+func (m *bufferedAmountLowMux) attach(bufferedAmount func() uint64, setThreshold func(uint64)) {
+	m.mu.Lock()
+	m.attached = true
+	m.bufferedAmount = bufferedAmount
+	m.setChannelThreshold = setThreshold
+	m.unlockAndFire(m.update())
+}
+
+func (m *bufferedAmountLowMux) getThreshold() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.threshold
+}
+
+func (m *bufferedAmountLowMux) setThreshold(threshold uint64) {
+	m.mu.Lock()
+	m.threshold = threshold
+	m.unlockAndFire(m.update())
+}
+
+func (m *bufferedAmountLowMux) getHandler() func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.handler
+}
+
+func (m *bufferedAmountLowMux) setHandler(f func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handler = f
+}
+
+// wait returns a channel closed when the bufferedAmount goes down to limit,
+// or when the data channel is closed
+func (m *bufferedAmountLowMux) wait(limit uint64) <-chan struct{} {
+	m.mu.Lock()
+	ready := make(chan struct{})
+	if m.closed || !m.attached || m.bufferedAmount() <= limit {
+		m.mu.Unlock()
+		close(ready)
+		return ready
+	}
+
+	m.waiters = append(m.waiters, &bufferedAmountLowWaiter{limit: limit, ready: ready})
+	m.unlockAndFire(m.update())
+	return ready
+}
+
+// close releases the waiters, the following waits return immediately
+func (m *bufferedAmountLowMux) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	for _, w := range m.waiters {
+		close(w.ready)
+	}
+	m.waiters = nil
+}
+
+// onBufferedAmountLow handles the bufferedAmountLow event of the data
+// channel
+func (m *bufferedAmountLowMux) onBufferedAmountLow() {
+	m.mu.Lock()
+	if len(m.waiters) == 0 {
+		// the data channel uses the application threshold
+		m.unlockAndFire(m.bufferedAmount() <= m.threshold)
+		return
+	}
+
+	fire := m.release(m.bufferedAmount())
+	m.unlockAndFire(m.update() || fire)
+}
+
+// unlockAndFire releases the lock, then calls the application handler if
+// its threshold was reached
+func (m *bufferedAmountLowMux) unlockAndFire(fire bool) {
+	handler := m.handler
+	m.mu.Unlock()
+
+	if fire && handler != nil {
+		handler()
+	}
+}
+
+// release releases the waiters whose limit is reached, and reports if the
+// application threshold was reached too
+func (m *bufferedAmountLowMux) release(amount uint64) bool {
+	waiters := m.waiters[:0]
+	for _, w := range m.waiters {
+		if amount <= w.limit {
+			close(w.ready)
+			continue
+		}
+		waiters = append(waiters, w)
+	}
+	m.waiters = waiters
+
+	if m.handlerPending && amount <= m.threshold {
+		m.handlerPending = false
+		return true
+	}
+	return false
+}
+
+// update sets the threshold of the data channel to the highest pending one,
+// and reports if the application threshold was reached meanwhile
+func (m *bufferedAmountLowMux) update() bool {
+	if !m.attached {
+		return false
+	}
+
+	fire := false
+	for {
+		if len(m.waiters) == 0 {
+			m.handlerPending = false
+			m.setChannelThreshold(m.threshold)
+			return fire
+		}
+
+		amount := m.bufferedAmount()
+		if amount > m.threshold {
+			m.handlerPending = true
+		}
+
+		threshold := uint64(0)
+		for _, w := range m.waiters {
+			if w.limit > threshold {
+				threshold = w.limit
+			}
+		}
+		if m.handlerPending && m.threshold > threshold {
+			threshold = m.threshold
+		}
+		m.setChannelThreshold(threshold)
+
+		// the threshold could have been reached before being set
+		if amount = m.bufferedAmount(); amount > threshold {
+			return fire
+		}
+		fire = m.release(amount) || fire
+	}
+}
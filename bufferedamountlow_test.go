@@ -0,0 +1,125 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBufferedChannel fires the bufferedAmountLow event like a SCTP stream
+type fakeBufferedChannel struct {
+	amount    uint64
+	threshold uint64
+	mux       *bufferedAmountLowMux
+}
+
+func (c *fakeBufferedChannel) bufferedAmount() uint64 {
+	return c.amount
+}
+
+func (c *fakeBufferedChannel) setThreshold(th uint64) {
+	c.threshold = th
+}
+
+func (c *fakeBufferedChannel) drain(amount uint64) {
+	from := c.amount
+	c.amount = amount
+	if from > c.threshold && amount <= c.threshold {
+		c.mux.onBufferedAmountLow()
+	}
+}
+
+func TestBufferedAmountLowMux(t *testing.T) {
+	setup := func(amount, threshold uint64) (*bufferedAmountLowMux, *fakeBufferedChannel, *int) {
+		m := &bufferedAmountLowMux{}
+		c := &fakeBufferedChannel{amount: amount, mux: m}
+		fired := 0
+		m.setThreshold(threshold)
+		m.setHandler(func() {
+			fired++
+		})
+		m.attach(c.bufferedAmount, c.setThreshold)
+		return m, c, &fired
+	}
+
+	isReady := func(ready <-chan struct{}) bool {
+		select {
+		case <-ready:
+			return true
+		default:
+			return false
+		}
+	}
+
+	t.Run("No waiters", func(t *testing.T) {
+		_, c, fired := setup(100, 10)
+		assert.Equal(t, uint64(10), c.threshold)
+		c.drain(5)
+		assert.Equal(t, 1, *fired)
+	})
+
+	t.Run("Limit over the threshold", func(t *testing.T) {
+		m, c, fired := setup(100, 10)
+		ready := m.wait(50)
+		assert.False(t, isReady(ready))
+		assert.Equal(t, uint64(50), c.threshold)
+
+		c.drain(40)
+		assert.True(t, isReady(ready))
+		assert.Equal(t, uint64(10), c.threshold)
+		assert.Equal(t, 0, *fired)
+
+		c.drain(5)
+		assert.Equal(t, 1, *fired)
+	})
+
+	t.Run("Limit under the threshold", func(t *testing.T) {
+		m, c, fired := setup(100, 80)
+		ready := m.wait(50)
+		assert.Equal(t, uint64(80), c.threshold)
+
+		c.drain(70)
+		assert.False(t, isReady(ready))
+		assert.Equal(t, 1, *fired)
+		assert.Equal(t, uint64(50), c.threshold)
+
+		c.drain(40)
+		assert.True(t, isReady(ready))
+		assert.Equal(t, 1, *fired)
+		assert.Equal(t, uint64(80), c.threshold)
+	})
+
+	t.Run("Multiple waiters", func(t *testing.T) {
+		m, c, fired := setup(100, 0)
+		low, high := m.wait(20), m.wait(60)
+		assert.Equal(t, uint64(60), c.threshold)
+
+		// both limits are reached at once
+		c.drain(10)
+		assert.True(t, isReady(low))
+		assert.True(t, isReady(high))
+		assert.Equal(t, 0, *fired)
+		assert.Equal(t, uint64(0), c.threshold)
+	})
+
+	t.Run("Limit reached", func(t *testing.T) {
+		m, c, _ := setup(100, 0)
+		assert.True(t, isReady(m.wait(100)))
+		assert.Equal(t, uint64(0), c.threshold)
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		m, _, _ := setup(100, 0)
+		ready := m.wait(50)
+		m.close()
+		assert.True(t, isReady(ready))
+		assert.True(t, isReady(m.wait(50)))
+	})
+
+	t.Run("Not attached", func(t *testing.T) {
+		m := &bufferedAmountLowMux{}
+		assert.True(t, isReady(m.wait(50)))
+	})
+}
@@ -3,13 +3,16 @@
 package webrtc
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 	"math/big"
 	"time"
@@ -27,7 +30,8 @@ type Certificate struct {
 // NewCertificate generates a new x509 compliant Certificate to be used
 // by DTLS for encrypting data sent over the wire. This method differs from
 // GenerateCertificate by allowing to specify a template x509.Certificate to
-// be used in order to define certificate parameters.
+// be used in order to define certificate parameters. The key must be a
+// *rsa.PrivateKey or a *ecdsa.PrivateKey.
 func NewCertificate(key crypto.PrivateKey, tpl x509.Certificate) (*Certificate, error) {
 	var err error
 	var certDER []byte
@@ -47,6 +51,10 @@ func NewCertificate(key crypto.PrivateKey, tpl x509.Certificate) (*Certificate,
 			return nil, &rtcerr.UnknownError{Err: err}
 		}
 	default:
+		// TODO support ed25519.PrivateKey once pion/dtls verifies the
+		// CertificateVerify it signs with an Ed25519 key, it signs the
+		// SHA-256 hash of the handshake messages but verifies the messages
+		// themselves.
 		return nil, &rtcerr.NotSupportedError{Err: ErrPrivateKeyType}
 	}
 
@@ -91,14 +99,14 @@ func (c Certificate) Expires() time.Time {
 	return c.x509Cert.NotAfter
 }
 
-// GetFingerprints returns the list of certificate fingerprints, one of which
-// is computed with the digest algorithm used in the certificate signature.
+// GetFingerprints returns the list of certificate fingerprints, computed with
+// the sha-256, sha-384 and sha-512 digest algorithms. The remote peer
+// verifies the strongest one it supports.
 func (c Certificate) GetFingerprints() ([]DTLSFingerprint, error) {
-	fingerprintAlgorithms := []crypto.Hash{crypto.SHA256}
+	fingerprintAlgorithms := []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512}
 	res := make([]DTLSFingerprint, len(fingerprintAlgorithms))
 
-	i := 0
-	for _, algo := range fingerprintAlgorithms {
+	for i, algo := range fingerprintAlgorithms {
 		name, err := fingerprint.StringFromHash(algo)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create fingerprint: %v", err)
@@ -113,12 +121,29 @@ func (c Certificate) GetFingerprints() ([]DTLSFingerprint, error) {
 		}
 	}
 
-	return res[:i+1], nil
+	return res, nil
 }
 
 // GenerateCertificate causes the creation of an X.509 certificate and
-// corresponding private key.
+// corresponding private key, valid for one month.
 func GenerateCertificate(secretKey crypto.PrivateKey) (*Certificate, error) {
+	now := time.Now()
+	return generateCertificate(secretKey, now, now.AddDate(0, 1, 0))
+}
+
+// GenerateCertificateWithValidity creates an X.509 certificate valid for
+// the given duration. A long-lived certificate can be generated once and
+// shared by the Configuration of many PeerConnections, it must be replaced
+// before it expires since the expired certificates are rejected.
+func GenerateCertificateWithValidity(secretKey crypto.PrivateKey, validity time.Duration) (*Certificate, error) {
+	if validity <= 0 {
+		return nil, &rtcerr.RangeError{Err: ErrInvalidCertificateValidity}
+	}
+	now := time.Now()
+	return generateCertificate(secretKey, now, now.Add(validity))
+}
+
+func generateCertificate(secretKey crypto.PrivateKey, notBefore, notAfter time.Time) (*Certificate, error) {
 	origin := make([]byte, 16)
 	/* #nosec */
 	if _, err := rand.Read(origin); err != nil {
@@ -141,9 +166,9 @@ func GenerateCertificate(secretKey crypto.PrivateKey) (*Certificate, error) {
 			x509.ExtKeyUsageServerAuth,
 		},
 		BasicConstraintsValid: true,
-		NotBefore:             time.Now(),
+		NotBefore:             notBefore,
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		NotAfter:              time.Now().AddDate(0, 1, 0),
+		NotAfter:              notAfter,
 		SerialNumber:          serialNumber,
 		Version:               2,
 		Subject:               pkix.Name{CommonName: hex.EncodeToString(origin)},
@@ -157,3 +182,56 @@ func GenerateCertificate(secretKey crypto.PrivateKey) (*Certificate, error) {
 func CertificateFromX509(privateKey crypto.PrivateKey, certificate *x509.Certificate) Certificate {
 	return Certificate{privateKey, certificate}
 }
+
+// CertificateFromTLS creates a new WebRTC Certificate from the leaf
+// certificate and the private key of a tls.Certificate, e.g. loaded with
+// tls.LoadX509KeyPair. The private key must be supported by NewCertificate.
+func CertificateFromTLS(certificate tls.Certificate) (*Certificate, error) {
+	switch certificate.PrivateKey.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+	default:
+		return nil, &rtcerr.NotSupportedError{Err: ErrPrivateKeyType}
+	}
+
+	x509Cert := certificate.Leaf
+	if x509Cert == nil {
+		if len(certificate.Certificate) == 0 {
+			return nil, &rtcerr.InvalidAccessError{Err: ErrCertificateMissing}
+		}
+		var err error
+		if x509Cert, err = x509.ParseCertificate(certificate.Certificate[0]); err != nil {
+			return nil, &rtcerr.InvalidAccessError{Err: err}
+		}
+	}
+
+	return &Certificate{privateKey: certificate.PrivateKey, x509Cert: x509Cert}, nil
+}
+
+// PEM returns the certificate followed by its PKCS #8 private key, encoded
+// in PEM. It can be stored to keep the same identity, and so the same
+// fingerprints, across restarts, and loaded with CertificateFromPEM.
+func (c Certificate) PEM() (string, error) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(c.privateKey)
+	if err != nil {
+		return "", &rtcerr.NotSupportedError{Err: err}
+	}
+
+	var out bytes.Buffer
+	if err := pem.Encode(&out, &pem.Block{Type: "CERTIFICATE", Bytes: c.x509Cert.Raw}); err != nil {
+		return "", &rtcerr.UnknownError{Err: err}
+	}
+	if err := pem.Encode(&out, &pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}); err != nil {
+		return "", &rtcerr.UnknownError{Err: err}
+	}
+	return out.String(), nil
+}
+
+// CertificateFromPEM creates a new WebRTC Certificate from the PEM encoded
+// certificate and private key returned by Certificate.PEM.
+func CertificateFromPEM(pems string) (*Certificate, error) {
+	certificate, err := tls.X509KeyPair([]byte(pems), []byte(pems))
+	if err != nil {
+		return nil, &rtcerr.SyntaxError{Err: err}
+	}
+	return CertificateFromTLS(certificate)
+}
@@ -3,7 +3,9 @@
 package webrtc
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
@@ -13,6 +15,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -88,3 +91,78 @@ func TestGenerateCertificateExpires(t *testing.T) {
 	now := time.Now()
 	assert.False(t, cert.Expires().IsZero() || now.After(cert.Expires()))
 }
+
+func TestGenerateCertificateWithValidity(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	_, err = GenerateCertificateWithValidity(sk, 0)
+	assert.Equal(t, &rtcerr.RangeError{Err: ErrInvalidCertificateValidity}, err)
+
+	before := time.Now()
+	cert, err := GenerateCertificateWithValidity(sk, 365*24*time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, cert.Expires().Before(before.Add(364*24*time.Hour)))
+	assert.False(t, cert.Expires().After(time.Now().Add(365*24*time.Hour)))
+
+	// a certificate shared by many PeerConnections
+	for i := 0; i < 3; i++ {
+		pc, err := NewPeerConnection(Configuration{Certificates: []Certificate{*cert}})
+		assert.NoError(t, err)
+		assert.True(t, pc.GetConfiguration().Certificates[0].Equals(*cert))
+		assert.NoError(t, pc.Close())
+	}
+}
+
+func TestGenerateCertificateEd25519(t *testing.T) {
+	_, sk, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	_, err = GenerateCertificate(sk)
+	assert.Equal(t, &rtcerr.NotSupportedError{Err: ErrPrivateKeyType}, err)
+}
+
+func TestCertificatePEM(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	for _, sk := range []crypto.PrivateKey{ecdsaKey, rsaKey} {
+		cert, err := GenerateCertificate(sk)
+		assert.Nil(t, err)
+
+		pems, err := cert.PEM()
+		assert.Nil(t, err)
+
+		loaded, err := CertificateFromPEM(pems)
+		assert.Nil(t, err)
+		assert.True(t, cert.Equals(*loaded))
+
+		fingerprints, err := cert.GetFingerprints()
+		assert.Nil(t, err)
+		loadedFingerprints, err := loaded.GetFingerprints()
+		assert.Nil(t, err)
+		assert.Equal(t, fingerprints, loadedFingerprints)
+	}
+
+	_, err = CertificateFromPEM("invalid")
+	assert.IsType(t, &rtcerr.SyntaxError{}, err)
+}
+
+func TestCertificateFromTLS(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	cert, err := GenerateCertificate(sk)
+	assert.Nil(t, err)
+
+	loaded, err := CertificateFromTLS(tls.Certificate{Certificate: [][]byte{cert.x509Cert.Raw}, PrivateKey: sk})
+	assert.Nil(t, err)
+	assert.True(t, cert.Equals(*loaded))
+
+	_, err = CertificateFromTLS(tls.Certificate{PrivateKey: sk})
+	assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrCertificateMissing}, err)
+
+	_, err = CertificateFromTLS(tls.Certificate{Certificate: [][]byte{cert.x509Cert.Raw}, PrivateKey: "key"})
+	assert.Equal(t, &rtcerr.NotSupportedError{Err: ErrPrivateKeyType}, err)
+}
@@ -15,7 +15,12 @@ type Configuration struct {
 	ICETransportPolicy ICETransportPolicy
 
 	// BundlePolicy indicates which media-bundling policy to use when gathering
-	// ICE candidates.
+	// ICE candidates. A single transport is always used, whatever the
+	// policy: with BundlePolicyMaxBundle the offered media sections, except
+	// the first one, are bundle-only, and the remote media sections outside
+	// the BUNDLE group, or all but the first one without BUNDLE, are
+	// rejected and reported to the OnTransceiverError handler with
+	// ErrUnbundledMediaSection.
 	BundlePolicy BundlePolicy
 
 	// RTCPMuxPolicy indicates which rtcp-mux policy to use when gathering ICE
@@ -38,6 +43,8 @@ type Configuration struct {
 	// used for a given connection; how certificates are selected is outside
 	// the scope of this specification. If this value is absent, then a default
 	// set of certificates is generated for each PeerConnection instance.
+	// The same certificates can be shared by many PeerConnections to avoid
+	// generating one for each of them, see GenerateCertificateWithValidity.
 	Certificates []Certificate
 
 	// ICECandidatePoolSize describes the size of the prefetched ICE pool.
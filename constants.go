@@ -11,4 +11,22 @@ const (
 	receiveMTU = 1460
 
 	mediaSectionApplication = "application"
+
+	// the maximum lengths of the generated ids
+	maxMidLength     = 16
+	maxCNAMELength   = 255
+	maxTrackIDLength = 64
+	maxRidLength     = 16
+
+	// attributeBundleOnly marks a media section usable only when bundled
+	// (RFC 8843 6)
+	attributeBundleOnly = "bundle-only"
+
+	// attributeExtMapAllowMixed allows mixing one-byte and two-byte RTP
+	// header extensions in a RTP stream (RFC 8285 6)
+	attributeExtMapAllowMixed = "extmap-allow-mixed"
+
+	// attributeMaxMessageSize is the largest message a SCTP endpoint can
+	// receive (RFC 8841 6)
+	attributeMaxMessageSize = "max-message-size"
 )
@@ -16,6 +16,7 @@ import (
 )
 
 const dataChannelBufferSize = math.MaxUint16 //message size limit for Chromium
+
 var errSCTPNotEstablished = errors.New("SCTP not established")
 
 // DataChannel represents a WebRTC DataChannel
@@ -29,11 +30,11 @@ type DataChannel struct {
 	ordered                    bool
 	maxPacketLifeTime          *uint16
 	maxRetransmits             *uint16
+	priority                   PriorityType
 	protocol                   string
 	negotiated                 bool
 	id                         *uint16
 	readyState                 DataChannelState
-	bufferedAmountLowThreshold uint64
 	detachCalled               bool
 
 	// The binaryType represents attribute MUST, on getting, return the value to
@@ -45,14 +46,19 @@ type DataChannel struct {
 	// binaryType                 string
 
 	onMessageHandler    func(DataChannelMessage)
+	borrowMessages      bool
 	openHandlerOnce     sync.Once
 	onOpenHandler       func()
 	onCloseHandler      func()
-	onBufferedAmountLow func()
 	onErrorHandler      func(error)
 
+	// bufferedAmountLow holds the bufferedAmountLow threshold and handler,
+	// shared with the SendWithBackpressure calls
+	bufferedAmountLow bufferedAmountLowMux
+
 	sctpTransport *SCTPTransport
 	dataChannel   *datachannel.DataChannel
+	detached      *detachedDataChannel
 
 	// A reference to the associated api object used by this datachannel
 	api *API
@@ -93,12 +99,44 @@ func (api *API) newDataChannel(params *DataChannelParameters, log logging.Levele
 		ordered:           params.Ordered,
 		maxPacketLifeTime: params.MaxPacketLifeTime,
 		maxRetransmits:    params.MaxRetransmits,
+		priority:          params.Priority,
 		readyState:        DataChannelStateConnecting,
 		api:               api,
 		log:               log,
 	}, nil
 }
 
+// newPriorityTypeFromChannelPriority returns the priority type of the
+// priority of a DATA_CHANNEL_OPEN message, the values between the ones of
+// the priority types are rounded up
+func newPriorityTypeFromChannelPriority(raw uint16) PriorityType {
+	switch {
+	case raw <= datachannel.ChannelPriorityBelowNormal:
+		return PriorityTypeVeryLow
+	case raw <= datachannel.ChannelPriorityNormal:
+		return PriorityTypeLow
+	case raw <= datachannel.ChannelPriorityHigh:
+		return PriorityTypeMedium
+	default:
+		return PriorityTypeHigh
+	}
+}
+
+// channelPriority returns the priority of the DATA_CHANNEL_OPEN messages, an
+// unknown priority type is the default one
+func (p PriorityType) channelPriority() uint16 {
+	switch p {
+	case PriorityTypeVeryLow:
+		return datachannel.ChannelPriorityBelowNormal
+	case PriorityTypeMedium:
+		return datachannel.ChannelPriorityHigh
+	case PriorityTypeHigh:
+		return datachannel.ChannelPriorityExtraHigh
+	default:
+		return datachannel.ChannelPriorityNormal
+	}
+}
+
 // open opens the datachannel over the sctp transport
 func (d *DataChannel) open(sctpTransport *SCTPTransport) error {
 	d.mu.Lock()
@@ -133,6 +171,9 @@ func (d *DataChannel) open(sctpTransport *SCTPTransport) error {
 			channelType = datachannel.ChannelTypePartialReliableRexmitUnordered
 		}
 	default:
+		// TODO pion/sctp starts the lifetime of a message when it's first
+		// transmitted, the messages queued behind the congestion window
+		// should expire too.
 		reliabilityParameter = uint32(*d.maxPacketLifeTime)
 		if d.ordered {
 			channelType = datachannel.ChannelTypePartialReliableTimed
@@ -143,7 +184,7 @@ func (d *DataChannel) open(sctpTransport *SCTPTransport) error {
 
 	cfg := &datachannel.Config{
 		ChannelType:          channelType,
-		Priority:             datachannel.ChannelPriorityNormal,
+		Priority:             d.priority.channelPriority(),
 		ReliabilityParameter: reliabilityParameter,
 		Label:                d.label,
 		Protocol:             d.protocol,
@@ -154,6 +195,7 @@ func (d *DataChannel) open(sctpTransport *SCTPTransport) error {
 	if d.id == nil {
 		err := d.sctpTransport.generateAndSetDataChannelID(d.sctpTransport.dtlsTransport.role(), &d.id)
 		if err != nil {
+			d.mu.Unlock()
 			return err
 		}
 	}
@@ -164,9 +206,6 @@ func (d *DataChannel) open(sctpTransport *SCTPTransport) error {
 		return err
 	}
 
-	// bufferedAmountLowThreshold and onBufferedAmountLow might be set earlier
-	dc.SetBufferedAmountLowThreshold(d.bufferedAmountLowThreshold)
-	dc.OnBufferedAmountLow(d.onBufferedAmountLow)
 	d.mu.Unlock()
 
 	d.handleOpen(dc)
@@ -264,16 +303,32 @@ func (d *DataChannel) OnMessage(f func(msg DataChannelMessage)) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	d.onMessageHandler = f
+	d.borrowMessages = false
+}
+
+// OnBorrowedMessage sets an event handler which is invoked on a message
+// arrival like OnMessage, without copying the message: msg.Data is only
+// valid until the handler returns, its buffer is reused for the next
+// message. It replaces the OnMessage handler.
+func (d *DataChannel) OnBorrowedMessage(f func(msg DataChannelMessage)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onMessageHandler = f
+	d.borrowMessages = true
 }
 
 func (d *DataChannel) onMessage(msg DataChannelMessage) {
 	d.mu.RLock()
 	hdlr := d.onMessageHandler
+	borrowMessages := d.borrowMessages
 	d.mu.RUnlock()
 
 	if hdlr == nil {
 		return
 	}
+	if !borrowMessages {
+		msg.Data = append([]byte{}, msg.Data...)
+	}
 	hdlr(msg)
 }
 
@@ -283,6 +338,11 @@ func (d *DataChannel) handleOpen(dc *datachannel.DataChannel) {
 	d.dataChannel = dc
 	d.mu.Unlock()
 
+	// the bufferedAmountLow threshold and handler might be set earlier, in
+	// OnDataChannel for the channels opened by the remote peer
+	dc.OnBufferedAmountLow(d.bufferedAmountLow.onBufferedAmountLow)
+	d.bufferedAmountLow.attach(dc.BufferedAmount, dc.SetBufferedAmountLowThreshold)
+
 	d.onOpen()
 
 	d.mu.Lock()
@@ -312,15 +372,12 @@ func (d *DataChannel) onError(err error) {
 }
 
 func (d *DataChannel) readLoop() {
+	// the messages are copied out of the buffer, unless they're borrowed
+	buffer := make([]byte, dataChannelBufferSize)
 	for {
-		buffer := make([]byte, dataChannelBufferSize)
 		n, isString, err := d.dataChannel.ReadDataChannel(buffer)
 		if err != nil {
-			d.setReadyState(DataChannelStateClosed)
-			if err != io.EOF {
-				d.onError(err)
-			}
-			d.onClose()
+			d.handleClose(err)
 			return
 		}
 
@@ -328,23 +385,68 @@ func (d *DataChannel) readLoop() {
 	}
 }
 
-// Send sends the binary message to the DataChannel peer
+// handleClose closes the DataChannel when its stream is reset, by either
+// peer, or fails
+func (d *DataChannel) handleClose(err error) {
+	d.setReadyState(DataChannelStateClosed)
+	if err != io.EOF {
+		d.onError(err)
+	}
+	d.onClose()
+}
+
+// Send sends the binary message to the DataChannel peer, it fails with a
+// TypeError if the message is larger than the MaxMessageSize of the
+// SCTPTransport, or with an OperationError if the send buffer limited with
+// SettingEngine.SetSCTPMaxSendBufferSize is full.
 func (d *DataChannel) Send(data []byte) error {
 	err := d.ensureOpen()
 	if err != nil {
 		return err
 	}
+	if err = d.ensureMessageSize(len(data)); err != nil {
+		return err
+	}
+	if err = d.ensureSendBuffer(len(data)); err != nil {
+		return err
+	}
 
 	_, err = d.dataChannel.WriteDataChannel(data, false)
 	return err
 }
 
-// SendText sends the text message to the DataChannel peer
+// SendWithBackpressure sends the binary message to the DataChannel peer
+// like Send, but it first waits while the BufferedAmount exceeds limit. It
+// keeps a fast sender, like a file transfer, from queueing more data than the
+// channel can drain. The limit can be exceeded by the concurrent callers.
+// It waits for the bufferedAmountLow event, the OnBufferedAmountLow handler
+// is still invoked at BufferedAmountLowThreshold.
+func (d *DataChannel) SendWithBackpressure(data []byte, limit uint64) error {
+	for {
+		// the wait ends with an error if the DataChannel gets closed
+		if err := d.ensureOpen(); err != nil {
+			return err
+		}
+		if d.BufferedAmount() <= limit {
+			return d.Send(data)
+		}
+		<-d.bufferedAmountLow.wait(limit)
+	}
+}
+
+// SendText sends the text message to the DataChannel peer, like Send it
+// fails if the message is too large
 func (d *DataChannel) SendText(s string) error {
 	err := d.ensureOpen()
 	if err != nil {
 		return err
 	}
+	if err = d.ensureMessageSize(len(s)); err != nil {
+		return err
+	}
+	if err = d.ensureSendBuffer(len(s)); err != nil {
+		return err
+	}
 
 	_, err = d.dataChannel.WriteDataChannel([]byte(s), true)
 	return err
@@ -359,6 +461,21 @@ func (d *DataChannel) ensureOpen() error {
 	return nil
 }
 
+func (d *DataChannel) ensureMessageSize(size int) error {
+	d.mu.RLock()
+	sctpTransport := d.sctpTransport
+	d.mu.RUnlock()
+	return sctpTransport.ensureMessageSize(size)
+}
+
+func (d *DataChannel) ensureSendBuffer(size int) error {
+	maxSendBufferSize := d.api.settingEngine.sctp.MaxSendBufferSize
+	if maxSendBufferSize != 0 && d.BufferedAmount()+uint64(size) > maxSendBufferSize {
+		return &rtcerr.OperationError{Err: ErrSendBufferFull}
+	}
+	return nil
+}
+
 // Detach allows you to detach the underlying datachannel. This provides
 // an idiomatic API to work with, however it disables the OnMessage callback.
 // Before calling Detach you have to enable this behavior by calling
@@ -366,7 +483,8 @@ func (d *DataChannel) ensureOpen() error {
 // is not supported.
 // Please refer to the data-channels-detach example and the
 // pion/datachannel documentation for the correct way to handle the
-// resulting DataChannel object.
+// resulting DataChannel object. The returned ReadWriteCloser is a
+// DetachedDataChannel, which supports deadlines.
 func (d *DataChannel) Detach() (datachannel.ReadWriteCloser, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -381,7 +499,10 @@ func (d *DataChannel) Detach() (datachannel.ReadWriteCloser, error) {
 
 	d.detachCalled = true
 
-	return d.dataChannel, nil
+	if d.detached == nil {
+		d.detached = newDetachedDataChannel(d.dataChannel, d)
+	}
+	return d.detached, nil
 }
 
 // Close Closes the DataChannel. It may be called regardless of whether
@@ -441,6 +562,19 @@ func (d *DataChannel) MaxRetransmits() *uint16 {
 	return d.maxRetransmits
 }
 
+// Priority represents the priority of this DataChannel, sent to the remote
+// peer when the DataChannel is announced in-band. The messages aren't
+// scheduled by priority, pion/sctp sends the messages of all the
+// DataChannels in the order they are queued. Keeping the BufferedAmount of
+// the low priority DataChannels small, with SendWithBackpressure or
+// OnBufferedAmountLow, limits the delay of the high priority messages.
+func (d *DataChannel) Priority() PriorityType {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.priority
+}
+
 // Protocol represents the name of the sub-protocol used with this
 // DataChannel.
 func (d *DataChannel) Protocol() string {
@@ -507,39 +641,20 @@ func (d *DataChannel) BufferedAmount() uint64 {
 // DataChannel, but the application may change its value at any time.
 // The threshold is set to 0 by default.
 func (d *DataChannel) BufferedAmountLowThreshold() uint64 {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-
-	if d.dataChannel == nil {
-		return d.bufferedAmountLowThreshold
-	}
-	return d.dataChannel.BufferedAmountLowThreshold()
+	return d.bufferedAmountLow.getThreshold()
 }
 
 // SetBufferedAmountLowThreshold is used to update the threshold.
 // See BufferedAmountLowThreshold().
 func (d *DataChannel) SetBufferedAmountLowThreshold(th uint64) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	d.bufferedAmountLowThreshold = th
-
-	if d.dataChannel != nil {
-		d.dataChannel.SetBufferedAmountLowThreshold(th)
-	}
+	d.bufferedAmountLow.setThreshold(th)
 }
 
 // OnBufferedAmountLow sets an event handler which is invoked when
 // the number of bytes of outgoing data becomes lower than the
 // BufferedAmountLowThreshold.
 func (d *DataChannel) OnBufferedAmountLow(f func()) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	d.onBufferedAmountLow = f
-	if d.dataChannel != nil {
-		d.dataChannel.OnBufferedAmountLow(f)
-	}
+	d.bufferedAmountLow.setHandler(f)
 }
 
 func (d *DataChannel) getStatsID() string {
@@ -555,13 +670,13 @@ func (d *DataChannel) collectStats(collector *statsReportCollector) {
 	defer d.mu.Unlock()
 
 	stats := DataChannelStats{
-		Timestamp: statsTimestampNow(),
-		Type:      StatsTypeDataChannel,
-		ID:        d.statsID,
-		Label:     d.label,
-		Protocol:  d.protocol,
-		// TransportID string `json:"transportId"`
-		State: d.readyState,
+		Timestamp:   statsTimestampNow(),
+		Type:        StatsTypeDataChannel,
+		ID:          d.statsID,
+		Label:       d.label,
+		Protocol:    d.protocol,
+		TransportID: sctpTransportStatsID,
+		State:       d.readyState,
 	}
 
 	if d.id != nil {
@@ -580,7 +695,11 @@ func (d *DataChannel) collectStats(collector *statsReportCollector) {
 
 func (d *DataChannel) setReadyState(r DataChannelState) {
 	d.mu.Lock()
-	defer d.mu.Unlock()
-
 	d.readyState = r
+	d.mu.Unlock()
+
+	// the SendWithBackpressure calls fail once the DataChannel is closing
+	if r == DataChannelStateClosing || r == DataChannelStateClosed {
+		d.bufferedAmountLow.close()
+	}
 }
@@ -0,0 +1,167 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/datachannel"
+	"github.com/pion/transport/deadline"
+)
+
+// DetachedDataChannel is the ReadWriteCloser returned by DataChannel.Detach.
+// Like a net.Conn, it supports deadlines: a Read or a Write called after the
+// deadline, or a Read started with a deadline when it's reached, fails with
+// context.DeadlineExceeded, a net.Error timeout. A zero time means no
+// deadline. Like DataChannel.Send, a Write fails if the message is larger
+// than the MaxMessageSize of the SCTPTransport or if the send buffer is full.
+// The DataChannel is closed, and its OnClose handler invoked, when a Read
+// fails because the channel was closed by either peer.
+type DetachedDataChannel interface {
+	datachannel.ReadWriteCloser
+
+	SetDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+// detachedRead is the result of a read of the underlying data channel
+type detachedRead struct {
+	data     []byte
+	isString bool
+	err      error
+}
+
+// detachedDataChannel adds the deadlines to a data channel. Without a read
+// deadline the reads are done directly in the caller's buffer, otherwise
+// they're done in the background, in a buffer reused by the next ones, and a
+// read interrupted by the deadline is kept for the next one.
+type detachedDataChannel struct {
+	*datachannel.DataChannel
+
+	owner     *DataChannel
+	closeOnce sync.Once
+
+	readDeadline  *deadline.Deadline
+	writeDeadline *deadline.Deadline
+
+	readLock    sync.Mutex
+	readBuffer  []byte
+	pendingRead chan detachedRead
+}
+
+func newDetachedDataChannel(dc *datachannel.DataChannel, owner *DataChannel) *detachedDataChannel {
+	return &detachedDataChannel{
+		DataChannel:   dc,
+		owner:         owner,
+		readDeadline:  deadline.New(),
+		writeDeadline: deadline.New(),
+	}
+}
+
+// Read reads a message
+func (c *detachedDataChannel) Read(p []byte) (int, error) {
+	n, _, err := c.ReadDataChannel(p)
+	return n, err
+}
+
+// ReadDataChannel reads a message and reports if it's a string, until the
+// read deadline. A read started without a deadline isn't interrupted by a
+// deadline set while it's blocked.
+func (c *detachedDataChannel) ReadDataChannel(p []byte) (int, bool, error) {
+	c.readLock.Lock()
+	defer c.readLock.Unlock()
+
+	if err := c.readDeadline.Err(); err != nil {
+		return 0, false, err
+	}
+
+	if _, ok := c.readDeadline.Deadline(); !ok && c.pendingRead == nil {
+		n, isString, err := c.DataChannel.ReadDataChannel(p)
+		if err != nil && err != io.ErrShortBuffer {
+			c.closeOnce.Do(func() {
+				c.owner.handleClose(err)
+			})
+		}
+		return n, isString, err
+	}
+
+	if c.pendingRead == nil {
+		pendingRead := make(chan detachedRead, 1)
+		if len(c.readBuffer) < len(p) {
+			c.readBuffer = make([]byte, len(p))
+		}
+		buffer := c.readBuffer[:len(p)]
+		go func() {
+			n, isString, err := c.DataChannel.ReadDataChannel(buffer)
+			pendingRead <- detachedRead{data: buffer[:n], isString: isString, err: err}
+		}()
+		c.pendingRead = pendingRead
+	}
+
+	select {
+	case read := <-c.pendingRead:
+		c.pendingRead = nil
+		if read.err == io.ErrShortBuffer {
+			return 0, false, read.err
+		} else if read.err != nil {
+			c.closeOnce.Do(func() {
+				c.owner.handleClose(read.err)
+			})
+			return 0, false, read.err
+		}
+		if len(read.data) > len(p) {
+			return 0, false, io.ErrShortBuffer
+		}
+		return copy(p, read.data), read.isString, nil
+	case <-c.readDeadline.Done():
+		return 0, false, context.DeadlineExceeded
+	}
+}
+
+// Write writes a binary message
+func (c *detachedDataChannel) Write(p []byte) (int, error) {
+	return c.WriteDataChannel(p, false)
+}
+
+// WriteDataChannel writes a message, the write doesn't block so the write
+// deadline only rejects the messages written after it
+func (c *detachedDataChannel) WriteDataChannel(p []byte, isString bool) (int, error) {
+	if err := c.writeDeadline.Err(); err != nil {
+		return 0, err
+	}
+	if err := c.owner.ensureMessageSize(len(p)); err != nil {
+		return 0, err
+	}
+	if err := c.owner.ensureSendBuffer(len(p)); err != nil {
+		return 0, err
+	}
+	return c.DataChannel.WriteDataChannel(p, isString)
+}
+
+// Close closes the DataChannel, like DataChannel.Close
+func (c *detachedDataChannel) Close() error {
+	return c.owner.Close()
+}
+
+// SetDeadline sets the read and write deadlines
+func (c *detachedDataChannel) SetDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	c.writeDeadline.Set(t)
+	return nil
+}
+
+// SetReadDeadline sets the deadline of the reads
+func (c *detachedDataChannel) SetReadDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	return nil
+}
+
+// SetWriteDeadline sets the deadline of the writes
+func (c *detachedDataChannel) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline.Set(t)
+	return nil
+}
@@ -4,19 +4,26 @@ package webrtc
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"math/big"
+	"net"
 	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/pion/datachannel"
 	"github.com/pion/logging"
 	"github.com/pion/transport/test"
+	"github.com/pion/transport/vnet"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -112,6 +119,66 @@ func TestDataChannel_MessagesAreOrdered(t *testing.T) {
 
 // Note(albrow): This test includes some features that aren't supported by the
 // Wasm bindings (at least for now).
+func TestDataChannel_OnBorrowedMessage(t *testing.T) {
+	for _, borrow := range []bool{false, true} {
+		borrow := borrow
+		t.Run(fmt.Sprintf("borrow=%v", borrow), func(t *testing.T) {
+			report := test.CheckRoutines(t)
+			defer report()
+
+			lim := test.TimeOut(time.Second * 20)
+			defer lim.Stop()
+
+			offerPC, answerPC, err := newPair()
+			assert.NoError(t, err)
+
+			const messages = 3
+			received := make(chan DataChannelMessage, messages)
+			answerPC.OnDataChannel(func(d *DataChannel) {
+				if d.Label() != expectedLabel {
+					return
+				}
+				handler := func(msg DataChannelMessage) {
+					assert.Equal(t, "message", string(msg.Data[:len(msg.Data)-1]))
+					received <- msg
+				}
+				if borrow {
+					d.OnBorrowedMessage(handler)
+				} else {
+					d.OnMessage(handler)
+				}
+			})
+
+			dc, err := offerPC.CreateDataChannel(expectedLabel, nil)
+			assert.NoError(t, err)
+			dc.OnOpen(func() {
+				for i := 0; i < messages; i++ {
+					assert.NoError(t, dc.SendText(fmt.Sprintf("message%d", i)))
+				}
+			})
+
+			assert.NoError(t, signalPair(offerPC, answerPC))
+
+			msgs := []DataChannelMessage{}
+			for i := 0; i < messages; i++ {
+				msgs = append(msgs, <-received)
+			}
+			for i, msg := range msgs {
+				assert.True(t, msg.IsString)
+				if borrow {
+					// all the messages are read in the same buffer
+					assert.Equal(t, &msgs[0].Data[0], &msg.Data[0])
+				} else {
+					assert.Equal(t, fmt.Sprintf("message%d", i), string(msg.Data))
+					assert.Equal(t, len(msg.Data), cap(msg.Data))
+				}
+			}
+
+			closePairNow(t, offerPC, answerPC)
+		})
+	}
+}
+
 func TestDataChannelParamters_Go(t *testing.T) {
 	report := test.CheckRoutines(t)
 	defer report()
@@ -169,6 +236,97 @@ func TestDataChannelParamters_Go(t *testing.T) {
 	})
 }
 
+func TestDataChannel_PartialReliability(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	router, err := vnet.NewRouter(&vnet.RouterConfig{
+		CIDR:          "1.2.3.0/24",
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	assert.NoError(t, err)
+
+	// every other message is lost once the channel is open
+	const messageSize = 500
+	var lossy atomicBool
+	var packets uint32
+	router.AddChunkFilter(func(c vnet.Chunk) bool {
+		if !lossy.get() || len(c.UserData()) < messageSize {
+			return true
+		}
+		return atomic.AddUint32(&packets, 1)%2 == 0
+	})
+
+	newPeerConnection := func() *PeerConnection {
+		n := vnet.NewNet(&vnet.NetConfig{})
+		assert.NoError(t, router.AddNet(n))
+
+		s := SettingEngine{}
+		s.SetVNet(n)
+		pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		return pc
+	}
+	pcOffer := newPeerConnection()
+	pcAnswer := newPeerConnection()
+	assert.NoError(t, router.Start())
+
+	ordered := false
+	maxRetransmits := uint16(0)
+	dc, err := pcOffer.CreateDataChannel(expectedLabel, &DataChannelInit{
+		Ordered:        &ordered,
+		MaxRetransmits: &maxRetransmits,
+	})
+	assert.NoError(t, err)
+
+	// the messages are lost only once the answerer has accepted the
+	// channel, the first unordered one could otherwise arrive before the
+	// DCEP open
+	var received uint32
+	opened := make(chan struct{})
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		if d.Label() != expectedLabel {
+			return
+		}
+		d.OnMessage(func(msg DataChannelMessage) {
+			atomic.AddUint32(&received, 1)
+		})
+		d.OnOpen(func() {
+			close(opened)
+		})
+	})
+
+	offerOpened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(offerOpened)
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-offerOpened
+	<-opened
+	lossy.set(true)
+
+	const messages = 20
+	for i := 0; i < messages; i++ {
+		assert.NoError(t, dc.Send(make([]byte, messageSize)))
+	}
+
+	// the lost messages are abandoned instead of being retransmitted
+	for dc.BufferedAmount() != 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	count := atomic.LoadUint32(&received)
+	assert.True(t, count > 0 && count < messages, "received %d messages", count)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+	assert.NoError(t, router.Stop())
+}
+
 func TestDataChannelBufferedAmount(t *testing.T) {
 	t.Run("set before datachannel becomes open", func(t *testing.T) {
 		report := test.CheckRoutines(t)
@@ -317,6 +475,572 @@ func TestDataChannelBufferedAmount(t *testing.T) {
 
 		assert.True(t, nCbs > 0, "callback should be made at least once")
 	})
+
+	t.Run("set in OnDataChannel", func(t *testing.T) {
+		report := test.CheckRoutines(t)
+		defer report()
+
+		var nCbs int32
+		buf := make([]byte, 1000)
+
+		offerPC, answerPC, err := newPair()
+		if err != nil {
+			t.Fatalf("Failed to create a PC pair for testing")
+		}
+
+		done := make(chan bool)
+
+		answerPC.OnDataChannel(func(d *DataChannel) {
+			// Make sure this is the data channel we were looking for. (Not the one
+			// created in signalPair).
+			if d.Label() != expectedLabel {
+				return
+			}
+			// The value and the callback function are temporarily stored in
+			// the dc object until the dc gets opened
+			d.SetBufferedAmountLowThreshold(1500)
+			d.OnBufferedAmountLow(func() {
+				atomic.AddInt32(&nCbs, 1)
+			})
+
+			d.OnOpen(func() {
+				for i := 0; i < 10; i++ {
+					e := d.Send(buf)
+					if e != nil {
+						t.Fatalf("Failed to send string on data channel")
+					}
+					assert.Equal(t, uint64(1500), d.BufferedAmountLowThreshold(), "value mismatch")
+				}
+			})
+		})
+
+		dc, err := offerPC.CreateDataChannel(expectedLabel, nil)
+		if err != nil {
+			t.Fatalf("Failed to create a PC pair for testing")
+		}
+
+		var nPacketsReceived int
+		dc.OnMessage(func(msg DataChannelMessage) {
+			nPacketsReceived++
+
+			if nPacketsReceived == 10 {
+				go func() {
+					time.Sleep(time.Second)
+					done <- true
+				}()
+			}
+		})
+
+		err = signalPair(offerPC, answerPC)
+		if err != nil {
+			t.Fatalf("Failed to signal our PC pair for testing")
+		}
+
+		closePair(t, offerPC, answerPC, done)
+
+		assert.True(t, atomic.LoadInt32(&nCbs) > 0, "callback should be made at least once")
+	})
+}
+
+func TestDataChannel_SendWithBackpressure(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	const (
+		messageSize = 10000
+		messages    = 100
+		limit       = 3 * messageSize
+	)
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	dc, err := offerPC.CreateDataChannel(expectedLabel, nil)
+	assert.NoError(t, err)
+
+	t.Run("not open", func(t *testing.T) {
+		err := dc.SendWithBackpressure(make([]byte, messageSize), limit)
+		assert.Equal(t, &rtcerr.InvalidStateError{Err: ErrDataChannelNotOpen}, err)
+	})
+
+	received := make(chan struct{})
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		if d.Label() != expectedLabel {
+			return
+		}
+		var nMessages int
+		d.OnMessage(func(msg DataChannelMessage) {
+			nMessages++
+			if nMessages == messages {
+				close(received)
+			}
+		})
+	})
+
+	sent := make(chan struct{})
+	dc.OnOpen(func() {
+		defer close(sent)
+		for i := 0; i < messages; i++ {
+			assert.NoError(t, dc.SendWithBackpressure(make([]byte, messageSize), limit))
+			// only the last message is queued over the limit
+			assert.True(t, dc.BufferedAmount() <= limit+messageSize, "bufferedAmount %d", dc.BufferedAmount())
+		}
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	<-sent
+	<-received
+
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+}
+
+func TestDataChannel_SCTPBufferSizes(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	const (
+		messageSize = 1000
+		messages    = 100
+	)
+
+	s := SettingEngine{}
+	s.SetSCTPMaxReceiveBufferSize(4 * messageSize)
+	s.SetSCTPMaxSendBufferSize(8 * messageSize)
+	api := NewAPI(WithSettingEngine(s))
+
+	offerPC, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	answerPC, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	dc, err := offerPC.CreateDataChannel(expectedLabel, nil)
+	assert.NoError(t, err)
+
+	received := make(chan struct{})
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		if d.Label() != expectedLabel {
+			return
+		}
+		var nMessages int
+		d.OnMessage(func(msg DataChannelMessage) {
+			nMessages++
+			if nMessages == messages {
+				close(received)
+			}
+		})
+	})
+
+	sent := make(chan struct{})
+	dc.OnOpen(func() {
+		defer close(sent)
+		assert.Equal(t, &rtcerr.OperationError{Err: ErrSendBufferFull}, dc.Send(make([]byte, 9*messageSize)))
+
+		// the small receive window still lets the messages through
+		for i := 0; i < messages; i++ {
+			assert.NoError(t, dc.SendWithBackpressure(make([]byte, messageSize), 4*messageSize))
+		}
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	<-sent
+	<-received
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestPriorityType_ChannelPriority(t *testing.T) {
+	testCases := []struct {
+		priority         PriorityType
+		channelPriority  uint16
+		expectedPriority PriorityType
+	}{
+		{PriorityType(Unknown), 256, PriorityTypeLow},
+		{PriorityTypeVeryLow, 128, PriorityTypeVeryLow},
+		{PriorityTypeLow, 256, PriorityTypeLow},
+		{PriorityTypeMedium, 512, PriorityTypeMedium},
+		{PriorityTypeHigh, 1024, PriorityTypeHigh},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.channelPriority,
+			testCase.priority.channelPriority(),
+			"testCase: %d %v", i, testCase,
+		)
+		assert.Equal(t,
+			testCase.expectedPriority,
+			newPriorityTypeFromChannelPriority(testCase.channelPriority),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+
+	// the values in between are rounded up
+	assert.Equal(t, PriorityTypeLow, newPriorityTypeFromChannelPriority(129))
+	assert.Equal(t, PriorityTypeHigh, newPriorityTypeFromChannelPriority(2048))
+}
+
+func TestDataChannel_Priority(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	priority := PriorityTypeHigh
+	dc, err := offerPC.CreateDataChannel(expectedLabel, &DataChannelInit{Priority: &priority})
+	assert.NoError(t, err)
+	assert.Equal(t, PriorityTypeHigh, dc.Priority())
+
+	defaultDC, err := offerPC.CreateDataChannel("default", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, PriorityTypeLow, defaultDC.Priority())
+
+	priorities := make(chan PriorityType, 2)
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		if d.Label() == expectedLabel || d.Label() == "default" {
+			priorities <- d.Priority()
+		}
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	received := []PriorityType{<-priorities, <-priorities}
+	assert.ElementsMatch(t, []PriorityType{PriorityTypeHigh, PriorityTypeLow}, received)
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestDataChannel_MaxMessageSize(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	dc, err := pcOffer.CreateDataChannel(expectedLabel, nil)
+	assert.NoError(t, err)
+
+	largeMessage := make([]byte, sctpMaxMessageSize)
+	_, err = rand.Read(largeMessage)
+	assert.NoError(t, err)
+
+	received := make(chan []byte)
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		if d.Label() != expectedLabel {
+			return
+		}
+		d.OnOpen(func() {
+			// the offer advertises the limit of pion/sctp
+			assert.Equal(t, float64(sctpMaxMessageSize), pcAnswer.sctpTransport.MaxMessageSize())
+			assert.NoError(t, d.Send(largeMessage))
+		})
+	})
+	dc.OnMessage(func(msg DataChannelMessage) {
+		received <- msg.Data
+	})
+
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(opened)
+	})
+
+	// the answer advertises a smaller limit
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	offerGatheringComplete := pcOffer.GatheringCompletePromise()
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	<-offerGatheringComplete
+	assert.NoError(t, pcAnswer.SetRemoteDescription(*pcOffer.LocalDescription()))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	answerGatheringComplete := pcAnswer.GatheringCompletePromise()
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	<-answerGatheringComplete
+	answer = *pcAnswer.LocalDescription()
+	assert.True(t, strings.Contains(answer.SDP, "a=max-message-size:65535\r\n"))
+	answer.SDP = strings.Replace(answer.SDP, "a=max-message-size:65535", "a=max-message-size:1024", 1)
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+	<-opened
+	assert.Equal(t, float64(1024), pcOffer.sctpTransport.MaxMessageSize())
+	assert.Equal(t, &rtcerr.TypeError{Err: ErrMessageTooLarge}, dc.Send(make([]byte, 1025)))
+	assert.Equal(t, &rtcerr.TypeError{Err: ErrMessageTooLarge}, dc.SendText(strings.Repeat("a", 1025)))
+	assert.NoError(t, dc.Send(make([]byte, 1024)))
+
+	assert.Equal(t, largeMessage, <-received)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestDataChannel_IDs(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	negotiated := true
+	_, err = pcOffer.CreateDataChannel("negotiated", &DataChannelInit{Negotiated: &negotiated})
+	assert.Equal(t, &rtcerr.TypeError{Err: ErrNegotiatedWithoutID}, err)
+
+	reservedID := sctpMaxChannels
+	_, err = pcOffer.CreateDataChannel("reserved", &DataChannelInit{ID: &reservedID})
+	assert.Equal(t, &rtcerr.TypeError{Err: ErrMaxDataChannelID}, err)
+
+	explicitID := uint16(10)
+	_, err = pcOffer.CreateDataChannel("explicit", &DataChannelInit{ID: &explicitID})
+	assert.NoError(t, err)
+	_, err = pcOffer.CreateDataChannel("explicit", &DataChannelInit{ID: &explicitID})
+	assert.Equal(t, &rtcerr.OperationError{Err: ErrDataChannelIDInUse}, err)
+
+	// both peers open channels at the same time, before and after connecting
+	const channels = 3
+	var wg sync.WaitGroup
+	createDataChannels := func(pc *PeerConnection) []*DataChannel {
+		dcs := []*DataChannel{}
+		for i := 0; i < channels; i++ {
+			dc, err := pc.CreateDataChannel(expectedLabel, nil)
+			assert.NoError(t, err)
+			wg.Add(1)
+			dc.OnOpen(wg.Done)
+			dcs = append(dcs, dc)
+		}
+		return dcs
+	}
+	remoteChannels := func(pc *PeerConnection) chan *DataChannel {
+		remote := make(chan *DataChannel, 2*channels)
+		pc.OnDataChannel(func(d *DataChannel) {
+			if d.Label() == expectedLabel {
+				remote <- d
+			}
+		})
+		return remote
+	}
+	remoteOfOffer, remoteOfAnswer := remoteChannels(pcOffer), remoteChannels(pcAnswer)
+
+	offerChannels := createDataChannels(pcOffer)
+	answerChannels := createDataChannels(pcAnswer)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	wg.Wait()
+	offerChannels = append(offerChannels, createDataChannels(pcOffer)...)
+	answerChannels = append(answerChannels, createDataChannels(pcAnswer)...)
+	wg.Wait()
+
+	ids := map[uint16]bool{explicitID: true}
+	checkIDs := func(dcs []*DataChannel, role DTLSRole) {
+		for _, dc := range dcs {
+			if !assert.NotNil(t, dc.ID()) {
+				continue
+			}
+			id := *dc.ID()
+			assert.False(t, ids[id], "id %d used twice", id)
+			ids[id] = true
+			assert.Equal(t, role == DTLSRoleServer, id%2 == 1, "id %d of the DTLS %s", id, role)
+		}
+	}
+	offerRole := pcOffer.dtlsTransport.role()
+	assert.NotEqual(t, offerRole, pcAnswer.dtlsTransport.role())
+	checkIDs(offerChannels, offerRole)
+	checkIDs(answerChannels, pcAnswer.dtlsTransport.role())
+
+	for i := 0; i < 2*channels; i++ {
+		<-remoteOfOffer
+		<-remoteOfAnswer
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestDataChannel_MaxChannels(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.SetSCTPMaxChannels(4)
+	api := NewAPI(WithSettingEngine(s))
+
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	aboveID := uint16(4)
+	_, err = pcOffer.CreateDataChannel("above", &DataChannelInit{ID: &aboveID})
+	assert.Equal(t, &rtcerr.OperationError{Err: ErrMaxDataChannelID}, err)
+
+	// each peer uses half of the ids, signalPair creates a channel too
+	var wg sync.WaitGroup
+	for _, pc := range []*PeerConnection{pcOffer, pcAnswer, pcAnswer} {
+		dc, err := pc.CreateDataChannel(expectedLabel, nil)
+		assert.NoError(t, err)
+		wg.Add(1)
+		dc.OnOpen(func() {
+			wg.Done()
+		})
+	}
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	wg.Wait()
+
+	_, err = pcAnswer.CreateDataChannel(expectedLabel, nil)
+	assert.Equal(t, &rtcerr.OperationError{Err: ErrMaxDataChannels}, err)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestDataChannel_DetachDeadlines(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.DetachDataChannels()
+	api := NewAPI(WithSettingEngine(s))
+
+	pca, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcb, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	detach := func(dc *DataChannel, detached chan DetachedDataChannel) {
+		dc.OnOpen(func() {
+			raw, err := dc.Detach()
+			assert.NoError(t, err)
+			detached <- raw.(DetachedDataChannel)
+		})
+	}
+
+	detachedA := make(chan DetachedDataChannel, 1)
+	dc, err := pca.CreateDataChannel(expectedLabel, nil)
+	assert.NoError(t, err)
+	detach(dc, detachedA)
+
+	detachedB := make(chan DetachedDataChannel, 1)
+	pcb.OnDataChannel(func(dc *DataChannel) {
+		if dc.Label() == expectedLabel {
+			detach(dc, detachedB)
+		}
+	})
+
+	assert.NoError(t, signalPair(pca, pcb))
+	a, b := <-detachedA, <-detachedB
+
+	// the read interrupted by the deadline gets the next message
+	buf := make([]byte, 32)
+	assert.NoError(t, b.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, err = b.Read(buf)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	if netErr, ok := err.(net.Error); assert.True(t, ok) {
+		assert.True(t, netErr.Timeout())
+	}
+
+	_, err = a.Write([]byte("ping"))
+	assert.NoError(t, err)
+	assert.NoError(t, b.SetReadDeadline(time.Time{}))
+	n, err := b.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(buf[:n]))
+
+	// without a deadline the read is done directly in the buffer
+	_, err = a.Write([]byte("pong"))
+	assert.NoError(t, err)
+	n, err = b.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "pong", string(buf[:n]))
+
+	assert.NoError(t, a.SetWriteDeadline(time.Now().Add(-time.Second)))
+	_, err = a.Write([]byte("ping"))
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.NoError(t, a.SetDeadline(time.Time{}))
+	_, err = a.Write([]byte("ping"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, a.Close())
+	assert.NoError(t, b.Close())
+	closePairNow(t, pca, pcb)
+}
+
+func TestDataChannel_DetachClose(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.DetachDataChannels()
+	api := NewAPI(WithSettingEngine(s))
+
+	pca, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcb, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	type detachedChannel struct {
+		dc     *DataChannel
+		raw    datachannel.ReadWriteCloser
+		closed chan struct{}
+	}
+	detach := func(dc *DataChannel, detached chan detachedChannel) {
+		closed := make(chan struct{})
+		dc.OnClose(func() {
+			close(closed)
+		})
+		dc.OnOpen(func() {
+			raw, err := dc.Detach()
+			assert.NoError(t, err)
+			detached <- detachedChannel{dc, raw, closed}
+		})
+	}
+
+	detachedA := make(chan detachedChannel, 1)
+	dc, err := pca.CreateDataChannel(expectedLabel, nil)
+	assert.NoError(t, err)
+	detach(dc, detachedA)
+
+	detachedB := make(chan detachedChannel, 1)
+	pcb.OnDataChannel(func(dc *DataChannel) {
+		if dc.Label() == expectedLabel {
+			detach(dc, detachedB)
+		}
+	})
+
+	assert.NoError(t, signalPair(pca, pcb))
+	a, b := <-detachedA, <-detachedB
+
+	assert.NoError(t, a.raw.Close())
+	assert.Equal(t, DataChannelStateClosing, a.dc.ReadyState())
+
+	// both peers see the stream reset
+	for _, c := range []detachedChannel{a, b} {
+		_, err = c.raw.Read(make([]byte, 32))
+		assert.Equal(t, io.EOF, err)
+		<-c.closed
+		assert.Equal(t, DataChannelStateClosed, c.dc.ReadyState())
+	}
+
+	closePairNow(t, pca, pcb)
 }
 
 func TestEOF(t *testing.T) {
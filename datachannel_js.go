@@ -7,6 +7,7 @@ import (
 	"syscall/js"
 
 	"github.com/pion/datachannel"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 )
 
 const dataChannelBufferSize = 16384 // Lowest common denominator among browsers
@@ -23,12 +24,51 @@ type DataChannel struct {
 	onOpenHandler       *js.Func
 	onCloseHandler      *js.Func
 	onMessageHandler    *js.Func
+	onErrorHandler      *js.Func
 	onBufferedAmountLow *js.Func
+	onCloseListener     *js.Func
+
+	// bufferedAmountLow holds the bufferedAmountLow threshold and handler,
+	// shared with the SendWithBackpressure calls
+	bufferedAmountLow bufferedAmountLowMux
+
+	detached *detachedDataChannel
 
 	// A reference to the associated api object used by this datachannel
 	api *API
 }
 
+// newDataChannel wraps a browser DataChannel. The binary messages are
+// received as ArrayBuffers, like the Uint8Arrays of the native DataChannel,
+// instead of Blobs that must be read asynchronously.
+func newDataChannel(underlying js.Value, api *API) *DataChannel {
+	underlying.Set("binaryType", "arraybuffer")
+	d := &DataChannel{
+		underlying: underlying,
+		api:        api,
+	}
+
+	onBufferedAmountLow := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go d.bufferedAmountLow.onBufferedAmountLow()
+		return js.Undefined()
+	})
+	d.onBufferedAmountLow = &onBufferedAmountLow
+	underlying.Set("onbufferedamountlow", onBufferedAmountLow)
+
+	// the listener doesn't replace the OnClose handler
+	onCloseListener := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go d.bufferedAmountLow.close()
+		return js.Undefined()
+	})
+	d.onCloseListener = &onCloseListener
+	underlying.Call("addEventListener", "close", onCloseListener)
+
+	d.bufferedAmountLow.attach(d.BufferedAmount, func(th uint64) {
+		underlying.Set("bufferedAmountLowThreshold", th)
+	})
+	return d
+}
+
 // OnOpen sets an event handler which is invoked when
 // the underlying data transport has been established (or re-established).
 func (d *DataChannel) OnOpen(f func()) {
@@ -59,6 +99,22 @@ func (d *DataChannel) OnClose(f func()) {
 	d.underlying.Set("onclose", onCloseHandler)
 }
 
+// OnError sets an event handler which is invoked when
+// the underlying data transport fails.
+func (d *DataChannel) OnError(f func(err error)) {
+	if d.onErrorHandler != nil {
+		oldHandler := d.onErrorHandler
+		defer oldHandler.Release()
+	}
+	onErrorHandler := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		err := js.Error{Value: args[0].Get("error")}
+		go f(err)
+		return js.Undefined()
+	})
+	d.onErrorHandler = &onErrorHandler
+	d.underlying.Set("onerror", onErrorHandler)
+}
+
 // OnMessage sets an event handler which is invoked on a binary message arrival
 // from a remote peer. Note that browsers may place limitations on message size.
 func (d *DataChannel) OnMessage(f func(msg DataChannelMessage)) {
@@ -82,6 +138,13 @@ func (d *DataChannel) OnMessage(f func(msg DataChannelMessage)) {
 	d.underlying.Set("onmessage", onMessageHandler)
 }
 
+// OnBorrowedMessage sets an event handler which is invoked on a message
+// arrival like OnMessage. The messages are always copied from JavaScript, so
+// it's the same as OnMessage.
+func (d *DataChannel) OnBorrowedMessage(f func(msg DataChannelMessage)) {
+	d.OnMessage(f)
+}
+
 // Send sends the binary message to the DataChannel peer
 func (d *DataChannel) Send(data []byte) (err error) {
 	defer func() {
@@ -95,6 +158,25 @@ func (d *DataChannel) Send(data []byte) (err error) {
 	return nil
 }
 
+// SendWithBackpressure sends the binary message to the DataChannel peer
+// like Send, but it first waits while the BufferedAmount exceeds limit. It
+// keeps a fast sender, like a file transfer, from queueing more data than the
+// channel can drain. The limit can be exceeded by the concurrent callers.
+// It waits for the bufferedamountlow event, the OnBufferedAmountLow handler
+// is still invoked at BufferedAmountLowThreshold.
+func (d *DataChannel) SendWithBackpressure(data []byte, limit uint64) error {
+	for {
+		// the wait ends with an error if the DataChannel gets closed
+		if d.ReadyState() != DataChannelStateOpen {
+			return &rtcerr.InvalidStateError{Err: ErrDataChannelNotOpen}
+		}
+		if d.BufferedAmount() <= limit {
+			return d.Send(data)
+		}
+		<-d.bufferedAmountLow.wait(limit)
+	}
+}
+
 // SendText sends the text message to the DataChannel peer
 func (d *DataChannel) SendText(s string) (err error) {
 	defer func() {
@@ -113,14 +195,17 @@ func (d *DataChannel) SendText(s string) (err error) {
 // is not supported.
 // Please reffer to the data-channels-detach example and the
 // pion/datachannel documentation for the correct way to handle the
-// resulting DataChannel object.
+// resulting DataChannel object. The returned ReadWriteCloser is a
+// DetachedDataChannel, which supports deadlines.
 func (d *DataChannel) Detach() (datachannel.ReadWriteCloser, error) {
 	if !d.api.settingEngine.detach.DataChannels {
 		return nil, fmt.Errorf("enable detaching by calling webrtc.DetachDataChannels()")
 	}
 
-	detached := newDetachedDataChannel(d)
-	return detached, nil
+	if d.detached == nil {
+		d.detached = newDetachedDataChannel(d)
+	}
+	return d.detached, nil
 }
 
 // Close Closes the DataChannel. It may be called regardless of whether
@@ -133,6 +218,7 @@ func (d *DataChannel) Close() (err error) {
 	}()
 
 	d.underlying.Call("close")
+	d.bufferedAmountLow.close()
 
 	// Release any handlers as required by the syscall/js API.
 	if d.onOpenHandler != nil {
@@ -144,9 +230,16 @@ func (d *DataChannel) Close() (err error) {
 	if d.onMessageHandler != nil {
 		d.onMessageHandler.Release()
 	}
+	if d.onErrorHandler != nil {
+		d.onErrorHandler.Release()
+	}
 	if d.onBufferedAmountLow != nil {
 		d.onBufferedAmountLow.Release()
 	}
+	if d.onCloseListener != nil {
+		d.underlying.Call("removeEventListener", "close", *d.onCloseListener)
+		d.onCloseListener.Release()
+	}
 
 	return nil
 }
@@ -192,6 +285,11 @@ func (d *DataChannel) Protocol() string {
 	return d.underlying.Get("protocol").String()
 }
 
+// Priority represents the priority of this DataChannel.
+func (d *DataChannel) Priority() PriorityType {
+	return newPriorityTypeFromString(d.underlying.Get("priority").String())
+}
+
 // Negotiated represents whether this DataChannel was negotiated by the
 // application (true), or not (false).
 func (d *DataChannel) Negotiated() bool {
@@ -232,29 +330,20 @@ func (d *DataChannel) BufferedAmount() uint64 {
 // event fires. BufferedAmountLowThreshold is initially zero on each new
 // DataChannel, but the application may change its value at any time.
 func (d *DataChannel) BufferedAmountLowThreshold() uint64 {
-	return uint64(d.underlying.Get("bufferedAmountLowThreshold").Int())
+	return d.bufferedAmountLow.getThreshold()
 }
 
 // SetBufferedAmountLowThreshold is used to update the threshold.
 // See BufferedAmountLowThreshold().
 func (d *DataChannel) SetBufferedAmountLowThreshold(th uint64) {
-	d.underlying.Set("bufferedAmountLowThreshold", th)
+	d.bufferedAmountLow.setThreshold(th)
 }
 
 // OnBufferedAmountLow sets an event handler which is invoked when
 // the number of bytes of outgoing data becomes lower than the
 // BufferedAmountLowThreshold.
 func (d *DataChannel) OnBufferedAmountLow(f func()) {
-	if d.onBufferedAmountLow != nil {
-		oldHandler := d.onBufferedAmountLow
-		defer oldHandler.Release()
-	}
-	onBufferedAmountLow := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		go f()
-		return js.Undefined()
-	})
-	d.onBufferedAmountLow = &onBufferedAmountLow
-	d.underlying.Set("onbufferedamountlow", onBufferedAmountLow)
+	d.bufferedAmountLow.setHandler(f)
 }
 
 // valueToDataChannelMessage converts the given value to a DataChannelMessage.
@@ -285,14 +374,16 @@ func valueToDataChannelMessage(val js.Value) DataChannelMessage {
 		// channel to signal when reading is done.
 		reader := js.Global().Get("FileReader").New()
 		doneChan := make(chan struct{})
-		reader.Call("addEventListener", "loadend", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		onLoadEnd := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 			go func() {
 				// Signal that the FileReader is done reading/loading by sending through
 				// the doneChan.
 				doneChan <- struct{}{}
 			}()
 			return js.Undefined()
-		}))
+		})
+		defer onLoadEnd.Release()
+		reader.Call("addEventListener", "loadend", onLoadEnd)
 
 		reader.Call("readAsArrayBuffer", val)
 
@@ -3,57 +3,127 @@
 package webrtc
 
 import (
-	"errors"
+	"context"
+	"io"
+	"sync"
+	"syscall/js"
+	"time"
+
+	"github.com/pion/datachannel"
+	"github.com/pion/transport/deadline"
 )
 
+// DetachedDataChannel is the ReadWriteCloser returned by DataChannel.Detach.
+// Like a net.Conn, it supports deadlines: a Read or a Write called after the
+// deadline, or a blocked Read when the deadline is reached, fails with
+// context.DeadlineExceeded, a net.Error timeout. A zero time means no
+// deadline. A Read fails with io.EOF once the channel is closed by either
+// peer.
+type DetachedDataChannel interface {
+	datachannel.ReadWriteCloser
+
+	SetDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+// detachedDataChannel reads the messages of the browser DataChannel through
+// its message and close events, the listeners don't replace the handlers set
+// with OnMessage and OnClose.
 type detachedDataChannel struct {
 	dc *DataChannel
 
-	read chan DataChannelMessage
-	done chan struct{}
+	read      chan DataChannelMessage
+	done      chan struct{}
+	closeOnce sync.Once
+
+	onMessage js.Func
+	onClose   js.Func
+
+	readDeadline  *deadline.Deadline
+	writeDeadline *deadline.Deadline
 }
 
 func newDetachedDataChannel(dc *DataChannel) *detachedDataChannel {
-	read := make(chan DataChannelMessage)
-	done := make(chan struct{})
+	c := &detachedDataChannel{
+		dc:            dc,
+		read:          make(chan DataChannelMessage),
+		done:          make(chan struct{}),
+		readDeadline:  deadline.New(),
+		writeDeadline: deadline.New(),
+	}
 
-	// Wire up callbacks
-	dc.OnMessage(func(msg DataChannelMessage) {
-		read <- msg // pion/webrtc/projects/15
+	c.onMessage = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		data := args[0].Get("data")
+		go func() {
+			// valueToDataChannelMessage may block when handling 'Blob' data
+			msg := valueToDataChannelMessage(data)
+			select {
+			case c.read <- msg:
+			case <-c.done:
+			}
+		}()
+		return js.Undefined()
+	})
+	c.onClose = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go c.close()
+		return js.Undefined()
 	})
+	dc.underlying.Call("addEventListener", "message", c.onMessage)
+	dc.underlying.Call("addEventListener", "close", c.onClose)
 
-	// pion/webrtc/projects/15
+	return c
+}
 
-	return &detachedDataChannel{
-		dc:   dc,
-		read: read,
-		done: done,
-	}
+// close unblocks the reads and removes the event listeners
+func (c *detachedDataChannel) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.dc.underlying.Call("removeEventListener", "message", c.onMessage)
+		c.dc.underlying.Call("removeEventListener", "close", c.onClose)
+		c.onMessage.Release()
+		c.onClose.Release()
+	})
 }
 
+// Read reads a message
 func (c *detachedDataChannel) Read(p []byte) (int, error) {
 	n, _, err := c.ReadDataChannel(p)
 	return n, err
 }
 
+// ReadDataChannel reads a message and reports if it's a string, until the
+// read deadline
 func (c *detachedDataChannel) ReadDataChannel(p []byte) (int, bool, error) {
+	if err := c.readDeadline.Err(); err != nil {
+		return 0, false, err
+	}
+
 	select {
-	case <-c.done:
-		return 0, false, errors.New("Reader closed")
 	case msg := <-c.read:
-		n := copy(p, msg.Data)
-		if n < len(msg.Data) {
-			return n, msg.IsString, errors.New("Read buffer to small")
+		if len(msg.Data) > len(p) {
+			return 0, false, io.ErrShortBuffer
 		}
-		return n, msg.IsString, nil
+		return copy(p, msg.Data), msg.IsString, nil
+	case <-c.done:
+		return 0, false, io.EOF
+	case <-c.readDeadline.Done():
+		return 0, false, context.DeadlineExceeded
 	}
 }
 
+// Write writes a binary message
 func (c *detachedDataChannel) Write(p []byte) (n int, err error) {
 	return c.WriteDataChannel(p, false)
 }
 
+// WriteDataChannel writes a message, the write doesn't block so the write
+// deadline only rejects the messages written after it
 func (c *detachedDataChannel) WriteDataChannel(p []byte, isString bool) (n int, err error) {
+	if err = c.writeDeadline.Err(); err != nil {
+		return 0, err
+	}
+
 	if isString {
 		err = c.dc.SendText(string(p))
 		return len(p), err
@@ -64,8 +134,28 @@ func (c *detachedDataChannel) WriteDataChannel(p []byte, isString bool) (n int,
 	return len(p), err
 }
 
+// Close closes the DataChannel, like DataChannel.Close
 func (c *detachedDataChannel) Close() error {
-	close(c.done)
+	c.close()
 
 	return c.dc.Close()
 }
+
+// SetDeadline sets the read and write deadlines
+func (c *detachedDataChannel) SetDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	c.writeDeadline.Set(t)
+	return nil
+}
+
+// SetReadDeadline sets the deadline of the reads
+func (c *detachedDataChannel) SetReadDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	return nil
+}
+
+// SetWriteDeadline sets the deadline of the writes
+func (c *detachedDataChannel) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline.Set(t)
+	return nil
+}
@@ -9,7 +9,9 @@ type DataChannelInit struct {
 
 	// MaxPacketLifeTime limits the time (in milliseconds) during which the
 	// channel will transmit or retransmit data if not acknowledged. This value
-	// may be clamped if it exceeds the maximum value supported.
+	// may be clamped if it exceeds the maximum value supported. The time is
+	// counted from the first transmission of the data, the data waiting for
+	// the congestion window isn't abandoned.
 	MaxPacketLifeTime *uint16
 
 	// MaxRetransmits limits the number of times a channel will retransmit data
@@ -25,9 +27,15 @@ type DataChannelInit struct {
 	// announce the channel in-band and instruct the other peer to dispatch a
 	// corresponding DataChannel. If set to true, it is up to the application
 	// to negotiate the channel and create an DataChannel with the same id
-	// at the other peer.
+	// at the other peer. A negotiated DataChannel must set the ID.
 	Negotiated *bool
 
-	// ID overrides the default selection of ID for this channel.
+	// ID overrides the default selection of ID for this channel. By default
+	// the DTLS client uses the even IDs and the server the odd ones, an ID
+	// already used by another channel is rejected.
 	ID *uint16
+
+	// Priority describes the priority of this channel. The default value of
+	// PriorityTypeLow is the normal priority of the in-band announcement.
+	Priority *PriorityType
 }
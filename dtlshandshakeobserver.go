@@ -0,0 +1,178 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	dtlsRecordHeaderSize     = 13
+	dtlsHandshakeHeaderSize  = 12
+	dtlsContentTypeHandshake = 22
+	dtlsHandshakeServerHello = 2
+
+	// the ServerHello version and random fields precede the session id
+	dtlsServerHelloSessionIDOffset = 34
+)
+
+// dtlsHandshakeObserver watches the handshake messages exchanged by the DTLS
+// connection to detect the retransmitted flights and the cipher suite chosen
+// by the server hello. Only the messages of the epoch 0 can be read, the
+// retransmissions of a flight with only encrypted messages, like the final
+// Finished one, aren't detected.
+type dtlsHandshakeObserver struct {
+	net.Conn
+
+	lock  sync.Mutex
+	start time.Time
+	done  bool
+	// sent are the sequence numbers of the handshake messages already sent
+	sent map[uint16]bool
+	// retransmitting is set while the datagrams of a retransmitted flight
+	// are written, lastRetransmitted is the first message of the last one
+	retransmitting    bool
+	lastRetransmitted uint16
+	retransmissions   int
+
+	cipherSuite      uint16
+	cipherSuiteKnown bool
+
+	onRetransmission func(elapsed time.Duration)
+}
+
+func newDTLSHandshakeObserver(conn net.Conn, onRetransmission func(elapsed time.Duration)) *dtlsHandshakeObserver {
+	return &dtlsHandshakeObserver{
+		Conn:             conn,
+		start:            time.Now(),
+		sent:             map[uint16]bool{},
+		onRetransmission: onRetransmission,
+	}
+}
+
+// Read inspects the handshake messages of a received datagram
+func (o *dtlsHandshakeObserver) Read(b []byte) (int, error) {
+	n, err := o.Conn.Read(b)
+	if err == nil {
+		o.lock.Lock()
+		if !o.done {
+			o.observeServerHello(handshakeMessages(b[:n]))
+		}
+		o.lock.Unlock()
+	}
+	return n, err
+}
+
+// Write inspects the handshake messages of a datagram before writing it
+func (o *dtlsHandshakeObserver) Write(b []byte) (int, error) {
+	o.lock.Lock()
+	if !o.done {
+		o.observe(b)
+	}
+	o.lock.Unlock()
+
+	return o.Conn.Write(b)
+}
+
+// handshakeMessages returns the handshake messages of the epoch 0 carried by
+// a datagram, starting with their handshake header
+func handshakeMessages(datagram []byte) [][]byte {
+	messages := [][]byte{}
+	for len(datagram) >= dtlsRecordHeaderSize {
+		length := int(binary.BigEndian.Uint16(datagram[11:]))
+		epoch := binary.BigEndian.Uint16(datagram[3:])
+		record := datagram[dtlsRecordHeaderSize:]
+		if length > len(record) {
+			break
+		}
+		if datagram[0] == dtlsContentTypeHandshake && epoch == 0 && length >= dtlsHandshakeHeaderSize {
+			messages = append(messages, record[:length])
+		}
+		datagram = record[length:]
+	}
+	return messages
+}
+
+// observeServerHello records the cipher suite of the first fragment of a
+// server hello message
+func (o *dtlsHandshakeObserver) observeServerHello(messages [][]byte) {
+	for _, message := range messages {
+		fragmentOffset := uint32(message[6])<<16 | uint32(message[7])<<8 | uint32(message[8])
+		if message[0] != dtlsHandshakeServerHello || fragmentOffset != 0 {
+			continue
+		}
+
+		body := message[dtlsHandshakeHeaderSize:]
+		if len(body) <= dtlsServerHelloSessionIDOffset {
+			continue
+		}
+		offset := dtlsServerHelloSessionIDOffset + 1 + int(body[dtlsServerHelloSessionIDOffset])
+		if len(body) < offset+2 {
+			continue
+		}
+		o.cipherSuite = binary.BigEndian.Uint16(body[offset:])
+		o.cipherSuiteKnown = true
+	}
+}
+
+func (o *dtlsHandshakeObserver) observe(datagram []byte) {
+	messages := handshakeMessages(datagram)
+	o.observeServerHello(messages)
+
+	seqs := []uint16{}
+	for _, message := range messages {
+		seqs = append(seqs, binary.BigEndian.Uint16(message[4:]))
+	}
+	if len(seqs) == 0 {
+		return
+	}
+
+	retransmitted := true
+	first := seqs[0]
+	for _, seq := range seqs {
+		if !o.sent[seq] {
+			retransmitted = false
+		}
+		o.sent[seq] = true
+		if seq < first {
+			first = seq
+		}
+	}
+
+	if !retransmitted {
+		o.retransmitting = false
+		return
+	}
+	// the next datagrams of a retransmitted flight carry the next messages
+	if o.retransmitting && first > o.lastRetransmitted {
+		return
+	}
+	o.retransmitting = true
+	o.lastRetransmitted = first
+	o.retransmissions++
+	if o.onRetransmission != nil {
+		go o.onRetransmission(time.Since(o.start))
+	}
+}
+
+// finish stops the observation when the handshake is over, and returns its
+// duration and the count of retransmitted flights
+func (o *dtlsHandshakeObserver) finish() (time.Duration, int) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.done = true
+	return time.Since(o.start), o.retransmissions
+}
+
+// negotiatedCipherSuite returns the cipher suite chosen by the server hello,
+// false if none was observed
+func (o *dtlsHandshakeObserver) negotiatedCipherSuite() (uint16, bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	return o.cipherSuite, o.cipherSuiteKnown
+}
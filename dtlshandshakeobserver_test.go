@@ -0,0 +1,139 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// dtlsHandshakeRecord returns a record of a handshake message of the epoch
+// 0, or an encrypted record of the epoch 1
+func dtlsHandshakeRecord(epoch, messageSeq uint16) []byte {
+	record := make([]byte, dtlsRecordHeaderSize+dtlsHandshakeHeaderSize)
+	record[0] = dtlsContentTypeHandshake
+	binary.BigEndian.PutUint16(record[3:], epoch)
+	binary.BigEndian.PutUint16(record[11:], dtlsHandshakeHeaderSize)
+	binary.BigEndian.PutUint16(record[dtlsRecordHeaderSize+4:], messageSeq)
+	return record
+}
+
+func TestDTLSHandshakeObserver(t *testing.T) {
+	a, b := net.Pipe()
+	defer func() {
+		assert.NoError(t, a.Close())
+		assert.NoError(t, b.Close())
+	}()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			if _, err := b.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	retransmitted := make(chan time.Duration, 10)
+	o := newDTLSHandshakeObserver(a, func(elapsed time.Duration) {
+		retransmitted <- elapsed
+	})
+
+	write := func(records ...[]byte) {
+		datagram := []byte{}
+		for _, record := range records {
+			datagram = append(datagram, record...)
+		}
+		n, err := o.Write(datagram)
+		assert.NoError(t, err)
+		assert.Equal(t, len(datagram), n)
+	}
+
+	// a flight sent in two datagrams, then retransmitted twice
+	write(dtlsHandshakeRecord(0, 0), dtlsHandshakeRecord(0, 1))
+	write(dtlsHandshakeRecord(0, 2))
+	for i := 0; i < 2; i++ {
+		write(dtlsHandshakeRecord(0, 0), dtlsHandshakeRecord(0, 1))
+		write(dtlsHandshakeRecord(0, 2))
+	}
+	<-retransmitted
+	<-retransmitted
+
+	// the next flight, with an encrypted message, isn't a retransmission
+	write(dtlsHandshakeRecord(0, 3), dtlsHandshakeRecord(1, 4))
+	write(dtlsHandshakeRecord(1, 4))
+
+	_, retransmissions := o.finish()
+	assert.Equal(t, 2, retransmissions)
+
+	// the records aren't observed after the handshake
+	write(dtlsHandshakeRecord(0, 0))
+	_, retransmissions = o.finish()
+	assert.Equal(t, 2, retransmissions)
+}
+
+func TestDTLSHandshakeObserver_CipherSuite(t *testing.T) {
+	a, b := net.Pipe()
+	defer func() {
+		assert.NoError(t, a.Close())
+		assert.NoError(t, b.Close())
+	}()
+
+	// a server hello with a 4 bytes session id, after an encrypted record
+	body := make([]byte, dtlsServerHelloSessionIDOffset+1+4+3)
+	body[dtlsServerHelloSessionIDOffset] = 4
+	binary.BigEndian.PutUint16(body[dtlsServerHelloSessionIDOffset+5:], 0xc02b)
+	serverHello := dtlsHandshakeRecord(0, 1)
+	serverHello[dtlsRecordHeaderSize] = dtlsHandshakeServerHello
+	binary.BigEndian.PutUint16(serverHello[11:], uint16(dtlsHandshakeHeaderSize+len(body)))
+	serverHello = append(serverHello, body...)
+
+	o := newDTLSHandshakeObserver(a, nil)
+	_, ok := o.negotiatedCipherSuite()
+	assert.False(t, ok)
+
+	go func() {
+		_, err := b.Write(append(dtlsHandshakeRecord(1, 0), serverHello...))
+		assert.NoError(t, err)
+	}()
+	buf := make([]byte, 1500)
+	_, err := o.Read(buf)
+	assert.NoError(t, err)
+
+	cipherSuite, ok := o.negotiatedCipherSuite()
+	assert.True(t, ok)
+	assert.Equal(t, uint16(0xc02b), cipherSuite)
+}
+
+func TestDTLSTransport_HandshakeHandlers(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	started := make(chan DTLSRole, 1)
+	completed := make(chan error, 1)
+	pcAnswer.dtlsTransport.OnHandshakeStart(func(role DTLSRole) {
+		started <- role
+	})
+	pcAnswer.dtlsTransport.OnHandshakeComplete(func(elapsed time.Duration, _ int, err error) {
+		assert.True(t, elapsed > 0)
+		completed <- err
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	assert.Equal(t, DTLSRoleServer, <-started)
+	assert.NoError(t, <-completed)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
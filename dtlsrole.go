@@ -64,6 +64,25 @@ func dtlsRoleFromRemoteSDP(sessionDescription *sdp.SessionDescription) DTLSRole
 	return DTLSRoleAuto
 }
 
+// negotiatedDTLSRole returns the local DTLS role negotiated by the provided
+// local and remote descriptions. If no role has been negotiated yet we return
+// DTLSRoleAuto
+func negotiatedDTLSRole(local, remote *SessionDescription) DTLSRole {
+	if local == nil || remote == nil {
+		return DTLSRoleAuto
+	}
+
+	switch dtlsRoleFromRemoteSDP(remote.parsed) {
+	case DTLSRoleClient:
+		return DTLSRoleServer
+	case DTLSRoleServer:
+		return DTLSRoleClient
+	}
+
+	// remote offered actpass, the role is the one of our answer
+	return dtlsRoleFromRemoteSDP(local.parsed)
+}
+
 func connectionRoleFromDtlsRole(d DTLSRole) sdp.ConnectionRole {
 	switch d {
 	case DTLSRoleClient:
@@ -0,0 +1,17 @@
+package webrtc
+
+// DTLSSecurityParameters holds the security parameters negotiated by the
+// DTLS handshake.
+type DTLSSecurityParameters struct {
+	// Version is the DTLS protocol version, like "DTLS 1.2"
+	Version string `json:"version"`
+
+	// CipherSuite is the name of the DTLS cipher suite in the IANA
+	// registry, like "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"
+	CipherSuite string `json:"cipherSuite"`
+
+	// SRTPProtectionProfile is the name of the SRTP protection profile in
+	// the IANA registry, like "SRTP_AES128_CM_HMAC_SHA1_80". It's empty when
+	// no profile is negotiated.
+	SRTPProtectionProfile string `json:"srtpProtectionProfile"`
+}
@@ -3,6 +3,8 @@
 package webrtc
 
 import (
+	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -10,12 +12,14 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/pion/dtls/v2"
 	"github.com/pion/dtls/v2/pkg/crypto/fingerprint"
+	"github.com/pion/logging"
 	"github.com/pion/srtp"
 	"github.com/pion/webrtc/v2/internal/mux"
 	"github.com/pion/webrtc/v2/internal/util"
@@ -29,15 +33,21 @@ import (
 type DTLSTransport struct {
 	lock sync.RWMutex
 
-	iceTransport      *ICETransport
-	certificates      []Certificate
-	remoteParameters  DTLSParameters
-	remoteCertificate []byte
-	state             DTLSTransportState
+	iceTransport       *ICETransport
+	certificates       []Certificate
+	remoteParameters   DTLSParameters
+	remoteCertificates [][]byte
+	state              DTLSTransportState
 
-	onStateChangeHdlr func(DTLSTransportState)
+	onStateChangeHdlr             func(DTLSTransportState)
+	onHandshakeStartHdlr          func(DTLSRole)
+	onHandshakeRetransmissionHdlr func(time.Duration)
+	onHandshakeCompleteHdlr       func(time.Duration, int, error)
 
 	conn *dtls.Conn
+	// cipherSuite is the cipher suite chosen by the server hello
+	cipherSuite      uint16
+	cipherSuiteKnown bool
 
 	srtpSession   *srtp.SessionSRTP
 	srtcpSession  *srtp.SessionSRTCP
@@ -47,6 +57,7 @@ type DTLSTransport struct {
 	dtlsMatcher mux.MatchFunc
 
 	api *API
+	log logging.LeveledLogger
 }
 
 // NewDTLSTransport creates a new DTLSTransport.
@@ -58,6 +69,7 @@ func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certifi
 		api:          api,
 		state:        DTLSTransportStateNew,
 		dtlsMatcher:  mux.MatchDTLS,
+		log:          api.settingEngine.LoggerFactory.NewLogger("ortc"),
 	}
 
 	if len(certificates) > 0 {
@@ -68,6 +80,11 @@ func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certifi
 			}
 			t.certificates = append(t.certificates, x509Cert)
 		}
+		if api.settingEngine.fipsMode {
+			if err := fipsCheckCertificates(t.certificates); err != nil {
+				return nil, err
+			}
+		}
 	} else {
 		sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 		if err != nil {
@@ -108,6 +125,33 @@ func (t *DTLSTransport) OnStateChange(f func(DTLSTransportState)) {
 	t.onStateChangeHdlr = f
 }
 
+// OnHandshakeStart sets a handler that is fired when the DTLS handshake
+// starts, with the local role.
+func (t *DTLSTransport) OnHandshakeStart(f func(role DTLSRole)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.onHandshakeStartHdlr = f
+}
+
+// OnHandshakeRetransmission sets a handler that is fired when a flight of
+// handshake messages is retransmitted, with the time elapsed since the start
+// of the handshake. The retransmissions of the flights with only encrypted
+// messages, like the last one of the DTLS server, aren't reported.
+func (t *DTLSTransport) OnHandshakeRetransmission(f func(elapsed time.Duration)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.onHandshakeRetransmissionHdlr = f
+}
+
+// OnHandshakeComplete sets a handler that is fired when the DTLS handshake
+// completes, or fails with err, with its duration and the count of the
+// retransmitted flights. It can measure the connection setup latency.
+func (t *DTLSTransport) OnHandshakeComplete(f func(elapsed time.Duration, retransmissions int, err error)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.onHandshakeCompleteHdlr = f
+}
+
 // State returns the current dtls transport state.
 func (t *DTLSTransport) State() DTLSTransportState {
 	t.lock.RLock()
@@ -134,12 +178,88 @@ func (t *DTLSTransport) GetLocalParameters() (DTLSParameters, error) {
 	}, nil
 }
 
-// GetRemoteCertificate returns the certificate chain in use by the remote side
-// returns an empty list prior to selection of the remote certificate
+// GetRemoteCertificate returns the DER encoded certificate in use by the
+// remote side, the first one of GetRemoteCertificates. It returns nil prior
+// to the completion of the DTLS handshake.
 func (t *DTLSTransport) GetRemoteCertificate() []byte {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
-	return t.remoteCertificate
+	if len(t.remoteCertificates) == 0 {
+		return nil
+	}
+	return t.remoteCertificates[0]
+}
+
+// GetRemoteCertificates returns the DER encoded certificate chain in use by
+// the remote side, starting with its own certificate. It returns nil prior to
+// the completion of the DTLS handshake.
+func (t *DTLSTransport) GetRemoteCertificates() [][]byte {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if len(t.remoteCertificates) == 0 {
+		return nil
+	}
+	return append([][]byte{}, t.remoteCertificates...)
+}
+
+// dtlsVersion is the only DTLS version supported by pion/dtls
+const dtlsVersion = "DTLS 1.2"
+
+// GetSecurityParameters returns the security parameters negotiated by the
+// DTLS handshake, to log or audit them.
+func (t *DTLSTransport) GetSecurityParameters() (DTLSSecurityParameters, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.conn == nil {
+		return DTLSSecurityParameters{}, &rtcerr.InvalidStateError{Err: ErrDTLSTransportNotConnected}
+	}
+
+	params := DTLSSecurityParameters{
+		Version: dtlsVersion,
+	}
+	if t.cipherSuiteKnown {
+		params.CipherSuite = dtls.CipherSuiteID(t.cipherSuite).String()
+	}
+	if profile, ok := t.conn.SelectedSRTPProtectionProfile(); ok {
+		params.SRTPProtectionProfile = srtpProtectionProfileName(profile)
+	}
+	return params, nil
+}
+
+// srtpProtectionProfileName returns the IANA name of a SRTP protection
+// profile
+func srtpProtectionProfileName(profile dtls.SRTPProtectionProfile) string {
+	switch profile {
+	case dtls.SRTP_AES128_CM_HMAC_SHA1_80:
+		return "SRTP_AES128_CM_HMAC_SHA1_80"
+	default:
+		return fmt.Sprintf("unknown(%v)", uint16(profile))
+	}
+}
+
+// srtpKeyingMaterialLabel is the label of the keying material of the SRTP
+// keys (RFC 5764 S4.2)
+const srtpKeyingMaterialLabel = "EXTRACTOR-dtls_srtp"
+
+// ExportKeyingMaterial returns length bytes of keying material exported from
+// the DTLS session (RFC 5705). Both sides derive the same secret with the
+// same label, so it can authenticate the application messages as coming from
+// the peer of this DTLS session. The label of the SRTP keys and the ones
+// used by the TLS PRF are reserved, and the context value isn't supported by
+// pion/dtls.
+func (t *DTLSTransport) ExportKeyingMaterial(label string, context []byte, length int) ([]byte, error) {
+	if label == srtpKeyingMaterialLabel {
+		return nil, &rtcerr.InvalidAccessError{Err: ErrReservedKeyingMaterialLabel}
+	}
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.conn == nil {
+		return nil, &rtcerr.InvalidStateError{Err: ErrDTLSTransportNotConnected}
+	}
+
+	connState := t.conn.ConnectionState()
+	return connState.ExportKeyingMaterial(label, context, length)
 }
 
 func (t *DTLSTransport) startSRTP() error {
@@ -150,6 +270,8 @@ func (t *DTLSTransport) startSRTP() error {
 		return nil
 	} else if t.conn == nil {
 		return fmt.Errorf("the DTLS transport has not started yet")
+	} else if t.api.settingEngine.dataChannelOnly {
+		return &rtcerr.InvalidStateError{Err: ErrDataChannelOnly}
 	}
 
 	srtpConfig := &srtp.Config{
@@ -190,12 +312,19 @@ func (t *DTLSTransport) startSRTP() error {
 		return fmt.Errorf("failed to extract sctp session keys: %v", err)
 	}
 
-	srtpSession, err := srtp.NewSessionSRTP(t.srtpEndpoint, srtpConfig)
+	var srtpConn, srtcpConn net.Conn = t.srtpEndpoint, t.srtcpEndpoint
+	if t.api.settingEngine.disableSRTPOverVNet {
+		if srtpConn, srtcpConn, err = t.plaintextRTPConns(srtpConfig); err != nil {
+			return fmt.Errorf("failed to start plaintext rtp: %v", err)
+		}
+	}
+
+	srtpSession, err := srtp.NewSessionSRTP(srtpConn, srtpConfig)
 	if err != nil {
 		return fmt.Errorf("failed to start srtp: %v", err)
 	}
 
-	srtcpSession, err := srtp.NewSessionSRTCP(t.srtcpEndpoint, srtpConfig)
+	srtcpSession, err := srtp.NewSessionSRTCP(srtcpConn, srtpConfig)
 	if err != nil {
 		return fmt.Errorf("failed to start srtp: %v", err)
 	}
@@ -205,6 +334,24 @@ func (t *DTLSTransport) startSRTP() error {
 	return nil
 }
 
+// plaintextRTPConns returns the conns carrying the SRTP and SRTCP sessions
+// in clear, only over a virtual VNet
+func (t *DTLSTransport) plaintextRTPConns(srtpConfig *srtp.Config) (net.Conn, net.Conn, error) {
+	if n := t.api.settingEngine.vnet; n == nil || !n.IsVirtual() {
+		return nil, nil, ErrSRTPDisabledWithoutVNet
+	}
+
+	srtpConn, err := newPlaintextRTPConn(t.srtpEndpoint, srtpConfig, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	srtcpConn, err := newPlaintextRTPConn(t.srtcpEndpoint, srtpConfig, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return srtpConn, srtcpConn, nil
+}
+
 func (t *DTLSTransport) getSRTPSession() (*srtp.SessionSRTP, error) {
 	t.lock.RLock()
 	if t.srtpSession != nil {
@@ -236,6 +383,11 @@ func (t *DTLSTransport) getSRTCPSession() (*srtp.SessionSRTCP, error) {
 }
 
 func (t *DTLSTransport) role() DTLSRole {
+	// If SettingEngine forces the role, ignore the remote one
+	if role := t.api.settingEngine.dtlsRole; role != DTLSRole(0) {
+		return role
+	}
+
 	// If remote has an explicit role use the inverse
 	switch t.remoteParameters.Role {
 	case DTLSRoleClient:
@@ -275,8 +427,10 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 			return DTLSRole(0), nil, &rtcerr.InvalidStateError{Err: fmt.Errorf("attempted to start DTLSTransport that is not in new state: %s", t.state)}
 		}
 
-		t.srtpEndpoint = t.iceTransport.NewEndpoint(mux.MatchSRTP)
-		t.srtcpEndpoint = t.iceTransport.NewEndpoint(mux.MatchSRTCP)
+		if !t.api.settingEngine.dataChannelOnly {
+			t.srtpEndpoint = t.iceTransport.NewEndpoint(mux.MatchSRTP)
+			t.srtcpEndpoint = t.iceTransport.NewEndpoint(mux.MatchSRTCP)
+		}
 		t.remoteParameters = remoteParameters
 
 		cert := t.certificates[0]
@@ -302,16 +456,45 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 		return err
 	}
 
+	t.lock.RLock()
+	onHandshakeStart := t.onHandshakeStartHdlr
+	onHandshakeRetransmission := t.onHandshakeRetransmissionHdlr
+	onHandshakeComplete := t.onHandshakeCompleteHdlr
+	t.lock.RUnlock()
+
+	// the handshake is observed to report the retransmissions and the
+	// negotiated cipher suite
+	observer := newDTLSHandshakeObserver(dtlsEndpoint, onHandshakeRetransmission)
+	if onHandshakeStart != nil {
+		go onHandshakeStart(role)
+	}
+
 	if t.api.settingEngine.replayProtection.DTLS != nil {
 		dtlsConfig.ReplayProtectionWindow = int(*t.api.settingEngine.replayProtection.DTLS)
 	}
 
+	if t.api.settingEngine.fipsMode {
+		applyFIPSMode(dtlsConfig)
+	}
+
+	dtlsConfig.FlightInterval = t.api.settingEngine.timeout.DTLSFlightInterval
+	if handshakeTimeout := t.api.settingEngine.timeout.DTLSHandshake; handshakeTimeout != 0 {
+		dtlsConfig.ConnectContextMaker = func() (context.Context, func()) {
+			return context.WithTimeout(context.Background(), handshakeTimeout)
+		}
+	}
+
 	// Connect as DTLS Client/Server, function is blocking and we
 	// must not hold the DTLSTransport lock
 	if role == DTLSRoleClient {
-		dtlsConn, err = dtls.Client(dtlsEndpoint, dtlsConfig)
+		dtlsConn, err = dtls.Client(observer, dtlsConfig)
 	} else {
-		dtlsConn, err = dtls.Server(dtlsEndpoint, dtlsConfig)
+		dtlsConn, err = dtls.Server(observer, dtlsConfig)
+	}
+
+	elapsed, retransmissions := observer.finish()
+	if onHandshakeComplete != nil {
+		go onHandshakeComplete(elapsed, retransmissions, err)
 	}
 
 	// Re-take the lock, nothing beyond here is blocking
@@ -324,27 +507,40 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 	}
 
 	t.conn = dtlsConn
+	t.cipherSuite, t.cipherSuiteKnown = observer.negotiatedCipherSuite()
+	t.remoteCertificates = t.conn.ConnectionState().PeerCertificates
 	t.onStateChange(DTLSTransportStateConnected)
 
-	if t.api.settingEngine.disableCertificateFingerprintVerification {
+	verifyPeerCertificate := t.api.settingEngine.verifyDTLSPeerCertificate
+	if t.api.settingEngine.disableCertificateFingerprintVerification && verifyPeerCertificate == nil {
 		return nil
 	}
 
 	// Check the fingerprint if a certificate was exchanged
-	remoteCerts := t.conn.ConnectionState().PeerCertificates
-	if len(remoteCerts) == 0 {
+	if len(t.remoteCertificates) == 0 {
 		t.onStateChange(DTLSTransportStateFailed)
 		return fmt.Errorf("peer didn't provide certificate via DTLS")
 	}
-	t.remoteCertificate = remoteCerts[0]
 
-	parsedRemoteCert, err := x509.ParseCertificate(t.remoteCertificate)
+	parsedRemoteCert, err := x509.ParseCertificate(t.remoteCertificates[0])
 	if err != nil {
 		t.onStateChange(DTLSTransportStateFailed)
 		return err
 	}
 
-	err = t.validateFingerPrint(parsedRemoteCert)
+	if t.api.settingEngine.fipsMode {
+		if err = fipsCheckPublicKey(parsedRemoteCert.PublicKey); err != nil {
+			t.onStateChange(DTLSTransportStateFailed)
+			return err
+		}
+	}
+
+	if !t.api.settingEngine.disableCertificateFingerprintVerification {
+		err = t.validateFingerPrint(parsedRemoteCert)
+	}
+	if verifyPeerCertificate != nil {
+		err = verifyPeerCertificate(parsedRemoteCert, err)
+	}
 	if err != nil {
 		t.onStateChange(DTLSTransportStateFailed)
 	}
@@ -381,9 +577,31 @@ func (t *DTLSTransport) Stop() error {
 	return util.FlattenErrs(closeErrs)
 }
 
+// strongestFingerprints returns the fingerprints using the strongest
+// supported hash function, the one to verify when multiple ones are provided
+// (RFC 8122 5)
+func strongestFingerprints(fingerprints []DTLSFingerprint) []DTLSFingerprint {
+	strongest := []DTLSFingerprint{}
+	var strongestHash crypto.Hash
+	for _, fp := range fingerprints {
+		hashAlgo, err := fingerprint.HashFromString(strings.ToLower(fp.Algorithm))
+		if err != nil || !hashAlgo.Available() {
+			continue
+		}
+		switch {
+		case len(strongest) == 0 || hashAlgo.Size() > strongestHash.Size():
+			strongest = []DTLSFingerprint{fp}
+			strongestHash = hashAlgo
+		case hashAlgo == strongestHash:
+			strongest = append(strongest, fp)
+		}
+	}
+	return strongest
+}
+
 func (t *DTLSTransport) validateFingerPrint(remoteCert *x509.Certificate) error {
-	for _, fp := range t.remoteParameters.Fingerprints {
-		hashAlgo, err := fingerprint.HashFromString(fp.Algorithm)
+	for _, fp := range strongestFingerprints(t.remoteParameters.Fingerprints) {
+		hashAlgo, err := fingerprint.HashFromString(strings.ToLower(fp.Algorithm))
 		if err != nil {
 			return err
 		}
@@ -4,11 +4,18 @@ package webrtc
 
 import (
 	"context"
+	"crypto/x509"
+	"errors"
 	"regexp"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/pion/srtp"
 	"github.com/pion/transport/test"
+	"github.com/pion/transport/vnet"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -56,9 +63,9 @@ func TestInvalidFingerprintCausesFailed(t *testing.T) {
 
 	select {
 	case offer := <-offerChan:
-		// Replace with invalid fingerprint
-		re := regexp.MustCompile(`sha-256 (.*?)\r`)
-		offer.SDP = re.ReplaceAllString(offer.SDP, "sha-256 AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA\r")
+		// Replace with invalid fingerprints
+		re := regexp.MustCompile(`(sha-\d+) (.*?)\r`)
+		offer.SDP = re.ReplaceAllString(offer.SDP, "$1 AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA:AA\r")
 
 		if err := pcAnswer.SetRemoteDescription(offer); err != nil {
 			t.Fatal(err)
@@ -134,3 +141,298 @@ func TestPeerConnection_DTLSRoleSettingEngine(t *testing.T) {
 		runTest(DTLSRoleClient)
 	})
 }
+
+func TestStrongestFingerprints(t *testing.T) {
+	assert.Equal(t, []DTLSFingerprint{{Algorithm: "SHA-512", Value: "CC"}, {Algorithm: "sha-512", Value: "DD"}}, strongestFingerprints([]DTLSFingerprint{
+		{Algorithm: "sha-256", Value: "AA"},
+		{Algorithm: "SHA-512", Value: "CC"},
+		{Algorithm: "foo", Value: "BB"},
+		{Algorithm: "sha-512", Value: "DD"},
+		{Algorithm: "sha-384", Value: "EE"},
+	}))
+	assert.Equal(t, []DTLSFingerprint{}, strongestFingerprints([]DTLSFingerprint{{Algorithm: "foo", Value: "BB"}}))
+}
+
+func TestPeerConnection_DTLSVerifyPeerCertificate(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	errPinning := errors.New("certificate not pinned")
+
+	for _, reject := range []bool{false, true} {
+		pcOffer, err := NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		verified := make(chan *x509.Certificate, 1)
+		s := SettingEngine{}
+		s.SetDTLSVerifyPeerCertificate(func(certificate *x509.Certificate, fingerprintErr error) error {
+			assert.NoError(t, fingerprintErr)
+			verified <- certificate
+			if reject {
+				return errPinning
+			}
+			return fingerprintErr
+		})
+		pcAnswer, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		expectedState := PeerConnectionStateConnected
+		if reject {
+			expectedState = PeerConnectionStateFailed
+		}
+		done := make(chan struct{})
+		pcAnswer.OnConnectionStateChange(func(state PeerConnectionState) {
+			if state == expectedState {
+				close(done)
+			}
+		})
+
+		assert.NoError(t, signalPair(pcOffer, pcAnswer))
+		<-done
+
+		certificate := <-verified
+		assert.Equal(t, pcOffer.configuration.Certificates[0].x509Cert.Raw, certificate.Raw)
+
+		assert.NoError(t, pcOffer.Close())
+		assert.NoError(t, pcAnswer.Close())
+	}
+}
+
+func TestPeerConnection_ForcedDTLSRole(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	offerSettings := SettingEngine{}
+	assert.NoError(t, offerSettings.SetDTLSRole(DTLSRoleServer))
+	pcOffer, err := NewAPI(WithSettingEngine(offerSettings)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerSettings := SettingEngine{}
+	assert.NoError(t, answerSettings.SetDTLSRole(DTLSRoleClient))
+	pcAnswer, err := NewAPI(WithSettingEngine(answerSettings)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=setup:passive")
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+
+	connected := make(chan struct{})
+	pcAnswer.OnConnectionStateChange(func(state PeerConnectionState) {
+		if state == PeerConnectionStateConnected {
+			close(connected)
+		}
+	})
+
+	// the offerer acts as server while announcing actpass, the answerer
+	// would be the server too without the forced role
+	<-pcOffer.GatheringCompletePromise()
+	offer = *pcOffer.LocalDescription()
+	offer.SDP = strings.Replace(offer.SDP, "a=setup:passive", "a=setup:actpass", -1)
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, answer.SDP, "a=setup:active")
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	<-pcAnswer.GatheringCompletePromise()
+	assert.NoError(t, pcOffer.SetRemoteDescription(*pcAnswer.LocalDescription()))
+
+	<-connected
+	assert.Equal(t, DTLSRoleServer, pcOffer.dtlsTransport.role())
+	assert.Equal(t, DTLSRoleClient, pcAnswer.dtlsTransport.role())
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestDTLSTransport_GetRemoteCertificates(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	// the certificate is available without the fingerprint verification too
+	s := SettingEngine{}
+	s.DisableCertificateFingerprintVerification(true)
+	pcAnswer, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	assert.Nil(t, pcAnswer.dtlsTransport.GetRemoteCertificate())
+	assert.Nil(t, pcAnswer.dtlsTransport.GetRemoteCertificates())
+
+	connected := make(chan struct{})
+	pcAnswer.OnConnectionStateChange(func(state PeerConnectionState) {
+		if state == PeerConnectionStateConnected {
+			close(connected)
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-connected
+
+	raw := pcOffer.configuration.Certificates[0].x509Cert.Raw
+	assert.Equal(t, raw, pcAnswer.dtlsTransport.GetRemoteCertificate())
+	assert.Equal(t, [][]byte{raw}, pcAnswer.dtlsTransport.GetRemoteCertificates())
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// Assert that the DTLS transport fails after the handshake timeout when the
+// remote peer doesn't answer
+func TestDTLSTransport_HandshakeTimeout(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.SetDTLSTimeouts(50*time.Millisecond, 500*time.Millisecond)
+	api := NewAPI(WithSettingEngine(s))
+
+	gatherer, err := api.NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+	iceTransport := api.NewICETransport(gatherer)
+	dtlsTransport, err := api.NewDTLSTransport(iceTransport, nil)
+	assert.NoError(t, err)
+	stackA := &testORTCStack{api: api, gatherer: gatherer, ice: iceTransport, dtls: dtlsTransport, sctp: api.NewSCTPTransport(dtlsTransport)}
+
+	stackB, err := newORTCStack()
+	assert.NoError(t, err)
+
+	sigA, err := stackA.getSignal()
+	assert.NoError(t, err)
+	sigB, err := stackB.getSignal()
+	assert.NoError(t, err)
+
+	// only the ICE transport of stackB is started
+	started := make(chan error)
+	go func() {
+		role := ICERoleControlled
+		assert.NoError(t, stackB.ice.SetRemoteCandidates(sigA.ICECandidates))
+		started <- stackB.ice.Start(nil, sigA.ICEParameters, &role)
+	}()
+	role := ICERoleControlling
+	assert.NoError(t, stackA.ice.SetRemoteCandidates(sigB.ICECandidates))
+	assert.NoError(t, stackA.ice.Start(nil, sigB.ICEParameters, &role))
+	assert.NoError(t, <-started)
+
+	start := time.Now()
+	assert.Error(t, stackA.dtls.Start(sigB.DTLSParameters))
+	assert.True(t, time.Since(start) < 5*time.Second, time.Since(start))
+	assert.Equal(t, DTLSTransportStateFailed, stackA.dtls.State())
+
+	assert.NoError(t, stackA.close())
+	assert.NoError(t, stackB.close())
+}
+
+func TestDTLSTransport_ExportKeyingMaterial(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = pcOffer.dtlsTransport.ExportKeyingMaterial("EXPORTER-test", nil, 32)
+	assert.Equal(t, &rtcerr.InvalidStateError{Err: ErrDTLSTransportNotConnected}, err)
+
+	connected := sync.WaitGroup{}
+	connected.Add(2)
+	for _, pc := range []*PeerConnection{pcOffer, pcAnswer} {
+		pc.OnConnectionStateChange(func(state PeerConnectionState) {
+			if state == PeerConnectionStateConnected {
+				connected.Done()
+			}
+		})
+	}
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	connected.Wait()
+
+	offerKey, err := pcOffer.dtlsTransport.ExportKeyingMaterial("EXPORTER-test", nil, 32)
+	assert.NoError(t, err)
+	answerKey, err := pcAnswer.dtlsTransport.ExportKeyingMaterial("EXPORTER-test", nil, 32)
+	assert.NoError(t, err)
+	assert.Len(t, offerKey, 32)
+	assert.Equal(t, offerKey, answerKey)
+
+	otherKey, err := pcAnswer.dtlsTransport.ExportKeyingMaterial("EXPORTER-other", nil, 32)
+	assert.NoError(t, err)
+	assert.NotEqual(t, offerKey, otherKey)
+
+	// the SRTP keys can't be exported
+	_, err = pcAnswer.dtlsTransport.ExportKeyingMaterial("EXTRACTOR-dtls_srtp", nil, 32)
+	assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrReservedKeyingMaterialLabel}, err)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestDTLSTransport_GetSecurityParameters(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = pcOffer.dtlsTransport.GetSecurityParameters()
+	assert.Equal(t, &rtcerr.InvalidStateError{Err: ErrDTLSTransportNotConnected}, err)
+
+	connected := sync.WaitGroup{}
+	connected.Add(2)
+	for _, pc := range []*PeerConnection{pcOffer, pcAnswer} {
+		pc.OnConnectionStateChange(func(state PeerConnectionState) {
+			if state == PeerConnectionStateConnected {
+				connected.Done()
+			}
+		})
+	}
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	connected.Wait()
+
+	expected := DTLSSecurityParameters{
+		Version:               "DTLS 1.2",
+		CipherSuite:           "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		SRTPProtectionProfile: "SRTP_AES128_CM_HMAC_SHA1_80",
+	}
+	for _, pc := range []*PeerConnection{pcOffer, pcAnswer} {
+		params, err := pc.dtlsTransport.GetSecurityParameters()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, params)
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestDTLSTransport_PlaintextRTPWithoutVNet(t *testing.T) {
+	for _, n := range []*vnet.Net{nil, vnet.NewNet(nil)} {
+		s := SettingEngine{}
+		s.SetVNet(n)
+		s.DisableSRTPOverVNet(true)
+		transport, err := NewAPI(WithSettingEngine(s)).NewDTLSTransport(nil, nil)
+		assert.NoError(t, err)
+
+		_, _, err = transport.plaintextRTPConns(&srtp.Config{})
+		assert.Equal(t, ErrSRTPDisabledWithoutVNet, err)
+	}
+}
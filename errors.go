@@ -34,6 +34,42 @@ var (
 	// chosen to generate a certificate is not supported.
 	ErrPrivateKeyType = errors.New("private key type not supported")
 
+	// ErrInvalidCertificateValidity indicates that a certificate was
+	// requested with a validity that isn't positive.
+	ErrInvalidCertificateValidity = errors.New("certificate validity must be positive")
+
+	// ErrCertificateMissing indicates that a tls.Certificate has no
+	// certificate to create a Certificate from.
+	ErrCertificateMissing = errors.New("no certificate in the tls.Certificate")
+
+	// ErrDTLSTransportNotConnected indicates an operation executed before
+	// the DTLS handshake completed.
+	ErrDTLSTransportNotConnected = errors.New("the DTLS transport is not connected")
+
+	// ErrReservedKeyingMaterialLabel indicates that the keying material of
+	// the SRTP keys was requested.
+	ErrReservedKeyingMaterialLabel = errors.New("the keying material label is reserved for the SRTP keys")
+
+	// ErrFIPSCertificate indicates that the key of a certificate is not
+	// accepted in the FIPS mode.
+	ErrFIPSCertificate = errors.New("certificate key not accepted in FIPS mode")
+
+	// ErrSRTPDisabledWithoutVNet indicates that SRTP was disabled without a
+	// virtual VNet.
+	ErrSRTPDisabledWithoutVNet = errors.New("SRTP can only be disabled over a virtual VNet")
+
+	// ErrInvalidMid indicates that a generated mid isn't a valid SDP token or
+	// it's already used in the session.
+	ErrInvalidMid = errors.New("generated mid is not valid")
+
+	// ErrInvalidCNAME indicates that a generated CNAME isn't a valid SDP
+	// attribute value.
+	ErrInvalidCNAME = errors.New("generated cname is not valid")
+
+	// ErrInvalidTrackID indicates that a generated track id isn't a valid SDP
+	// token.
+	ErrInvalidTrackID = errors.New("generated track id is not valid")
+
 	// ErrModifyingPeerIdentity indicates that an attempt to modify
 	// PeerIdentity was made after PeerConnection has been initialized.
 	ErrModifyingPeerIdentity = errors.New("peerIdentity cannot be modified")
@@ -50,6 +86,10 @@ var (
 	// RTCPMuxPolicy was made after PeerConnection has been initialized.
 	ErrModifyingRTCPMuxPolicy = errors.New("rtcp mux policy cannot be modified")
 
+	// ErrModifyingSDPSemantics indicates that an attempt to modify
+	// SDPSemantics was made after PeerConnection has been initialized.
+	ErrModifyingSDPSemantics = errors.New("sdp semantics cannot be modified")
+
 	// ErrModifyingICECandidatePoolSize indicates that an attempt to modify
 	// ICECandidatePoolSize was made after PeerConnection has been initialized.
 	ErrModifyingICECandidatePoolSize = errors.New("ice candidate pool size cannot be modified")
@@ -62,11 +102,19 @@ var (
 	// specified for a data channel has been exceeded.
 	ErrMaxDataChannelID = errors.New("maximum number ID for datachannel specified")
 
+	// ErrMaxDataChannels indicates that no DataChannel can be created since
+	// the MaxChannels of the SCTPTransport are open, or all the IDs are used.
+	ErrMaxDataChannels = errors.New("maximum number of data channels reached")
+
 	// ErrNegotiatedWithoutID indicates that an attempt to create a data channel
 	// was made while setting the negotiated option to true without providing
 	// the negotiated channel ID.
 	ErrNegotiatedWithoutID = errors.New("negotiated set without channel id")
 
+	// ErrDataChannelIDInUse indicates that an attempt to create a data channel
+	// was made with the ID of an existing data channel.
+	ErrDataChannelIDInUse = errors.New("data channel id already in use")
+
 	// ErrRetransmitsOrPacketLifeTime indicates that an attempt to create a data
 	// channel was made with both options MaxPacketLifeTime and MaxRetransmits
 	// set together. Such configuration is not supported by the specification
@@ -76,10 +124,63 @@ var (
 	// ErrCodecNotFound is returned when a codec search to the Media Engine fails
 	ErrCodecNotFound = errors.New("codec not found")
 
+	// ErrInvalidPayloadTypeRange indicates that an invalid dynamic payload
+	// types range was provided to the Media Engine
+	ErrInvalidPayloadTypeRange = errors.New("invalid dynamic payload types range")
+
+	// ErrNoFreePayloadType indicates that the Media Engine doesn't have any
+	// free payload type left in the dynamic payload types range
+	ErrNoFreePayloadType = errors.New("no free dynamic payload type")
+
+	// ErrNoMatchingCodecs indicates that a remote media section was rejected
+	// since none of its codecs is supported by the Media Engine
+	ErrNoMatchingCodecs = errors.New("no matching codecs in media section")
+
+	// ErrMediaSectionRejected indicates that a media section was rejected by
+	// the remote peer
+	ErrMediaSectionRejected = errors.New("media section rejected by the remote peer")
+
+	// ErrTransformedMediaSections indicates that the transformation of a local
+	// description changed its media sections or their mids
+	ErrTransformedMediaSections = errors.New("transformed description media sections don't match the generated ones")
+
+	// ErrNonMuxedRTCP indicates that a remote media section doesn't multiplex
+	// RTCP on the RTP transport, a separate RTCP transport isn't supported
+	ErrNonMuxedRTCP = errors.New("media section without rtcp-mux")
+
+	// ErrUnbundledMediaSection indicates that a remote media section isn't
+	// bundled with the others, a transport per media section isn't supported
+	ErrUnbundledMediaSection = errors.New("media section not in the BUNDLE group")
+
+	// ErrRollbackFirstRemoteOffer indicates that the first remote offer
+	// can't be rolled back since the transports have already been started
+	// with it
+	ErrRollbackFirstRemoteOffer = errors.New("can't rollback the first remote offer")
+
 	// ErrNoRemoteDescription indicates that an operation was rejected because
 	// the remote description is not set
 	ErrNoRemoteDescription = errors.New("remote description is not set")
 
+	// ErrSignalingStateCannotRollback indicates that a rollback was set in
+	// the stable signaling state
+	ErrSignalingStateCannotRollback = errors.New("can't rollback from stable state")
+
+	// ErrSignalingStateProposedTransitionInvalid indicates that a description
+	// was set in a signaling state that doesn't allow its type
+	ErrSignalingStateProposedTransitionInvalid = errors.New("invalid proposed signaling state transition")
+
+	// ErrSDPDoesNotMatchOffer indicates that SetLocalDescription was called
+	// with an offer different from the one returned by the last CreateOffer
+	ErrSDPDoesNotMatchOffer = errors.New("new sdp does not match previous offer")
+
+	// ErrSDPDoesNotMatchAnswer indicates that SetLocalDescription was called
+	// with an answer different from the one returned by the last CreateAnswer
+	ErrSDPDoesNotMatchAnswer = errors.New("new sdp does not match previous answer")
+
+	// ErrNoLocalDescription indicates that an operation was rejected because
+	// the local description is not set
+	ErrNoLocalDescription = errors.New("local description is not set")
+
 	// ErrIncorrectSDPSemantics indicates that the PeerConnection was configured to
 	// generate SDP Answers with different SDP Semantics than the received Offer
 	ErrIncorrectSDPSemantics = errors.New("offer SDP semantics does not match configuration")
@@ -88,6 +189,18 @@ var (
 	//longer then 65535 bytes
 	ErrProtocolTooLarge = errors.New("protocol is larger then 65535 bytes")
 
+	// ErrMessageTooLarge indicates a DataChannel message larger than the
+	// MaxMessageSize of the SCTPTransport, the limit of the remote peer
+	ErrMessageTooLarge = errors.New("message is larger than the maximum message size")
+
+	// ErrSendBufferFull indicates a DataChannel message that would exceed the
+	// send buffer size set with SettingEngine.SetSCTPMaxSendBufferSize
+	ErrSendBufferFull = errors.New("data channel send buffer is full")
+
+	// ErrDataChannelOnly indicates that media was added to a PeerConnection
+	// created with SettingEngine.SetDataChannelOnly
+	ErrDataChannelOnly = errors.New("the PeerConnection only supports data channels")
+
 	// ErrSenderNotCreatedByConnection indicates RemoveTrack was called with a RtpSender not created
 	// by this PeerConnection
 	ErrSenderNotCreatedByConnection = errors.New("RtpSender not created by this PeerConnection")
@@ -104,6 +217,10 @@ var (
 	// has an conflicting fingerprints
 	ErrSessionDescriptionConflictingFingerprints = errors.New("SetRemoteDescription called with multiple conflicting fingerprint")
 
+	// ErrSessionDescriptionUnsupportedFingerprint indicates SetRemoteDescription was called with a SessionDescription
+	// whose fingerprints all use unsupported hash functions
+	ErrSessionDescriptionUnsupportedFingerprint = errors.New("SetRemoteDescription called with no fingerprint using a supported hash function")
+
 	// ErrSessionDescriptionMissingIceUfrag indicates SetRemoteDescription was called with a SessionDescription that
 	// is missing an ice-ufrag value
 	ErrSessionDescriptionMissingIceUfrag = errors.New("SetRemoteDescription called with no ice-ufrag")
@@ -111,4 +228,20 @@ var (
 	// ErrSessionDescriptionMissingIcePwd indicates SetRemoteDescription was called with a SessionDescription that
 	// is missing an ice-pwd value
 	ErrSessionDescriptionMissingIcePwd = errors.New("SetRemoteDescription called with no ice-pwd")
+
+	// ErrSessionDescriptionMissingMid indicates SetRemoteDescription was called with a SessionDescription that
+	// has a media section without a mid value
+	ErrSessionDescriptionMissingMid = errors.New("SetRemoteDescription called with a media section without mid")
+
+	// ErrSessionDescriptionInvalidSetup indicates SetRemoteDescription was called with a SessionDescription that
+	// has a setup value not valid for its type
+	ErrSessionDescriptionInvalidSetup = errors.New("SetRemoteDescription called with an invalid setup")
+
+	// ErrSessionDescriptionInvalidRid indicates SetRemoteDescription was called with a SessionDescription that
+	// has an invalid or duplicated rid
+	ErrSessionDescriptionInvalidRid = errors.New("SetRemoteDescription called with an invalid rid")
+
+	// ErrSessionDescriptionInvalidSimulcast indicates SetRemoteDescription was called with a SessionDescription that
+	// has an invalid simulcast value or one referencing undeclared rids
+	ErrSessionDescriptionInvalidSimulcast = errors.New("SetRemoteDescription called with an invalid simulcast")
 )
@@ -0,0 +1,93 @@
+// +build !js
+
+package webrtc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"strings"
+
+	"github.com/pion/dtls/v2"
+	"github.com/pion/dtls/v2/pkg/crypto/fingerprint"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
+
+// fipsMinRSAKeySize is the smallest RSA modulus, in bits, accepted in the
+// FIPS mode (NIST SP 800-131A)
+const fipsMinRSAKeySize = 2048
+
+// fipsCipherSuites are the DTLS cipher suites accepted in the FIPS mode, the
+// AEAD ones of NIST SP 800-52
+var fipsCipherSuites = []dtls.CipherSuiteID{
+	dtls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	dtls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+}
+
+// fipsSignatureSchemes are the DTLS signature schemes accepted in the FIPS
+// mode (FIPS 186-4), without Ed25519 and SHA-1
+var fipsSignatureSchemes = []tls.SignatureScheme{
+	tls.ECDSAWithP256AndSHA256,
+	tls.ECDSAWithP384AndSHA384,
+	tls.ECDSAWithP521AndSHA512,
+	tls.PKCS1WithSHA256,
+	tls.PKCS1WithSHA384,
+	tls.PKCS1WithSHA512,
+}
+
+// fipsSRTPProtectionProfiles are the SRTP protection profiles accepted in
+// the FIPS mode, the AES counter mode and HMAC-SHA1 ones
+var fipsSRTPProtectionProfiles = []dtls.SRTPProtectionProfile{
+	dtls.SRTP_AES128_CM_HMAC_SHA1_80,
+}
+
+// fipsCheckPublicKey returns an error if the key of a certificate isn't
+// accepted in the FIPS mode
+func fipsCheckPublicKey(key crypto.PublicKey) error {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+			return nil
+		}
+	case *rsa.PublicKey:
+		if k.N.BitLen() >= fipsMinRSAKeySize {
+			return nil
+		}
+	}
+	return &rtcerr.NotSupportedError{Err: ErrFIPSCertificate}
+}
+
+// fipsCheckCertificates returns an error if the key of a local certificate
+// isn't accepted in the FIPS mode
+func fipsCheckCertificates(certificates []Certificate) error {
+	for _, certificate := range certificates {
+		if err := fipsCheckPublicKey(certificate.x509Cert.PublicKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fipsApprovedFingerprints reports if the fingerprints to verify use a hash
+// function accepted in the FIPS mode, SHA-256 or stronger
+func fipsApprovedFingerprints(fingerprints []DTLSFingerprint) bool {
+	strongest := strongestFingerprints(fingerprints)
+	if len(strongest) == 0 {
+		return false
+	}
+	hashAlgo, err := fingerprint.HashFromString(strings.ToLower(strongest[0].Algorithm))
+	return err == nil && hashAlgo.Size() >= crypto.SHA256.Size()
+}
+
+// applyFIPSMode limits the DTLS configuration to the algorithms accepted in
+// the FIPS mode. The ECDHE curve isn't configurable, pion/dtls always uses
+// X25519.
+func applyFIPSMode(config *dtls.Config) {
+	config.CipherSuites = fipsCipherSuites
+	config.SignatureSchemes = fipsSignatureSchemes
+	config.SRTPProtectionProfiles = fipsSRTPProtectionProfiles
+	config.ExtendedMasterSecret = dtls.RequireExtendedMasterSecret
+}
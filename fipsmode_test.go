@@ -0,0 +1,127 @@
+// +build !js
+
+package webrtc
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFIPSCheckPublicKey(t *testing.T) {
+	ecdsaP256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	ecdsaP224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	assert.NoError(t, err)
+	rsa2048, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rsa1024, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	ed25519Key, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	assert.NoError(t, fipsCheckPublicKey(&ecdsaP256.PublicKey))
+	assert.NoError(t, fipsCheckPublicKey(&rsa2048.PublicKey))
+
+	expectedErr := &rtcerr.NotSupportedError{Err: ErrFIPSCertificate}
+	assert.Equal(t, expectedErr, fipsCheckPublicKey(&ecdsaP224.PublicKey))
+	assert.Equal(t, expectedErr, fipsCheckPublicKey(&rsa1024.PublicKey))
+	assert.Equal(t, expectedErr, fipsCheckPublicKey(ed25519Key))
+}
+
+func TestFIPSApprovedFingerprints(t *testing.T) {
+	assert.True(t, fipsApprovedFingerprints([]DTLSFingerprint{{Algorithm: "sha-256", Value: "AA"}}))
+	assert.True(t, fipsApprovedFingerprints([]DTLSFingerprint{{Algorithm: "sha-1", Value: "AA"}, {Algorithm: "sha-512", Value: "AA"}}))
+	assert.False(t, fipsApprovedFingerprints([]DTLSFingerprint{{Algorithm: "sha-1", Value: "AA"}}))
+	assert.False(t, fipsApprovedFingerprints([]DTLSFingerprint{{Algorithm: "sha-123", Value: "AA"}}))
+	assert.False(t, fipsApprovedFingerprints(nil))
+}
+
+func TestPeerConnection_FIPSMode(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	s := SettingEngine{}
+	s.SetFIPSMode(true)
+	api := NewAPI(WithSettingEngine(s))
+
+	ecdsaP224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	assert.NoError(t, err)
+	weakCertificate, err := GenerateCertificate(ecdsaP224)
+	assert.NoError(t, err)
+
+	t.Run("LocalCertificate", func(t *testing.T) {
+		_, err := api.NewPeerConnection(Configuration{Certificates: []Certificate{*weakCertificate}})
+		assert.Equal(t, &rtcerr.NotSupportedError{Err: ErrFIPSCertificate}, err)
+	})
+
+	t.Run("RemoteFingerprint", func(t *testing.T) {
+		pcOffer, err := NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		pcAnswer, err := api.NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		_, err = pcOffer.CreateDataChannel("data", nil)
+		assert.NoError(t, err)
+		offer, err := pcOffer.CreateOffer(nil)
+		assert.NoError(t, err)
+
+		// only the SHA-1 fingerprint is kept
+		lines := []string{}
+		for _, line := range strings.Split(offer.SDP, "\r\n") {
+			if !strings.HasPrefix(line, "a=fingerprint:") {
+				lines = append(lines, line)
+			}
+		}
+		lines = append(lines[:len(lines)-1], "a=fingerprint:sha-1 AA:BB", "")
+		offer.SDP = strings.Join(lines, "\r\n")
+
+		err = pcAnswer.SetRemoteDescription(offer)
+		assert.Equal(t, &SDPValidationError{MediaIndex: -1, Attribute: "fingerprint", Err: ErrSessionDescriptionUnsupportedFingerprint}, err)
+
+		assert.NoError(t, pcOffer.Close())
+		assert.NoError(t, pcAnswer.Close())
+	})
+
+	for _, testCase := range []struct {
+		name         string
+		certificates []Certificate
+		state        PeerConnectionState
+	}{
+		{"Approved", nil, PeerConnectionStateConnected},
+		{"RemoteCertificate", []Certificate{*weakCertificate}, PeerConnectionStateFailed},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			pcOffer, err := NewPeerConnection(Configuration{Certificates: testCase.certificates})
+			assert.NoError(t, err)
+			pcAnswer, err := api.NewPeerConnection(Configuration{})
+			assert.NoError(t, err)
+
+			done := make(chan struct{})
+			pcAnswer.OnConnectionStateChange(func(state PeerConnectionState) {
+				if state == testCase.state {
+					close(done)
+				}
+			})
+
+			assert.NoError(t, signalPair(pcOffer, pcAnswer))
+			<-done
+
+			assert.NoError(t, pcOffer.Close())
+			assert.NoError(t, pcAnswer.Close())
+		})
+	}
+}
@@ -0,0 +1,281 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+const (
+	// freezeDetectorMaxMissing is the maximum number of lost packets that
+	// are tracked and requested with a NACK
+	freezeDetectorMaxMissing = 128
+
+	// firLength is the length, in 32bit words minus one, of a FIR packet
+	// with a single entry
+	firLength = 4
+	// formatFIR is the FMT of a FIR payload specific feedback message
+	formatFIR = 4
+)
+
+// freezeRecoveryStep is a step of the sequence run to recover a frozen stream
+type freezeRecoveryStep int
+
+const (
+	freezeRecoveryNone freezeRecoveryStep = iota
+	freezeRecoveryNACK
+	freezeRecoveryPLI
+	freezeRecoveryFIR
+	freezeRecoveryFrozen
+)
+
+// freezeDetector detects when a received video stream stops producing
+// decodable frames. After a packet loss the stream is considered broken until
+// all the lost packets are retransmitted or a new keyframe is received.
+type freezeDetector struct {
+	mu sync.Mutex
+
+	codecName string
+	// interval is the time given to every recovery step
+	interval time.Duration
+
+	started bool
+	lastSeq uint16
+
+	// missing are the lost packets sequence numbers, overflow reports if
+	// more packets than the tracked ones were lost
+	missing  map[uint16]struct{}
+	overflow bool
+
+	broken   bool
+	step     freezeRecoveryStep
+	stepTime time.Time
+}
+
+func newFreezeDetector(codecName string, interval time.Duration) *freezeDetector {
+	return &freezeDetector{
+		codecName: codecName,
+		interval:  interval,
+		missing:   map[uint16]struct{}{},
+	}
+}
+
+// observe accounts a received packet
+func (d *freezeDetector) observe(p *rtp.Packet, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seq := p.SequenceNumber
+	switch diff := seq - d.lastSeq; {
+	case !d.started:
+		d.started = true
+		d.lastSeq = seq
+	case diff == 0:
+		// duplicated packet
+		return
+	case diff < 0x8000:
+		if diff > 1 {
+			for s := d.lastSeq + 1; s != seq; s++ {
+				if len(d.missing) >= freezeDetectorMaxMissing {
+					d.overflow = true
+					break
+				}
+				d.missing[s] = struct{}{}
+			}
+			if !d.broken {
+				d.broken = true
+				d.step = freezeRecoveryNone
+				d.stepTime = now
+			}
+		}
+		d.lastSeq = seq
+	default:
+		// a retransmitted or reordered packet
+		delete(d.missing, seq)
+	}
+
+	if isKeyframe(d.codecName, p.Payload) {
+		d.recovered()
+		return
+	}
+	if d.broken && len(d.missing) == 0 && !d.overflow {
+		d.recovered()
+	}
+}
+
+func (d *freezeDetector) recovered() {
+	d.broken = false
+	d.overflow = false
+	d.step = freezeRecoveryNone
+	d.missing = map[uint16]struct{}{}
+}
+
+// next returns the recovery step to run now, if any, and the lost packets
+// sequence numbers to request
+func (d *freezeDetector) next(now time.Time) (freezeRecoveryStep, []uint16) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.broken || d.step == freezeRecoveryFrozen || now.Sub(d.stepTime) < d.interval {
+		return freezeRecoveryNone, nil
+	}
+
+	d.step++
+	d.stepTime = now
+
+	missing := make([]uint16, 0, len(d.missing))
+	for s := range d.missing {
+		missing = append(missing, s)
+	}
+	// order by distance from the last received packet, the oldest first
+	sort.Slice(missing, func(i, j int) bool {
+		return d.lastSeq-missing[i] > d.lastSeq-missing[j]
+	})
+	return d.step, missing
+}
+
+// retry makes the next call of next return the provided step again, it's
+// used when the step couldn't be run
+func (d *freezeDetector) retry(step freezeRecoveryStep) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.broken && d.step == step {
+		d.step--
+		d.stepTime = time.Time{}
+	}
+}
+
+// nackPairs builds the NACK pairs requesting the provided sequence numbers
+// ordered from the oldest
+func nackPairs(seqs []uint16) []rtcp.NackPair {
+	pairs := []rtcp.NackPair{}
+	for _, s := range seqs {
+		if n := len(pairs); n > 0 {
+			if diff := s - pairs[n-1].PacketID; diff > 0 && diff <= 16 {
+				pairs[n-1].LostPackets |= 1 << (diff - 1)
+				continue
+			}
+		}
+		pairs = append(pairs, rtcp.NackPair{PacketID: s})
+	}
+	return pairs
+}
+
+// marshalFullIntraRequest marshals a FIR message (RFC 5104 4.3.1) for the
+// provided media ssrc
+func marshalFullIntraRequest(mediaSSRC uint32, seq uint8) ([]byte, error) {
+	h := rtcp.Header{
+		Count:  formatFIR,
+		Type:   rtcp.TypePayloadSpecificFeedback,
+		Length: firLength,
+	}
+	hData, err := h.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	rawPacket := make([]byte, (firLength+1)*4)
+	copy(rawPacket, hData)
+	// the media source ssrc is unused and the target ssrc is in the entry
+	binary.BigEndian.PutUint32(rawPacket[12:], mediaSSRC)
+	rawPacket[16] = seq
+	return rawPacket, nil
+}
+
+// isKeyframe reports if the payload is the start of a keyframe for the
+// provided codec. Unknown codecs never report a keyframe.
+func isKeyframe(codecName string, payload []byte) bool {
+	switch codecName {
+	case VP8:
+		return isVP8Keyframe(payload)
+	case VP9:
+		return isVP9Keyframe(payload)
+	case H264:
+		return isH264Keyframe(payload)
+	}
+	return false
+}
+
+func isVP8Keyframe(payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+	// only the first partition of a frame has the payload header
+	if payload[0]&0x10 == 0 || payload[0]&0x07 != 0 {
+		return false
+	}
+
+	i := 1
+	if payload[0]&0x80 != 0 {
+		if len(payload) < 2 {
+			return false
+		}
+		ext := payload[1]
+		i++
+		if ext&0x80 != 0 {
+			if len(payload) <= i {
+				return false
+			}
+			// 15 bits picture id
+			if payload[i]&0x80 != 0 {
+				i++
+			}
+			i++
+		}
+		if ext&0x40 != 0 {
+			i++
+		}
+		if ext&0x30 != 0 {
+			i++
+		}
+	}
+	if len(payload) <= i {
+		return false
+	}
+	// the inverse keyframe flag of the payload header
+	return payload[i]&0x01 == 0
+}
+
+func isVP9Keyframe(payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+	// not inter-picture predicted and start of a frame
+	return payload[0]&0x40 == 0 && payload[0]&0x08 != 0
+}
+
+func isH264Keyframe(payload []byte) bool {
+	const (
+		naluTypeIDR  = 5
+		naluTypeSPS  = 7
+		naluTypeSTAP = 24
+		naluTypeFUA  = 28
+	)
+
+	if len(payload) < 1 {
+		return false
+	}
+	switch naluType := payload[0] & 0x1F; naluType {
+	case naluTypeIDR, naluTypeSPS:
+		return true
+	case naluTypeSTAP:
+		for i := 1; i+2 < len(payload); {
+			size := int(binary.BigEndian.Uint16(payload[i:]))
+			if t := payload[i+2] & 0x1F; t == naluTypeIDR || t == naluTypeSPS {
+				return true
+			}
+			i += 2 + size
+		}
+	case naluTypeFUA:
+		// start of a fragmented IDR
+		return len(payload) > 1 && payload[1]&0x80 != 0 && payload[1]&0x1F == naluTypeIDR
+	}
+	return false
+}
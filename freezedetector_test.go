@@ -0,0 +1,123 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreezeDetector(t *testing.T) {
+	interval := 100 * time.Millisecond
+	start := time.Now()
+	d := newFreezeDetector(VP8, interval)
+
+	deltaFrame := []byte{0x10, 0x01}
+	keyFrame := []byte{0x10, 0x00}
+	packet := func(seq uint16, payload []byte) *rtp.Packet {
+		return &rtp.Packet{Header: rtp.Header{SequenceNumber: seq}, Payload: payload}
+	}
+
+	d.observe(packet(65534, deltaFrame), start)
+	d.observe(packet(65535, deltaFrame), start)
+	step, _ := d.next(start.Add(interval))
+	assert.Equal(t, freezeRecoveryNone, step)
+
+	// retransmitted packets recover the stream
+	d.observe(packet(2, deltaFrame), start)
+	d.observe(packet(1, deltaFrame), start)
+	d.observe(packet(0, deltaFrame), start)
+	step, _ = d.next(start.Add(interval))
+	assert.Equal(t, freezeRecoveryNone, step)
+
+	// a lost packet starts the recovery sequence
+	d.observe(packet(5, deltaFrame), start)
+	step, _ = d.next(start.Add(interval / 2))
+	assert.Equal(t, freezeRecoveryNone, step)
+
+	step, missing := d.next(start.Add(interval))
+	assert.Equal(t, freezeRecoveryNACK, step)
+	assert.Equal(t, []uint16{3, 4}, missing)
+
+	step, _ = d.next(start.Add(2 * interval))
+	assert.Equal(t, freezeRecoveryPLI, step)
+	step, _ = d.next(start.Add(3 * interval))
+	assert.Equal(t, freezeRecoveryFIR, step)
+	step, _ = d.next(start.Add(4 * interval))
+	assert.Equal(t, freezeRecoveryFrozen, step)
+	step, _ = d.next(start.Add(5 * interval))
+	assert.Equal(t, freezeRecoveryNone, step)
+
+	// a keyframe recovers the stream
+	d.observe(packet(6, keyFrame), start.Add(5*interval))
+	step, _ = d.next(start.Add(10 * interval))
+	assert.Equal(t, freezeRecoveryNone, step)
+
+	// a step that failed is retried
+	d.observe(packet(8, deltaFrame), start.Add(10*interval))
+	step, _ = d.next(start.Add(11 * interval))
+	assert.Equal(t, freezeRecoveryNACK, step)
+	d.retry(step)
+	step, missing = d.next(start.Add(11 * interval))
+	assert.Equal(t, freezeRecoveryNACK, step)
+	assert.Equal(t, []uint16{7}, missing)
+	step, _ = d.next(start.Add(12 * interval))
+	assert.Equal(t, freezeRecoveryPLI, step)
+
+	d.observe(packet(9, keyFrame), start.Add(12*interval))
+	step, _ = d.next(start.Add(10 * interval))
+	assert.Equal(t, freezeRecoveryNone, step)
+}
+
+func TestNackPairs(t *testing.T) {
+	assert.Equal(t, []rtcp.NackPair{
+		{PacketID: 65530, LostPackets: 0x8001},
+		{PacketID: 12, LostPackets: 0},
+	}, nackPairs([]uint16{65530, 65531, 10, 12}))
+}
+
+func TestMarshalFullIntraRequest(t *testing.T) {
+	raw, err := marshalFullIntraRequest(0x12345678, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, len(raw))
+
+	var h rtcp.Header
+	assert.NoError(t, h.Unmarshal(raw))
+	assert.Equal(t, rtcp.TypePayloadSpecificFeedback, h.Type)
+	assert.Equal(t, uint8(formatFIR), h.Count)
+	assert.Equal(t, uint16(firLength), h.Length)
+	assert.Equal(t, uint32(0x12345678), binary.BigEndian.Uint32(raw[12:]))
+	assert.Equal(t, uint8(3), raw[16])
+}
+
+func TestIsKeyframe(t *testing.T) {
+	for _, test := range []struct {
+		codec    string
+		payload  []byte
+		keyframe bool
+	}{
+		{VP8, []byte{0x10, 0x00}, true},
+		{VP8, []byte{0x10, 0x01}, false},
+		{VP8, []byte{0x00, 0x00}, false},
+		// extended control bits with a 15 bits picture id
+		{VP8, []byte{0x90, 0x80, 0x81, 0x02, 0x00}, true},
+		{VP8, []byte{0x90, 0x80, 0x81, 0x02}, false},
+		{VP9, []byte{0x08}, true},
+		{VP9, []byte{0x48}, false},
+		{H264, []byte{0x65}, true},
+		{H264, []byte{0x61}, false},
+		// STAP-A with SPS
+		{H264, []byte{0x18, 0x00, 0x01, 0x67}, true},
+		// FU-A start of IDR
+		{H264, []byte{0x7c, 0x85}, true},
+		{H264, []byte{0x7c, 0x05}, false},
+		{Opus, []byte{0x00}, false},
+	} {
+		assert.Equal(t, test.keyframe, isKeyframe(test.codec, test.payload), "%s %x", test.codec, test.payload)
+	}
+}
@@ -13,6 +13,7 @@ require (
 	github.com/pion/sctp v1.7.6
 	github.com/pion/sdp/v2 v2.3.7
 	github.com/pion/srtp v1.3.3
+	github.com/pion/stun v0.3.3
 	github.com/pion/transport v0.10.0
 	github.com/sclevine/agouti v3.0.0+incompatible
 	github.com/stretchr/testify v1.5.1
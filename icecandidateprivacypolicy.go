@@ -0,0 +1,106 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+)
+
+// ICECandidatePrivacyPolicy controls which local addresses are revealed by
+// the signaled local candidates, like the IP handling policies of the
+// browsers. The candidates that aren't signaled can still be used by the
+// connectivity checks.
+type ICECandidatePrivacyPolicy int
+
+const (
+	// ICECandidatePrivacyPolicyAll signals all the local candidates.
+	ICECandidatePrivacyPolicyAll ICECandidatePrivacyPolicy = iota
+
+	// ICECandidatePrivacyPolicyNoPrivate doesn't signal the host candidates
+	// with a private, link-local or loopback address, and masks these
+	// addresses when they're the related address of a candidate.
+	ICECandidatePrivacyPolicyNoPrivate
+
+	// ICECandidatePrivacyPolicyNoHost doesn't gather the host candidates,
+	// only the server reflexive and relay candidates are signaled and their
+	// related addresses are masked.
+	ICECandidatePrivacyPolicyNoHost
+)
+
+// This is done this way because of a linter.
+const (
+	iceCandidatePrivacyPolicyAllStr       = "all"
+	iceCandidatePrivacyPolicyNoPrivateStr = "no-private"
+	iceCandidatePrivacyPolicyNoHostStr    = "no-host"
+)
+
+func (p ICECandidatePrivacyPolicy) String() string {
+	switch p {
+	case ICECandidatePrivacyPolicyAll:
+		return iceCandidatePrivacyPolicyAllStr
+	case ICECandidatePrivacyPolicyNoPrivate:
+		return iceCandidatePrivacyPolicyNoPrivateStr
+	case ICECandidatePrivacyPolicyNoHost:
+		return iceCandidatePrivacyPolicyNoHostStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// maskedRelatedAddress replaces the related addresses hidden by the policy,
+// like the browsers do
+const maskedRelatedAddress = "0.0.0.0"
+
+// privateIPNets are the private (RFC 1918, RFC 6598 and RFC 4193) address
+// ranges
+var privateIPNets = func() []*net.IPNet {
+	nets := []*net.IPNet{}
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"} {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}()
+
+// isPrivateAddress reports if an address reveals the local network. The
+// hostnames, like the mDNS ones, don't.
+func isPrivateAddress(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return true
+	}
+	for _, ipNet := range privateIPNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply applies the policy to a local candidate before it's signaled, it
+// returns false when the candidate must not be signaled
+func (p ICECandidatePrivacyPolicy) apply(c *ICECandidate) bool {
+	switch p {
+	case ICECandidatePrivacyPolicyNoPrivate:
+		if c.Typ == ICECandidateTypeHost && isPrivateAddress(c.Address) {
+			return false
+		}
+		if isPrivateAddress(c.RelatedAddress) {
+			c.RelatedAddress, c.RelatedPort = maskedRelatedAddress, 0
+		}
+	case ICECandidatePrivacyPolicyNoHost:
+		if c.Typ == ICECandidateTypeHost {
+			return false
+		}
+		if c.RelatedAddress != "" {
+			c.RelatedAddress, c.RelatedPort = maskedRelatedAddress, 0
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestICECandidatePrivacyPolicy_apply(t *testing.T) {
+	host := ICECandidate{Typ: ICECandidateTypeHost, Address: "192.168.1.2", Port: 5000}
+	publicHost := ICECandidate{Typ: ICECandidateTypeHost, Address: "1.2.3.4", Port: 5000}
+	mDNSHost := ICECandidate{Typ: ICECandidateTypeHost, Address: "f2b5c3a8.local", Port: 5000}
+	srflx := ICECandidate{Typ: ICECandidateTypeSrflx, Address: "1.2.3.4", Port: 6000, RelatedAddress: "10.0.0.2", RelatedPort: 5000}
+	relay := ICECandidate{Typ: ICECandidateTypeRelay, Address: "5.6.7.8", Port: 7000, RelatedAddress: "1.2.3.4", RelatedPort: 6000}
+
+	maskedSrflx := srflx
+	maskedSrflx.RelatedAddress, maskedSrflx.RelatedPort = maskedRelatedAddress, 0
+	maskedRelay := relay
+	maskedRelay.RelatedAddress, maskedRelay.RelatedPort = maskedRelatedAddress, 0
+
+	for _, test := range []struct {
+		policy   ICECandidatePrivacyPolicy
+		expected []ICECandidate
+	}{
+		{ICECandidatePrivacyPolicyAll, []ICECandidate{host, publicHost, mDNSHost, srflx, relay}},
+		{ICECandidatePrivacyPolicyNoPrivate, []ICECandidate{publicHost, mDNSHost, maskedSrflx, relay}},
+		{ICECandidatePrivacyPolicyNoHost, []ICECandidate{maskedSrflx, maskedRelay}},
+	} {
+		signaled := []ICECandidate{}
+		for _, c := range []ICECandidate{host, publicHost, mDNSHost, srflx, relay} {
+			if test.policy.apply(&c) {
+				signaled = append(signaled, c)
+			}
+		}
+		assert.Equal(t, test.expected, signaled, test.policy.String())
+	}
+}
+
+func TestIsPrivateAddress(t *testing.T) {
+	for address, expected := range map[string]bool{
+		"10.1.2.3":       true,
+		"172.20.0.1":     true,
+		"172.32.0.1":     false,
+		"192.168.0.1":    true,
+		"100.64.0.1":     true,
+		"127.0.0.1":      true,
+		"169.254.1.1":    true,
+		"fd00::1":        true,
+		"fe80::1":        true,
+		"2001:db8::1":    false,
+		"8.8.8.8":        false,
+		"f2b5c3a8.local": false,
+	} {
+		assert.Equal(t, expected, isPrivateAddress(address), address)
+	}
+}
@@ -3,13 +3,21 @@
 package webrtc
 
 import (
+	"context"
+	"errors"
+	"net"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/pion/ice"
 	"github.com/pion/logging"
 )
 
+// iceServerResolveTimeout bounds the resolution of the hostname of an ICE
+// server
+const iceServerResolveTimeout = 5 * time.Second
+
 // ICEGatherer gathers local host, server reflexive and relay
 // candidates, as well as enabling the retrieval of local Interactive
 // Connectivity Establishment (ICE) parameters which can be
@@ -19,14 +27,28 @@ type ICEGatherer struct {
 	log   logging.LeveledLogger
 	state ICEGathererState
 
-	validatedServers []*ice.URL
-	gatherPolicy     ICETransportPolicy
+	servers      []ICEServer
+	gatherPolicy ICETransportPolicy
 
 	agent *ice.Agent
+	// pendingAgent is the agent created by an ICE restart, it replaces
+	// agent when the restart is completed
+	pendingAgent *ice.Agent
 
 	onLocalCandidateHdlr atomic.Value // func(candidate *ICECandidate)
 	onStateChangeHdlr    atomic.Value // func(state ICEGathererState)
 
+	// gatheringComplete is closed when the gathering is complete or the
+	// gatherer is closed, a restart replaces it
+	gatheringComplete     chan struct{}
+	gatheringCompleteLock sync.Mutex
+
+	// serverHealth are the health checks results of the ICE servers, by URL
+	serverHealth     map[string]*iceServerHealth
+	serverHealthLock sync.Mutex
+	// serverHealthDone stops the health checks
+	serverHealthDone chan struct{}
+
 	api *API
 }
 
@@ -34,23 +56,20 @@ type ICEGatherer struct {
 // This constructor is part of the ORTC API. It is not
 // meant to be used together with the basic WebRTC API.
 func (api *API) NewICEGatherer(opts ICEGatherOptions) (*ICEGatherer, error) {
-	var validatedServers []*ice.URL
-	if len(opts.ICEServers) > 0 {
-		for _, server := range opts.ICEServers {
-			url, err := server.urls()
-			if err != nil {
-				return nil, err
-			}
-			validatedServers = append(validatedServers, url...)
+	for _, server := range opts.ICEServers {
+		if err := server.validate(); err != nil {
+			return nil, err
 		}
 	}
 
 	return &ICEGatherer{
-		state:            ICEGathererStateNew,
-		gatherPolicy:     opts.ICEGatherPolicy,
-		validatedServers: validatedServers,
-		api:              api,
-		log:              api.settingEngine.LoggerFactory.NewLogger("ice"),
+		state:             ICEGathererStateNew,
+		gatherPolicy:      opts.ICEGatherPolicy,
+		servers:           opts.ICEServers,
+		api:               api,
+		gatheringComplete: make(chan struct{}),
+		serverHealth:      map[string]*iceServerHealth{},
+		log:               api.settingEngine.LoggerFactory.NewLogger("ice"),
 	}, nil
 }
 
@@ -62,11 +81,38 @@ func (g *ICEGatherer) createAgent() error {
 		return nil
 	}
 
+	agent, err := g.newAgent(g.api.settingEngine.candidates.UsernameFragment, g.api.settingEngine.candidates.Password)
+	if err != nil {
+		return err
+	}
+
+	g.agent = agent
+	if !g.api.settingEngine.candidates.ICETrickle {
+		g.storeState(ICEGathererStateComplete)
+	}
+
+	if interval := g.api.settingEngine.iceServerHealthCheckInterval; interval > 0 && len(g.servers) > 0 {
+		g.serverHealthDone = make(chan struct{})
+		go g.checkServers(interval, g.serverHealthDone)
+	}
+
+	return nil
+}
+
+// newAgent creates a new ice agent with the provided local credentials,
+// random credentials are generated when they are empty
+func (g *ICEGatherer) newAgent(ufrag, pwd string) (*ice.Agent, error) {
+	// only the relayed candidates are gathered with the relay policy, so
+	// they're the only local candidates of the checked pairs. The policies
+	// win over ICE lite, the agent creation then fails since a lite agent
+	// must use host candidates.
 	candidateTypes := []ice.CandidateType{}
-	if g.api.settingEngine.candidates.ICELite {
-		candidateTypes = append(candidateTypes, ice.CandidateTypeHost)
-	} else if g.gatherPolicy == ICETransportPolicyRelay {
+	if g.gatherPolicy == ICETransportPolicyRelay {
 		candidateTypes = append(candidateTypes, ice.CandidateTypeRelay)
+	} else if g.api.settingEngine.candidates.PrivacyPolicy == ICECandidatePrivacyPolicyNoHost {
+		candidateTypes = append(candidateTypes, ice.CandidateTypeServerReflexive, ice.CandidateTypeRelay)
+	} else if g.api.settingEngine.candidates.ICELite {
+		candidateTypes = append(candidateTypes, ice.CandidateTypeHost)
 	}
 
 	var nat1To1CandiTyp ice.CandidateType
@@ -75,19 +121,34 @@ func (g *ICEGatherer) createAgent() error {
 		nat1To1CandiTyp = ice.CandidateTypeHost
 	case ICECandidateTypeSrflx:
 		nat1To1CandiTyp = ice.CandidateTypeServerReflexive
+	case ICECandidateTypePrflx:
+		// the other types are rejected by pion/ice, instead of silently
+		// replacing the host candidates
+		nat1To1CandiTyp = ice.CandidateTypePeerReflexive
+	case ICECandidateTypeRelay:
+		nat1To1CandiTyp = ice.CandidateTypeRelay
 	default:
 		nat1To1CandiTyp = ice.CandidateTypeUnspecified
 	}
 
-	var multicastDNSMode ice.MulticastDNSMode
-	if g.api.settingEngine.candidates.GenerateMulticastDNSCandidates {
-		multicastDNSMode = ice.MulticastDNSModeQueryAndGather
+	urls, err := g.serverURLs()
+	if err != nil {
+		return nil, err
+	}
+
+	multicastDNSMode := g.api.settingEngine.candidates.MulticastDNSMode
+	if multicastDNSMode == 0 {
+		multicastDNSMode = ice.MulticastDNSModeQueryOnly
+		// the mDNS socket would bind a real interface
+		if g.api.settingEngine.vnet != nil && g.api.settingEngine.vnet.IsVirtual() {
+			multicastDNSMode = ice.MulticastDNSModeDisabled
+		}
 	}
 
 	config := &ice.AgentConfig{
 		Trickle:                   g.api.settingEngine.candidates.ICETrickle,
 		Lite:                      g.api.settingEngine.candidates.ICELite,
-		Urls:                      g.validatedServers,
+		Urls:                      urls,
 		PortMin:                   g.api.settingEngine.ephemeralUDP.PortMin,
 		PortMax:                   g.api.settingEngine.ephemeralUDP.PortMax,
 		ConnectionTimeout:         g.api.settingEngine.timeout.ICEConnection,
@@ -105,8 +166,9 @@ func (g *ICEGatherer) createAgent() error {
 		Net:                       g.api.settingEngine.vnet,
 		MulticastDNSMode:          multicastDNSMode,
 		MulticastDNSHostName:      g.api.settingEngine.candidates.MulticastDNSHostName,
-		LocalUfrag:                g.api.settingEngine.candidates.UsernameFragment,
-		LocalPwd:                  g.api.settingEngine.candidates.Password,
+		LocalUfrag:                ufrag,
+		LocalPwd:                  pwd,
+		InsecureSkipVerify:        g.api.settingEngine.candidates.TURNInsecureSkipVerify,
 	}
 
 	requestedNetworkTypes := g.api.settingEngine.candidates.ICENetworkTypes
@@ -115,22 +177,177 @@ func (g *ICEGatherer) createAgent() error {
 	}
 
 	for _, typ := range requestedNetworkTypes {
+		if typ.Protocol() == iceProtocolTCPStr {
+			g.log.Warnf("No candidates are gathered for %s, ICE-TCP isn't supported", typ)
+		}
 		config.NetworkTypes = append(config.NetworkTypes, ice.NetworkType(typ))
 	}
 
-	agent, err := ice.NewAgent(config)
+	return ice.NewAgent(config)
+}
+
+// serverURLs returns the URLs of the ICE servers for a new agent, with the
+// credentials of the servers with a CredentialProvider and the hostnames
+// resolved by the ICEServerResolver, if any
+func (g *ICEGatherer) serverURLs() ([]*ice.URL, error) {
+	urls, err := g.unresolvedServerURLs()
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := g.api.settingEngine.candidates.ICEServerResolver
+	if resolver == nil {
+		return urls, nil
+	}
+
+	resolved := []*ice.URL{}
+	for _, url := range urls {
+		if url.Scheme != ice.SchemeTypeTURNS && net.ParseIP(url.Host) == nil {
+			ip, err := resolveIPv4(resolver, url.Host)
+			if err != nil {
+				g.log.Warnf("Skipping the ICE server %s: %v", url, err)
+				continue
+			}
+			url.Host = ip.String()
+		}
+		resolved = append(resolved, url)
+	}
+	return resolved, nil
+}
+
+// unresolvedServerURLs returns the URLs of the ICE servers with the
+// credentials of the servers with a CredentialProvider
+func (g *ICEGatherer) unresolvedServerURLs() ([]*ice.URL, error) {
+	var urls []*ice.URL
+	for _, server := range g.servers {
+		server, err := server.provideCredentials()
+		if err != nil {
+			return nil, err
+		}
+		serverURLs, err := server.urls()
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, serverURLs...)
+	}
+	return urls, nil
+}
+
+// resolveIPv4 resolves a hostname to its first IPv4 address, the only one
+// pion/ice uses for the STUN and TURN servers
+func resolveIPv4(resolver ICEServerResolver, host string) (net.IP, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), iceServerResolveTimeout)
+	defer cancel()
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if ip := addr.IP.To4(); ip != nil {
+			return ip, nil
+		}
+	}
+	return nil, &net.DNSError{Err: "no IPv4 address", Name: host}
+}
+
+// restart creates a new agent with new local credentials. The new agent is
+// used to generate the local parameters and candidates but the current agent
+// is kept until the restart is completed with commitRestart or canceled with
+// cancelRestart.
+func (g *ICEGatherer) restart() error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.agent == nil || g.pendingAgent != nil {
+		return nil
+	}
+
+	agent, err := g.newAgent("", "")
 	if err != nil {
 		return err
 	}
 
-	g.agent = agent
-	if !g.api.settingEngine.candidates.ICETrickle {
-		atomicStoreICEGathererState(&g.state, ICEGathererStateComplete)
+	g.pendingAgent = agent
+	if g.api.settingEngine.candidates.ICETrickle {
+		// the candidates of the new agent must be gathered
+		g.storeState(ICEGathererStateNew)
 	}
 
 	return nil
 }
 
+// setGatherPolicy sets the policy of the next gatherings, the candidates
+// already gathered aren't affected
+func (g *ICEGatherer) setGatherPolicy(policy ICETransportPolicy) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.gatherPolicy = policy
+}
+
+// Restart starts an ICE restart, creating an agent with new local
+// parameters. The new parameters, and the candidates gathered with Gather,
+// are returned by GetLocalParameters and GetLocalCandidates to be signaled to
+// the remote peer. The current agent is used until the restart is completed
+// by ICETransport.Restart.
+func (g *ICEGatherer) Restart() error {
+	if g.getAgent() == nil {
+		return errors.New("ICEGatherer not gathering, unable to restart")
+	}
+	return g.restart()
+}
+
+// isRestarting reports if there's a pending restart
+func (g *ICEGatherer) isRestarting() bool {
+	return g.getPendingAgent() != nil
+}
+
+func (g *ICEGatherer) getPendingAgent() *ice.Agent {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.pendingAgent
+}
+
+// commitRestart replaces the current agent with the pending one and returns
+// the replaced agent
+func (g *ICEGatherer) commitRestart() *ice.Agent {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.pendingAgent == nil {
+		return nil
+	}
+
+	agent := g.agent
+	g.agent, g.pendingAgent = g.pendingAgent, nil
+	return agent
+}
+
+// cancelRestart discards the pending agent
+func (g *ICEGatherer) cancelRestart() error {
+	g.lock.Lock()
+	agent := g.pendingAgent
+	g.pendingAgent = nil
+	g.lock.Unlock()
+
+	if agent == nil {
+		return nil
+	}
+	g.storeState(ICEGathererStateComplete)
+	return agent.Close()
+}
+
+// localAgent returns the agent that provides the local parameters and
+// candidates, the pending one during a restart
+func (g *ICEGatherer) localAgent() *ice.Agent {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	if g.pendingAgent != nil {
+		return g.pendingAgent
+	}
+	return g.agent
+}
+
 // Gather ICE candidates.
 func (g *ICEGatherer) Gather() error {
 	if err := g.createAgent(); err != nil {
@@ -142,10 +359,8 @@ func (g *ICEGatherer) Gather() error {
 		onLocalCandidateHdlr = hdlr
 	}
 
-	g.lock.Lock()
 	isTrickle := g.api.settingEngine.candidates.ICETrickle
-	agent := g.agent
-	g.lock.Unlock()
+	agent := g.localAgent()
 
 	if !isTrickle {
 		return nil
@@ -159,6 +374,9 @@ func (g *ICEGatherer) Gather() error {
 				g.log.Warnf("Failed to convert ice.Candidate: %s", err)
 				return
 			}
+			if !g.api.settingEngine.candidates.PrivacyPolicy.apply(&c) {
+				return
+			}
 			onLocalCandidateHdlr(&c)
 		} else {
 			g.setState(ICEGathererStateComplete)
@@ -176,6 +394,18 @@ func (g *ICEGatherer) Close() error {
 	g.lock.Lock()
 	defer g.lock.Unlock()
 
+	if g.serverHealthDone != nil {
+		close(g.serverHealthDone)
+		g.serverHealthDone = nil
+	}
+
+	if g.pendingAgent != nil {
+		if err := g.pendingAgent.Close(); err != nil {
+			return err
+		}
+		g.pendingAgent = nil
+	}
+
 	if g.agent == nil {
 		return nil
 	} else if err := g.agent.Close(); err != nil {
@@ -194,11 +424,11 @@ func (g *ICEGatherer) GetLocalParameters() (ICEParameters, error) {
 		return ICEParameters{}, err
 	}
 
-	frag, pwd := g.agent.GetLocalUserCredentials()
+	frag, pwd := g.localAgent().GetLocalUserCredentials()
 	return ICEParameters{
 		UsernameFragment: frag,
 		Password:         pwd,
-		ICELite:          false,
+		ICELite:          g.api.settingEngine.candidates.ICELite,
 	}, nil
 }
 
@@ -207,12 +437,23 @@ func (g *ICEGatherer) GetLocalCandidates() ([]ICECandidate, error) {
 	if err := g.createAgent(); err != nil {
 		return nil, err
 	}
-	iceCandidates, err := g.agent.GetLocalCandidates()
+	iceCandidates, err := g.localAgent().GetLocalCandidates()
 	if err != nil {
 		return nil, err
 	}
 
-	return newICECandidatesFromICE(iceCandidates)
+	candidates, err := newICECandidatesFromICE(iceCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	signaled := []ICECandidate{}
+	for i := range candidates {
+		if g.api.settingEngine.candidates.PrivacyPolicy.apply(&candidates[i]) {
+			signaled = append(signaled, candidates[i])
+		}
+	}
+	return signaled, nil
 }
 
 // OnLocalCandidate sets an event handler which fires when a new local ICE candidate is available
@@ -232,13 +473,42 @@ func (g *ICEGatherer) State() ICEGathererState {
 }
 
 func (g *ICEGatherer) setState(s ICEGathererState) {
-	atomicStoreICEGathererState(&g.state, s)
+	g.storeState(s)
 
 	if hdlr, ok := g.onStateChangeHdlr.Load().(func(state ICEGathererState)); ok && hdlr != nil {
 		hdlr(s)
 	}
 }
 
+// storeState sets the state without firing the state change handler
+func (g *ICEGatherer) storeState(s ICEGathererState) {
+	g.gatheringCompleteLock.Lock()
+	defer g.gatheringCompleteLock.Unlock()
+
+	done := false
+	select {
+	case <-g.gatheringComplete:
+		done = true
+	default:
+	}
+
+	switch {
+	case (s == ICEGathererStateComplete || s == ICEGathererStateClosed) && !done:
+		close(g.gatheringComplete)
+	case (s == ICEGathererStateNew || s == ICEGathererStateGathering) && done:
+		g.gatheringComplete = make(chan struct{})
+	}
+	atomicStoreICEGathererState(&g.state, s)
+}
+
+// gatheringCompleteChan returns a channel closed when the current gathering
+// is complete or the gatherer is closed
+func (g *ICEGatherer) gatheringCompleteChan() <-chan struct{} {
+	g.gatheringCompleteLock.Lock()
+	defer g.gatheringCompleteLock.Unlock()
+	return g.gatheringComplete
+}
+
 func (g *ICEGatherer) getAgent() *ice.Agent {
 	g.lock.RLock()
 	defer g.lock.RUnlock()
@@ -258,16 +528,24 @@ func (g *ICEGatherer) SignalCandidates() error {
 		onLocalCandidateHdlr = hdlr
 	}
 
-	if onLocalCandidateHdlr != nil {
-		go func() {
+	go func() {
+		if onLocalCandidateHdlr != nil {
 			for i := range candidates {
 				onLocalCandidateHdlr(&candidates[i])
 			}
+		}
+		// the candidates have been gathered when the agent has been created,
+		// signal the completion like a trickle gathering does
+		if g.State() != ICEGathererStateClosed {
+			g.setState(ICEGathererStateComplete)
+		}
+
+		if onLocalCandidateHdlr != nil {
 			// Call the handler one last time with nil. This is a signal that candidate
 			// gathering is complete.
 			onLocalCandidateHdlr(nil)
-		}()
-	}
+		}
+	}()
 	return nil
 }
 
@@ -277,54 +555,10 @@ func (g *ICEGatherer) collectStats(collector *statsReportCollector) {
 		return
 	}
 
+	g.collectServerStats(collector)
+
 	collector.Collecting()
 	go func(collector *statsReportCollector, agent *ice.Agent) {
-		for _, candidatePairStats := range agent.GetCandidatePairsStats() {
-			collector.Collecting()
-
-			state, err := toStatsICECandidatePairState(candidatePairStats.State)
-			if err != nil {
-				g.log.Error(err.Error())
-			}
-
-			pairID := newICECandidatePairStatsID(candidatePairStats.LocalCandidateID,
-				candidatePairStats.RemoteCandidateID)
-
-			stats := ICECandidatePairStats{
-				Timestamp: statsTimestampFrom(candidatePairStats.Timestamp),
-				Type:      StatsTypeCandidatePair,
-				ID:        pairID,
-				// TransportID:
-				LocalCandidateID:            candidatePairStats.LocalCandidateID,
-				RemoteCandidateID:           candidatePairStats.RemoteCandidateID,
-				State:                       state,
-				Nominated:                   candidatePairStats.Nominated,
-				PacketsSent:                 candidatePairStats.PacketsSent,
-				PacketsReceived:             candidatePairStats.PacketsReceived,
-				BytesSent:                   candidatePairStats.BytesSent,
-				BytesReceived:               candidatePairStats.BytesReceived,
-				LastPacketSentTimestamp:     statsTimestampFrom(candidatePairStats.LastPacketSentTimestamp),
-				LastPacketReceivedTimestamp: statsTimestampFrom(candidatePairStats.LastPacketReceivedTimestamp),
-				FirstRequestTimestamp:       statsTimestampFrom(candidatePairStats.FirstRequestTimestamp),
-				LastRequestTimestamp:        statsTimestampFrom(candidatePairStats.LastRequestTimestamp),
-				LastResponseTimestamp:       statsTimestampFrom(candidatePairStats.LastResponseTimestamp),
-				TotalRoundTripTime:          candidatePairStats.TotalRoundTripTime,
-				CurrentRoundTripTime:        candidatePairStats.CurrentRoundTripTime,
-				AvailableOutgoingBitrate:    candidatePairStats.AvailableOutgoingBitrate,
-				AvailableIncomingBitrate:    candidatePairStats.AvailableIncomingBitrate,
-				CircuitBreakerTriggerCount:  candidatePairStats.CircuitBreakerTriggerCount,
-				RequestsReceived:            candidatePairStats.RequestsReceived,
-				RequestsSent:                candidatePairStats.RequestsSent,
-				ResponsesReceived:           candidatePairStats.ResponsesReceived,
-				ResponsesSent:               candidatePairStats.ResponsesSent,
-				RetransmissionsReceived:     candidatePairStats.RetransmissionsReceived,
-				RetransmissionsSent:         candidatePairStats.RetransmissionsSent,
-				ConsentRequestsSent:         candidatePairStats.ConsentRequestsSent,
-				ConsentExpiredTimestamp:     statsTimestampFrom(candidatePairStats.ConsentExpiredTimestamp),
-			}
-			collector.Collect(stats.ID, stats)
-		}
-
 		for _, candidateStats := range agent.GetLocalCandidatesStats() {
 			collector.Collecting()
 
@@ -342,6 +576,7 @@ func (g *ICEGatherer) collectStats(collector *statsReportCollector) {
 				Timestamp:     statsTimestampFrom(candidateStats.Timestamp),
 				ID:            candidateStats.ID,
 				Type:          StatsTypeLocalCandidate,
+				TransportID:   iceTransportStatsID,
 				NetworkType:   networkType,
 				IP:            candidateStats.IP,
 				Port:          int32(candidateStats.Port),
@@ -352,6 +587,9 @@ func (g *ICEGatherer) collectStats(collector *statsReportCollector) {
 				RelayProtocol: candidateStats.RelayProtocol,
 				Deleted:       candidateStats.Deleted,
 			}
+			if stats.URL == "" && candidateType == ICECandidateTypeRelay {
+				stats.URL = g.relayServerURL(stats.IP)
+			}
 			collector.Collect(stats.ID, stats)
 		}
 
@@ -371,6 +609,7 @@ func (g *ICEGatherer) collectStats(collector *statsReportCollector) {
 				Timestamp:     statsTimestampFrom(candidateStats.Timestamp),
 				ID:            candidateStats.ID,
 				Type:          StatsTypeRemoteCandidate,
+				TransportID:   iceTransportStatsID,
 				NetworkType:   networkType,
 				IP:            candidateStats.IP,
 				Port:          int32(candidateStats.Port),
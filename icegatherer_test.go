@@ -4,11 +4,16 @@ package webrtc
 
 import (
 	"context"
+	"net"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/pion/ice"
+	"github.com/pion/logging"
 	"github.com/pion/transport/test"
+	"github.com/pion/transport/vnet"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -123,7 +128,7 @@ func TestICEGather_mDNSCandidateGathering(t *testing.T) {
 	defer report()
 
 	s := SettingEngine{}
-	s.GenerateMulticastDNSCandidates(true)
+	s.SetICEMulticastDNSMode(ice.MulticastDNSModeQueryAndGather)
 
 	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
 	if err != nil {
@@ -144,3 +149,322 @@ func TestICEGather_mDNSCandidateGathering(t *testing.T) {
 	<-gotMulticastDNSCandidate.Done()
 	assert.NoError(t, gatherer.Close())
 }
+
+func TestICEGather_mDNSCandidateResolution(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerSettings := SettingEngine{}
+	offerSettings.SetICEMulticastDNSMode(ice.MulticastDNSModeQueryAndGather)
+	pcOffer, err := NewAPI(WithSettingEngine(offerSettings)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	// the answerer resolves the offered candidates with the default mode
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	connected, connectedFunc := context.WithCancel(context.Background())
+	pcAnswer.OnICEConnectionStateChange(func(s ICEConnectionState) {
+		if s == ICEConnectionStateConnected {
+			connectedFunc()
+		}
+	})
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	offerWithCandidates, err := pcOffer.GetCompleteLocalDescription()
+	assert.NoError(t, err)
+	assert.Contains(t, offerWithCandidates.SDP, ".local")
+	assert.NoError(t, pcAnswer.SetRemoteDescription(*offerWithCandidates))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	answerWithCandidates, err := pcAnswer.GetCompleteLocalDescription()
+	assert.NoError(t, err)
+
+	// without the answered candidates the connection needs the resolution of
+	// the offered mDNS ones
+	lines := []string{}
+	for _, line := range strings.Split(answerWithCandidates.SDP, "\r\n") {
+		if !strings.HasPrefix(line, "a=candidate:") {
+			lines = append(lines, line)
+		}
+	}
+	answerWithCandidates.SDP = strings.Join(lines, "\r\n")
+	assert.NoError(t, pcOffer.SetRemoteDescription(*answerWithCandidates))
+
+	<-connected.Done()
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestICEGather_EphemeralUDPPortRange(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	const portMin, portMax = 5000, 5100
+
+	s := SettingEngine{}
+	assert.NoError(t, s.SetEphemeralUDPPortRange(portMin, portMax))
+
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+
+	candidates, err := gatherer.GetLocalCandidates()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, candidates)
+	for _, c := range candidates {
+		assert.True(t, c.Port >= portMin && c.Port <= portMax, "port %d out of range", c.Port)
+	}
+
+	assert.NoError(t, gatherer.Close())
+}
+
+func TestICEGather_NAT1To1IPs(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	const publicIP = "1.2.3.4"
+
+	for _, candidateType := range []ICECandidateType{ICECandidateTypeHost, ICECandidateTypeSrflx} {
+		s := SettingEngine{}
+		s.SetNetworkTypes([]NetworkType{NetworkTypeUDP4})
+		s.SetNAT1To1IPs([]string{publicIP}, candidateType)
+
+		gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+		assert.NoError(t, err)
+
+		candidates, err := gatherer.GetLocalCandidates()
+		assert.NoError(t, err)
+
+		havePublicIP := false
+		for _, c := range candidates {
+			if c.Address != publicIP {
+				// the host candidates keep the private addresses only when
+				// the public one is advertised with srflx candidates
+				assert.Equal(t, ICECandidateTypeSrflx, candidateType)
+				assert.Equal(t, ICECandidateTypeHost, c.Typ)
+				continue
+			}
+			havePublicIP = true
+			assert.Equal(t, candidateType, c.Typ)
+		}
+		assert.True(t, havePublicIP, candidateType.String())
+
+		assert.NoError(t, gatherer.Close())
+	}
+
+	s := SettingEngine{}
+	s.SetNAT1To1IPs([]string{publicIP}, ICECandidateTypeRelay)
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+	_, err = gatherer.GetLocalCandidates()
+	assert.Equal(t, ice.ErrUnsupportedNAT1To1IPCandidateType, err)
+	assert.NoError(t, gatherer.Close())
+}
+
+func TestICEGather_InterfaceFilter(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for _, accept := range []bool{true, false} {
+		filtered := []string{}
+		s := SettingEngine{}
+		s.SetInterfaceFilter(func(name string) bool {
+			filtered = append(filtered, name)
+			return accept
+		})
+
+		gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+		assert.NoError(t, err)
+
+		candidates, err := gatherer.GetLocalCandidates()
+		assert.NoError(t, err)
+		assert.NotEmpty(t, filtered)
+		if accept {
+			assert.NotEmpty(t, candidates)
+		} else {
+			assert.Empty(t, candidates)
+		}
+
+		assert.NoError(t, gatherer.Close())
+	}
+}
+
+func TestICEGather_NetworkTypes(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for _, networkType := range []NetworkType{NetworkTypeUDP4, NetworkTypeUDP6} {
+		s := SettingEngine{}
+		s.SetNetworkTypes([]NetworkType{networkType})
+
+		gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+		assert.NoError(t, err)
+
+		candidates, err := gatherer.GetLocalCandidates()
+		assert.NoError(t, err)
+		for _, c := range candidates {
+			isIPv4 := net.ParseIP(c.Address).To4() != nil
+			assert.Equal(t, networkType == NetworkTypeUDP4, isIPv4, c.Address)
+		}
+
+		assert.NoError(t, gatherer.Close())
+	}
+}
+
+func TestICEGather_CredentialProvider(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// the agent doesn't gather the candidates on creation with trickle
+	s := SettingEngine{}
+	s.SetTrickle(true)
+
+	calls := 0
+	credential := interface{}("placeholder")
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{
+		ICEServers: []ICEServer{{
+			URLs:           []string{"turn:127.0.0.1?transport=udp"},
+			CredentialType: ICECredentialTypePassword,
+			CredentialProvider: func() (string, interface{}, error) {
+				calls++
+				return "unittest", credential, nil
+			},
+		}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, calls)
+
+	// the credentials are provided for the agent and the agent of every ICE
+	// restart
+	assert.NoError(t, gatherer.createAgent())
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, gatherer.restart())
+	assert.Equal(t, 2, calls)
+	assert.NoError(t, gatherer.commitRestart().Close())
+
+	// the provided credentials are validated
+	credential = false
+	assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrTurnCredentials}, gatherer.restart())
+	assert.Equal(t, 3, calls)
+
+	assert.NoError(t, gatherer.Close())
+}
+
+type testICEServerResolver map[string][]net.IPAddr
+
+func (r testICEServerResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func TestICEGather_ICEServerResolver(t *testing.T) {
+	s := SettingEngine{}
+	s.SetICEServerResolver(testICEServerResolver{
+		"stun.example.test": {{IP: net.ParseIP("::1")}, {IP: net.ParseIP("192.0.2.1")}},
+		"turn.example.test": {{IP: net.ParseIP("192.0.2.2")}},
+	})
+
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{
+		ICEServers: []ICEServer{
+			{URLs: []string{"stun:stun.example.test", "stun:unknown.example.test", "stun:192.0.2.3"}},
+			{
+				URLs:           []string{"turn:turn.example.test?transport=tcp", "turns:turn.example.test"},
+				Username:       "unittest",
+				Credential:     "placeholder",
+				CredentialType: ICECredentialTypePassword,
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	urls, err := gatherer.serverURLs()
+	assert.NoError(t, err)
+	hosts := []string{}
+	for _, url := range urls {
+		hosts = append(hosts, url.Host)
+	}
+	// the unknown host is skipped and the turns host is kept
+	assert.Equal(t, []string{"192.0.2.1", "192.0.2.3", "192.0.2.2", "turn.example.test"}, hosts)
+}
+
+func TestICEGather_CandidatePrivacyPolicy(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	router, err := vnet.NewRouter(&vnet.RouterConfig{
+		CIDR:          "10.0.0.0/24",
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	assert.NoError(t, err)
+
+	// the virtual network only has a private address
+	gatherCandidates := func(policy ICECandidatePrivacyPolicy) []ICECandidate {
+		n := vnet.NewNet(&vnet.NetConfig{})
+		assert.NoError(t, router.AddNet(n))
+
+		s := SettingEngine{}
+		s.SetVNet(n)
+		s.SetICECandidatePrivacyPolicy(policy)
+
+		gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+		assert.NoError(t, err)
+		candidates, err := gatherer.GetLocalCandidates()
+		assert.NoError(t, err)
+		assert.NoError(t, gatherer.Close())
+		return candidates
+	}
+
+	candidates := gatherCandidates(ICECandidatePrivacyPolicyAll)
+	assert.NotEmpty(t, candidates)
+	for _, c := range candidates {
+		assert.True(t, strings.HasPrefix(c.Address, "10.0.0."), c.Address)
+	}
+	assert.Empty(t, gatherCandidates(ICECandidatePrivacyPolicyNoPrivate))
+	assert.Empty(t, gatherCandidates(ICECandidatePrivacyPolicyNoHost))
+
+	// a lite agent must use host candidates
+	s := SettingEngine{}
+	s.SetLite(true)
+	s.SetICECandidatePrivacyPolicy(ICECandidatePrivacyPolicyNoHost)
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+	_, err = gatherer.GetLocalCandidates()
+	assert.Equal(t, ice.ErrLiteUsingNonHostCandidates, err)
+	assert.NoError(t, gatherer.Close())
+}
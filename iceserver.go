@@ -3,17 +3,37 @@
 package webrtc
 
 import (
+	"context"
+	"net"
+
 	"github.com/pion/ice"
 	"github.com/pion/webrtc/v2/pkg/rtcerr"
 )
 
 // ICEServer describes a single STUN and TURN server that can be used by
-// the ICEAgent to establish a connection with a peer.
+// the ICEAgent to establish a connection with a peer. A TURN server is
+// reached over UDP (turn:host), TCP (turn:host?transport=tcp), TLS
+// (turns:host) or DTLS (turns:host?transport=udp), the relayed candidates
+// are still UDP ones.
 type ICEServer struct {
 	URLs           []string
 	Username       string
 	Credential     interface{}
 	CredentialType ICECredentialType
+
+	// CredentialProvider, if set, provides the Username and Credential of
+	// the server each time the candidates are gathered, including on an ICE
+	// restart, so expiring credentials like the ones of the TURN REST API
+	// can be refreshed. The configured Username and Credential are then
+	// ignored. It's called by the ICEGatherer and must not call back into
+	// the PeerConnection.
+	CredentialProvider func() (username string, credential interface{}, err error)
+}
+
+// ICEServerResolver resolves the hostnames of the STUN and TURN servers,
+// *net.Resolver implements it.
+type ICEServerResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
 }
 
 func (s ICEServer) parseURL(i int) (*ice.URL, error) {
@@ -34,7 +54,8 @@ func (s ICEServer) urls() ([]*ice.URL, error) {
 			return nil, err
 		}
 
-		if url.Scheme == ice.SchemeTypeTURN || url.Scheme == ice.SchemeTypeTURNS {
+		// the credentials of a provider are validated at gathering time
+		if (url.Scheme == ice.SchemeTypeTURN || url.Scheme == ice.SchemeTypeTURNS) && s.CredentialProvider == nil {
 			// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11.3.2)
 			if s.Username == "" || s.Credential == nil {
 				return nil, &rtcerr.InvalidAccessError{Err: ErrNoTurnCredentials}
@@ -66,3 +87,18 @@ func (s ICEServer) urls() ([]*ice.URL, error) {
 
 	return urls, nil
 }
+
+// provideCredentials returns a copy of the server with the credentials of
+// its CredentialProvider, if any
+func (s ICEServer) provideCredentials() (ICEServer, error) {
+	if s.CredentialProvider == nil {
+		return s, nil
+	}
+
+	username, credential, err := s.CredentialProvider()
+	if err != nil {
+		return s, err
+	}
+	s.Username, s.Credential, s.CredentialProvider = username, credential, nil
+	return s, nil
+}
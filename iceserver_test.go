@@ -3,6 +3,7 @@
 package webrtc
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/pion/ice"
@@ -31,6 +32,13 @@ func TestICEServer_validate(t *testing.T) {
 				},
 				CredentialType: ICECredentialTypeOauth,
 			}, true},
+			{ICEServer{
+				URLs:           []string{"turn:192.158.29.39?transport=udp"},
+				CredentialType: ICECredentialTypePassword,
+				CredentialProvider: func() (string, interface{}, error) {
+					return "unittest", "placeholder", nil
+				},
+			}, true},
 		}
 
 		for i, testCase := range testCases {
@@ -82,3 +90,52 @@ func TestICEServer_validate(t *testing.T) {
 		}
 	})
 }
+
+func TestICEServer_provideCredentials(t *testing.T) {
+	server := ICEServer{
+		URLs:           []string{"turn:192.158.29.39?transport=udp"},
+		Username:       "expired",
+		Credential:     "expired",
+		CredentialType: ICECredentialTypePassword,
+		CredentialProvider: func() (string, interface{}, error) {
+			return "unittest", "placeholder", nil
+		},
+	}
+
+	provided, err := server.provideCredentials()
+	assert.NoError(t, err)
+	urls, err := provided.urls()
+	assert.NoError(t, err)
+	assert.Equal(t, "unittest", urls[0].Username)
+	assert.Equal(t, "placeholder", urls[0].Password)
+
+	errProvider := errors.New("provider failure")
+	server.CredentialProvider = func() (string, interface{}, error) {
+		return "", nil, errProvider
+	}
+	_, err = server.provideCredentials()
+	assert.Equal(t, errProvider, err)
+}
+
+func TestICEServer_urlsTransports(t *testing.T) {
+	for _, testCase := range []struct {
+		url    string
+		scheme ice.SchemeType
+		proto  ice.ProtoType
+	}{
+		{"turn:192.158.29.39", ice.SchemeTypeTURN, ice.ProtoTypeUDP},
+		{"turn:192.158.29.39?transport=tcp", ice.SchemeTypeTURN, ice.ProtoTypeTCP},
+		{"turns:192.158.29.39", ice.SchemeTypeTURNS, ice.ProtoTypeTCP},
+		{"turns:192.158.29.39?transport=udp", ice.SchemeTypeTURNS, ice.ProtoTypeUDP},
+	} {
+		urls, err := ICEServer{
+			URLs:           []string{testCase.url},
+			Username:       "unittest",
+			Credential:     "placeholder",
+			CredentialType: ICECredentialTypePassword,
+		}.urls()
+		assert.NoError(t, err, testCase.url)
+		assert.Equal(t, testCase.scheme, urls[0].Scheme, testCase.url)
+		assert.Equal(t, testCase.proto, urls[0].Proto, testCase.url)
+	}
+}
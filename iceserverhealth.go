@@ -0,0 +1,215 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pion/ice"
+	"github.com/pion/stun"
+	"github.com/pion/transport/vnet"
+)
+
+// iceServerCheckTimeout bounds the wait of the response to a health check
+const iceServerCheckTimeout = 5 * time.Second
+
+// iceServerHealth is the state of the health checks of an ICE server
+type iceServerHealth struct {
+	stats  ICEServerStats
+	scheme ice.SchemeType
+	// addr is the resolved address of the server, nil until it's resolved
+	addr *net.UDPAddr
+}
+
+// checkServers checks the ICE servers every interval, until done is closed
+func (g *ICEGatherer) checkServers(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		g.checkServersOnce(done)
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkServersOnce checks all the ICE servers reached over UDP in parallel.
+// The binding requests aren't authenticated, so the URLs are parsed without
+// the credentials and the CredentialProviders aren't called.
+func (g *ICEGatherer) checkServersOnce(done <-chan struct{}) {
+	urls := []*ice.URL{}
+	for _, server := range g.servers {
+		for i := range server.URLs {
+			url, err := server.parseURL(i)
+			if err != nil {
+				g.log.Warnf("Failed to parse the ICE server URL %s: %v", server.URLs[i], err)
+				continue
+			}
+			urls = append(urls, url)
+		}
+	}
+
+	results := make(chan struct{})
+	checks := 0
+	for _, url := range urls {
+		if url.Proto != ice.ProtoTypeUDP || (url.Scheme != ice.SchemeTypeSTUN && url.Scheme != ice.SchemeTypeTURN) {
+			continue
+		}
+		checks++
+		go func(url *ice.URL) {
+			addr, rtt, err := g.checkServer(url, done)
+			g.recordServerCheck(url, addr, rtt, err)
+			results <- struct{}{}
+		}(url)
+	}
+	for ; checks > 0; checks-- {
+		<-results
+	}
+}
+
+// checkServer sends a STUN binding request to an ICE server and returns its
+// address and the round trip time of the response
+func (g *ICEGatherer) checkServer(url *ice.URL, done <-chan struct{}) (*net.UDPAddr, time.Duration, error) {
+	n := g.api.settingEngine.vnet
+	if n == nil {
+		n = vnet.NewNet(nil)
+	}
+
+	addr, err := g.resolveServer(n, url)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	conn, err := n.ListenPacket("udp4", "0.0.0.0:0")
+	if err != nil {
+		return addr, 0, err
+	}
+	defer func() {
+		if closeErr := conn.Close(); closeErr != nil {
+			g.log.Warnf("Failed to close the health check socket: %v", closeErr)
+		}
+	}()
+
+	// the check is interrupted when the gatherer is closed
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-done:
+			_ = conn.SetReadDeadline(time.Now())
+		case <-finished:
+		}
+	}()
+
+	request, err := stun.Build(stun.TransactionID, stun.BindingRequest)
+	if err != nil {
+		return addr, 0, err
+	}
+
+	start := time.Now()
+	if err = conn.SetReadDeadline(start.Add(iceServerCheckTimeout)); err != nil {
+		return addr, 0, err
+	}
+	if _, err = conn.WriteTo(request.Raw, addr); err != nil {
+		return addr, 0, err
+	}
+
+	buf := make([]byte, receiveMTU)
+	for {
+		size, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return addr, 0, err
+		}
+		// an error response proves the server is reachable too
+		response := &stun.Message{Raw: buf[:size]}
+		if response.Decode() == nil && response.TransactionID == request.TransactionID {
+			return addr, time.Since(start), nil
+		}
+	}
+}
+
+// resolveServer resolves the address of an ICE server, with the
+// ICEServerResolver if any
+func (g *ICEGatherer) resolveServer(n *vnet.Net, url *ice.URL) (*net.UDPAddr, error) {
+	host := url.Host
+	if resolver := g.api.settingEngine.candidates.ICEServerResolver; resolver != nil && net.ParseIP(host) == nil {
+		ip, err := resolveIPv4(resolver, host)
+		if err != nil {
+			return nil, err
+		}
+		host = ip.String()
+	}
+	return n.ResolveUDPAddr("udp4", net.JoinHostPort(host, fmt.Sprint(url.Port)))
+}
+
+// recordServerCheck updates the stats of an ICE server with the result of a
+// health check, and logs when its reachability changes
+func (g *ICEGatherer) recordServerCheck(url *ice.URL, addr *net.UDPAddr, rtt time.Duration, err error) {
+	g.serverHealthLock.Lock()
+	defer g.serverHealthLock.Unlock()
+
+	key := url.String()
+	health, ok := g.serverHealth[key]
+	if !ok {
+		health = &iceServerHealth{stats: ICEServerStats{
+			Type:          StatsTypeICEServer,
+			ID:            "ICEServer-" + key,
+			URL:           key,
+			Port:          int32(url.Port),
+			RelayProtocol: url.Proto.String(),
+		}, scheme: url.Scheme}
+		g.serverHealth[key] = health
+	}
+	if addr != nil {
+		health.addr = addr
+	}
+
+	wasReachable := health.stats.Reachable
+	health.stats.Timestamp = statsTimestampNow()
+	health.stats.TotalRequestsSent++
+	health.stats.Reachable = err == nil
+	if err != nil {
+		if wasReachable || !ok {
+			g.log.Warnf("The ICE server %s is unreachable: %v", key, err)
+		}
+		return
+	}
+	health.stats.TotalResponsesReceived++
+	health.stats.TotalRoundTripTime += rtt.Seconds()
+	if !wasReachable && ok {
+		g.log.Infof("The ICE server %s is reachable again", key)
+	}
+}
+
+// relayServerURL returns the URL of the checked TURN server with the address
+// of a relay candidate, if any. pion/ice doesn't report the server that
+// allocated a relay candidate, so it's found only for the servers relaying
+// from the address they're reached at.
+func (g *ICEGatherer) relayServerURL(ip string) string {
+	g.serverHealthLock.Lock()
+	defer g.serverHealthLock.Unlock()
+
+	for key, health := range g.serverHealth {
+		if health.scheme == ice.SchemeTypeTURN && health.addr != nil && health.addr.IP.String() == ip {
+			return key
+		}
+	}
+	return ""
+}
+
+// collectServerStats collects the stats of the checked ICE servers
+func (g *ICEGatherer) collectServerStats(collector *statsReportCollector) {
+	g.serverHealthLock.Lock()
+	defer g.serverHealthLock.Unlock()
+
+	for _, health := range g.serverHealth {
+		collector.Collecting()
+		collector.Collect(health.stats.ID, health.stats)
+	}
+}
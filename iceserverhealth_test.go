@@ -0,0 +1,130 @@
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/ice"
+	"github.com/pion/logging"
+	"github.com/pion/stun"
+	"github.com/pion/transport/test"
+	"github.com/pion/transport/vnet"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveSTUN answers the STUN binding requests received by conn, until it's
+// closed
+func serveSTUN(conn net.PacketConn) {
+	buf := make([]byte, receiveMTU)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		request := &stun.Message{Raw: buf[:n]}
+		if request.Decode() != nil {
+			continue
+		}
+		udpAddr := addr.(*net.UDPAddr)
+		response, err := stun.Build(request, stun.BindingSuccess, &stun.XORMappedAddress{IP: udpAddr.IP, Port: udpAddr.Port}, stun.Fingerprint)
+		if err != nil {
+			continue
+		}
+		if _, err = conn.WriteTo(response.Raw, addr); err != nil {
+			return
+		}
+	}
+}
+
+func TestICEGather_ServerHealthChecks(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	router, err := vnet.NewRouter(&vnet.RouterConfig{
+		CIDR:          "10.0.0.0/24",
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	assert.NoError(t, err)
+
+	serverNet := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"10.0.0.100"}})
+	assert.NoError(t, router.AddNet(serverNet))
+	clientNet := vnet.NewNet(&vnet.NetConfig{})
+	assert.NoError(t, router.AddNet(clientNet))
+	assert.NoError(t, router.Start())
+
+	serverConn, err := serverNet.ListenPacket("udp4", "10.0.0.100:3478")
+	assert.NoError(t, err)
+	go serveSTUN(serverConn)
+
+	s := SettingEngine{}
+	s.SetVNet(clientNet)
+	s.SetICEServerHealthChecks(time.Hour)
+
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{
+		ICEServers: []ICEServer{{URLs: []string{"stun:10.0.0.100:3478"}}},
+	})
+	assert.NoError(t, err)
+	_, err = gatherer.GetLocalCandidates()
+	assert.NoError(t, err)
+
+	var stats ICEServerStats
+	for {
+		collector := newStatsReportCollector()
+		gatherer.collectStats(collector)
+		if s, ok := collector.Ready()["ICEServer-stun:10.0.0.100:3478"]; ok {
+			stats = s.(ICEServerStats)
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, StatsTypeICEServer, stats.Type)
+	assert.Equal(t, int32(3478), stats.Port)
+	assert.Equal(t, "udp", stats.RelayProtocol)
+	assert.Equal(t, uint32(1), stats.TotalRequestsSent)
+	assert.Equal(t, uint32(1), stats.TotalResponsesReceived)
+	assert.True(t, stats.Reachable)
+	assert.True(t, stats.TotalRoundTripTime > 0)
+
+	// a TURN server relaying from the address it's reached at is found
+	turnURL, err := ice.ParseURL("turn:10.0.0.100:3478")
+	assert.NoError(t, err)
+	gatherer.recordServerCheck(turnURL, &net.UDPAddr{IP: net.ParseIP("10.0.0.100"), Port: 3478}, time.Millisecond, nil)
+	assert.Equal(t, "turn:10.0.0.100:3478?transport=udp", gatherer.relayServerURL("10.0.0.100"))
+	assert.Equal(t, "", gatherer.relayServerURL("10.0.0.101"))
+
+	assert.NoError(t, gatherer.Close())
+
+	// the checks don't need the credentials of the servers
+	providerCalls := 0
+	s = SettingEngine{}
+	s.SetVNet(clientNet)
+	gatherer, err = NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{
+		ICEServers: []ICEServer{{
+			URLs: []string{"turn:10.0.0.100:3478"},
+			CredentialProvider: func() (string, interface{}, error) {
+				providerCalls++
+				return "", nil, errors.New("no credentials")
+			},
+		}},
+	})
+	assert.NoError(t, err)
+	gatherer.checkServersOnce(make(chan struct{}))
+	assert.Equal(t, 0, providerCalls)
+	collector := newStatsReportCollector()
+	gatherer.collectServerStats(collector)
+	stats, ok := collector.Ready()["ICEServer-turn:10.0.0.100:3478?transport=udp"].(ICEServerStats)
+	assert.True(t, ok)
+	assert.True(t, stats.Reachable)
+	assert.NoError(t, gatherer.Close())
+
+	assert.NoError(t, serverConn.Close())
+	assert.NoError(t, router.Stop())
+}
@@ -5,6 +5,7 @@ package webrtc
 import (
 	"context"
 	"errors"
+	"net"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -28,10 +29,27 @@ type ICETransport struct {
 	onSelectedCandidatePairChangeHdlr atomic.Value // func(*ICECandidatePair)
 
 	state ICETransportState
+	// failed is set when the connection is failed by the ICETransport, the
+	// later agent events are ignored until an ICE restart. failedTimer fails
+	// a connection that stays disconnected for the failed timeout,
+	// failedTimerGeneration identifies the current one to its callback.
+	failed                bool
+	failedTimer           *time.Timer
+	failedTimerGeneration uint64
+	// selectedCandidatePair is the pair selected by the current agent,
+	// pendingSelectedCandidatePair the one selected by the agent of a pending
+	// ICE restart, it's notified when the restart is completed
+	selectedCandidatePair        *ICECandidatePair
+	pendingSelectedCandidatePair *ICECandidatePair
 
 	gatherer *ICEGatherer
-	conn     *ice.Conn
-	mux      *mux.Mux
+	// agent is the agent of the current connection
+	agent *ice.Agent
+	conn  *ice.Conn
+	// restartableConn is the connection used by the mux, its underlying
+	// connection is replaced by an ICE restart
+	restartableConn *restartableConn
+	mux             *mux.Mux
 
 	loggerFactory logging.LoggerFactory
 
@@ -46,10 +64,6 @@ type ICETransport struct {
 //
 // }
 //
-// func (t *ICETransport) GetSelectedCandidatePair() ICECandidatePair {
-//
-// }
-//
 // func (t *ICETransport) GetLocalParameters() ICEParameters {
 //
 // }
@@ -86,24 +100,8 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 		return errors.New("ICEAgent does not exist, unable to start ICETransport")
 	}
 
-	if err := agent.OnConnectionStateChange(func(iceState ice.ConnectionState) {
-		state := newICETransportStateFromICE(iceState)
-		t.lock.Lock()
-		t.state = state
-		t.lock.Unlock()
-
-		t.onConnectionStateChange(state)
-	}); err != nil {
-		return err
-	}
-	if err := agent.OnSelectedCandidatePairChange(func(local, remote ice.Candidate) {
-		candidates, err := newICECandidatesFromICE([]ice.Candidate{local, remote})
-		if err != nil {
-			t.log.Warnf("Unable to convert ICE candidates to ICECandidates: %s", err)
-			return
-		}
-		t.onSelectedCandidatePairChange(NewICECandidatePair(&candidates[0], &candidates[1]))
-	}); err != nil {
+	t.agent = agent
+	if err := t.setAgentHandlers(agent); err != nil {
 		return err
 	}
 
@@ -117,46 +115,199 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 	// added so that the agent can complete a connection
 	t.lock.Unlock()
 
-	var iceConn *ice.Conn
-	var err error
-	switch *role {
+	iceConn, err := connect(agent, params, *role)
+
+	// Reacquire the lock to set the connection/mux
+	t.lock.Lock()
+	if err != nil {
+		return err
+	}
+
+	t.conn = iceConn
+	t.restartableConn = newRestartableConn(iceConn)
+
+	config := mux.Config{
+		Conn:          t.restartableConn,
+		BufferSize:    receiveMTU,
+		LoggerFactory: t.loggerFactory,
+	}
+	t.mux = mux.NewMux(config)
+
+	return nil
+}
+
+// connect establishes the connection of the agent with the remote peer
+func connect(agent *ice.Agent, params ICEParameters, role ICERole) (*ice.Conn, error) {
+	switch role {
 	case ICERoleControlling:
-		iceConn, err = agent.Dial(context.TODO(),
+		return agent.Dial(context.TODO(),
 			params.UsernameFragment,
 			params.Password)
 
 	case ICERoleControlled:
-		iceConn, err = agent.Accept(context.TODO(),
+		return agent.Accept(context.TODO(),
 			params.UsernameFragment,
 			params.Password)
 
 	default:
-		err = errors.New("unknown ICE Role")
+		return nil, errors.New("unknown ICE Role")
 	}
+}
 
-	// Reacquire the lock to set the connection/mux
+// setAgentHandlers sets the agent handlers, the events are ignored when the
+// agent isn't the one of the current connection
+func (t *ICETransport) setAgentHandlers(agent *ice.Agent) error {
+	failedTimeout := t.gatherer.api.settingEngine.timeout.ICEFailed
+	if err := agent.OnConnectionStateChange(func(iceState ice.ConnectionState) {
+		state := newICETransportStateFromICE(iceState)
+		t.lock.Lock()
+		if t.agent != agent || t.failed {
+			t.lock.Unlock()
+			return
+		}
+		t.stopFailedTimer()
+		switch {
+		case state != ICETransportStateDisconnected:
+		case failedTimeout > 0:
+			t.failedTimerGeneration++
+			generation := t.failedTimerGeneration
+			t.failedTimer = time.AfterFunc(failedTimeout, func() {
+				t.failDisconnected(agent, generation)
+			})
+		}
+		t.state = state
+		t.lock.Unlock()
+
+		t.onConnectionStateChange(state)
+	}); err != nil {
+		return err
+	}
+	return agent.OnSelectedCandidatePairChange(func(local, remote ice.Candidate) {
+		candidates, err := newICECandidatesFromICE([]ice.Candidate{local, remote})
+		if err != nil {
+			t.log.Warnf("Unable to convert ICE candidates to ICECandidates: %s", err)
+			return
+		}
+		pair := NewICECandidatePair(&candidates[0], &candidates[1])
+
+		t.lock.Lock()
+		current := t.agent == agent
+		switch {
+		case current:
+			t.selectedCandidatePair = pair
+		case t.gatherer.getPendingAgent() == agent:
+			t.pendingSelectedCandidatePair = pair
+		}
+		t.lock.Unlock()
+
+		if current {
+			t.onSelectedCandidatePairChange(pair)
+		}
+	})
+}
+
+// restart connects the pending agent of the gatherer, created by an ICE
+// restart, with the remote peer using the provided remote parameters. When
+// connected the current connection is replaced, keeping the upper transports
+// running, and the previous agent is closed.
+func (t *ICETransport) restart(params ICEParameters) error {
 	t.lock.Lock()
+	agent := t.gatherer.getPendingAgent()
+	if agent == nil {
+		t.lock.Unlock()
+		return errors.New("no pending ICE restart")
+	}
+	if t.mux == nil {
+		t.lock.Unlock()
+		return errors.New("ICETransport not started, unable to restart")
+	}
+	if err := t.setAgentHandlers(agent); err != nil {
+		t.lock.Unlock()
+		return err
+	}
+	t.pendingSelectedCandidatePair = nil
+	role := t.role
+	t.lock.Unlock()
+
+	iceConn, err := connect(agent, params, role)
 	if err != nil {
 		return err
 	}
 
+	t.lock.Lock()
+	previousAgent := t.gatherer.commitRestart()
+	t.agent = agent
 	t.conn = iceConn
+	t.restartableConn.setConn(iceConn)
+	t.state = ICETransportStateConnected
+	t.failed = false
+	t.stopFailedTimer()
+	pair := t.pendingSelectedCandidatePair
+	if pair != nil {
+		t.selectedCandidatePair = pair
+	}
+	t.pendingSelectedCandidatePair = nil
+	t.lock.Unlock()
 
-	config := mux.Config{
-		Conn:          t.conn,
-		BufferSize:    receiveMTU,
-		LoggerFactory: t.loggerFactory,
+	t.onConnectionStateChange(ICETransportStateConnected)
+	// the pair selected by the new agent replaces the previous one
+	if pair != nil {
+		t.onSelectedCandidatePairChange(pair)
 	}
-	t.mux = mux.NewMux(config)
 
+	if previousAgent != nil {
+		return previousAgent.Close()
+	}
 	return nil
 }
 
+// Restart completes the ICE restart started with ICEGatherer.Restart, once
+// the remote peer restarted too. The new agent connects with the new remote
+// parameters, blocking like Start, and replaces the current one while the
+// DTLS and SCTP transports keep running. The remote candidates added after
+// the ICEGatherer restart are for the new agent.
+func (t *ICETransport) Restart(remoteParameters ICEParameters) error {
+	return t.restart(remoteParameters)
+}
+
+// failDisconnected fails the connection of the agent when it's still
+// disconnected at the expiry of the failed timer of the generation
+func (t *ICETransport) failDisconnected(agent *ice.Agent, generation uint64) {
+	t.lock.Lock()
+	if t.agent != agent || t.failedTimer == nil || t.failedTimerGeneration != generation {
+		t.lock.Unlock()
+		return
+	}
+	t.failedTimer = nil
+	t.failed = true
+	t.state = ICETransportStateFailed
+	t.lock.Unlock()
+
+	t.onConnectionStateChange(ICETransportStateFailed)
+}
+
+// stopFailedTimer stops the failed timer, if any. The caller must hold the
+// lock.
+func (t *ICETransport) stopFailedTimer() {
+	if t.failedTimer != nil {
+		t.failedTimer.Stop()
+		t.failedTimer = nil
+	}
+}
+
 // Stop irreversibly stops the ICETransport.
 func (t *ICETransport) Stop() error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	t.stopFailedTimer()
+
+	if t.gatherer != nil {
+		if err := t.gatherer.cancelRestart(); err != nil {
+			return err
+		}
+	}
+
 	if t.mux != nil {
 		return t.mux.Close()
 	} else if t.gatherer != nil {
@@ -165,8 +316,20 @@ func (t *ICETransport) Stop() error {
 	return nil
 }
 
+// GetSelectedCandidatePair returns the candidate pair used by the connection,
+// nil when no pair has been selected yet. The pair is the one notified by
+// OnSelectedCandidatePairChange.
+func (t *ICETransport) GetSelectedCandidatePair() *ICECandidatePair {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.selectedCandidatePair
+}
+
 // OnSelectedCandidatePairChange sets a handler that is invoked when a new
-// ICE candidate pair is selected
+// ICE candidate pair is selected, the previous one being replaced by a path
+// migration or an ICE restart. The local and remote candidates of the pair
+// tell, for example, if the connection falls back to a relay.
 func (t *ICETransport) OnSelectedCandidatePairChange(f func(*ICECandidatePair)) {
 	t.onSelectedCandidatePairChangeHdlr.Store(f)
 }
@@ -208,7 +371,11 @@ func (t *ICETransport) SetRemoteCandidates(remoteCandidates []ICECandidate) erro
 		return err
 	}
 
-	agent := t.gatherer.getAgent()
+	// during a restart the remote candidates are for the new agent
+	agent := t.gatherer.getPendingAgent()
+	if agent == nil {
+		agent = t.gatherer.getAgent()
+	}
 	if agent == nil {
 		return errors.New("ICEAgent does not exist, unable to set remote candidates")
 	}
@@ -241,7 +408,11 @@ func (t *ICETransport) AddRemoteCandidate(remoteCandidate ICECandidate) error {
 		return err
 	}
 
-	agent := t.gatherer.getAgent()
+	// during a restart the remote candidates are for the new agent
+	agent := t.gatherer.getPendingAgent()
+	if agent == nil {
+		agent = t.gatherer.getAgent()
+	}
 	if agent == nil {
 		return errors.New("ICEAgent does not exist, unable to add remote candidates")
 	}
@@ -284,6 +455,8 @@ func (t *ICETransport) ensureGatherer() error {
 func (t *ICETransport) collectStats(collector *statsReportCollector) {
 	t.lock.Lock()
 	conn := t.conn
+	role := t.role
+	selectedPair := t.selectedCandidatePair
 	t.lock.Unlock()
 
 	collector.Collecting()
@@ -291,13 +464,150 @@ func (t *ICETransport) collectStats(collector *statsReportCollector) {
 	stats := TransportStats{
 		Timestamp: statsTimestampFrom(time.Now()),
 		Type:      StatsTypeTransport,
-		ID:        "iceTransport",
+		ID:        iceTransportStatsID,
+		ICERole:   role,
 	}
 
 	if conn != nil {
 		stats.BytesSent = conn.BytesSent()
 		stats.BytesReceived = conn.BytesReceived()
 	}
+	if selectedPair != nil {
+		stats.SelectedCandidatePairID = newICECandidatePairStatsID(selectedPair.Local.statsID, selectedPair.Remote.statsID)
+	}
 
 	collector.Collect(stats.ID, stats)
+
+	agent := t.gatherer.getAgent()
+	if agent == nil {
+		return
+	}
+
+	collector.Collecting()
+	go func(collector *statsReportCollector, agent *ice.Agent, selectedPairID string, bytesSent, bytesReceived uint64) {
+		for _, candidatePairStats := range agent.GetCandidatePairsStats() {
+			collector.Collecting()
+
+			state, err := toStatsICECandidatePairState(candidatePairStats.State)
+			if err != nil {
+				t.log.Error(err.Error())
+			}
+
+			pairID := newICECandidatePairStatsID(candidatePairStats.LocalCandidateID,
+				candidatePairStats.RemoteCandidateID)
+
+			stats := ICECandidatePairStats{
+				Timestamp:                   statsTimestampFrom(candidatePairStats.Timestamp),
+				Type:                        StatsTypeCandidatePair,
+				ID:                          pairID,
+				TransportID:                 iceTransportStatsID,
+				LocalCandidateID:            candidatePairStats.LocalCandidateID,
+				RemoteCandidateID:           candidatePairStats.RemoteCandidateID,
+				State:                       state,
+				Nominated:                   candidatePairStats.Nominated,
+				PacketsSent:                 candidatePairStats.PacketsSent,
+				PacketsReceived:             candidatePairStats.PacketsReceived,
+				BytesSent:                   candidatePairStats.BytesSent,
+				BytesReceived:               candidatePairStats.BytesReceived,
+				LastPacketSentTimestamp:     statsTimestampFrom(candidatePairStats.LastPacketSentTimestamp),
+				LastPacketReceivedTimestamp: statsTimestampFrom(candidatePairStats.LastPacketReceivedTimestamp),
+				FirstRequestTimestamp:       statsTimestampFrom(candidatePairStats.FirstRequestTimestamp),
+				LastRequestTimestamp:        statsTimestampFrom(candidatePairStats.LastRequestTimestamp),
+				LastResponseTimestamp:       statsTimestampFrom(candidatePairStats.LastResponseTimestamp),
+				TotalRoundTripTime:          candidatePairStats.TotalRoundTripTime,
+				CurrentRoundTripTime:        candidatePairStats.CurrentRoundTripTime,
+				AvailableOutgoingBitrate:    candidatePairStats.AvailableOutgoingBitrate,
+				AvailableIncomingBitrate:    candidatePairStats.AvailableIncomingBitrate,
+				CircuitBreakerTriggerCount:  candidatePairStats.CircuitBreakerTriggerCount,
+				RequestsReceived:            candidatePairStats.RequestsReceived,
+				RequestsSent:                candidatePairStats.RequestsSent,
+				ResponsesReceived:           candidatePairStats.ResponsesReceived,
+				ResponsesSent:               candidatePairStats.ResponsesSent,
+				RetransmissionsReceived:     candidatePairStats.RetransmissionsReceived,
+				RetransmissionsSent:         candidatePairStats.RetransmissionsSent,
+				ConsentRequestsSent:         candidatePairStats.ConsentRequestsSent,
+				ConsentExpiredTimestamp:     statsTimestampFrom(candidatePairStats.ConsentExpiredTimestamp),
+			}
+			// the traffic of the connection goes through the selected pair
+			if pairID == selectedPairID {
+				stats.BytesSent = bytesSent
+				stats.BytesReceived = bytesReceived
+			}
+			collector.Collect(stats.ID, stats)
+		}
+
+		collector.Done()
+	}(collector, agent, stats.SelectedCandidatePairID, stats.BytesSent, stats.BytesReceived)
+}
+
+// iceTransportStatsID is the ID of the TransportStats of the ICETransport
+const iceTransportStatsID = "iceTransport"
+
+// restartableConn is a net.Conn whose underlying ICE connection can be
+// replaced. The reads from a replaced connection continue on the new one.
+type restartableConn struct {
+	lock sync.RWMutex
+	conn *ice.Conn
+}
+
+func newRestartableConn(conn *ice.Conn) *restartableConn {
+	return &restartableConn{conn: conn}
+}
+
+func (c *restartableConn) getConn() *ice.Conn {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.conn
+}
+
+func (c *restartableConn) setConn(conn *ice.Conn) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.conn = conn
+}
+
+func (c *restartableConn) Read(b []byte) (int, error) {
+	for {
+		conn := c.getConn()
+		n, err := conn.Read(b)
+		if err != nil && c.getConn() != conn {
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *restartableConn) Write(b []byte) (int, error) {
+	for {
+		conn := c.getConn()
+		n, err := conn.Write(b)
+		if err != nil && c.getConn() != conn {
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *restartableConn) Close() error {
+	return c.getConn().Close()
+}
+
+func (c *restartableConn) LocalAddr() net.Addr {
+	return c.getConn().LocalAddr()
+}
+
+func (c *restartableConn) RemoteAddr() net.Addr {
+	return c.getConn().RemoteAddr()
+}
+
+func (c *restartableConn) SetDeadline(t time.Time) error {
+	return c.getConn().SetDeadline(t)
+}
+
+func (c *restartableConn) SetReadDeadline(t time.Time) error {
+	return c.getConn().SetReadDeadline(t)
+}
+
+func (c *restartableConn) SetWriteDeadline(t time.Time) error {
+	return c.getConn().SetWriteDeadline(t)
 }
@@ -62,5 +62,14 @@ func TestICETransport_OnSelectedCandidatePairChange(t *testing.T) {
 		t.Fatalf("Sender ICETransport OnSelectedCandidateChange was never called")
 	}
 
+	for _, pc := range []*PeerConnection{pcOffer, pcAnswer} {
+		pair := pc.iceTransport.GetSelectedCandidatePair()
+		if assert.NotNil(t, pair) {
+			assert.Equal(t, ICECandidateTypeHost, pair.Local.Typ)
+			assert.NotZero(t, pair.Local.Priority)
+			assert.NotEmpty(t, pair.Remote.Address)
+		}
+	}
+
 	closePairNow(t, pcOffer, pcAnswer)
 }
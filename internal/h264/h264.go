@@ -0,0 +1,130 @@
+// Package h264 applies the sample transforms to the payload of the H.264 NAL
+// units, keeping the NAL unit headers in the clear as SFrame does
+package h264
+
+import (
+	"github.com/pion/webrtc/v2/pkg/media"
+)
+
+// trailer terminates the escaped payloads, so they never end with a zero
+// byte that would be taken as part of the next start code
+const trailer = 0x80
+
+// emulationPrevention is the byte inserted after two zero bytes to avoid
+// emulating a start code
+const emulationPrevention = 0x03
+
+var startCode = []byte{0x00, 0x00, 0x00, 0x01}
+
+// TransformOutgoing applies transform to the payload of every NAL unit of an
+// Annex B sample before it's packetized. The transformed payloads are escaped
+// with emulation prevention bytes and terminated by a trailer byte, so they
+// can't contain start codes.
+func TransformOutgoing(sample media.Sample, transform media.SampleTransform) (media.Sample, error) {
+	return transformNALUnits(sample, func(payload []byte) ([]byte, error) {
+		transformed, err := transform(media.Sample{Data: payload, Samples: sample.Samples})
+		if err != nil {
+			return nil, err
+		}
+		return escape(transformed.Data), nil
+	})
+}
+
+// TransformIncoming reverts the escaping of TransformOutgoing and applies
+// transform to the payload of every NAL unit of a depacketized sample
+func TransformIncoming(sample media.Sample, transform media.SampleTransform) (media.Sample, error) {
+	return transformNALUnits(sample, func(payload []byte) ([]byte, error) {
+		transformed, err := transform(media.Sample{Data: unescape(payload), Samples: sample.Samples})
+		if err != nil {
+			return nil, err
+		}
+		return transformed.Data, nil
+	})
+}
+
+func transformNALUnits(sample media.Sample, transform func([]byte) ([]byte, error)) (media.Sample, error) {
+	data := []byte{}
+	for _, nalu := range splitNALUnits(sample.Data) {
+		payload, err := transform(nalu[1:])
+		if err != nil {
+			return media.Sample{}, err
+		}
+		data = append(data, startCode...)
+		data = append(data, nalu[0])
+		data = append(data, payload...)
+	}
+	return media.Sample{Data: data, Samples: sample.Samples}, nil
+}
+
+// splitNALUnits returns the NAL units of an Annex B stream like the H.264
+// payloader does: the data is a single NAL unit if it has no start codes,
+// otherwise the data before the first start code is ignored
+func splitNALUnits(data []byte) [][]byte {
+	nalus := [][]byte{}
+	start, zeros := -1, 0
+	for i, b := range data {
+		switch {
+		case b == 0x00:
+			zeros++
+			continue
+		case b == 0x01 && zeros >= 2:
+			if start >= 0 && i-zeros > start {
+				nalus = append(nalus, data[start:i-zeros])
+			}
+			start = i + 1
+		}
+		zeros = 0
+	}
+
+	switch {
+	case start < 0 && len(data) > 0:
+		nalus = append(nalus, data)
+	case start >= 0 && start < len(data):
+		nalus = append(nalus, data[start:])
+	}
+	return nalus
+}
+
+// escape inserts an emulation prevention byte after every two zero bytes
+// followed by a byte lower than 4, then appends the trailer
+func escape(data []byte) []byte {
+	escaped := make([]byte, 0, len(data)+len(data)/2+1)
+	zeros := 0
+	for _, b := range data {
+		if zeros >= 2 && b <= emulationPrevention {
+			escaped = append(escaped, emulationPrevention)
+			zeros = 0
+		}
+		escaped = append(escaped, b)
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return append(escaped, trailer)
+}
+
+// unescape removes the trailer and the emulation prevention bytes inserted by
+// escape
+func unescape(data []byte) []byte {
+	if len(data) > 0 && data[len(data)-1] == trailer {
+		data = data[:len(data)-1]
+	}
+
+	unescaped := make([]byte, 0, len(data))
+	zeros := 0
+	for _, b := range data {
+		if zeros >= 2 && b == emulationPrevention {
+			zeros = 0
+			continue
+		}
+		unescaped = append(unescaped, b)
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return unescaped
+}
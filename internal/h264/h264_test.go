@@ -0,0 +1,68 @@
+package h264
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitNALUnits(t *testing.T) {
+	for _, test := range []struct {
+		data  []byte
+		nalus [][]byte
+	}{
+		{[]byte{}, [][]byte{}},
+		{[]byte{0x09, 0x10}, [][]byte{{0x09, 0x10}}},
+		{[]byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x00, 0x00, 0x01, 0x68}, [][]byte{{0x67, 0x42}, {0x68}}},
+		{[]byte{0xFF, 0x00, 0x00, 0x01, 0x65, 0x00, 0x00, 0x00, 0x01}, [][]byte{{0x65}}},
+	} {
+		assert.Equal(t, test.nalus, splitNALUnits(test.data))
+	}
+}
+
+func TestEscape(t *testing.T) {
+	for _, test := range []struct {
+		data    []byte
+		escaped []byte
+	}{
+		{[]byte{}, []byte{trailer}},
+		{[]byte{0x00}, []byte{0x00, trailer}},
+		{[]byte{0x00, 0x00, 0x01}, []byte{0x00, 0x00, 0x03, 0x01, trailer}},
+		{[]byte{0x00, 0x00, 0x00, 0x00}, []byte{0x00, 0x00, 0x03, 0x00, 0x00, trailer}},
+		{[]byte{0x00, 0x00, 0x03, 0x80}, []byte{0x00, 0x00, 0x03, 0x03, 0x80, trailer}},
+		{[]byte{0x00, 0x00, 0x04}, []byte{0x00, 0x00, 0x04, trailer}},
+	} {
+		escaped := escape(test.data)
+		assert.Equal(t, test.escaped, escaped)
+		assert.Equal(t, 1, len(splitNALUnits(append([]byte{0x01}, escaped...))), "escaped data contains a start code")
+		assert.Equal(t, test.data, unescape(escaped))
+	}
+}
+
+func TestTransform(t *testing.T) {
+	// the transform makes the payloads contain start codes
+	xor := func(sample media.Sample) (media.Sample, error) {
+		data := make([]byte, len(sample.Data))
+		for i, b := range sample.Data {
+			data[i] = b ^ 0x42
+		}
+		return media.Sample{Data: data, Samples: sample.Samples}, nil
+	}
+
+	sample := media.Sample{Data: []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x42, 0x43, 0x00, 0x00, 0x00, 0x01, 0x65, 0x10, 0x42}, Samples: 3000}
+	outgoing, err := TransformOutgoing(sample, xor)
+	assert.NoError(t, err)
+	assert.Equal(t, media.Sample{Data: []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x00, 0x00, 0x03, 0x01, trailer, 0x00, 0x00, 0x00, 0x01, 0x65, 0x52, 0x00, trailer}, Samples: 3000}, outgoing)
+
+	incoming, err := TransformIncoming(outgoing, xor)
+	assert.NoError(t, err)
+	assert.Equal(t, sample, incoming)
+
+	errTransform := errors.New("transform failed")
+	_, err = TransformOutgoing(sample, func(media.Sample) (media.Sample, error) {
+		return media.Sample{}, errTransform
+	})
+	assert.Equal(t, errTransform, err)
+}
@@ -17,6 +17,7 @@ const (
 	DefaultPayloadTypePCMU = 0
 	DefaultPayloadTypePCMA = 8
 	DefaultPayloadTypeG722 = 9
+	DefaultPayloadTypeCN   = 13
 	DefaultPayloadTypeOpus = 111
 	DefaultPayloadTypeVP8  = 96
 	DefaultPayloadTypeVP9  = 98
@@ -24,32 +25,250 @@ const (
 
 	mediaNameAudio = "audio"
 	mediaNameVideo = "video"
+
+	dynamicPayloadTypeMin = 96
+	dynamicPayloadTypeMax = 127
 )
 
 // MediaEngine defines the codecs supported by a PeerConnection
 type MediaEngine struct {
 	codecs []*RTPCodec
+	rtx    bool
+	// rtxCodecs are the RTX codecs registered automatically, their dynamic
+	// payload type is moved when a codec is registered with the same one
+	rtxCodecs map[*RTPCodec]struct{}
+
+	dynamicPayloadTypes struct {
+		set      bool
+		min, max uint8
+		reserved map[uint8]struct{}
+	}
 }
 
-// RegisterCodec registers a codec to a media engine
+// RegisterCodec registers a codec to a media engine.
+// When RTX is enabled, registering a video codec also registers an
+// associated RTX codec using a free dynamic payload type. An automatically
+// registered RTX codec using the payload type of the codec is moved to
+// another free payload type.
 func (m *MediaEngine) RegisterCodec(codec *RTPCodec) uint8 {
 	// pion/webrtc#43
 	m.codecs = append(m.codecs, codec)
+	m.moveRTXCodecs(codec)
+	if m.rtx {
+		m.registerRTXCodec(codec)
+	}
 	return codec.PayloadType
 }
 
-// RegisterDefaultCodecs is a helper that registers the default codecs supported by Pion WebRTC
+// EnableRTX enables the automatic registration of a RTX (RFC 4588) codec
+// for every video codec registered in the MediaEngine, including the ones
+// already registered. Every RTX codec uses a free dynamic payload type
+// and its apt parameter points to the associated codec payload type. The
+// RED, ULPFEC and FlexFEC codecs don't get a RTX codec.
+func (m *MediaEngine) EnableRTX() {
+	m.rtx = true
+
+	for _, codec := range append([]*RTPCodec{}, m.codecs...) {
+		m.registerRTXCodec(codec)
+	}
+}
+
+// registerRTXCodec registers a RTX codec for the provided codec if it's
+// a video codec carrying media and doesn't already have an associated RTX
+// codec
+func (m *MediaEngine) registerRTXCodec(codec *RTPCodec) {
+	if codec.Type != RTPCodecTypeVideo {
+		return
+	}
+	switch codec.Name {
+	case RTX, RED, ULPFEC, FlexFEC:
+		return
+	}
+	if m.getRTXCodec(codec.PayloadType) != nil {
+		return
+	}
+
+	payloadType, ok := m.freePayloadType()
+	if !ok {
+		return
+	}
+
+	rtx := NewRTPRTXCodec(payloadType, codec.ClockRate, codec.PayloadType)
+	if m.rtxCodecs == nil {
+		m.rtxCodecs = make(map[*RTPCodec]struct{})
+	}
+	m.rtxCodecs[rtx] = struct{}{}
+	m.codecs = append(m.codecs, rtx)
+}
+
+// moveRTXCodecs moves the automatically registered RTX codecs using the
+// payload type of the provided codec to a free payload type, they are
+// removed when there isn't one
+func (m *MediaEngine) moveRTXCodecs(codec *RTPCodec) {
+	for rtx := range m.rtxCodecs {
+		if rtx == codec || rtx.PayloadType != codec.PayloadType {
+			continue
+		}
+
+		if payloadType, ok := m.freePayloadType(); ok {
+			rtx.PayloadType = payloadType
+			continue
+		}
+
+		delete(m.rtxCodecs, rtx)
+		for i, c := range m.codecs {
+			if c == rtx {
+				m.codecs = append(m.codecs[:i], m.codecs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// getRTXCodec returns the RTX codec associated to the provided payload type
+func (m *MediaEngine) getRTXCodec(payloadType uint8) *RTPCodec {
+	apt := fmt.Sprintf("apt=%d", payloadType)
+	for _, codec := range m.codecs {
+		if codec.Name == RTX && codec.SDPFmtpLine == apt {
+			return codec
+		}
+	}
+	return nil
+}
+
+// SetDynamicPayloadTypeRange sets the range of payload types used when
+// the MediaEngine allocates a dynamic payload type. The range must be
+// part of the dynamic payload types (96-127), which is the default range.
+func (m *MediaEngine) SetDynamicPayloadTypeRange(min, max uint8) error {
+	if min < dynamicPayloadTypeMin || min > max || max > dynamicPayloadTypeMax {
+		return ErrInvalidPayloadTypeRange
+	}
+
+	m.dynamicPayloadTypes.set = true
+	m.dynamicPayloadTypes.min = min
+	m.dynamicPayloadTypes.max = max
+	return nil
+}
+
+// ReservePayloadTypes excludes the provided payload types from the ones
+// the MediaEngine can allocate dynamically. This is useful when
+// interacting with gateways reserving some payload types for their own use.
+func (m *MediaEngine) ReservePayloadTypes(payloadTypes ...uint8) {
+	if m.dynamicPayloadTypes.reserved == nil {
+		m.dynamicPayloadTypes.reserved = make(map[uint8]struct{})
+	}
+	for _, pt := range payloadTypes {
+		m.dynamicPayloadTypes.reserved[pt] = struct{}{}
+	}
+}
+
+// RegisterDynamicCodec registers a codec to a media engine replacing its
+// payload type with a free one allocated from the dynamic payload types
+// range. It returns the allocated payload type.
+func (m *MediaEngine) RegisterDynamicCodec(codec *RTPCodec) (uint8, error) {
+	payloadType, ok := m.freePayloadType()
+	if !ok {
+		return 0, ErrNoFreePayloadType
+	}
+
+	codec.PayloadType = payloadType
+	return m.RegisterCodec(codec), nil
+}
+
+func (m *MediaEngine) dynamicPayloadTypeRange() (uint8, uint8) {
+	if m.dynamicPayloadTypes.set {
+		return m.dynamicPayloadTypes.min, m.dynamicPayloadTypes.max
+	}
+	return dynamicPayloadTypeMin, dynamicPayloadTypeMax
+}
+
+// isUsableDynamicPayloadType reports if the payload type is part of the
+// dynamic payload types range and isn't reserved
+func (m *MediaEngine) isUsableDynamicPayloadType(payloadType uint8) bool {
+	min, max := m.dynamicPayloadTypeRange()
+	if payloadType < min || payloadType > max {
+		return false
+	}
+	_, reserved := m.dynamicPayloadTypes.reserved[payloadType]
+	return !reserved
+}
+
+// isAllocatable reports if the payload type is usable and isn't already
+// used by a registered codec
+func (m *MediaEngine) isAllocatable(payloadType uint8) bool {
+	if !m.isUsableDynamicPayloadType(payloadType) {
+		return false
+	}
+	_, err := m.getCodec(payloadType)
+	return err == ErrCodecNotFound
+}
+
+// freePayloadType returns the first dynamic payload type not already used
+// by a registered codec
+func (m *MediaEngine) freePayloadType() (uint8, bool) {
+	min, max := m.dynamicPayloadTypeRange()
+	for pt := int(min); pt <= int(max); pt++ {
+		if m.isAllocatable(uint8(pt)) {
+			return uint8(pt), true
+		}
+	}
+	return 0, false
+}
+
+// registerDefaultCodec registers a default codec. If the default payload type is
+// a dynamic one that is reserved or outside the dynamic payload types range a
+// free payload type will be allocated.
+func (m *MediaEngine) registerDefaultCodec(codec *RTPCodec) error {
+	if codec.PayloadType >= dynamicPayloadTypeMin && !m.isUsableDynamicPayloadType(codec.PayloadType) {
+		_, err := m.RegisterDynamicCodec(codec)
+		return err
+	}
+	m.RegisterCodec(codec)
+	return nil
+}
+
+// RegisterDefaultCodecs is a helper that registers the default codecs supported by Pion WebRTC.
+// Default dynamic payload types that are reserved or outside the configured dynamic payload
+// types range are replaced by a free one. When the range doesn't have enough free payload
+// types no codec is registered, use RegisterDefaultCodecsChecked to get the error.
 func (m *MediaEngine) RegisterDefaultCodecs() {
-	// Audio Codecs in order of preference
-	m.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
-	m.RegisterCodec(NewRTPPCMUCodec(DefaultPayloadTypePCMU, 8000))
-	m.RegisterCodec(NewRTPPCMACodec(DefaultPayloadTypePCMA, 8000))
-	m.RegisterCodec(NewRTPG722Codec(DefaultPayloadTypeG722, 8000))
+	_ = m.RegisterDefaultCodecsChecked()
+}
 
-	// Video Codecs in order of preference
-	m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
-	m.RegisterCodec(NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000))
-	m.RegisterCodec(NewRTPH264Codec(DefaultPayloadTypeH264, 90000))
+// RegisterDefaultCodecsChecked registers the default codecs like RegisterDefaultCodecs.
+// ErrNoFreePayloadType is returned when the range doesn't have enough free payload types
+// for all the default codecs, the MediaEngine is then left as it was before the call.
+func (m *MediaEngine) RegisterDefaultCodecsChecked() error {
+	codecs := append([]*RTPCodec{}, m.codecs...)
+	rtxPayloadTypes := make(map[*RTPCodec]uint8, len(m.rtxCodecs))
+	for rtx := range m.rtxCodecs {
+		rtxPayloadTypes[rtx] = rtx.PayloadType
+	}
+
+	for _, codec := range []*RTPCodec{
+		// Audio Codecs in order of preference
+		NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000),
+		NewRTPPCMUCodec(DefaultPayloadTypePCMU, 8000),
+		NewRTPPCMACodec(DefaultPayloadTypePCMA, 8000),
+		NewRTPG722Codec(DefaultPayloadTypeG722, 8000),
+
+		// Video Codecs in order of preference
+		NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000),
+		NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000),
+		NewRTPH264Codec(DefaultPayloadTypeH264, 90000),
+	} {
+		if err := m.registerDefaultCodec(codec); err != nil {
+			// roll back the partial registration
+			m.codecs = codecs
+			m.rtxCodecs = make(map[*RTPCodec]struct{}, len(rtxPayloadTypes))
+			for rtx, payloadType := range rtxPayloadTypes {
+				rtx.PayloadType = payloadType
+				m.rtxCodecs[rtx] = struct{}{}
+			}
+			return err
+		}
+	}
+	return nil
 }
 
 // PopulateFromSDP finds all codecs in a session description and adds them to a MediaEngine, using dynamic
@@ -93,13 +312,23 @@ func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 				codec = NewRTPVP9Codec(payloadType, payloadCodec.ClockRate)
 			case strings.EqualFold(payloadCodec.Name, H264):
 				codec = NewRTPH264Codec(payloadType, payloadCodec.ClockRate)
+			case strings.EqualFold(payloadCodec.Name, RED) && md.MediaName.Media == mediaNameVideo:
+				codec = NewRTPREDCodec(payloadType, payloadCodec.ClockRate)
+			case strings.EqualFold(payloadCodec.Name, ULPFEC):
+				codec = NewRTPULPFECCodec(payloadType, payloadCodec.ClockRate)
+			case strings.EqualFold(payloadCodec.Name, FlexFEC):
+				codec = NewRTPFlexFECCodec(payloadType, payloadCodec.ClockRate)
+			case strings.EqualFold(payloadCodec.Name, RTX):
+				codec = NewRTPRTXCodec(payloadType, payloadCodec.ClockRate, 0)
 			default:
 				// ignoring other codecs
 				continue
 			}
 
+			// the remote description already declares its RTX codecs so
+			// don't automatically register new ones
 			codec.SDPFmtpLine = payloadCodec.Fmtp
-			m.RegisterCodec(codec)
+			m.codecs = append(m.codecs, codec)
 		}
 	}
 	return nil
@@ -127,6 +356,16 @@ func (m *MediaEngine) getCodecSDP(sdpCodec sdp.Codec) (*RTPCodec, error) {
 	return nil, ErrCodecNotFound
 }
 
+// getCodecByName returns the first codec of the provided kind with the provided name
+func (m *MediaEngine) getCodecByName(kind RTPCodecType, name string) *RTPCodec {
+	for _, codec := range m.codecs {
+		if codec.Type == kind && strings.EqualFold(codec.Name, name) {
+			return codec
+		}
+	}
+	return nil
+}
+
 // GetCodecsByKind returns all codecs of a chosen kind in the codecs list
 func (m *MediaEngine) GetCodecsByKind(kind RTPCodecType) []*RTPCodec {
 	var codecs []*RTPCodec
@@ -143,10 +382,17 @@ const (
 	PCMU = "PCMU"
 	PCMA = "PCMA"
 	G722 = "G722"
+	G729 = "G729"
+	CN   = "CN"
 	Opus = "opus"
 	VP8  = "VP8"
 	VP9  = "VP9"
 	H264 = "H264"
+	RTX  = "rtx"
+
+	RED     = "red"
+	ULPFEC  = "ulpfec"
+	FlexFEC = "flexfec-03"
 )
 
 // NewRTPPCMUCodec is a helper to create a PCMU codec
@@ -185,6 +431,20 @@ func NewRTPG722Codec(payloadType uint8, clockrate uint32) *RTPCodec {
 	return c
 }
 
+// NewRTPCNCodec is a helper to create a comfort noise (RFC 3389) codec. It's
+// offered only when the voice activity detection isn't disabled by the
+// OfferAnswerOptions.
+func NewRTPCNCodec(payloadType uint8, clockrate uint32) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		CN,
+		clockrate,
+		0,
+		"",
+		payloadType,
+		nil)
+	return c
+}
+
 // NewRTPOpusCodec is a helper to create an Opus codec
 func NewRTPOpusCodec(payloadType uint8, clockrate uint32) *RTPCodec {
 	c := NewRTPCodec(RTPCodecTypeAudio,
@@ -259,6 +519,57 @@ func NewRTPH264CodecExt(payloadType uint8, clockrate uint32, rtcpfb []RTCPFeedba
 	return c
 }
 
+// NewRTPRTXCodec is a helper to create a RTX (RFC 4588) codec used to
+// retransmit packets of the codec with the apt payload type
+func NewRTPRTXCodec(payloadType uint8, clockrate uint32, apt uint8) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeVideo,
+		RTX,
+		clockrate,
+		0,
+		fmt.Sprintf("apt=%d", apt),
+		payloadType,
+		nil)
+	return c
+}
+
+// NewRTPREDCodec is a helper to create a video RED (RFC 2198) codec. Remote
+// RED streams are unwrapped, and lost packets are recovered when an ULPFEC
+// codec is also registered, before being returned by ReadRTP.
+func NewRTPREDCodec(payloadType uint8, clockrate uint32) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeVideo,
+		RED,
+		clockrate,
+		0,
+		"",
+		payloadType,
+		nil)
+	return c
+}
+
+// NewRTPULPFECCodec is a helper to create an ULPFEC (RFC 5109) codec
+func NewRTPULPFECCodec(payloadType uint8, clockrate uint32) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeVideo,
+		ULPFEC,
+		clockrate,
+		0,
+		"",
+		payloadType,
+		nil)
+	return c
+}
+
+// NewRTPFlexFECCodec is a helper to create a FlexFEC (draft-ietf-payload-flexible-fec-scheme-03) codec
+func NewRTPFlexFECCodec(payloadType uint8, clockrate uint32) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeVideo,
+		FlexFEC,
+		clockrate,
+		0,
+		"repair-window=10000000",
+		payloadType,
+		nil)
+	return c
+}
+
 // RTPCodecType determines the type of a codec
 type RTPCodecType int
 
@@ -3,6 +3,7 @@
 package webrtc
 
 import (
+	"fmt"
 	"regexp"
 	"testing"
 
@@ -108,3 +109,188 @@ func TestOpusCase(t *testing.T) {
 	assert.True(t, regexp.MustCompile(`(?m)^a=rtpmap:\d+ opus/48000/2`).MatchString(offer.SDP))
 	assert.NoError(t, pc.Close())
 }
+
+func TestMediaEngineRTX(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	m.EnableRTX()
+	m.RegisterCodec(NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000))
+	m.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+
+	rtx := m.getRTXCodec(DefaultPayloadTypeVP8)
+	if assert.NotNil(t, rtx) {
+		assert.Equal(t, uint8(97), rtx.PayloadType)
+		assert.Equal(t, "apt=96", rtx.SDPFmtpLine)
+	}
+
+	rtx = m.getRTXCodec(DefaultPayloadTypeVP9)
+	if assert.NotNil(t, rtx) {
+		assert.Equal(t, uint8(99), rtx.PayloadType)
+		assert.Equal(t, "apt=98", rtx.SDPFmtpLine)
+	}
+
+	assert.Nil(t, m.getRTXCodec(DefaultPayloadTypeOpus))
+	assert.Len(t, m.GetCodecsByKind(RTPCodecTypeVideo), 4)
+
+	// enabling RTX again must not register duplicated RTX codecs
+	m.EnableRTX()
+	assert.Len(t, m.GetCodecsByKind(RTPCodecTypeVideo), 4)
+
+	api := NewAPI(WithMediaEngine(m))
+	pc, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pc.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	assert.True(t, regexp.MustCompile(`(?m)^a=rtpmap:97 rtx/90000`).MatchString(offer.SDP))
+	assert.True(t, regexp.MustCompile(`(?m)^a=fmtp:97 apt=96`).MatchString(offer.SDP))
+	assert.True(t, regexp.MustCompile(`(?m)^a=fmtp:99 apt=98`).MatchString(offer.SDP))
+	assert.NoError(t, pc.Close())
+}
+
+func TestMediaEngineRTXPayloadTypes(t *testing.T) {
+	m := MediaEngine{}
+	m.EnableRTX()
+	m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	m.RegisterCodec(NewRTPREDCodec(116, 90000))
+	m.RegisterCodec(NewRTPULPFECCodec(117, 90000))
+	m.RegisterCodec(NewRTPFlexFECCodec(118, 90000))
+
+	assert.Nil(t, m.getRTXCodec(116))
+	assert.Nil(t, m.getRTXCodec(117))
+	assert.Nil(t, m.getRTXCodec(118))
+	assert.Len(t, m.GetCodecsByKind(RTPCodecTypeVideo), 5)
+
+	// registering a codec with the payload type of a RTX codec moves it
+	m.RegisterCodec(NewRTPH264Codec(97, 90000))
+	h264, err := m.getCodec(97)
+	assert.NoError(t, err)
+	assert.Equal(t, H264, h264.Name)
+
+	rtx := m.getRTXCodec(DefaultPayloadTypeVP8)
+	if assert.NotNil(t, rtx) {
+		assert.Equal(t, uint8(98), rtx.PayloadType)
+	}
+	rtx = m.getRTXCodec(97)
+	if assert.NotNil(t, rtx) {
+		assert.Equal(t, uint8(99), rtx.PayloadType)
+	}
+}
+
+func TestMediaEngineFlexFEC(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	m.RegisterCodec(NewRTPFlexFECCodec(118, 90000))
+
+	api := NewAPI(WithMediaEngine(m))
+	pc, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pc.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pc.AddTrack(track)
+	assert.NoError(t, err)
+	assert.NotZero(t, sender.getFECSSRC())
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	assert.True(t, regexp.MustCompile(`(?m)^a=rtpmap:118 flexfec-03/90000`).MatchString(offer.SDP))
+	assert.True(t, regexp.MustCompile(`(?m)^a=fmtp:118 repair-window=10000000`).MatchString(offer.SDP))
+	assert.True(t, regexp.MustCompile(fmt.Sprintf(`(?m)^a=ssrc-group:FEC-FR 5000 %d`, sender.getFECSSRC())).MatchString(offer.SDP))
+	assert.NoError(t, pc.Close())
+}
+
+func TestMediaEngineREDULPFEC(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	m.RegisterCodec(NewRTPREDCodec(116, 90000))
+	m.RegisterCodec(NewRTPULPFECCodec(117, 90000))
+
+	api := NewAPI(WithMediaEngine(m))
+	pc, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pc.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	assert.True(t, regexp.MustCompile(`(?m)^a=rtpmap:116 red/90000`).MatchString(offer.SDP))
+	assert.True(t, regexp.MustCompile(`(?m)^a=rtpmap:117 ulpfec/90000`).MatchString(offer.SDP))
+
+	remote := MediaEngine{}
+	assert.NoError(t, remote.PopulateFromSDP(offer))
+	red, err := remote.getCodec(116)
+	assert.NoError(t, err)
+	assert.Equal(t, RED, red.Name)
+	ulpfec, err := remote.getCodec(117)
+	assert.NoError(t, err)
+	assert.Equal(t, ULPFEC, ulpfec.Name)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestMediaEngineDynamicPayloadTypes(t *testing.T) {
+	t.Run("Invalid range", func(t *testing.T) {
+		m := MediaEngine{}
+		assert.Equal(t, ErrInvalidPayloadTypeRange, m.SetDynamicPayloadTypeRange(110, 100))
+		assert.Equal(t, ErrInvalidPayloadTypeRange, m.SetDynamicPayloadTypeRange(100, 128))
+		assert.Equal(t, ErrInvalidPayloadTypeRange, m.SetDynamicPayloadTypeRange(35, 100))
+	})
+
+	t.Run("Default codecs", func(t *testing.T) {
+		m := MediaEngine{}
+		assert.NoError(t, m.SetDynamicPayloadTypeRange(100, 110))
+		m.ReservePayloadTypes(100, 101)
+		assert.NoError(t, m.RegisterDefaultCodecsChecked())
+
+		for _, codec := range m.codecs {
+			if codec.PayloadType < dynamicPayloadTypeMin {
+				continue
+			}
+			assert.True(t, codec.PayloadType >= 102 && codec.PayloadType <= 110, "codec %s has payload type %d", codec.Name, codec.PayloadType)
+		}
+
+		// PCMU, PCMA and G722 use static payload types
+		_, err := m.getCodec(DefaultPayloadTypePCMU)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Exhausted range", func(t *testing.T) {
+		m := MediaEngine{}
+		assert.NoError(t, m.SetDynamicPayloadTypeRange(120, 121))
+
+		pt, err := m.RegisterDynamicCodec(NewRTPVP8Codec(0, 90000))
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(120), pt)
+
+		pt, err = m.RegisterDynamicCodec(NewRTPVP9Codec(0, 90000))
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(121), pt)
+
+		_, err = m.RegisterDynamicCodec(NewRTPH264Codec(0, 90000))
+		assert.Equal(t, ErrNoFreePayloadType, err)
+
+		// the default codecs don't fall back to the reserved payload types
+		// and the partial registration is rolled back
+		m = MediaEngine{}
+		assert.NoError(t, m.SetDynamicPayloadTypeRange(120, 122))
+		m.EnableRTX()
+		m.RegisterCodec(NewRTPVP8Codec(120, 90000))
+		assert.Equal(t, ErrNoFreePayloadType, m.RegisterDefaultCodecsChecked())
+		assert.Len(t, m.codecs, 2)
+		rtx := m.getRTXCodec(120)
+		if assert.NotNil(t, rtx) {
+			assert.Equal(t, uint8(121), rtx.PayloadType)
+		}
+
+		m.RegisterDefaultCodecs()
+		assert.Len(t, m.codecs, 2)
+	})
+}
@@ -0,0 +1,177 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+)
+
+// MediaStream groups the remote tracks sharing the same msid. This is
+// useful for applications that reason in terms of participants instead
+// of single tracks.
+type MediaStream struct {
+	mu sync.RWMutex
+
+	id     string
+	tracks []*Track
+}
+
+// ID returns the MediaStream id (the msid shared by all its tracks)
+func (s *MediaStream) ID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.id
+}
+
+// GetTracks returns the tracks that are currently part of the MediaStream
+func (s *MediaStream) GetTracks() []*Track {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tracks := make([]*Track, len(s.tracks))
+	copy(tracks, s.tracks)
+	return tracks
+}
+
+// addTrack adds a track to the stream and returns true if it's the first one
+func (s *MediaStream) addTrack(t *Track) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, track := range s.tracks {
+		if track == t {
+			return false
+		}
+	}
+	s.tracks = append(s.tracks, t)
+	return len(s.tracks) == 1
+}
+
+// removeTrack removes a track from the stream and returns true if the stream
+// doesn't have any other track
+func (s *MediaStream) removeTrack(t *Track) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, track := range s.tracks {
+		if track == t {
+			s.tracks = append(s.tracks[:i], s.tracks[i+1:]...)
+			break
+		}
+	}
+	return len(s.tracks) == 0
+}
+
+// mediaStreams keeps track of the remote MediaStreams of a PeerConnection
+type mediaStreams struct {
+	mu sync.Mutex
+
+	streams map[string]*MediaStream
+	// tracks are the remote tracks announced by OnTrack, only they are part
+	// of the streams
+	tracks map[*Track]struct{}
+
+	onStreamAddedHandler   func(*MediaStream)
+	onStreamRemovedHandler func(*MediaStream)
+}
+
+func newMediaStreams() *mediaStreams {
+	return &mediaStreams{
+		streams: make(map[string]*MediaStream),
+		tracks:  make(map[*Track]struct{}),
+	}
+}
+
+func (m *mediaStreams) onStreamAdded(f func(*MediaStream)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onStreamAddedHandler = f
+}
+
+func (m *mediaStreams) onStreamRemoved(f func(*MediaStream)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onStreamRemovedHandler = f
+}
+
+// addTrack adds the announced track to the MediaStream matching its label
+// (msid), creating it if needed. Tracks without a msid aren't part of any
+// stream.
+func (m *mediaStreams) addTrack(t *Track) {
+	m.mu.Lock()
+	m.tracks[t] = struct{}{}
+	m.mu.Unlock()
+
+	m.addToStream(t)
+}
+
+// removeTrack removes the track from the MediaStream with the provided id,
+// the stream is removed when it doesn't contain other tracks
+func (m *mediaStreams) removeTrack(id string, t *Track) {
+	m.mu.Lock()
+	delete(m.tracks, t)
+	m.mu.Unlock()
+
+	m.removeFromStream(id, t)
+}
+
+// moveTrack moves a relabelled track from the MediaStream with the provided
+// id to the one matching its new label. Tracks not announced yet are added
+// to their stream when they are.
+func (m *mediaStreams) moveTrack(previousID string, t *Track) {
+	m.mu.Lock()
+	_, announced := m.tracks[t]
+	m.mu.Unlock()
+	if !announced {
+		return
+	}
+
+	m.removeFromStream(previousID, t)
+	m.addToStream(t)
+}
+
+func (m *mediaStreams) addToStream(t *Track) {
+	id := t.Label()
+	if id == "" {
+		return
+	}
+
+	m.mu.Lock()
+	s, ok := m.streams[id]
+	if !ok {
+		s = &MediaStream{id: id}
+		m.streams[id] = s
+	}
+	hdlr := m.onStreamAddedHandler
+	m.mu.Unlock()
+
+	if s.addTrack(t) && hdlr != nil {
+		go hdlr(s)
+	}
+}
+
+func (m *mediaStreams) removeFromStream(id string, t *Track) {
+	m.mu.Lock()
+	s, ok := m.streams[id]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	empty := s.removeTrack(t)
+	if empty {
+		delete(m.streams, id)
+	}
+	hdlr := m.onStreamRemovedHandler
+	m.mu.Unlock()
+
+	if empty && hdlr != nil {
+		go hdlr(s)
+	}
+}
+
+// get returns the MediaStream with the provided id
+func (m *mediaStreams) get(id string) *MediaStream {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.streams[id]
+}
@@ -0,0 +1,43 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaStreams_MoveTrack(t *testing.T) {
+	m := newMediaStreams()
+	relabel := func(track *Track, label string) {
+		previousLabel := track.label
+		track.label = label
+		m.moveTrack(previousLabel, track)
+	}
+
+	// a track without msid joins the stream of its new msid
+	track := &Track{}
+	m.addTrack(track)
+	relabel(track, "stream1")
+	assert.Equal(t, []*Track{track}, m.get("stream1").GetTracks())
+
+	relabel(track, "stream2")
+	assert.Nil(t, m.get("stream1"))
+	assert.Equal(t, []*Track{track}, m.get("stream2").GetTracks())
+
+	relabel(track, "")
+	assert.Nil(t, m.get("stream2"))
+
+	// a track not announced yet joins its stream with OnTrack
+	pending := &Track{label: "stream1"}
+	relabel(pending, "stream3")
+	assert.Nil(t, m.get("stream3"))
+	m.addTrack(pending)
+	assert.Equal(t, []*Track{pending}, m.get("stream3").GetTracks())
+
+	m.removeTrack("stream3", pending)
+	assert.Nil(t, m.get("stream3"))
+	relabel(pending, "stream1")
+	assert.Nil(t, m.get("stream1"))
+}
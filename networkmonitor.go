@@ -0,0 +1,83 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pion/transport/vnet"
+)
+
+// monitorNetwork checks the local addresses, returned by localAddresses,
+// every interval until done is closed, and requests an ICE restart when they
+// change. pion/ice can't gather new candidates for a running agent, the
+// agent of the restart gathers them.
+func (pc *PeerConnection) monitorNetwork(interval time.Duration, done <-chan struct{}, localAddresses func() (string, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	addresses, err := localAddresses()
+	if err != nil {
+		pc.log.Warnf("Failed to get the local addresses: %v", err)
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		current, err := localAddresses()
+		if err != nil {
+			pc.log.Warnf("Failed to get the local addresses: %v", err)
+			continue
+		}
+		if current == addresses {
+			continue
+		}
+		addresses = current
+
+		pc.log.Infof("The local addresses changed, restarting ICE")
+		pc.RestartICE()
+	}
+}
+
+// localAddresses returns the addresses of the interfaces the host candidates
+// are gathered on, like pion/ice selects them
+func (pc *PeerConnection) localAddresses() (string, error) {
+	n := pc.api.settingEngine.vnet
+	if n == nil || !n.IsVirtual() {
+		// the real interfaces are read when the Net is created
+		n = vnet.NewNet(nil)
+	}
+	filter := pc.api.settingEngine.candidates.InterfaceFilter
+
+	ifaces, err := n.Interfaces()
+	if err != nil {
+		return "", err
+	}
+
+	addresses := []string{}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		if filter != nil && !filter(iface.Name) {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			addresses = append(addresses, addr.String())
+		}
+	}
+	sort.Strings(addresses)
+	return strings.Join(addresses, ","), nil
+}
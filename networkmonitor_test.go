@@ -0,0 +1,95 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/transport/vnet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerConnection_LocalAddresses(t *testing.T) {
+	n := vnet.NewNet(&vnet.NetConfig{})
+	s := SettingEngine{}
+	s.SetVNet(n)
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	addresses, err := pc.localAddresses()
+	assert.NoError(t, err)
+
+	// the loopback addresses are ignored
+	lo0, err := n.InterfaceByName("lo0")
+	assert.NoError(t, err)
+	lo0.AddAddr(&net.IPNet{IP: net.ParseIP("127.0.0.2"), Mask: net.CIDRMask(8, 32)})
+	current, err := pc.localAddresses()
+	assert.NoError(t, err)
+	assert.Equal(t, addresses, current)
+
+	eth0, err := n.InterfaceByName("eth0")
+	assert.NoError(t, err)
+	eth0.AddAddr(&net.IPNet{IP: net.ParseIP("192.0.2.1"), Mask: net.CIDRMask(24, 32)})
+	current, err = pc.localAddresses()
+	assert.NoError(t, err)
+	assert.NotEqual(t, addresses, current)
+	assert.Contains(t, current, "192.0.2.1/24")
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestPeerConnection_ICERestartOnNetworkChange(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetICERestartOnNetworkChange(20 * time.Millisecond)
+	pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	assert.NotNil(t, pc.networkMonitorDone)
+	assert.NoError(t, pc.Close())
+
+	pc, err = NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	negotiationNeeded := make(chan struct{})
+	pc.OnNegotiationNeeded(func() {
+		close(negotiationNeeded)
+	})
+
+	// the addresses are provided to the monitor, stopped by Close
+	var lock sync.Mutex
+	addresses := "192.0.2.1/24"
+	pc.networkMonitorDone = make(chan struct{})
+	go pc.monitorNetwork(20*time.Millisecond, pc.networkMonitorDone, func() (string, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return addresses, nil
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-negotiationNeeded:
+		t.Fatal("unexpected ICE restart")
+	default:
+	}
+
+	lock.Lock()
+	addresses = "192.0.2.1/24,198.51.100.1/24"
+	lock.Unlock()
+	<-negotiationNeeded
+
+	pc.mu.RLock()
+	assert.True(t, pc.iceRestartRequested)
+	pc.mu.RUnlock()
+
+	assert.NoError(t, pc.Close())
+}
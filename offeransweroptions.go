@@ -5,7 +5,16 @@ package webrtc
 type OfferAnswerOptions struct {
 	// VoiceActivityDetection allows the application to provide information
 	// about whether it wishes voice detection feature to be enabled or disabled.
+	// It's kept for compatibility and ignored, since its zero value can't
+	// keep the default: use DisableVoiceActivityDetection.
 	VoiceActivityDetection bool
+
+	// DisableVoiceActivityDetection disables the voice activity detection,
+	// which is enabled by default. When enabled the codecs registered in the
+	// MediaEngine are used as they are. When disabled the comfort noise
+	// codecs aren't negotiated and the audio codecs are asked to not
+	// suppress silence.
+	DisableVoiceActivityDetection bool
 }
 
 // AnswerOptions structure describes the options used to control the answer
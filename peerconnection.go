@@ -25,6 +25,17 @@ import (
 	"github.com/pion/webrtc/v2/pkg/rtcerr"
 )
 
+type negotiationNeededState int
+
+const (
+	// negotiationNeededStateEmpty no check is running or queued
+	negotiationNeededStateEmpty negotiationNeededState = iota
+	// negotiationNeededStateRun a check is running
+	negotiationNeededStateRun
+	// negotiationNeededStateQueue a check is running and another one is queued
+	negotiationNeededStateQueue
+)
+
 // PeerConnection represents a WebRTC connection that establishes a
 // peer-to-peer communications with another PeerConnection instance in a
 // browser, or to another endpoint implementing the required protocols.
@@ -51,16 +62,31 @@ type PeerConnection struct {
 
 	isClosed                     *atomicBool
 	negotiationNeeded            bool
+	negotiationNeededState       negotiationNeededState
 	nonTrickleCandidatesSignaled *atomicBool
 
+	// iceRestartRequested is set by RestartICE to restart ICE with the next
+	// offer
+	iceRestartRequested bool
+	// networkMonitorDone stops the network monitor, if any
+	networkMonitorDone chan struct{}
+
 	lastOffer  string
 	lastAnswer string
+	// lastOfferVoiceActivityDetection and lastAnswerVoiceActivityDetection
+	// report if the last offer and answer enabled the voice activity
+	// detection
+	lastOfferVoiceActivityDetection  bool
+	lastAnswerVoiceActivityDetection bool
 
 	// a value containing the last known greater mid value
 	// we internally generate mids as numbers. Needed since JSEP
 	// requires that when reusing a media section a new unique mid
 	// should be defined (see JSEP 3.4.1).
 	greaterMid int
+	// dataMid is the mid of the offered data media section when the mids
+	// are generated by the SettingEngine generator
+	dataMid string
 
 	currentSDESMidExtValue int
 
@@ -68,14 +94,27 @@ type PeerConnection struct {
 
 	pendingReadStreamsSRTP map[uint32]*srtp.ReadStreamSRTP
 
+	// mediaStreams groups the remote tracks by msid
+	mediaStreams *mediaStreams
+
 	onSignalingStateChangeHandler     func(SignalingState)
 	onICEConnectionStateChangeHandler func(ICEConnectionState)
 	onConnectionStateChangeHandler    func(PeerConnectionState)
 	onTrackHandler                    func(*Track, *RTPReceiver)
+	onTrackFirstPacketHandler         func(*Track, *TrackRTPStream, time.Duration)
 	onDataChannelHandler              func(*DataChannel)
+	onNegotiationNeededHandler        func()
+	onTransceiverErrorHandler         func(*RTPTransceiver, error)
+
+	transceiverFeed *transceiverFeed
 
 	onMediaNegotiationHandler func(t *RTPTransceiver, offering bool) *NegotiationData
 
+	onTransformLocalDescriptionHandler func(SDPType, *sdp.SessionDescription) error
+
+	// remoteDescriptionTime is when the last remote description has been set
+	remoteDescriptionTime time.Time
+
 	iceGatherer   *ICEGatherer
 	iceTransport  *ICETransport
 	dtlsTransport *DTLSTransport
@@ -103,6 +142,8 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 	pc := &PeerConnection{
 		statsID: fmt.Sprintf("PeerConnection-%d", time.Now().UnixNano()),
 		ops:     newOperations(),
+
+		transceiverFeed: newTransceiverFeed(),
 		configuration: Configuration{
 			ICEServers:           []ICEServer{},
 			ICETransportPolicy:   ICETransportPolicyAll,
@@ -119,6 +160,7 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		greaterMid:                   -1,
 		currentSDESMidExtValue:       -1,
 		pendingReadStreamsSRTP:       make(map[uint32]*srtp.ReadStreamSRTP),
+		mediaStreams:                 newMediaStreams(),
 		signalingState:               SignalingStateStable,
 		iceConnectionState:           ICEConnectionStateNew,
 		connectionState:              PeerConnectionStateNew,
@@ -167,6 +209,11 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		}
 	})
 
+	if interval := pc.api.settingEngine.networkMonitorInterval; interval > 0 {
+		pc.networkMonitorDone = make(chan struct{})
+		go pc.monitorNetwork(interval, pc.networkMonitorDone, pc.localAddresses)
+	}
+
 	return pc, nil
 }
 
@@ -189,6 +236,11 @@ func (pc *PeerConnection) initConfiguration(configuration Configuration) error {
 			}
 			pc.configuration.Certificates = append(pc.configuration.Certificates, x509Cert)
 		}
+		if pc.api.settingEngine.fipsMode {
+			if err := fipsCheckCertificates(pc.configuration.Certificates); err != nil {
+				return err
+			}
+		}
 	} else {
 		sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 		if err != nil {
@@ -253,6 +305,176 @@ func (pc *PeerConnection) onSignalingStateChange(newState SignalingState) {
 	}
 }
 
+// OnNegotiationNeeded sets an event handler which is invoked when
+// a change has occurred which requires session negotiation
+func (pc *PeerConnection) OnNegotiationNeeded(f func()) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onNegotiationNeededHandler = f
+}
+
+// onNegotiationNeeded enqueues the negotiation needed check. Multiple calls
+// made while a check is pending are coalesced into a single additional check
+// https://www.w3.org/TR/webrtc/#updating-the-negotiation-needed-flag
+func (pc *PeerConnection) onNegotiationNeeded() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	switch pc.negotiationNeededState {
+	case negotiationNeededStateRun:
+		pc.negotiationNeededState = negotiationNeededStateQueue
+		return
+	case negotiationNeededStateQueue:
+		return
+	}
+	pc.negotiationNeededState = negotiationNeededStateRun
+	pc.ops.Enqueue(pc.negotiationNeededOp)
+}
+
+func (pc *PeerConnection) negotiationNeededOp() {
+	// run again if another check was requested meanwhile
+	defer func() {
+		pc.mu.Lock()
+		rerun := pc.negotiationNeededState == negotiationNeededStateQueue
+		pc.negotiationNeededState = negotiationNeededStateEmpty
+		pc.mu.Unlock()
+		if rerun {
+			pc.onNegotiationNeeded()
+		}
+	}()
+
+	// Step 2.1
+	if pc.isClosed.get() {
+		return
+	}
+
+	// Step 2.3
+	if pc.SignalingState() != SignalingStateStable {
+		return
+	}
+
+	isNegotiationNeeded := pc.checkNegotiationNeeded()
+
+	pc.mu.Lock()
+	// Step 2.4
+	if !isNegotiationNeeded {
+		pc.negotiationNeeded = false
+		pc.mu.Unlock()
+		return
+	}
+	// Step 2.5
+	if pc.negotiationNeeded {
+		pc.mu.Unlock()
+		return
+	}
+	// Step 2.6
+	pc.negotiationNeeded = true
+	hdlr := pc.onNegotiationNeededHandler
+	pc.mu.Unlock()
+
+	// Step 2.7
+	if hdlr != nil {
+		go hdlr()
+	}
+}
+
+// checkNegotiationNeeded reports if the current local state differs from the
+// last negotiated local description
+// https://www.w3.org/TR/webrtc/#dfn-check-if-negotiation-is-needed
+func (pc *PeerConnection) checkNegotiationNeeded() bool {
+	haveDataChannels := false
+	if pc.sctpTransport != nil {
+		pc.sctpTransport.lock.RLock()
+		haveDataChannels = len(pc.sctpTransport.dataChannels) != 0
+		pc.sctpTransport.lock.RUnlock()
+	}
+
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	if pc.iceRestartRequested {
+		return true
+	}
+
+	// Step 1 and 3
+	localDesc := pc.currentLocalDescription
+	if localDesc == nil || localDesc.parsed == nil {
+		return true
+	}
+
+	// Step 4
+	if haveDataChannels && !haveApplicationMediaSection(localDesc.parsed) {
+		return true
+	}
+
+	if descriptionIsPlanB(localDesc) {
+		return !sameSendingTracks(localDesc.parsed, pc.rtpTransceivers)
+	}
+
+	for _, t := range pc.rtpTransceivers {
+		// Step 5.1 and 5.2
+		mid := t.Mid()
+		if mid == "" {
+			return true
+		}
+		m := getMediaSectionByMid(localDesc.parsed, mid)
+		if m == nil {
+			return true
+		}
+
+		// rejected media sections are never renegotiated
+		if isMediaSectionRejected(m) {
+			continue
+		}
+
+		// Step 5.3
+		if !currentDirectionMatches(localDesc, pc.currentRemoteDescription, m, t) {
+			return true
+		}
+		if sender := t.Sender(); sender != nil {
+			if track := sender.Track(); track != nil {
+				msid, ok := m.Attribute(sdp.AttrKeyMsid)
+				if !ok || msid != track.Label()+" "+track.ID() {
+					return true
+				}
+			}
+		}
+	}
+
+	// Step 6
+	return false
+}
+
+// currentDirectionMatches reports if the direction negotiated for the
+// transceiver media section matches the transceiver direction. When the local
+// description is an offer the direction can match the one in the local offer
+// or the one, reversed, in the remote answer. When it's an answer it must
+// match the transceiver direction intersected with the offered one
+// https://www.w3.org/TR/webrtc/#dfn-check-if-negotiation-is-needed
+func currentDirectionMatches(localDesc, remoteDesc *SessionDescription, m *sdp.MediaDescription, t *RTPTransceiver) bool {
+	var remoteMedia *sdp.MediaDescription
+	if remoteDesc != nil && remoteDesc.parsed != nil {
+		remoteMedia = getMediaSectionByMid(remoteDesc.parsed, getMidValue(m))
+	}
+
+	direction := t.Direction()
+	if localDesc.Type == SDPTypeAnswer {
+		if remoteMedia == nil {
+			return false
+		}
+		return getPeerDirection(m) == answerDirection([]*RTPTransceiver{t}, getPeerDirection(remoteMedia))
+	}
+
+	if getPeerDirection(m) == direction {
+		return true
+	}
+	if remoteMedia == nil {
+		return false
+	}
+	answered := getPeerDirection(remoteMedia)
+	return newRTPTransceiverDirection(answered.hasRecv(), answered.hasSend()) == direction
+}
+
 // OnDataChannel sets an event handler which is invoked when a data
 // channel message arrives from a remote peer.
 func (pc *PeerConnection) OnDataChannel(f func(*DataChannel)) {
@@ -287,11 +509,57 @@ func (pc *PeerConnection) onTrack(t *Track, r *RTPReceiver) {
 	hdlr := pc.onTrackHandler
 
 	pc.log.Debugf("got new track: %+v", t)
-	if hdlr != nil && t != nil {
+	if t == nil {
+		return
+	}
+
+	pc.mediaStreams.addTrack(t)
+	if hdlr != nil {
 		go hdlr(t, r)
 	}
 }
 
+// OnTrackFirstPacket sets an event handler which is called when the first
+// RTP packet of a remote track stream is received. The handler receives the
+// time elapsed since the last remote description was set, so it can be used
+// to detect negotiated streams that never receive media.
+func (pc *PeerConnection) OnTrackFirstPacket(f func(*Track, *TrackRTPStream, time.Duration)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onTrackFirstPacketHandler = f
+}
+
+// onTrackFirstPacket must be called with pc.mu held
+func (pc *PeerConnection) onTrackFirstPacket(t *Track, s *TrackRTPStream) {
+	hdlr := pc.onTrackFirstPacketHandler
+	latency := time.Since(pc.remoteDescriptionTime)
+
+	pc.log.Debugf("got first packet for track %s stream ssrc %d after %s", t.ID(), s.SSRC(), latency)
+	if hdlr != nil {
+		go hdlr(t, s, latency)
+	}
+}
+
+// OnStreamAdded sets an event handler which is called when the first
+// remote track of a MediaStream (tracks sharing the same msid) arrives
+// from a remote peer. Tracks added later to the stream are available from
+// MediaStream.GetTracks and are still notified through OnTrack.
+func (pc *PeerConnection) OnStreamAdded(f func(*MediaStream)) {
+	pc.mediaStreams.onStreamAdded(f)
+}
+
+// OnStreamRemoved sets an event handler which is called when the last
+// remote track of a MediaStream has been removed by a renegotiation.
+func (pc *PeerConnection) OnStreamRemoved(f func(*MediaStream)) {
+	pc.mediaStreams.onStreamRemoved(f)
+}
+
+// GetRemoteStream returns the remote MediaStream with the provided id or
+// nil if it doesn't exist
+func (pc *PeerConnection) GetRemoteStream(id string) *MediaStream {
+	return pc.mediaStreams.get(id)
+}
+
 // SupportedExtMap represent an extmap
 type SupportedExtMap struct {
 	Direction sdp.Direction
@@ -299,11 +567,72 @@ type SupportedExtMap struct {
 	ExtAttr   *string
 }
 
+// OnTransformLocalDescription sets a function called with the descriptions
+// generated by CreateOffer and CreateAnswer before they're marshaled. It can
+// modify the description (i.e. adding bandwidth lines or changing the codecs
+// parameters) and the modified one is returned and then applied by
+// SetLocalDescription. The media sections and their mids must be kept, a
+// returned error fails the description creation.
+func (pc *PeerConnection) OnTransformLocalDescription(f func(sdpType SDPType, d *sdp.SessionDescription) error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onTransformLocalDescriptionHandler = f
+}
+
+func (pc *PeerConnection) transformLocalDescription(sdpType SDPType, d *sdp.SessionDescription) error {
+	pc.mu.RLock()
+	hdlr := pc.onTransformLocalDescriptionHandler
+	pc.mu.RUnlock()
+
+	if hdlr == nil {
+		return nil
+	}
+
+	mids := []string{}
+	for _, media := range d.MediaDescriptions {
+		mids = append(mids, getMidValue(media))
+	}
+	if err := hdlr(sdpType, d); err != nil {
+		return err
+	}
+
+	// the transceivers are bound to the media sections
+	if len(d.MediaDescriptions) != len(mids) {
+		return ErrTransformedMediaSections
+	}
+	for i, media := range d.MediaDescriptions {
+		if getMidValue(media) != mids[i] {
+			return ErrTransformedMediaSections
+		}
+	}
+	return nil
+}
+
 // NegotiationData represent specific negotiation data provided by the caller
 type NegotiationData struct {
 	SupportedExtMaps []SupportedExtMap
 }
 
+// OnTransceiverError sets an event handler which is invoked when the media
+// section of a transceiver couldn't be negotiated. The media section is
+// rejected while the other ones are negotiated normally.
+func (pc *PeerConnection) OnTransceiverError(f func(*RTPTransceiver, error)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onTransceiverErrorHandler = f
+}
+
+func (pc *PeerConnection) onTransceiverError(t *RTPTransceiver, err error) {
+	pc.mu.RLock()
+	hdlr := pc.onTransceiverErrorHandler
+	pc.mu.RUnlock()
+
+	pc.log.Warnf("media section with mid %q rejected: %s", t.Mid(), err)
+	if hdlr != nil {
+		go hdlr(t, err)
+	}
+}
+
 // OnMediaNegotiation sets an event handler which is called when remote track
 // arrives from a remote peer.
 func (pc *PeerConnection) OnMediaNegotiation(f func(t *RTPTransceiver, offering bool) *NegotiationData) {
@@ -411,6 +740,12 @@ func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 		pc.configuration.RTCPMuxPolicy = configuration.RTCPMuxPolicy
 	}
 
+	// the mapping between transceivers and media sections depends on the
+	// SDPSemantics so it can't be changed
+	if configuration.SDPSemantics != SDPSemantics(Unknown) && configuration.SDPSemantics != pc.configuration.SDPSemantics {
+		return &rtcerr.InvalidModificationError{Err: ErrModifyingSDPSemantics}
+	}
+
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #7)
 	if configuration.ICECandidatePoolSize != 0 {
 		if pc.configuration.ICECandidatePoolSize != configuration.ICECandidatePoolSize &&
@@ -423,6 +758,8 @@ func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #8)
 	if configuration.ICETransportPolicy != ICETransportPolicy(Unknown) {
 		pc.configuration.ICETransportPolicy = configuration.ICETransportPolicy
+		// the policy is applied from the next gathering, an ICE restart
+		pc.iceGatherer.setGatherPolicy(configuration.ICETransportPolicy)
 	}
 
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11)
@@ -455,26 +792,37 @@ func (pc *PeerConnection) getStatsID() string {
 
 // CreateOffer starts the PeerConnection and generates the localDescription
 func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription, error) {
+	defer pc.updateTransceiverFeed()
+
 	useIdentity := pc.idpLoginURL != nil
 	switch {
-	case options != nil:
-		return SessionDescription{}, fmt.Errorf("TODO handle options")
 	case useIdentity:
 		return SessionDescription{}, fmt.Errorf("TODO handle identity provider")
 	case pc.isClosed.get():
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
+	if pc.takeICERestart(options) {
+		if err := pc.iceGatherer.restart(); err != nil {
+			return SessionDescription{}, err
+		}
+	}
+
+	// the current descriptions are replaced, not modified, when they change
+	pc.mu.RLock()
+	currentLocalDescription, currentRemoteDescription := pc.currentLocalDescription, pc.currentRemoteDescription
+	pc.mu.RUnlock()
+
 	isPlanB := pc.configuration.SDPSemantics == SDPSemanticsPlanB
-	if pc.currentRemoteDescription != nil {
-		isPlanB = descriptionIsPlanB(pc.RemoteDescription())
+	if currentRemoteDescription != nil {
+		isPlanB = descriptionIsPlanB(pc.remoteDescription())
 	}
 
 	// include unmatched local transceivers
 	if !isPlanB {
 		// update the greater mid if the remote description provides a greater one
-		if pc.currentRemoteDescription != nil {
-			for _, media := range pc.currentRemoteDescription.parsed.MediaDescriptions {
+		if currentRemoteDescription != nil {
+			for _, media := range currentRemoteDescription.parsed.MediaDescriptions {
 				mid := getMidValue(media)
 				if mid == "" {
 					continue
@@ -492,11 +840,13 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 			if t.Mid() != "" {
 				continue
 			}
-			pc.greaterMid++
-			err := t.setMid(strconv.Itoa(pc.greaterMid))
+			mid, err := pc.generateMid()
 			if err != nil {
 				return SessionDescription{}, err
 			}
+			if err := t.setMid(mid); err != nil {
+				return SessionDescription{}, err
+			}
 
 			if t.getNegotiationData() == nil {
 				negotiationData, err := pc.onMediaNegotiation(t, true)
@@ -517,26 +867,43 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 		err error
 	)
 
-	if pc.currentRemoteDescription == nil {
+	if currentRemoteDescription == nil {
 		d, err = pc.generateUnmatchedSDP(useIdentity)
 	} else {
-		d, err = pc.generateMatchedSDP(useIdentity, true /*includeUnmatched */, connectionRoleFromDtlsRole(defaultDtlsRoleOffer))
+		d, err = pc.generateMatchedSDP(useIdentity, true /*includeUnmatched */, pc.offeringConnectionRole())
 	}
 	if err != nil {
 		return SessionDescription{}, err
 	}
 
+	voiceActivityDetection := options == nil || !options.DisableVoiceActivityDetection
+	if !voiceActivityDetection {
+		disableVoiceActivityDetection(d)
+	}
+
+	if err := pc.transformLocalDescription(SDPTypeOffer, d); err != nil {
+		return SessionDescription{}, err
+	}
+
+	// a subsequent offer changes only the updated media sections
+	if currentLocalDescription != nil {
+		reusePreviousDescription(d, currentLocalDescription.parsed)
+	}
+
 	sdpBytes, err := d.Marshal()
 	if err != nil {
 		return SessionDescription{}, err
 	}
 
+	// the generated description isn't cached, its attributes aren't split
+	// in key and value like the parsed ones
 	desc := SessionDescription{
 		Type:   SDPTypeOffer,
 		SDP:    string(sdpBytes),
 		parsed: d,
 	}
 	pc.lastOffer = desc.SDP
+	pc.lastOfferVoiceActivityDetection = voiceActivityDetection
 	return desc, nil
 }
 
@@ -626,13 +993,131 @@ func (pc *PeerConnection) createICETransport() *ICETransport {
 	return t
 }
 
+// generateMid returns the mid of a new media section, the next numeric one or
+// the one returned by the SettingEngine generator
+func (pc *PeerConnection) generateMid() (string, error) {
+	generate := pc.api.settingEngine.generators.Mid
+	if generate == nil {
+		pc.greaterMid++
+		return strconv.Itoa(pc.greaterMid), nil
+	}
+
+	mid := generate()
+	if !isSDPToken(mid, maxMidLength) || pc.isMidUsed(mid) {
+		return "", ErrInvalidMid
+	}
+	return mid, nil
+}
+
+// isMidUsed reports if the mid is used by a transceiver or a media section of
+// the current descriptions
+func (pc *PeerConnection) isMidUsed(mid string) bool {
+	if mid == pc.dataMid {
+		return true
+	}
+	for _, t := range pc.GetTransceivers() {
+		if t.Mid() == mid {
+			return true
+		}
+	}
+	pc.mu.RLock()
+	currentLocalDescription := pc.currentLocalDescription
+	pc.mu.RUnlock()
+	for _, desc := range []*SessionDescription{currentLocalDescription, pc.remoteDescription()} {
+		if desc != nil && desc.parsed != nil && getMediaSectionByMid(desc.parsed, mid) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RestartICE requests an ICE restart: the next offer will have new ICE
+// credentials and candidates. The current connection is kept until the
+// restart completes, so it can be used to recover after network changes.
+// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-restartice
+func (pc *PeerConnection) RestartICE() {
+	pc.mu.Lock()
+	pc.iceRestartRequested = true
+	pc.mu.Unlock()
+
+	pc.onNegotiationNeeded()
+}
+
+// takeICERestart reports if an ICE restart must be started by the offer being
+// created, clearing the request made with RestartICE. A restart is meaningful
+// only when the transports have already been started.
+func (pc *PeerConnection) takeICERestart(options *OfferOptions) bool {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	requested := pc.iceRestartRequested || (options != nil && options.ICERestart)
+	pc.iceRestartRequested = false
+	return requested && pc.currentRemoteDescription != nil
+}
+
+// handleICERestart handles the ICE restarts of a remote description setting
+// the remote candidates of the new agent. A remote offer with a new ufrag
+// starts a restart, completed when answering, while the answer to a local
+// offer that started a restart completes it.
+func (pc *PeerConnection) handleICERestart(desc *SessionDescription, weOffer bool) error {
+	remoteUfrag, _, candidates, err := extractICEDetails(desc.parsed)
+	if err != nil {
+		return err
+	}
+
+	if !weOffer {
+		currentRemoteUfrag, _, _, err := extractICEDetails(pc.currentRemoteDescription.parsed)
+		if err != nil {
+			return err
+		}
+		if remoteUfrag == currentRemoteUfrag {
+			return nil
+		}
+		if err := pc.iceGatherer.restart(); err != nil {
+			return err
+		}
+	}
+
+	if !pc.iceGatherer.isRestarting() {
+		return nil
+	}
+	for _, c := range candidates {
+		if err := pc.iceTransport.AddRemoteCandidate(c); err != nil {
+			return err
+		}
+	}
+
+	if weOffer {
+		pc.completeICERestart(desc)
+	}
+	return nil
+}
+
+// completeICERestart enqueues the connection of the new agent with the remote
+// ICE parameters of the provided description
+func (pc *PeerConnection) completeICERestart(remoteDesc *SessionDescription) {
+	pc.ops.Enqueue(func() {
+		remoteUfrag, remotePwd, _, err := extractICEDetails(remoteDesc.parsed)
+		if err != nil {
+			pc.log.Warnf("Failed to restart ICE: %s", err)
+			return
+		}
+		if err := pc.iceTransport.restart(ICEParameters{
+			UsernameFragment: remoteUfrag,
+			Password:         remotePwd,
+		}); err != nil {
+			pc.log.Warnf("Failed to restart ICE: %s", err)
+		}
+	})
+}
+
 // CreateAnswer starts the PeerConnection and generates the localDescription
 func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescription, error) {
+	defer pc.updateTransceiverFeed()
+
 	useIdentity := pc.idpLoginURL != nil
 	switch {
-	case options != nil:
-		return SessionDescription{}, fmt.Errorf("TODO handle options")
-	case pc.RemoteDescription() == nil:
+	case pc.remoteDescription() == nil:
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrNoRemoteDescription}
 	case useIdentity:
 		return SessionDescription{}, fmt.Errorf("TODO handle identity provider")
@@ -645,22 +1130,52 @@ func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescripti
 		connectionRole = connectionRoleFromDtlsRole(defaultDtlsRoleAnswer)
 	}
 
+	pc.mu.RLock()
+	currentLocalDescription, currentRemoteDescription := pc.currentLocalDescription, pc.currentRemoteDescription
+	pc.mu.RUnlock()
+
+	// when renegotiating keep the DTLS role of the current association since
+	// the DTLS transport is not restarted
+	if role := negotiatedDTLSRole(currentLocalDescription, currentRemoteDescription); role != DTLSRoleAuto {
+		connectionRole = connectionRoleFromDtlsRole(role)
+	}
+
+	if role := pc.api.settingEngine.dtlsRole; role != DTLSRole(0) {
+		connectionRole = connectionRoleFromDtlsRole(role)
+	}
+
 	d, err := pc.generateMatchedSDP(useIdentity, false /*includeUnmatched */, connectionRole)
 	if err != nil {
 		return SessionDescription{}, err
 	}
 
+	voiceActivityDetection := options == nil || !options.DisableVoiceActivityDetection
+	if !voiceActivityDetection {
+		disableVoiceActivityDetection(d)
+	}
+
+	if err := pc.transformLocalDescription(SDPTypeAnswer, d); err != nil {
+		return SessionDescription{}, err
+	}
+
+	if currentLocalDescription != nil {
+		reusePreviousDescription(d, currentLocalDescription.parsed)
+	}
+
 	sdpBytes, err := d.Marshal()
 	if err != nil {
 		return SessionDescription{}, err
 	}
 
+	// the generated description isn't cached, its attributes aren't split
+	// in key and value like the parsed ones
 	desc := SessionDescription{
 		Type:   SDPTypeAnswer,
 		SDP:    string(sdpBytes),
 		parsed: d,
 	}
 	pc.lastAnswer = desc.SDP
+	pc.lastAnswerVoiceActivityDetection = voiceActivityDetection
 	return desc, nil
 }
 
@@ -677,8 +1192,8 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 		cur := pc.signalingState
 		setLocal := stateChangeOpSetLocal
 		setRemote := stateChangeOpSetRemote
-		newSDPDoesNotMatchOffer := &rtcerr.InvalidModificationError{Err: fmt.Errorf("new sdp does not match previous offer")}
-		newSDPDoesNotMatchAnswer := &rtcerr.InvalidModificationError{Err: fmt.Errorf("new sdp does not match previous answer")}
+		newSDPDoesNotMatchOffer := &rtcerr.InvalidModificationError{Err: ErrSDPDoesNotMatchOffer}
+		newSDPDoesNotMatchAnswer := &rtcerr.InvalidModificationError{Err: ErrSDPDoesNotMatchAnswer}
 
 		var nextState SignalingState
 		var err error
@@ -711,6 +1226,7 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 				nextState, err = checkNextSignalingState(cur, SignalingStateStable, setLocal, sd.Type)
 				if err == nil {
 					pc.pendingLocalDescription = nil
+					pc.pendingRemoteDescription = nil
 				}
 			// have-remote-offer->SetLocal(pranswer)->have-local-pranswer
 			case SDPTypePranswer:
@@ -746,6 +1262,7 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 				nextState, err = checkNextSignalingState(cur, SignalingStateStable, setRemote, sd.Type)
 				if err == nil {
 					pc.pendingRemoteDescription = nil
+					pc.pendingLocalDescription = nil
 				}
 			// have-local-offer->SetRemote(pranswer)->have-remote-pranswer
 			case SDPTypePranswer:
@@ -760,23 +1277,47 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 			return nextState, &rtcerr.OperationError{Err: fmt.Errorf("unhandled state change op: %q", op)}
 		}
 
-		return nextState, nil
+		return nextState, err
 	}()
 
+	if err == nil && sd.Type == SDPTypeAnswer {
+		pc.releaseRecycledTransceivers()
+	}
 	if err == nil {
+		pc.mu.Lock()
 		pc.signalingState = nextState
+		if nextState == SignalingStateStable {
+			pc.negotiationNeeded = false
+		}
+		pc.mu.Unlock()
 		pc.onSignalingStateChange(nextState)
+		if nextState == SignalingStateStable {
+			pc.onNegotiationNeeded()
+		}
 	}
 	return err
 }
 
 // SetLocalDescription sets the SessionDescription of the local peer
 func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
+	defer pc.updateTransceiverFeed()
+
 	if pc.isClosed.get() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
+	pc.mu.RLock()
 	haveLocalDescription := pc.currentLocalDescription != nil
+	pc.mu.RUnlock()
+
+	if desc.Type == SDPTypeRollback {
+		if err := pc.setDescription(&desc, stateChangeOpSetLocal); err != nil {
+			return err
+		}
+		pc.rollbackTransceivers()
+		// an ICE restart started by the rolled back offer
+		return pc.iceGatherer.cancelRestart()
+	}
 
 	// JSEP 5.4
 	if desc.SDP == "" {
@@ -792,16 +1333,19 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 		}
 	}
 
-	desc.parsed = &sdp.SessionDescription{}
-	if err := desc.parsed.Unmarshal([]byte(desc.SDP)); err != nil {
+	if _, err := desc.parse(); err != nil {
 		return err
 	}
 	if err := pc.setDescription(&desc, stateChangeOpSetLocal); err != nil {
 		return err
 	}
+	pc.updateSendersVoiceActivityDetection(&desc)
 
 	weAnswer := desc.Type == SDPTypeAnswer
-	remoteDesc := pc.RemoteDescription()
+	remoteDesc := pc.remoteDescription()
+	if weAnswer && remoteDesc != nil && pc.iceGatherer.isRestarting() {
+		pc.completeICERestart(remoteDesc)
+	}
 	if weAnswer && remoteDesc != nil {
 		pc.ops.Enqueue(func() {
 			pc.startRTP(haveLocalDescription, remoteDesc)
@@ -820,10 +1364,59 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 		return nil
 	}
 
-	if pc.iceGatherer.State() == ICEGathererStateNew {
-		return pc.iceGatherer.Gather()
-	}
-	return nil
+	if pc.iceGatherer.State() == ICEGathererStateNew {
+		return pc.iceGatherer.Gather()
+	}
+	return nil
+}
+
+// updateSendersVoiceActivityDetection configures the silence suppression of
+// the audio senders of the local description media sections as requested when
+// the description was created
+func (pc *PeerConnection) updateSendersVoiceActivityDetection(desc *SessionDescription) {
+	enabled := pc.lastOfferVoiceActivityDetection
+	if desc.Type == SDPTypeAnswer || desc.Type == SDPTypePranswer {
+		enabled = pc.lastAnswerVoiceActivityDetection
+	}
+
+	for _, t := range pc.GetTransceivers() {
+		sender := t.Sender()
+		if sender == nil || t.kind != RTPCodecTypeAudio || t.Mid() == "" {
+			continue
+		}
+		if media := getMediaSectionByMid(desc.parsed, t.Mid()); media != nil && !isMediaSectionRejected(media) {
+			sender.setVoiceActivityDetection(enabled)
+		}
+	}
+}
+
+// GetCompleteLocalDescription waits for the ICE gathering to complete and
+// returns the local description with all the local candidates followed by
+// a=end-of-candidates. It's meant for the signaling channels that can exchange
+// only one description, without trickling the candidates. It must be called
+// after SetLocalDescription.
+func (pc *PeerConnection) GetCompleteLocalDescription() (*SessionDescription, error) {
+	if pc.isClosed.get() {
+		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	}
+	if pc.LocalDescription() == nil {
+		return nil, &rtcerr.InvalidStateError{Err: ErrNoLocalDescription}
+	}
+
+	<-pc.iceGatherer.gatheringCompleteChan()
+	if pc.isClosed.get() {
+		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	}
+	return pc.LocalDescription(), nil
+}
+
+// GatheringCompletePromise returns a channel closed when the ICE gathering
+// is complete, or the PeerConnection is closed. It's the channel of the
+// current gathering, an ICE restart starts a new one. Like
+// GetCompleteLocalDescription it's meant for the applications that can't
+// trickle the candidates.
+func (pc *PeerConnection) GatheringCompletePromise() <-chan struct{} {
+	return pc.iceGatherer.gatheringCompleteChan()
 }
 
 // LocalDescription returns PendingLocalDescription if it is not null and
@@ -839,28 +1432,58 @@ func (pc *PeerConnection) LocalDescription() *SessionDescription {
 
 // SetRemoteDescription sets the SessionDescription of the remote peer
 func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
+	defer pc.updateTransceiverFeed()
+
 	if pc.isClosed.get() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
 	haveRemoteDescription := pc.currentRemoteDescription != nil
 
-	desc.parsed = &sdp.SessionDescription{}
-	if err := desc.parsed.Unmarshal([]byte(desc.SDP)); err != nil {
+	if desc.Type == SDPTypeRollback {
+		// the transports are started with the first remote offer
+		pc.mu.RLock()
+		firstRemoteOffer := pc.currentRemoteDescription == nil && pc.pendingRemoteDescription != nil
+		pc.mu.RUnlock()
+		if firstRemoteOffer {
+			return &rtcerr.InvalidModificationError{Err: ErrRollbackFirstRemoteOffer}
+		}
+
+		if err := pc.setDescription(&desc, stateChangeOpSetRemote); err != nil {
+			return err
+		}
+		pc.rollbackTransceivers()
+		// an ICE restart started by the rolled back offer
+		return pc.iceGatherer.cancelRestart()
+	}
+
+	if _, err := desc.parse(); err != nil {
+		return err
+	}
+	if err := validateRemoteDescription(&desc, pc.api.settingEngine.fipsMode); err != nil {
 		return err
 	}
 	if err := pc.setDescription(&desc, stateChangeOpSetRemote); err != nil {
 		return err
 	}
 
+	pc.mu.Lock()
+	pc.remoteDescriptionTime = time.Now()
+	pc.mu.Unlock()
+
 	weOffer := desc.Type == SDPTypeAnswer
 
 	var t *RTPTransceiver
 	localTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
-	detectedPlanB := descriptionIsPlanB(pc.RemoteDescription())
+	detectedPlanB := descriptionIsPlanB(pc.remoteDescription())
 
 	if !detectedPlanB {
-		for _, media := range pc.RemoteDescription().parsed.MediaDescriptions {
+		remoteMids := map[string]bool{}
+		for _, media := range pc.remoteDescription().parsed.MediaDescriptions {
+			remoteMids[getMidValue(media)] = true
+		}
+
+		for i, media := range pc.remoteDescription().parsed.MediaDescriptions {
 			midValue := getMidValue(media)
 			if midValue == "" {
 				return fmt.Errorf("RemoteDescription contained media section without mid value")
@@ -870,9 +1493,24 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 				continue
 			}
 
-			hasRids := len(getRids(media)) > 0
+			// the remote description has already been validated
+			ridDescriptions, simulcast, _ := getSimulcast(media)
+			sendRids, _ := simulcastStreamDetails(ridDescriptions, simulcast)
+			hasRids := len(sendRids) > 0
 
 			kind := NewRTPCodecType(media.MediaName.Media)
+			if kind != 0 && isMediaSectionRejected(media) {
+				// the media section has been rejected
+				t, localTransceivers = findByMid(midValue, localTransceivers)
+				if t != nil {
+					t.setRejected(true)
+					if weOffer {
+						pc.onTransceiverError(t, ErrMediaSectionRejected)
+					}
+				}
+				continue
+			}
+
 			direction := getPeerDirection(media)
 			if kind == 0 || direction == RTPTransceiverDirection(Unknown) {
 				continue
@@ -907,6 +1545,14 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 				//}
 			} else {
 				t, localTransceivers = satisfyTypeAndDirection(kind, direction, localTransceivers)
+				if t == nil && !weOffer {
+					// the remote offer could have recycled the m-line of a stopped media section
+					if t, localTransceivers = findRecyclable(kind, remoteMids, localTransceivers); t != nil {
+						if err := pc.recycleTransceiver(t, midValue); err != nil {
+							return err
+						}
+					}
+				}
 			}
 			if t == nil {
 				if weOffer {
@@ -917,6 +1563,7 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 					return err
 				}
 				t = pc.newRTPTransceiver(receiver, nil, RTPTransceiverDirectionRecvonly, kind)
+				t.createdByRemote = true
 			}
 
 			// mark the receiver as useRid here and not when calling Receiver.Receive in startRTP since it's executed
@@ -929,6 +1576,40 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 			}
 			t.setRemoteDirection(direction)
 
+			if pc.api.settingEngine.dataChannelOnly {
+				t.setRejected(true)
+				pc.onTransceiverError(t, ErrDataChannelOnly)
+				continue
+			}
+
+			// a transport per media section isn't supported
+			if !useSharedTransport(desc.parsed, i) {
+				t.setRejected(true)
+				pc.onTransceiverError(t, newSDPValidationError(i, media, sdp.AttrKeyGroup, ErrUnbundledMediaSection))
+				continue
+			}
+
+			// a separate RTCP transport isn't supported, whatever the
+			// RTCPMuxPolicy, reject the media sections not multiplexing it
+			if !haveRTCPMux(desc.parsed, media) {
+				if pc.configuration.RTCPMuxPolicy == RTCPMuxPolicyNegotiate {
+					pc.log.Warnf("rejecting media section %s since non-multiplexed RTCP is not supported", midValue)
+				}
+				t.setRejected(true)
+				pc.onTransceiverError(t, newSDPValidationError(i, media, sdp.AttrKeyRTCPMux, ErrNonMuxedRTCP))
+				continue
+			}
+
+			// reject only this media section when we don't support any of its codecs
+			codec := getMatchingCodec(media, kind, pc.api.mediaEngine)
+			if codec == nil {
+				t.setRejected(true)
+				pc.onTransceiverError(t, newSDPValidationError(i, media, "rtpmap", ErrNoMatchingCodecs))
+				continue
+			}
+			t.setRejected(false)
+			t.setCodec(codec)
+
 			if t.getNegotiationData() == nil {
 				negotiationData, err := pc.onMediaNegotiation(t, weOffer)
 				if err != nil {
@@ -941,10 +1622,15 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 			if err := pc.handleAnswerExtMaps(t, media, weOffer); err != nil {
 				return err
 			}
+			// the local descriptions always allow it, or mirror the offer
+			t.setExtMapAllowMixed(haveExtMapAllowMixed(desc.parsed, media))
 		}
 	}
 
 	if haveRemoteDescription {
+		if err := pc.handleICERestart(&desc, weOffer); err != nil {
+			return err
+		}
 		if weOffer {
 			pc.ops.Enqueue(func() {
 				pc.startRTP(true, &desc)
@@ -953,12 +1639,10 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 		return nil
 	}
 
-	remoteIsLite := false
-	if liteValue, haveRemoteIs := desc.parsed.Attribute(sdp.AttrKeyICELite); haveRemoteIs && liteValue == sdp.AttrKeyICELite {
-		remoteIsLite = true
-	}
+	// a=ice-lite is a session level property attribute
+	_, remoteIsLite := desc.parsed.Attribute(sdp.AttrKeyICELite)
 
-	fingerprint, fingerprintHash, err := extractFingerprint(desc.parsed)
+	fingerprints, err := extractFingerprints(desc.parsed)
 	if err != nil {
 		return err
 	}
@@ -975,17 +1659,24 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	}
 
 	iceRole := ICERoleControlled
-	// If one of the agents is lite and the other one is not, the lite agent must be the controlling agent.
+	// If one of the agents is lite and the other one is not, the full agent must be the controlling agent.
 	// If both or neither agents are lite the offering agent is controlling.
 	// RFC 8445 S6.1.1
 	if (weOffer && remoteIsLite == pc.api.settingEngine.candidates.ICELite) || (remoteIsLite && !pc.api.settingEngine.candidates.ICELite) {
 		iceRole = ICERoleControlling
 	}
+	if pc.api.settingEngine.iceRole != ICERole(Unknown) {
+		iceRole = pc.api.settingEngine.iceRole
+	}
 
 	// Start the networking in a new routine since it will block until
 	// the connection is actually established.
 	pc.ops.Enqueue(func() {
-		pc.startTransports(iceRole, dtlsRoleFromRemoteSDP(desc.parsed), remoteUfrag, remotePwd, fingerprint, fingerprintHash)
+		pc.startTransports(iceRole, dtlsRoleFromRemoteSDP(desc.parsed), ICEParameters{
+			UsernameFragment: remoteUfrag,
+			Password:         remotePwd,
+			ICELite:          remoteIsLite,
+		}, fingerprints)
 		if weOffer {
 			pc.startRTP(false, &desc)
 		}
@@ -993,15 +1684,124 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	return nil
 }
 
+// rollbackTransceivers reverts the transceivers to the state of the current
+// descriptions after a rollback: the mids that aren't part of the current
+// descriptions are released, the remote directions are restored and the
+// transceivers created by the rolled back remote offer, and not used by
+// AddTrack later, are stopped and removed
+func (pc *PeerConnection) rollbackTransceivers() {
+	pc.mu.Lock()
+	negotiated := map[string]*sdp.MediaDescription{}
+	for _, desc := range []*SessionDescription{pc.currentLocalDescription, pc.currentRemoteDescription} {
+		if desc == nil || desc.parsed == nil {
+			continue
+		}
+		for _, media := range desc.parsed.MediaDescriptions {
+			negotiated[getMidValue(media)] = nil
+		}
+	}
+	if pc.currentRemoteDescription != nil && pc.currentRemoteDescription.parsed != nil {
+		for _, media := range pc.currentRemoteDescription.parsed.MediaDescriptions {
+			negotiated[getMidValue(media)] = media
+		}
+	}
+
+	removed := []*RTPTransceiver{}
+	unused := []*recycledMediaSection{}
+	transceivers := []*RTPTransceiver{}
+	for _, t := range pc.rtpTransceivers {
+		if remoteMedia, ok := negotiated[t.Mid()]; ok && t.Mid() != "" {
+			if remoteMedia != nil {
+				t.setRemoteDirection(getPeerDirection(remoteMedia))
+			}
+			transceivers = append(transceivers, t)
+			continue
+		}
+
+		// the recycled transceivers go back to their stopped media section
+		if recycled := t.recycled; recycled != nil {
+			if _, ok := negotiated[recycled.mid]; ok && recycled.mid != "" {
+				// the receiver and sender created for the rolled back offer
+				// have never been started
+				replaced := &recycledMediaSection{}
+				if recycled.receiver != nil {
+					replaced.receiver = t.Receiver()
+					t.setReceiver(recycled.receiver)
+				}
+				if recycled.sender != nil {
+					replaced.sender = t.Sender()
+					t.setSender(recycled.sender)
+				}
+				unused = append(unused, replaced)
+				t.recycled = nil
+				t.mid.Store(recycled.mid)
+				t.setRejected(true)
+				transceivers = append(transceivers, t)
+				continue
+			}
+		}
+
+		if t.createdByRemote && t.Sender() == nil {
+			removed = append(removed, t)
+			continue
+		}
+
+		t.mid.Store("")
+		t.setRemoteDirection(RTPTransceiverDirection(Unknown))
+		t.setRejected(false)
+		transceivers = append(transceivers, t)
+	}
+	pc.rtpTransceivers = transceivers
+	pc.mu.Unlock()
+
+	for _, t := range removed {
+		if err := t.Stop(); err != nil {
+			pc.log.Warnf("Failed to stop rolled back transceiver: %s", err)
+		}
+	}
+	for _, r := range unused {
+		if err := r.stop(); err != nil {
+			pc.log.Warnf("Failed to stop rolled back receiver or sender: %s", err)
+		}
+	}
+}
+
+// releaseRecycledTransceivers stops the receivers and senders replaced in the
+// recycled transceivers once the answer is applied, they can't be restored
+// anymore
+func (pc *PeerConnection) releaseRecycledTransceivers() {
+	pc.mu.Lock()
+	released := []*recycledMediaSection{}
+	for _, t := range pc.rtpTransceivers {
+		if t.recycled != nil {
+			released = append(released, t.recycled)
+			t.recycled = nil
+		}
+	}
+	pc.mu.Unlock()
+
+	for _, r := range released {
+		if err := r.stop(); err != nil {
+			pc.log.Warnf("Failed to stop recycled receiver or sender: %s", err)
+		}
+	}
+}
+
 func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPReceiver) {
 	encodings := []RTPDecodingParameters{}
 	if incoming.useRid {
-		for _, stream := range incoming.ridStreams {
-			encodings = append(encodings, RTPDecodingParameters{RTPCodingParameters{RID: stream.rid}})
+		for _, rid := range incoming.rids {
+			stream := incoming.ridStreams[rid]
+			encodings = append(encodings, RTPDecodingParameters{
+				RTPCodingParameters: RTPCodingParameters{RID: stream.rid},
+				Paused:              stream.paused,
+				PayloadTypes:        stream.payloadTypes,
+				Restrictions:        stream.restrictions,
+			})
 		}
 	} else {
 		for _, stream := range incoming.ssrcStreams {
-			encodings = append(encodings, RTPDecodingParameters{RTPCodingParameters{SSRC: stream.ssrc}})
+			encodings = append(encodings, RTPDecodingParameters{RTPCodingParameters: RTPCodingParameters{SSRC: stream.ssrc}})
 		}
 	}
 	err := receiver.Receive(RTPReceiveParameters{
@@ -1039,11 +1839,11 @@ func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPRece
 			pc.mu.RLock()
 			defer pc.mu.RUnlock()
 
-			if pc.onTrackHandler != nil {
-				pc.onTrack(receiver.Track(), receiver)
-			} else {
+			if pc.onTrackHandler == nil {
 				pc.log.Warnf("OnTrack unset, unable to handle incoming media streams")
 			}
+			pc.onTrackFirstPacket(receiver.Track(), receiver.Track().streams[0])
+			pc.onTrack(receiver.Track(), receiver)
 		}()
 	}
 }
@@ -1057,7 +1857,7 @@ func (pc *PeerConnection) startRTPReceivers(incomingTracks map[string]trackDetai
 	case SDPSemanticsPlanB:
 		remoteIsPlanB = true
 	case SDPSemanticsUnifiedPlanWithFallback:
-		remoteIsPlanB = descriptionIsPlanB(pc.RemoteDescription())
+		remoteIsPlanB = descriptionIsPlanB(pc.remoteDescription())
 	}
 
 	// Ensure we haven't already started a transceiver for this trackid
@@ -1093,6 +1893,7 @@ func (pc *PeerConnection) startRTPReceivers(incomingTracks map[string]trackDetai
 			}
 
 			if (incoming.kind != t.kind) ||
+				t.isRejected() ||
 				(t.Direction() != RTPTransceiverDirectionRecvonly && t.Direction() != RTPTransceiverDirectionSendrecv) ||
 				(t.Receiver()) == nil ||
 				(t.Receiver().haveReceived()) {
@@ -1126,6 +1927,9 @@ func (pc *PeerConnection) startRTPReceivers(incomingTracks map[string]trackDetai
 func (pc *PeerConnection) startRTPSenders(currentTransceivers []*RTPTransceiver) {
 	for _, transceiver := range currentTransceivers {
 		// TODO(sgotti) when in future we'll avoid replacing a transceiver sender just check the transceiver negotiation status
+		if transceiver.isRejected() {
+			continue
+		}
 		if transceiver.Sender() != nil && transceiver.Sender().isNegotiated() && !transceiver.Sender().hasSent() {
 			err := transceiver.Sender().Send(RTPSendParameters{
 				Encodings: RTPEncodingParameters{
@@ -1142,10 +1946,10 @@ func (pc *PeerConnection) startRTPSenders(currentTransceivers []*RTPTransceiver)
 }
 
 // Start SCTP subsystem
-func (pc *PeerConnection) startSCTP() {
+func (pc *PeerConnection) startSCTP(remoteMaxMessageSize uint32) {
 	// Start sctp
 	if err := pc.sctpTransport.Start(SCTPCapabilities{
-		MaxMessageSize: 0,
+		MaxMessageSize: remoteMaxMessageSize,
 	}); err != nil {
 		pc.log.Warnf("Failed to start SCTP: %s", err)
 		if err = pc.sctpTransport.Stop(); err != nil {
@@ -1185,7 +1989,7 @@ func (pc *PeerConnection) startSCTP() {
 // a single media section and no ssrc attributes or just ignore it.
 func (pc *PeerConnection) handleUnknownSRTP() {
 	handleUndeclaredSSRC := func(ssrc uint32) bool {
-		if remoteDescription := pc.RemoteDescription(); remoteDescription != nil {
+		if remoteDescription := pc.remoteDescription(); remoteDescription != nil {
 			if len(remoteDescription.parsed.MediaDescriptions) == 1 {
 				onlyMediaSection := remoteDescription.parsed.MediaDescriptions[0]
 				for _, a := range onlyMediaSection.Attributes {
@@ -1340,13 +2144,18 @@ func (pc *PeerConnection) handleUnknownSRTP() {
 
 								delete(pc.pendingReadStreamsSRTP, ssrc)
 
+								for _, stream := range receiver.Track().Streams() {
+									if stream.RID() == rid {
+										pc.onTrackFirstPacket(receiver.Track(), stream)
+									}
+								}
+
 								// emit onTrack when the first stream has been added
 								if receiver.readyStreams() == 1 {
-									if pc.onTrackHandler != nil {
-										pc.onTrack(receiver.Track(), receiver)
-									} else {
+									if pc.onTrackHandler == nil {
 										pc.log.Warnf("OnTrack unset, unable to handle incoming media streams")
 									}
+									pc.onTrack(receiver.Track(), receiver)
 								}
 
 								break
@@ -1387,6 +2196,20 @@ func (pc *PeerConnection) handleUnknownSRTP() {
 // determine if setRemoteDescription has already been called.
 // https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-remotedescription
 func (pc *PeerConnection) RemoteDescription() *SessionDescription {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.pendingRemoteDescription != nil {
+		pc.pendingRemoteDescription.updateSDP()
+		return copySessionDescription(pc.pendingRemoteDescription)
+	}
+	pc.currentRemoteDescription.updateSDP()
+	return copySessionDescription(pc.currentRemoteDescription)
+}
+
+// remoteDescription returns the internal pending or current remote
+// description, like RemoteDescription but without copying it
+func (pc *PeerConnection) remoteDescription() *SessionDescription {
 	pc.mu.RLock()
 	defer pc.mu.RUnlock()
 
@@ -1399,7 +2222,7 @@ func (pc *PeerConnection) RemoteDescription() *SessionDescription {
 // AddICECandidate accepts an ICE candidate string and adds it
 // to the existing set of candidates
 func (pc *PeerConnection) AddICECandidate(candidate ICECandidateInit) error {
-	if pc.RemoteDescription() == nil {
+	if pc.remoteDescription() == nil {
 		return &rtcerr.InvalidStateError{Err: ErrNoRemoteDescription}
 	}
 
@@ -1415,7 +2238,75 @@ func (pc *PeerConnection) AddICECandidate(candidate ICECandidateInit) error {
 		return err
 	}
 
-	return pc.iceTransport.AddRemoteCandidate(iceCandidate)
+	if err := pc.iceTransport.AddRemoteCandidate(iceCandidate); err != nil {
+		return err
+	}
+	pc.addRemoteDescriptionCandidate(candidate, sdpCandidate)
+	return nil
+}
+
+// addRemoteDescriptionCandidate adds the candidate to its media section of the
+// remote description. The description is replaced instead of modified since
+// it could be in use.
+func (pc *PeerConnection) addRemoteDescriptionCandidate(candidate ICECandidateInit, sdpCandidate sdp.ICECandidate) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	desc := pc.pendingRemoteDescription
+	if desc == nil {
+		desc = pc.currentRemoteDescription
+	}
+	if desc == nil {
+		return
+	}
+
+	current, err := desc.parse()
+	if err != nil {
+		return
+	}
+
+	var index int
+	switch {
+	case candidate.SDPMid != nil:
+		index = -1
+		for i, media := range current.MediaDescriptions {
+			if getMidValue(media) == *candidate.SDPMid {
+				index = i
+				break
+			}
+		}
+	case candidate.SDPMLineIndex != nil:
+		index = int(*candidate.SDPMLineIndex)
+	default:
+		// the media sections are bundled on the first one
+		index = 0
+	}
+	if index < 0 || index >= len(current.MediaDescriptions) {
+		return
+	}
+
+	value := sdpCandidate.Marshal()
+	for _, a := range current.MediaDescriptions[index].Attributes {
+		if a.Key == "candidate" && a.Value == value {
+			return
+		}
+	}
+
+	// the parsed description is shared, only the updated media section is
+	// copied and the SDP is marshaled when it's exposed
+	parsed := *current
+	parsed.MediaDescriptions = append([]*sdp.MediaDescription{}, current.MediaDescriptions...)
+	media := *current.MediaDescriptions[index]
+	media.Attributes = append([]sdp.Attribute{}, media.Attributes...)
+	media.WithICECandidate(sdpCandidate)
+	parsed.MediaDescriptions[index] = &media
+
+	updated := &SessionDescription{Type: desc.Type, parsed: &parsed, sdpStale: true}
+	if desc == pc.pendingRemoteDescription {
+		pc.pendingRemoteDescription = updated
+	} else {
+		pc.currentRemoteDescription = updated
+	}
 }
 
 // ICEConnectionState returns the ICE connection state of the
@@ -1465,18 +2356,30 @@ func (pc *PeerConnection) GetTransceivers() []*RTPTransceiver {
 
 // AddTrack adds a Track to the PeerConnection
 func (pc *PeerConnection) AddTrack(track *Track) (*RTPSender, error) {
+	defer pc.updateTransceiverFeed()
+
 	if pc.isClosed.get() {
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	} else if pc.api.settingEngine.dataChannelOnly {
+		return nil, &rtcerr.InvalidStateError{Err: ErrDataChannelOnly}
 	}
 
+	// we'll offer again the reused transceivers (i.e. the ones whose track
+	// has been removed) with the new direction, while an answer must keep
+	// the remote offered one
+	willOffer := pc.SignalingState() != SignalingStateHaveRemoteOffer
+
 	var transceiver *RTPTransceiver
 	for _, t := range pc.GetTransceivers() {
 		// use existing transceiver if:
 		// * not stopped
 		// * same kind
 		// * without a sender
-		// * the transceiver has not been negotiated yet or negotiated and remote direction can receive
-		if !t.stopped && t.kind == track.Kind() && t.Sender() == nil && (t.getRemoteDirection() == RTPTransceiverDirection(Unknown) || t.getRemoteDirection() == RTPTransceiverDirectionSendrecv || t.getRemoteDirection() == RTPTransceiverDirectionRecvonly) {
+		// * the transceiver has not been negotiated yet, negotiated and remote direction can receive or
+		//   it'll be renegotiated by our next offer
+		remoteDirection := t.getRemoteDirection()
+		remoteCanReceive := remoteDirection == RTPTransceiverDirection(Unknown) || remoteDirection == RTPTransceiverDirectionSendrecv || remoteDirection == RTPTransceiverDirectionRecvonly
+		if !t.stopped && t.kind == track.Kind() && t.Sender() == nil && !t.isRejected() && (remoteCanReceive || willOffer) {
 			transceiver = t
 			break
 		}
@@ -1491,6 +2394,7 @@ func (pc *PeerConnection) AddTrack(track *Track) (*RTPSender, error) {
 		if err := transceiver.setSendingTrack(track); err != nil {
 			return nil, err
 		}
+		pc.onNegotiationNeeded()
 		return sender, nil
 	}
 
@@ -1510,6 +2414,8 @@ func (pc *PeerConnection) AddTransceiver(trackOrKind RTPCodecType, init ...RtpTr
 
 // RemoveTrack removes a Track from the PeerConnection
 func (pc *PeerConnection) RemoveTrack(sender *RTPSender) error {
+	defer pc.updateTransceiverFeed()
+
 	if pc.isClosed.get() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
@@ -1528,13 +2434,21 @@ func (pc *PeerConnection) RemoveTrack(sender *RTPSender) error {
 		return err
 	}
 
-	return transceiver.setSendingTrack(nil)
+	if err := transceiver.setSendingTrack(nil); err != nil {
+		return err
+	}
+	pc.onNegotiationNeeded()
+	return nil
 }
 
 // AddTransceiverFromKind Create a new RTCRtpTransceiver(SendRecv or RecvOnly) and add it to the set of transceivers.
 func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpTransceiverInit) (*RTPTransceiver, error) {
+	defer pc.updateTransceiverFeed()
+
 	if pc.isClosed.get() {
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	} else if pc.api.settingEngine.dataChannelOnly {
+		return nil, &rtcerr.InvalidStateError{Err: ErrDataChannelOnly}
 	}
 
 	direction := RTPTransceiverDirectionSendrecv
@@ -1551,7 +2465,14 @@ func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpT
 			return nil, fmt.Errorf("no %s codecs found", kind.String())
 		}
 
-		track, err := pc.NewTrack(codecs[0].PayloadType, mathRand.Uint32(), util.RandSeq(trackDefaultIDLength), util.RandSeq(trackDefaultLabelLength))
+		trackID := util.RandSeq(trackDefaultIDLength)
+		if generate := pc.api.settingEngine.generators.TrackID; generate != nil {
+			if trackID = generate(); !isSDPToken(trackID, maxTrackIDLength) {
+				return nil, ErrInvalidTrackID
+			}
+		}
+
+		track, err := pc.NewTrack(codecs[0].PayloadType, mathRand.Uint32(), trackID, util.RandSeq(trackDefaultLabelLength))
 		if err != nil {
 			return nil, err
 		}
@@ -1564,12 +2485,14 @@ func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpT
 			return nil, err
 		}
 
-		return pc.newRTPTransceiver(
+		t := pc.newRTPTransceiver(
 			receiver,
 			nil,
 			RTPTransceiverDirectionRecvonly,
 			kind,
-		), nil
+		)
+		pc.onNegotiationNeeded()
+		return t, nil
 	default:
 		return nil, fmt.Errorf("AddTransceiverFromKind currently only supports recvonly and sendrecv")
 	}
@@ -1577,8 +2500,12 @@ func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpT
 
 // AddTransceiverFromTrack Creates a new send only transceiver and add it to the set of
 func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTransceiverInit) (*RTPTransceiver, error) {
+	defer pc.updateTransceiverFeed()
+
 	if pc.isClosed.get() {
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	} else if pc.api.settingEngine.dataChannelOnly {
+		return nil, &rtcerr.InvalidStateError{Err: ErrDataChannelOnly}
 	}
 
 	direction := RTPTransceiverDirectionSendrecv
@@ -1600,12 +2527,14 @@ func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTrans
 			return nil, err
 		}
 
-		return pc.newRTPTransceiver(
+		t := pc.newRTPTransceiver(
 			receiver,
 			sender,
 			RTPTransceiverDirectionSendrecv,
 			track.Kind(),
-		), nil
+		)
+		pc.onNegotiationNeeded()
+		return t, nil
 
 	case RTPTransceiverDirectionSendonly:
 		sender, err := pc.api.NewRTPSender(track, pc.dtlsTransport)
@@ -1613,12 +2542,14 @@ func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTrans
 			return nil, err
 		}
 
-		return pc.newRTPTransceiver(
+		t := pc.newRTPTransceiver(
 			nil,
 			sender,
 			RTPTransceiverDirectionSendonly,
 			track.Kind(),
-		), nil
+		)
+		pc.onNegotiationNeeded()
+		return t, nil
 	default:
 		return nil, fmt.Errorf("AddTransceiverFromTrack currently only supports sendonly and sendrecv")
 	}
@@ -1634,8 +2565,9 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 	}
 
 	params := &DataChannelParameters{
-		Label:   label,
-		Ordered: true,
+		Label:    label,
+		Ordered:  true,
+		Priority: PriorityTypeLow,
 	}
 
 	// https://w3c.github.io/webrtc-pc/#peer-to-peer-data-api (Step #19)
@@ -1675,6 +2607,10 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 		if options.Negotiated != nil {
 			params.Negotiated = *options.Negotiated
 		}
+
+		if options.Priority != nil {
+			params.Priority = *options.Priority
+		}
 	}
 
 	d, err := pc.api.newDataChannel(params, pc.log)
@@ -1687,7 +2623,33 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 		return nil, &rtcerr.TypeError{Err: ErrRetransmitsOrPacketLifeTime}
 	}
 
+	// https://w3c.github.io/webrtc-pc/#peer-to-peer-data-api
+	// A negotiated DataChannel must have an id
+	if d.negotiated && d.id == nil {
+		return nil, &rtcerr.TypeError{Err: ErrNegotiatedWithoutID}
+	}
+
+	// The stream 65535 is reserved
+	if d.id != nil && *d.id >= sctpMaxChannels {
+		return nil, &rtcerr.TypeError{Err: ErrMaxDataChannelID}
+	}
+
+	// The streams above the MaxChannels can't be used
+	if d.id != nil && *d.id >= pc.sctpTransport.MaxChannels() {
+		return nil, &rtcerr.OperationError{Err: ErrMaxDataChannelID}
+	}
+
+	if err = pc.sctpTransport.ensureChannelCount(); err != nil {
+		return nil, err
+	}
+
 	pc.sctpTransport.lock.Lock()
+	// Both peers use the id of a negotiated DataChannel, the other ids are
+	// even or odd depending on the DTLS role, but an explicit id can collide
+	if d.id != nil && pc.sctpTransport.isChannelWithID(*d.id) {
+		pc.sctpTransport.lock.Unlock()
+		return nil, &rtcerr.OperationError{Err: ErrDataChannelIDInUse}
+	}
 	pc.sctpTransport.dataChannels = append(pc.sctpTransport.dataChannels, d)
 	pc.sctpTransport.dataChannelsRequested++
 	pc.sctpTransport.lock.Unlock()
@@ -1699,6 +2661,8 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 		}
 	}
 
+	pc.onNegotiationNeeded()
+
 	return d, nil
 }
 
@@ -1742,7 +2706,13 @@ func (pc *PeerConnection) Close() error {
 	pc.isClosed.set(true)
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #4)
+	pc.mu.Lock()
 	pc.signalingState = SignalingStateClosed
+	pc.mu.Unlock()
+
+	if pc.networkMonitorDone != nil {
+		close(pc.networkMonitorDone)
+	}
 
 	// Try closing everything and collect the errors
 	// Shutdown strategy:
@@ -1788,11 +2758,33 @@ func (pc *PeerConnection) Close() error {
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #12)
 	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransport.State())
 
+	pc.updateTransceiverFeed()
+	pc.transceiverFeed.close()
+
 	return util.FlattenErrs(closeErrs)
 }
 
+// SubscribeTransceiverEvents returns a channel receiving the changes of the
+// PeerConnection transceivers and a function canceling the subscription. The
+// current transceivers are reported first, as if just added. The events are
+// queued without limits so a subscriber never misses a change, the channel is
+// closed after the PeerConnection is closed and the queued events received.
+func (pc *PeerConnection) SubscribeTransceiverEvents() (<-chan TransceiverEvent, func()) {
+	return pc.transceiverFeed.subscribe()
+}
+
+// updateTransceiverFeed reports the changes of the transceivers since the last
+// update to the subscribers
+func (pc *PeerConnection) updateTransceiverFeed() {
+	pc.transceiverFeed.update(pc.GetTransceivers())
+}
+
 // NewTrack Creates a new Track
 func (pc *PeerConnection) NewTrack(payloadType uint8, ssrc uint32, id, label string) (*Track, error) {
+	if pc.api.settingEngine.dataChannelOnly {
+		return nil, &rtcerr.InvalidStateError{Err: ErrDataChannelOnly}
+	}
+
 	codec, err := pc.api.mediaEngine.getCodec(payloadType)
 	if err != nil {
 		return nil, err
@@ -1803,13 +2795,43 @@ func (pc *PeerConnection) NewTrack(payloadType uint8, ssrc uint32, id, label str
 	return NewTrack(payloadType, ssrc, id, label, codec)
 }
 
+// recycleTransceiver associates the transceiver of a stopped media section to
+// the media section with the provided mid, replacing its receiver and sender
+// if they have been already started. The replaced ones are stopped when the
+// answer is applied, and restored by a rollback
+func (pc *PeerConnection) recycleTransceiver(t *RTPTransceiver, mid string) error {
+	recycled := &recycledMediaSection{mid: t.Mid()}
+	if receiver := t.Receiver(); receiver != nil && receiver.haveReceived() {
+		newReceiver, err := pc.api.NewRTPReceiver(t.kind, pc.dtlsTransport)
+		if err != nil {
+			return err
+		}
+		recycled.receiver = receiver
+		t.setReceiver(newReceiver)
+	}
+	if sender := t.Sender(); sender != nil && sender.hasSent() {
+		newSender, err := pc.api.NewRTPSender(sender.Track(), pc.dtlsTransport)
+		if err != nil {
+			return err
+		}
+		recycled.sender = sender
+		t.setSender(newSender)
+	}
+
+	t.recycled = recycled
+	t.mid.Store(mid)
+	t.setRejected(false)
+	t.setCodec(nil)
+	return nil
+}
+
 func (pc *PeerConnection) newRTPTransceiver(
 	receiver *RTPReceiver,
 	sender *RTPSender,
 	direction RTPTransceiverDirection,
 	kind RTPCodecType,
 ) *RTPTransceiver {
-	t := &RTPTransceiver{kind: kind}
+	t := &RTPTransceiver{kind: kind, onStopHandler: pc.updateTransceiverFeed}
 	t.setReceiver(receiver)
 	t.setSender(sender)
 	t.setDirection(direction)
@@ -1825,7 +2847,10 @@ func (pc *PeerConnection) newRTPTransceiver(
 // into the stable state plus any local candidates that have been generated
 // by the ICEAgent since the offer or answer was created.
 func (pc *PeerConnection) CurrentLocalDescription() *SessionDescription {
-	return populateLocalCandidates(pc.currentLocalDescription, pc.iceGatherer, pc.ICEGatheringState())
+	pc.mu.RLock()
+	desc := pc.currentLocalDescription
+	pc.mu.RUnlock()
+	return populateLocalCandidates(desc, pc.iceGatherer, pc.ICEGatheringState())
 }
 
 // PendingLocalDescription represents a local description that is in the
@@ -1833,7 +2858,10 @@ func (pc *PeerConnection) CurrentLocalDescription() *SessionDescription {
 // generated by the ICEAgent since the offer or answer was created. If the
 // PeerConnection is in the stable state, the value is null.
 func (pc *PeerConnection) PendingLocalDescription() *SessionDescription {
-	return populateLocalCandidates(pc.pendingLocalDescription, pc.iceGatherer, pc.ICEGatheringState())
+	pc.mu.RLock()
+	desc := pc.pendingLocalDescription
+	pc.mu.RUnlock()
+	return populateLocalCandidates(desc, pc.iceGatherer, pc.ICEGatheringState())
 }
 
 // CurrentRemoteDescription represents the last remote description that was
@@ -1841,7 +2869,10 @@ func (pc *PeerConnection) PendingLocalDescription() *SessionDescription {
 // into the stable state plus any remote candidates that have been supplied
 // via AddICECandidate() since the offer or answer was created.
 func (pc *PeerConnection) CurrentRemoteDescription() *SessionDescription {
-	return pc.currentRemoteDescription
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.currentRemoteDescription.updateSDP()
+	return copySessionDescription(pc.currentRemoteDescription)
 }
 
 // PendingRemoteDescription represents a remote description that is in the
@@ -1850,12 +2881,17 @@ func (pc *PeerConnection) CurrentRemoteDescription() *SessionDescription {
 // created. If the PeerConnection is in the stable state, the value is
 // null.
 func (pc *PeerConnection) PendingRemoteDescription() *SessionDescription {
-	return pc.pendingRemoteDescription
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.pendingRemoteDescription.updateSDP()
+	return copySessionDescription(pc.pendingRemoteDescription)
 }
 
 // SignalingState attribute returns the signaling state of the
 // PeerConnection instance.
 func (pc *PeerConnection) SignalingState() SignalingState {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
 	return pc.signalingState
 }
 
@@ -1939,17 +2975,9 @@ func (pc *PeerConnection) GetStats() StatsReport {
 }
 
 // Start all transports. PeerConnection now has enough state
-func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, remoteUfrag, remotePwd, fingerprint, fingerprintHash string) {
+func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, remoteParameters ICEParameters, fingerprints []DTLSFingerprint) {
 	// Start the ice transport
-	err := pc.iceTransport.Start(
-		pc.iceGatherer,
-		ICEParameters{
-			UsernameFragment: remoteUfrag,
-			Password:         remotePwd,
-			ICELite:          false,
-		},
-		&iceRole,
-	)
+	err := pc.iceTransport.Start(pc.iceGatherer, remoteParameters, &iceRole)
 	if err != nil {
 		pc.log.Warnf("Failed to start manager: %s", err)
 		return
@@ -1958,7 +2986,7 @@ func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, re
 	// Start the dtls transport
 	err = pc.dtlsTransport.Start(DTLSParameters{
 		Role:         dtlsRole,
-		Fingerprints: []DTLSFingerprint{{Algorithm: fingerprintHash, Value: fingerprint}},
+		Fingerprints: fingerprints,
 	})
 	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransport.State())
 	if err != nil {
@@ -1988,7 +3016,9 @@ func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDesc
 				// with planB trackId is the receiver track id
 				trackID = t.Receiver().Track().id
 			}
+			track := t.Receiver().Track()
 			t.Receiver().Track().mu.Lock()
+			previousLabel := track.label
 
 			matched := false
 			// handle changed ssrc in same transceiver
@@ -2022,13 +3052,19 @@ func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDesc
 					}
 				}
 			}
+			label := track.label
 			t.Receiver().Track().mu.Unlock()
 			if matched {
+				// move the track to its new MediaStream
+				if label != previousLabel {
+					pc.mediaStreams.moveTrack(previousLabel, track)
+				}
 				continue
 			}
 
 			// no matching track id or track with different ssrc
 			// remove receiver
+			pc.mediaStreams.removeTrack(previousLabel, track)
 			if err := t.Receiver().Stop(); err != nil {
 				pc.log.Warnf("Failed to stop RtpReceiver: %s", err)
 				continue
@@ -2047,9 +3083,12 @@ func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDesc
 	pc.startRTPSenders(currentTransceivers)
 
 	if !isRenegotiation {
-		pc.handleUnknownSRTP()
+		// a data channel only connection doesn't have the SRTP sessions
+		if !pc.api.settingEngine.dataChannelOnly {
+			pc.handleUnknownSRTP()
+		}
 		if haveApplicationMediaSection(remoteDesc.parsed) {
-			pc.startSCTP()
+			pc.startSCTP(getMaxMessageSize(remoteDesc.parsed))
 		}
 	}
 }
@@ -2066,6 +3105,8 @@ func (pc *PeerConnection) generateUnmatchedSDP(useIdentity bool) (*sdp.SessionDe
 	if err := addFingerprints(d, pc.configuration.Certificates[0]); err != nil {
 		return nil, err
 	}
+	// the received RTP header extensions can always be mixed
+	d.WithPropertyAttribute(attributeExtMapAllowMixed)
 
 	iceParams, err := pc.iceGatherer.GetLocalParameters()
 	if err != nil {
@@ -2095,10 +3136,10 @@ func (pc *PeerConnection) generateUnmatchedSDP(useIdentity bool) (*sdp.SessionDe
 			}
 		}
 
-		if len(video) > 1 {
+		if len(video) > 0 {
 			mediaSections = append(mediaSections, mediaSection{id: "video", transceivers: video})
 		}
-		if len(audio) > 1 {
+		if len(audio) > 0 {
 			mediaSections = append(mediaSections, mediaSection{id: "audio", transceivers: audio})
 		}
 		mediaSections = append(mediaSections, mediaSection{id: "data", data: true})
@@ -2110,10 +3151,35 @@ func (pc *PeerConnection) generateUnmatchedSDP(useIdentity bool) (*sdp.SessionDe
 			mediaSections = append(mediaSections, mediaSection{id: t.Mid(), transceivers: []*RTPTransceiver{t}, extMaps: t.extMaps})
 		}
 
-		mediaSections = append(mediaSections, mediaSection{id: strconv.Itoa(len(mediaSections)), data: true})
+		dataMid := strconv.Itoa(len(mediaSections))
+		if pc.api.settingEngine.generators.Mid != nil {
+			if pc.dataMid == "" {
+				if pc.dataMid, err = pc.generateMid(); err != nil {
+					return nil, err
+				}
+			}
+			dataMid = pc.dataMid
+		}
+		mediaSections = append(mediaSections, mediaSection{id: dataMid, data: true})
+	}
+
+	// with max-bundle only the first media section has its own transport
+	// https://tools.ietf.org/html/draft-ietf-rtcweb-jsep-26#section-5.2.1
+	if pc.configuration.BundlePolicy == BundlePolicyMaxBundle {
+		for i := 1; i < len(mediaSections); i++ {
+			mediaSections[i].bundleOnly = true
+		}
 	}
 
-	return populateSDP(d, isPlanB, pc.api.settingEngine.candidates.ICELite, pc.api.mediaEngine, connectionRoleFromDtlsRole(defaultDtlsRoleOffer), candidates, iceParams, mediaSections, pc.ICEGatheringState())
+	return populateSDP(d, isPlanB, pc.api.settingEngine.candidates.ICELite, true, pc.api.mediaEngine, pc.offeringConnectionRole(), candidates, iceParams, mediaSections, pc.ICEGatheringState())
+}
+
+// offeringConnectionRole returns the a=setup value of the local offers
+func (pc *PeerConnection) offeringConnectionRole() sdp.ConnectionRole {
+	if role := pc.api.settingEngine.dtlsRole; role != DTLSRole(0) {
+		return connectionRoleFromDtlsRole(role)
+	}
+	return connectionRoleFromDtlsRole(defaultDtlsRoleOffer)
 }
 
 // generateMatchedSDP generates a SDP and takes the remote state into account
@@ -2124,6 +3190,14 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 		return nil, err
 	}
 
+	// the offers always allow mixing the RTP header extensions, the answers
+	// mirror the level of the offered attribute
+	_, sessionAllowMixed := pc.remoteDescription().parsed.Attribute(attributeExtMapAllowMixed)
+	sessionAllowMixed = sessionAllowMixed || includeUnmatched
+	if sessionAllowMixed {
+		d.WithPropertyAttribute(attributeExtMapAllowMixed)
+	}
+
 	iceParams, err := pc.iceGatherer.GetLocalParameters()
 	if err != nil {
 		return nil, err
@@ -2136,18 +3210,32 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 
 	var t *RTPTransceiver
 	localTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
-	detectedPlanB := descriptionIsPlanB(pc.RemoteDescription())
+	detectedPlanB := descriptionIsPlanB(pc.remoteDescription())
+	remoteBundle := haveBundleGroup(pc.remoteDescription().parsed)
 	mediaSections := []mediaSection{}
 
-	for _, media := range pc.RemoteDescription().parsed.MediaDescriptions {
+	for index, media := range pc.remoteDescription().parsed.MediaDescriptions {
 		midValue := getMidValue(media)
 		if midValue == "" {
 			return nil, fmt.Errorf("RemoteDescription contained media section without mid value")
 		}
 
-		rids := getRids(media)
+		// all the media sections share a single transport, the ones that
+		// aren't bundled with it are rejected
+		if !useSharedTransport(pc.remoteDescription().parsed, index) {
+			if t, localTransceivers = findByMid(midValue, localTransceivers); t != nil {
+				t.setRejected(true)
+			}
+			mediaSections = append(mediaSections, mediaSection{id: midValue, rejected: true, remoteMedia: media})
+			continue
+		}
+
+		rids, simulcast, err := getSimulcast(media)
+		if err != nil {
+			return nil, err
+		}
 		hasRids := len(rids) > 0
-		hasSimulcast := hasSimulcast(media)
+		hasSimulcast := simulcast != nil
 		// accept only rids with simulcast
 		if hasRids && !hasSimulcast {
 			return nil, fmt.Errorf("rids requires also simulcast")
@@ -2161,13 +3249,29 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 			continue
 		}
 
+		// the rejected media sections mirror the offered ones if requested
+		var rejectedMedia *sdp.MediaDescription
+		if pc.api.settingEngine.mirrorRejectedMediaSections {
+			rejectedMedia = media
+		}
+
 		kind := NewRTPCodecType(media.MediaName.Media)
 		direction := getPeerDirection(media)
 		if kind == 0 || direction == RTPTransceiverDirection(Unknown) {
+			// reject the unsupported media sections keeping the offered order
+			mediaSections = append(mediaSections, mediaSection{id: midValue, rejected: true, remoteMedia: media})
 			continue
 		}
 
 		sdpSemantics := pc.configuration.SDPSemantics
+		_, mediaAllowMixed := media.Attribute(attributeExtMapAllowMixed)
+		mediaAllowMixed = mediaAllowMixed && !sessionAllowMixed
+
+		// the answered direction depends on the offered one
+		offeredDirection := RTPTransceiverDirection(Unknown)
+		if !includeUnmatched {
+			offeredDirection = direction
+		}
 
 		switch {
 		case sdpSemantics == SDPSemanticsPlanB || sdpSemantics == SDPSemanticsUnifiedPlanWithFallback && detectedPlanB:
@@ -2193,15 +3297,28 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 				}
 				mediaTransceivers = append(mediaTransceivers, t)
 			}
-			mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: mediaTransceivers})
+			mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: mediaTransceivers, extMapAllowMixed: mediaAllowMixed, offeredDirection: offeredDirection, remoteMedia: rejectedMedia})
 		case sdpSemantics == SDPSemanticsUnifiedPlan || sdpSemantics == SDPSemanticsUnifiedPlanWithFallback:
 			if detectedPlanB {
 				return nil, &rtcerr.TypeError{Err: ErrIncorrectSDPSemantics}
 			}
 			t, localTransceivers = findByMid(midValue, localTransceivers)
+			if t == nil && isMediaSectionRejected(media) {
+				// keep the rejected media section without a local transceiver
+				t = &RTPTransceiver{kind: kind}
+				t.setDirection(RTPTransceiverDirectionInactive)
+				mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: []*RTPTransceiver{t}, rejected: true, remoteMedia: rejectedMedia})
+				continue
+			}
 			if t == nil {
 				return nil, fmt.Errorf("cannot find transceiver with mid %q", midValue)
 			}
+			// when answering keep the media sections rejected while offering
+			// them again gives a chance to renegotiate them
+			if !includeUnmatched && t.isRejected() {
+				mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: []*RTPTransceiver{t}, rejected: true, remoteMedia: rejectedMedia})
+				continue
+			}
 			if t.Sender() != nil {
 				t.Sender().setNegotiated()
 			}
@@ -2214,7 +3331,11 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 			}
 
 			mediaTransceivers := []*RTPTransceiver{t}
-			mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: mediaTransceivers, recvSimulcast: hasSimulcast, recvRids: rids, extMaps: t.extMaps})
+			var recvSimulcast [][]simulcastStream
+			if hasSimulcast {
+				recvSimulcast = simulcast.send
+			}
+			mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: mediaTransceivers, recvSimulcast: recvSimulcast, extMaps: t.extMaps, extMapAllowMixed: mediaAllowMixed, offeredDirection: offeredDirection, remoteMedia: rejectedMedia})
 		}
 	}
 
@@ -2232,7 +3353,7 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 		pc.log.Info("Plan-B Offer detected; responding with Plan-B Answer")
 	}
 
-	return populateSDP(d, detectedPlanB, pc.api.settingEngine.candidates.ICELite, pc.api.mediaEngine, connectionRole, candidates, iceParams, mediaSections, pc.ICEGatheringState())
+	return populateSDP(d, detectedPlanB, pc.api.settingEngine.candidates.ICELite, remoteBundle, pc.api.mediaEngine, connectionRole, candidates, iceParams, mediaSections, pc.ICEGatheringState())
 }
 
 func (pc *PeerConnection) handleAnswerExtMaps(t *RTPTransceiver, media *sdp.MediaDescription, weOffer bool) error {
@@ -2249,8 +3370,8 @@ func (pc *PeerConnection) handleAnswerExtMaps(t *RTPTransceiver, media *sdp.Medi
 			continue
 		}
 		if remoteExtMap, ok := remoteExtMaps[em.Value]; ok {
-			if remoteExtMap.Value != em.Value {
-				return fmt.Errorf("RemoteDescription changed some extmaps values")
+			if remoteExtMap.URI.String() != em.URI.String() {
+				return fmt.Errorf("RemoteDescription uses extmap value %d for multiple uris", em.Value)
 			}
 		} else {
 			remoteExtMaps[em.Value] = em
@@ -2266,7 +3387,7 @@ func (pc *PeerConnection) handleAnswerExtMaps(t *RTPTransceiver, media *sdp.Medi
 	for _, curRemoteExtMap := range remoteExtMaps {
 		if remoteExtMap, ok := curRemoteExtMaps[curRemoteExtMap.Value]; ok {
 			// check that the remote extmaps haven't changed some already known values
-			if remoteExtMap.Value != curRemoteExtMap.Value {
+			if remoteExtMap.URI.String() != curRemoteExtMap.URI.String() {
 				return fmt.Errorf("RemoteDescription changed some extmaps values")
 			}
 		} else {
@@ -2275,88 +3396,35 @@ func (pc *PeerConnection) handleAnswerExtMaps(t *RTPTransceiver, media *sdp.Medi
 		}
 	}
 
-	// if we received an offer and extmap is not yet negotiated ask the user and populate the initial extmaps for this mid
-	pc.log.Infof("weOffer: %t", weOffer)
-	pc.log.Infof("t.extMaps: %+v", t.extMaps)
-	if !weOffer && t.extMaps == nil {
+	// If we received an offer mirror the offered values of the supported
+	// extmaps, the not supported ones are rejected omitting them
+	if !weOffer {
 		t.extMaps = make(map[int]*sdp.ExtMap)
-		supportedExtMaps := make(map[int]*sdp.ExtMap)
 
+		supportedExtMaps := []SupportedExtMap{}
 		if t.getNegotiationData() != nil {
-			pc.log.Infof("supportedExtMaps: %+v", t.getNegotiationData().SupportedExtMaps)
-			curValue := 1
-			values := map[int]struct{}{}
-			if pc.currentSDESMidExtValue > 0 {
-				values[pc.currentSDESMidExtValue] = struct{}{}
-			}
-			for _, supportedExtMap := range t.getNegotiationData().SupportedExtMaps {
-				// use a generic value with the exception of the mid uri extmap that requires a global value
-				for {
-					if _, ok := values[curValue]; ok {
-						curValue++
-						continue
-					}
-					values[curValue] = struct{}{}
-					break
-				}
-				value := curValue
-				if supportedExtMap.URI.String() == sdesMidURI && pc.currentSDESMidExtValue > 0 {
-					value = pc.currentSDESMidExtValue
-				}
-				extMap := &sdp.ExtMap{
-					Value:     value,
-					URI:       supportedExtMap.URI,
-					Direction: supportedExtMap.Direction,
-					ExtAttr:   supportedExtMap.ExtAttr,
-				}
-				supportedExtMaps[value] = extMap
-			}
+			supportedExtMaps = t.getNegotiationData().SupportedExtMaps
 		}
-		pc.log.Infof("supportedExtMaps: %+v", supportedExtMaps)
-		pc.log.Infof("t.extMaps: %+v", t.extMaps)
-
-		// On both offer and answer update our extMaps adapting to the remote values
-		for _, remoteExtMap := range t.remoteExtMaps {
-			// add extmap if supported
-			for _, extMap := range supportedExtMaps {
-				pc.log.Infof("extMap: %+v", extMap)
-				pc.log.Infof("remoteExtMap: %+v", remoteExtMap)
-				if extMap.URI.String() == remoteExtMap.URI.String() {
-					// use direction relative to remote extMap
-
-					// start with unknown direction
-					direction := extMap.Direction
-					switch remoteExtMap.Direction {
-					case sdp.DirectionRecvOnly:
-						switch extMap.Direction {
-						case sdp.DirectionRecvOnly:
-							// skip extmap since our supported direction is not acceptable by remote
-							continue
-						default:
-							direction = sdp.DirectionSendOnly
-						}
-					case sdp.DirectionSendOnly:
-						switch extMap.Direction {
-						case sdp.DirectionSendOnly:
-							// skip extmap since our supported direction is not acceptable by remote
-							continue
-						default:
-							direction = sdp.DirectionRecvOnly
-						}
-					}
-
-					t.extMaps[remoteExtMap.Value] = &sdp.ExtMap{
-						Value:     remoteExtMap.Value,
-						URI:       remoteExtMap.URI,
-						Direction: direction,
-						ExtAttr:   extMap.ExtAttr,
-					}
+		for _, remoteExtMap := range remoteExtMaps {
+			for _, supportedExtMap := range supportedExtMaps {
+				if supportedExtMap.URI.String() != remoteExtMap.URI.String() {
+					continue
+				}
+				direction, ok := answerExtMapDirection(supportedExtMap.Direction, remoteExtMap.Direction)
+				if !ok {
 					break
 				}
 
+				t.extMaps[remoteExtMap.Value] = &sdp.ExtMap{
+					Value:     remoteExtMap.Value,
+					URI:       remoteExtMap.URI,
+					Direction: direction,
+					ExtAttr:   supportedExtMap.ExtAttr,
+				}
 				if remoteExtMap.URI.String() == sdesMidURI && pc.currentSDESMidExtValue < 0 {
 					pc.currentSDESMidExtValue = remoteExtMap.Value
 				}
+				break
 			}
 		}
 	}
@@ -2365,7 +3433,7 @@ func (pc *PeerConnection) handleAnswerExtMaps(t *RTPTransceiver, media *sdp.Medi
 	if weOffer {
 		for _, extMap := range t.extMaps {
 			found := false
-			for _, remoteExtMap := range t.remoteExtMaps {
+			for _, remoteExtMap := range remoteExtMaps {
 				if extMap.URI.String() == remoteExtMap.URI.String() {
 					found = true
 					break
@@ -2383,6 +3451,25 @@ func (pc *PeerConnection) handleAnswerExtMaps(t *RTPTransceiver, media *sdp.Medi
 	return nil
 }
 
+// answerExtMapDirection returns the direction of a supported extmap relative
+// to the offered one, false if the supported direction isn't acceptable by the
+// remote peer
+func answerExtMapDirection(supported, offered sdp.Direction) (sdp.Direction, bool) {
+	switch offered {
+	case sdp.DirectionRecvOnly:
+		if supported == sdp.DirectionRecvOnly {
+			return supported, false
+		}
+		return sdp.DirectionSendOnly, true
+	case sdp.DirectionSendOnly:
+		if supported == sdp.DirectionSendOnly {
+			return supported, false
+		}
+		return sdp.DirectionRecvOnly, true
+	}
+	return supported, true
+}
+
 func (pc *PeerConnection) handleOfferExtmaps(t *RTPTransceiver) error {
 	if t.extMaps != nil {
 		return nil
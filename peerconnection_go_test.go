@@ -3,21 +3,28 @@
 package webrtc
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"math/big"
 	"reflect"
 	"regexp"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/pion/ice"
+	"github.com/pion/logging"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v2"
 	"github.com/pion/transport/test"
+	"github.com/pion/transport/vnet"
 	"github.com/pion/webrtc/v2/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 )
@@ -127,7 +134,7 @@ func TestNew_Go(t *testing.T) {
 func TestPeerConnection_SetConfiguration_Go(t *testing.T) {
 	// Note: this test includes all SetConfiguration features that are supported
 	// by Go but not the WASM bindings, namely: ICEServer.Credential,
-	// ICEServer.CredentialType, and Certificates.
+	// ICEServer.CredentialType, Certificates and SDPSemantics.
 	report := test.CheckRoutines(t)
 	defer report()
 
@@ -232,6 +239,16 @@ func TestPeerConnection_SetConfiguration_Go(t *testing.T) {
 			},
 			wantErr: &rtcerr.InvalidAccessError{Err: ErrNoTurnCredentials},
 		},
+		{
+			name: "update SDPSemantics",
+			init: func() (*PeerConnection, error) {
+				return NewPeerConnection(Configuration{})
+			},
+			config: Configuration{
+				SDPSemantics: SDPSemanticsPlanB,
+			},
+			wantErr: &rtcerr.InvalidModificationError{Err: ErrModifyingSDPSemantics},
+		},
 	} {
 		pc, err := test.init()
 		if err != nil {
@@ -365,6 +382,137 @@ func TestPeerConnection_PropertyGetters(t *testing.T) {
 	assert.Equal(t, pc.connectionState, pc.ConnectionState(), "should match")
 }
 
+func TestPeerConnection_Descriptions(t *testing.T) {
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	assertDescriptions := func(pc *PeerConnection, currentLocal, pendingLocal, currentRemote, pendingRemote SDPType) {
+		descType := func(desc *SessionDescription) SDPType {
+			if desc == nil {
+				return SDPType(Unknown)
+			}
+			return desc.Type
+		}
+		assert.Equal(t, currentLocal, descType(pc.CurrentLocalDescription()), "current local")
+		assert.Equal(t, pendingLocal, descType(pc.PendingLocalDescription()), "pending local")
+		assert.Equal(t, currentRemote, descType(pc.CurrentRemoteDescription()), "current remote")
+		assert.Equal(t, pendingRemote, descType(pc.PendingRemoteDescription()), "pending remote")
+	}
+	none := SDPType(Unknown)
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assertDescriptions(pcOffer, none, SDPTypeOffer, none, none)
+
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+	assertDescriptions(pcAnswer, none, none, none, SDPTypeOffer)
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	pranswer := SessionDescription{Type: SDPTypePranswer, SDP: answer.SDP}
+	assert.NoError(t, pcAnswer.SetLocalDescription(pranswer))
+	assertDescriptions(pcAnswer, none, SDPTypePranswer, none, SDPTypeOffer)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	assertDescriptions(pcAnswer, SDPTypeAnswer, none, SDPTypeOffer, none)
+
+	assert.NoError(t, pcOffer.SetRemoteDescription(pranswer))
+	assertDescriptions(pcOffer, none, SDPTypeOffer, none, SDPTypePranswer)
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+	assertDescriptions(pcOffer, SDPTypeOffer, none, SDPTypeAnswer, none)
+
+	// a rolled back offer keeps the current descriptions
+	offer, err = pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assertDescriptions(pcOffer, SDPTypeOffer, SDPTypeOffer, SDPTypeAnswer, none)
+	assert.NoError(t, pcOffer.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}))
+	assertDescriptions(pcOffer, SDPTypeOffer, none, SDPTypeAnswer, none)
+
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+	assertDescriptions(pcAnswer, SDPTypeAnswer, none, SDPTypeOffer, SDPTypeOffer)
+	assert.NoError(t, pcAnswer.SetRemoteDescription(SessionDescription{Type: SDPTypeRollback}))
+	assertDescriptions(pcAnswer, SDPTypeAnswer, none, SDPTypeOffer, none)
+
+	// the remote candidates are added to the remote description
+	mid := getMidValue(pcOffer.currentRemoteDescription.parsed.MediaDescriptions[0])
+	candidate := "candidate:1 1 udp 2130706431 192.168.0.1 53987 typ host"
+	assert.NoError(t, pcOffer.AddICECandidate(ICECandidateInit{Candidate: candidate, SDPMid: &mid}))
+	// the SDP is marshaled when it's exposed
+	assert.True(t, pcOffer.currentRemoteDescription.sdpStale)
+	assert.Contains(t, pcOffer.CurrentRemoteDescription().SDP, "a="+candidate)
+	assert.False(t, pcOffer.currentRemoteDescription.sdpStale)
+	assert.Equal(t, 1, strings.Count(pcOffer.CurrentRemoteDescription().SDP, candidate))
+	assert.NoError(t, pcOffer.AddICECandidate(ICECandidateInit{Candidate: candidate, SDPMid: &mid}))
+	assert.Equal(t, 1, strings.Count(pcOffer.CurrentRemoteDescription().SDP, candidate))
+	assert.Equal(t, 1, strings.Count(pcOffer.RemoteDescription().SDP, candidate))
+
+	// the returned descriptions are copies
+	for _, desc := range []*SessionDescription{pcOffer.CurrentRemoteDescription(), pcOffer.RemoteDescription()} {
+		desc.SDP = ""
+	}
+	assert.NotEqual(t, "", pcOffer.CurrentRemoteDescription().SDP)
+	assert.NotEqual(t, "", pcOffer.RemoteDescription().SDP)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_TransformLocalDescription(t *testing.T) {
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	pcOffer.OnTransformLocalDescription(func(sdpType SDPType, d *sdp.SessionDescription) error {
+		assert.Equal(t, SDPTypeOffer, sdpType)
+		d.MediaDescriptions[0].Bandwidth = append(d.MediaDescriptions[0].Bandwidth, sdp.Bandwidth{Type: "AS", Bandwidth: 500})
+		return nil
+	})
+	pcAnswer.OnTransformLocalDescription(func(sdpType SDPType, d *sdp.SessionDescription) error {
+		assert.Equal(t, SDPTypeAnswer, sdpType)
+		d.MediaDescriptions[0].Bandwidth = append(d.MediaDescriptions[0].Bandwidth, sdp.Bandwidth{Type: "AS", Bandwidth: 300})
+		return nil
+	})
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "b=AS:500")
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.Contains(t, pcOffer.LocalDescription().SDP, "b=AS:500")
+
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, answer.SDP, "b=AS:300")
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+	// the media sections can't be changed
+	pcOffer.OnTransformLocalDescription(func(sdpType SDPType, d *sdp.SessionDescription) error {
+		d.MediaDescriptions = d.MediaDescriptions[1:]
+		return nil
+	})
+	_, err = pcOffer.CreateOffer(nil)
+	assert.Equal(t, ErrTransformedMediaSections, err)
+
+	errTransform := errors.New("transform error")
+	pcOffer.OnTransformLocalDescription(func(sdpType SDPType, d *sdp.SessionDescription) error {
+		return errTransform
+	})
+	_, err = pcOffer.CreateOffer(nil)
+	assert.Equal(t, errTransform, err)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
 func TestPeerConnection_AnswerWithoutOffer(t *testing.T) {
 	report := test.CheckRoutines(t)
 	defer report()
@@ -605,10 +753,178 @@ func TestPeerConnection_OfferingLite(t *testing.T) {
 	})
 
 	<-iceComplete
+	// the full agent is controlling even if it's the answerer
+	assert.Contains(t, offerPC.LocalDescription().SDP, "a=ice-lite\r\n")
+	assert.Equal(t, ICERoleControlled, offerPC.iceTransport.Role())
+	assert.Equal(t, ICERoleControlling, answerPC.iceTransport.Role())
+
 	assert.NoError(t, offerPC.Close())
 	assert.NoError(t, answerPC.Close())
 }
 
+// Assert that two PeerConnections connect over a virtual network, using only
+// its addresses
+func TestPeerConnection_VNet(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	router, err := vnet.NewRouter(&vnet.RouterConfig{
+		CIDR:          "1.2.3.0/24",
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	assert.NoError(t, err)
+
+	newPeerConnection := func() *PeerConnection {
+		n := vnet.NewNet(&vnet.NetConfig{})
+		assert.NoError(t, router.AddNet(n))
+
+		s := SettingEngine{}
+		s.SetVNet(n)
+		pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		return pc
+	}
+	pcOffer := newPeerConnection()
+	pcAnswer := newPeerConnection()
+	assert.NoError(t, router.Start())
+
+	connected := make(chan struct{})
+	pcOffer.OnICEConnectionStateChange(func(iceState ICEConnectionState) {
+		if iceState == ICEConnectionStateConnected {
+			close(connected)
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-connected
+
+	pair := pcOffer.iceTransport.GetSelectedCandidatePair()
+	assert.True(t, strings.HasPrefix(pair.Local.Address, "1.2.3."), pair.Local.Address)
+	assert.True(t, strings.HasPrefix(pair.Remote.Address, "1.2.3."), pair.Remote.Address)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+	assert.NoError(t, router.Stop())
+}
+
+// Assert that the RTP packets are sent in clear over a virtual network when
+// SRTP is disabled
+func TestPeerConnection_DisableSRTPOverVNet(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	router, err := vnet.NewRouter(&vnet.RouterConfig{
+		CIDR:          "1.2.3.0/24",
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	assert.NoError(t, err)
+
+	payload := []byte("plaintext-rtp-payload")
+	var seenInClear atomicBool
+	router.AddChunkFilter(func(c vnet.Chunk) bool {
+		if bytes.Contains(c.UserData(), payload) {
+			seenInClear.set(true)
+		}
+		return true
+	})
+
+	newPeerConnection := func() *PeerConnection {
+		n := vnet.NewNet(&vnet.NetConfig{})
+		assert.NoError(t, router.AddNet(n))
+
+		s := SettingEngine{}
+		s.SetVNet(n)
+		s.DisableSRTPOverVNet(true)
+		m := MediaEngine{}
+		m.RegisterDefaultCodecs()
+		pc, err := NewAPI(WithSettingEngine(s), WithMediaEngine(m)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		return pc
+	}
+	pcOffer := newPeerConnection()
+	pcAnswer := newPeerConnection()
+	assert.NoError(t, router.Start())
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	received := make(chan []byte)
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		packet, err := track.ReadRTP()
+		assert.NoError(t, err)
+		received <- packet.Payload
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	func() {
+		for sequenceNumber := uint16(0); ; sequenceNumber++ {
+			select {
+			case receivedPayload := <-received:
+				assert.Equal(t, payload, receivedPayload)
+				return
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteRTP(&rtp.Packet{
+					Header: rtp.Header{
+						Version:        2,
+						PayloadType:    track.PayloadType(),
+						SequenceNumber: sequenceNumber,
+						SSRC:           track.SSRC(),
+					},
+					Payload: payload,
+				}))
+			}
+		}
+	}()
+	assert.True(t, seenInClear.get())
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+	assert.NoError(t, router.Stop())
+}
+
+func TestPeerConnection_ICERole(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	// the offerer is controlled and the answerer controlling
+	sOffer := SettingEngine{}
+	assert.NoError(t, sOffer.SetICERole(ICERoleControlled))
+	pcOffer, err := NewAPI(WithSettingEngine(sOffer)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	sAnswer := SettingEngine{}
+	assert.NoError(t, sAnswer.SetICERole(ICERoleControlling))
+	pcAnswer, err := NewAPI(WithSettingEngine(sAnswer)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	connected := make(chan struct{})
+	pcAnswer.OnICEConnectionStateChange(func(iceState ICEConnectionState) {
+		if iceState == ICEConnectionStateConnected {
+			close(connected)
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-connected
+	assert.Equal(t, ICERoleControlled, pcOffer.iceTransport.Role())
+	assert.Equal(t, ICERoleControlling, pcAnswer.iceTransport.Role())
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
 func TestPeerConnection_AnsweringLite(t *testing.T) {
 	report := test.CheckRoutines(t)
 	defer report()
@@ -641,6 +957,10 @@ func TestPeerConnection_AnsweringLite(t *testing.T) {
 	})
 
 	<-iceComplete
+	assert.Contains(t, answerPC.LocalDescription().SDP, "a=ice-lite\r\n")
+	assert.Equal(t, ICERoleControlling, offerPC.iceTransport.Role())
+	assert.Equal(t, ICERoleControlled, answerPC.iceTransport.Role())
+
 	assert.NoError(t, offerPC.Close())
 	assert.NoError(t, answerPC.Close())
 }
@@ -708,6 +1028,240 @@ func TestOnICEGatheringStateChange(t *testing.T) {
 	}
 }
 
+func TestPeerConnection_SignalingStateTransitions(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	states := make(chan SignalingState, 10)
+	pcAnswer.OnSignalingStateChange(func(state SignalingState) {
+		states <- state
+	})
+
+	err = pcAnswer.SetLocalDescription(SessionDescription{Type: SDPTypeRollback})
+	assert.Equal(t, &rtcerr.InvalidModificationError{Err: &SignalingStateTransitionError{
+		Current: SignalingStateStable,
+		Next:    SignalingStateStable,
+		Type:    SDPTypeRollback,
+		Err:     ErrSignalingStateCannotRollback,
+	}}, err)
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+	assert.Equal(t, SignalingStateHaveRemoteOffer, pcAnswer.SignalingState())
+	assert.Equal(t, SignalingStateHaveRemoteOffer, <-states)
+
+	// a second offer can't be set before answering the first one
+	err = pcAnswer.SetRemoteDescription(offer)
+	assert.Equal(t, &rtcerr.InvalidModificationError{Err: &SignalingStateTransitionError{
+		Current: SignalingStateHaveRemoteOffer,
+		Next:    SignalingStateHaveRemoteOffer,
+		Remote:  true,
+		Type:    SDPTypeOffer,
+		Err:     ErrSignalingStateProposedTransitionInvalid,
+	}}, err)
+	assert.EqualError(t, err, "InvalidModificationError: invalid proposed signaling state transition have-remote-offer->SetRemote(offer)->have-remote-offer")
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	assert.Equal(t, SignalingStateStable, pcAnswer.SignalingState())
+	assert.Equal(t, SignalingStateStable, <-states)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_GetCompleteLocalDescription(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	for _, trickle := range []bool{true, false} {
+		s := SettingEngine{}
+		s.SetTrickle(trickle)
+
+		pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		_, err = pc.GetCompleteLocalDescription()
+		assert.Equal(t, &rtcerr.InvalidStateError{Err: ErrNoLocalDescription}, err)
+
+		_, err = pc.CreateDataChannel("data", nil)
+		assert.NoError(t, err)
+		offer, err := pc.CreateOffer(nil)
+		assert.NoError(t, err)
+		assert.NoError(t, pc.SetLocalDescription(offer))
+
+		desc, err := pc.GetCompleteLocalDescription()
+		assert.NoError(t, err)
+		assert.Equal(t, ICEGatheringStateComplete, pc.ICEGatheringState())
+		assert.Contains(t, desc.SDP, "a=candidate:")
+		assert.Contains(t, desc.SDP, "a=end-of-candidates")
+
+		assert.NoError(t, pc.Close())
+		_, err = pc.GetCompleteLocalDescription()
+		assert.Equal(t, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}, err)
+	}
+}
+
+// Assert that the gathering completion is signaled by the state change
+// handler, a nil candidate and the GatheringCompletePromise
+func TestPeerConnection_GatheringCompletePromise(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	for _, trickle := range []bool{true, false} {
+		s := SettingEngine{}
+		s.SetTrickle(trickle)
+
+		pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		seenComplete := make(chan struct{})
+		pc.OnICEGatheringStateChange(func(s ICEGathererState) {
+			if s == ICEGathererStateComplete {
+				close(seenComplete)
+			}
+		})
+		seenEndOfCandidates := make(chan struct{})
+		pc.OnICECandidate(func(c *ICECandidate) {
+			if c == nil {
+				close(seenEndOfCandidates)
+			}
+		})
+
+		_, err = pc.CreateDataChannel("data", nil)
+		assert.NoError(t, err)
+		offer, err := pc.CreateOffer(nil)
+		assert.NoError(t, err)
+		gatheringComplete := pc.GatheringCompletePromise()
+		assert.NoError(t, pc.SetLocalDescription(offer))
+
+		<-gatheringComplete
+		<-seenComplete
+		<-seenEndOfCandidates
+		assert.Equal(t, ICEGatheringStateComplete, pc.ICEGatheringState())
+		assert.Contains(t, pc.LocalDescription().SDP, "a=end-of-candidates")
+
+		assert.NoError(t, pc.Close())
+	}
+}
+
+// Assert that only the relayed candidates are gathered with the relay
+// policy, and that a policy change is applied by an ICE restart
+func TestPeerConnection_RelayPolicy(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := NewAPI().newPair(Configuration{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.Contains(t, pcOffer.CurrentLocalDescription().SDP, "typ host")
+
+	assert.NoError(t, pcOffer.SetConfiguration(Configuration{ICETransportPolicy: ICETransportPolicyRelay}))
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "typ host")
+
+	// there's no TURN server
+	offer, err = pcOffer.CreateOffer(&OfferOptions{ICERestart: true})
+	assert.NoError(t, err)
+	gatheringComplete := pcOffer.GatheringCompletePromise()
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	<-gatheringComplete
+	assert.NotContains(t, pcOffer.PendingLocalDescription().SDP, "a=candidate:")
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// Assert that a data channel only connection works without a MediaEngine
+func TestPeerConnection_DataChannelOnly(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := NewAPI().newPair(Configuration{})
+	assert.NoError(t, err)
+
+	opened := make(chan struct{})
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnOpen(func() {
+			close(opened)
+		})
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.NotContains(t, pcOffer.LocalDescription().SDP, "m=audio")
+	assert.NotContains(t, pcOffer.LocalDescription().SDP, "m=video")
+	<-opened
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// Assert that a PeerConnection created with SetDataChannelOnly rejects the
+// media and doesn't create the SRTP endpoints
+func TestPeerConnection_SetDataChannelOnly(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	pcOffer, err := NewAPI(WithMediaEngine(m)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	s := SettingEngine{}
+	s.SetDataChannelOnly(true)
+	pcAnswer, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	expectedErr := &rtcerr.InvalidStateError{Err: ErrDataChannelOnly}
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.Equal(t, expectedErr, err)
+	_, err = pcAnswer.NewTrack(DefaultPayloadTypeOpus, 1, "audio", "pion")
+	assert.Equal(t, expectedErr, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, 1, "audio", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTrack(track)
+	assert.Equal(t, expectedErr, err)
+
+	opened := make(chan struct{})
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		if d.Label() != "data" {
+			return
+		}
+		d.OnOpen(func() {
+			close(opened)
+		})
+	})
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.Contains(t, pcAnswer.LocalDescription().SDP, "m=audio 0 ")
+	<-opened
+
+	pcAnswer.dtlsTransport.lock.RLock()
+	assert.Nil(t, pcAnswer.dtlsTransport.srtpEndpoint)
+	assert.Nil(t, pcAnswer.dtlsTransport.srtcpEndpoint)
+	pcAnswer.dtlsTransport.lock.RUnlock()
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
 // Assert that when Trickle is enabled two connections can connect
 func TestPeerConnectionTrickle(t *testing.T) {
 	lim := test.TimeOut(time.Second * 10)
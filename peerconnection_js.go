@@ -24,6 +24,7 @@ type PeerConnection struct {
 	onICEConnectionStateChangeHandler *js.Func
 	onICECandidateHandler             *js.Func
 	onICEGatheringStateChangeHandler  *js.Func
+	onNegotiationNeededHandler        *js.Func
 
 	// A reference to the associated API state used by this connection
 	api *API
@@ -66,6 +67,21 @@ func (pc *PeerConnection) OnSignalingStateChange(f func(SignalingState)) {
 	pc.underlying.Set("onsignalingstatechange", onSignalingStateChangeHandler)
 }
 
+// OnNegotiationNeeded sets an event handler which is invoked when
+// a change has occurred which requires session negotiation
+func (pc *PeerConnection) OnNegotiationNeeded(f func()) {
+	if pc.onNegotiationNeededHandler != nil {
+		oldHandler := pc.onNegotiationNeededHandler
+		defer oldHandler.Release()
+	}
+	onNegotiationNeededHandler := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go f()
+		return js.Undefined()
+	})
+	pc.onNegotiationNeededHandler = &onNegotiationNeededHandler
+	pc.underlying.Set("onnegotiationneeded", onNegotiationNeededHandler)
+}
+
 // OnDataChannel sets an event handler which is invoked when a data
 // channel message arrives from a remote peer.
 func (pc *PeerConnection) OnDataChannel(f func(*DataChannel)) {
@@ -80,10 +96,7 @@ func (pc *PeerConnection) OnDataChannel(f func(*DataChannel)) {
 		// memory leaks where we don't clean up handler functions. Could possibly fix
 		// by keeping a mutex-protected list of all DataChannel references as a
 		// property of this PeerConnection, but at the cost of additional overhead.
-		dataChannel := &DataChannel{
-			underlying: args[0].Get("channel"),
-			api:        pc.api,
-		}
+		dataChannel := newDataChannel(args[0].Get("channel"), pc.api)
 		go f(dataChannel)
 		return js.Undefined()
 	})
@@ -225,6 +238,12 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (_ SessionDescripti
 	return *valueToSessionDescription(desc), nil
 }
 
+// RestartICE requests an ICE restart: the next offer will have new ICE
+// credentials and candidates
+func (pc *PeerConnection) RestartICE() {
+	pc.underlying.Call("restartIce")
+}
+
 // CreateAnswer starts the PeerConnection and generates the localDescription
 func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (_ SessionDescription, err error) {
 	defer func() {
@@ -340,10 +359,7 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 		}
 	}()
 	channel := pc.underlying.Call("createDataChannel", label, dataChannelInitToValue(options))
-	return &DataChannel{
-		underlying: channel,
-		api:        pc.api,
-	}, nil
+	return newDataChannel(channel, pc.api), nil
 }
 
 // SetIdentityProvider is used to configure an identity provider to generate identity assertions
@@ -386,6 +402,9 @@ func (pc *PeerConnection) Close() (err error) {
 	if pc.onICEGatheringStateChangeHandler != nil {
 		pc.onICEGatheringStateChangeHandler.Release()
 	}
+	if pc.onNegotiationNeededHandler != nil {
+		pc.onNegotiationNeededHandler.Release()
+	}
 
 	return nil
 }
@@ -577,19 +596,24 @@ func offerOptionsToValue(offerOptions *OfferOptions) js.Value {
 	if offerOptions == nil {
 		return js.Undefined()
 	}
-	return js.ValueOf(map[string]interface{}{
-		"iceRestart":             offerOptions.ICERestart,
-		"voiceActivityDetection": offerOptions.VoiceActivityDetection,
-	})
+	options := map[string]interface{}{
+		"iceRestart": offerOptions.ICERestart,
+	}
+	if offerOptions.DisableVoiceActivityDetection {
+		options["voiceActivityDetection"] = false
+	}
+	return js.ValueOf(options)
 }
 
 func answerOptionsToValue(answerOptions *AnswerOptions) js.Value {
 	if answerOptions == nil {
 		return js.Undefined()
 	}
-	return js.ValueOf(map[string]interface{}{
-		"voiceActivityDetection": answerOptions.VoiceActivityDetection,
-	})
+	options := map[string]interface{}{}
+	if answerOptions.DisableVoiceActivityDetection {
+		options["voiceActivityDetection"] = false
+	}
+	return js.ValueOf(options)
 }
 
 func iceCandidateInitToValue(candidate ICECandidateInit) js.Value {
@@ -606,6 +630,11 @@ func dataChannelInitToValue(options *DataChannelInit) js.Value {
 		return js.Undefined()
 	}
 
+	priority := js.Undefined()
+	if options.Priority != nil {
+		priority = js.ValueOf(options.Priority.String())
+	}
+
 	maxPacketLifeTime := uint16PointerToValue(options.MaxPacketLifeTime)
 	return js.ValueOf(map[string]interface{}{
 		"ordered":           boolPointerToValue(options.Ordered),
@@ -617,5 +646,6 @@ func dataChannelInitToValue(options *DataChannelInit) js.Value {
 		"protocol":          stringPointerToValue(options.Protocol),
 		"negotiated":        boolPointerToValue(options.Negotiated),
 		"id":                uint16PointerToValue(options.ID),
+		"priority":          priority,
 	})
 }
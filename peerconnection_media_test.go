@@ -5,9 +5,11 @@ package webrtc
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"math/rand"
+	"net/url"
 	"reflect"
 	"strings"
 	"sync"
@@ -15,6 +17,7 @@ import (
 	"time"
 
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/sdp/v2"
 	"github.com/pion/transport/test"
 	"github.com/pion/webrtc/v2/pkg/media"
@@ -541,6 +544,142 @@ func TestUndeclaredSSRC(t *testing.T) {
 	assert.NoError(t, pcAnswer.Close())
 }
 
+func TestPeerConnection_Media_OnTrackFirstPacket(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTrack(vp8Track)
+	assert.NoError(t, err)
+
+	signalTime := time.Now()
+	firstPacket := make(chan time.Duration)
+	pcAnswer.OnTrackFirstPacket(func(track *Track, stream *TrackRTPStream, latency time.Duration) {
+		assert.Equal(t, vp8Track.ID(), track.ID())
+		assert.Equal(t, vp8Track.SSRC(), stream.SSRC())
+		firstPacket <- latency
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	done, writerExited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(writerExited)
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 20):
+				assert.NoError(t, vp8Track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+			}
+		}
+	}()
+
+	latency := <-firstPacket
+	close(done)
+	<-writerExited
+	assert.True(t, latency > 0)
+	assert.True(t, latency <= time.Since(signalTime))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_Media_FreezeRecovery(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetFreezeRecoveryInterval(50 * time.Millisecond)
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+
+	sender, err := pcOffer.AddTrack(vp8Track)
+	assert.NoError(t, err)
+
+	nackReceived, pliReceived := make(chan struct{}), make(chan struct{})
+	go func() {
+		var nackOnce, pliOnce sync.Once
+		for {
+			packets, err := sender.ReadRTCP()
+			if err != nil {
+				return
+			}
+			for _, p := range packets {
+				switch p.(type) {
+				case *rtcp.TransportLayerNack:
+					nackOnce.Do(func() { close(nackReceived) })
+				case *rtcp.PictureLossIndication:
+					pliOnce.Do(func() { close(pliReceived) })
+				}
+			}
+		}
+	}()
+
+	onTrackFired, frozen := make(chan struct{}), make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		track.Streams()[0].OnFrozen(func() {
+			close(frozen)
+		})
+		close(onTrackFired)
+
+		// the freezes are detected reading the raw packets too
+		b := make([]byte, receiveMTU)
+		for {
+			if _, err := track.Read(b); err != nil {
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// send only delta frames, losing a packet after the track is received
+	var seq uint16
+	sentAfterOnTrack := 0
+	for sending := true; sending; {
+		select {
+		case <-frozen:
+			sending = false
+		case <-time.After(time.Millisecond * 20):
+			seq++
+			select {
+			case <-onTrackFired:
+				if sentAfterOnTrack++; sentAfterOnTrack == 5 {
+					seq++
+				}
+			default:
+			}
+			assert.NoError(t, vp8Track.WriteRTP(&rtp.Packet{
+				Header:  rtp.Header{Version: 2, SSRC: vp8Track.SSRC(), PayloadType: DefaultPayloadTypeVP8, SequenceNumber: seq},
+				Payload: []byte{0x10, 0x01},
+			}))
+		}
+	}
+
+	<-nackReceived
+	<-pliReceived
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
 func TestOfferRejectionMissingCodec(t *testing.T) {
 	lim := test.TimeOut(time.Second * 30)
 	defer lim.Stop()
@@ -592,6 +731,62 @@ func TestOfferRejectionMissingCodec(t *testing.T) {
 	assert.NoError(t, pc.Close())
 }
 
+func TestOfferRejectionUnmatchedCodec(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerMediaEngine := MediaEngine{}
+	offerMediaEngine.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	offerMediaEngine.RegisterCodec(NewRTPPCMUCodec(DefaultPayloadTypePCMU, 8000))
+	pcOffer, err := NewAPI(WithMediaEngine(offerMediaEngine)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerMediaEngine := MediaEngine{}
+	answerMediaEngine.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	answerMediaEngine.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	pcAnswer, err := NewAPI(WithMediaEngine(answerMediaEngine)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerErr := make(chan error, 1)
+	pcAnswer.OnTransceiverError(func(tr *RTPTransceiver, err error) {
+		assert.Equal(t, RTPCodecTypeAudio, tr.Kind())
+		answerErr <- err
+	})
+	offerErr := make(chan error, 1)
+	pcOffer.OnTransceiverError(func(tr *RTPTransceiver, err error) {
+		assert.Equal(t, RTPCodecTypeAudio, tr.Kind())
+		offerErr <- err
+	})
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	validationErr, ok := (<-answerErr).(*SDPValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, ErrNoMatchingCodecs, validationErr.Err)
+	assert.Equal(t, ErrMediaSectionRejected, <-offerErr)
+
+	for _, m := range pcOffer.remoteDescription().parsed.MediaDescriptions {
+		_, haveMid := m.Attribute(sdp.AttrKeyMID)
+		assert.True(t, haveMid)
+		switch m.MediaName.Media {
+		case "audio":
+			assert.Equal(t, 0, m.MediaName.Port.Value)
+		case "video":
+			assert.NotEqual(t, 0, m.MediaName.Port.Value)
+		}
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
 func TestAddTransceiverFromTrackSendOnly(t *testing.T) {
 	lim := test.TimeOut(time.Second * 30)
 	defer lim.Stop()
@@ -925,6 +1120,7 @@ a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:4
 a=setup:actpass
 a=mid:0
 a=sendrecv
+a=rtcp-mux
 a=rtpmap:111 opus/48000/2
 a=fmtp:111 minptime=10;useinbandfec=1
 m=video 9 UDP/TLS/RTP/SAVPF 96
@@ -937,6 +1133,7 @@ a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:4
 a=setup:actpass
 a=mid:1
 a=sendrecv
+a=rtcp-mux
 a=rtpmap:96 H264/90000
 a=fmtp:96 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=640c1f
 `
@@ -987,6 +1184,644 @@ a=fmtp:96 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=640c1f
 	assert.NoError(t, pc.Close())
 }
 
+func TestAnswerMediaSectionsOrder(t *testing.T) {
+	const sdpOffer = `v=0
+o=- 6476616870435111971 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:46:FD:A8:2A:F3:13:94:6E:E6:43:23:CB:D7
+a=group:BUNDLE video0 text audio0 video1 app
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=ice-ufrag:sRIG
+a=ice-pwd:yZb5ZMsBlPoK577sGhjvEUtT
+a=setup:actpass
+a=mid:video0
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:96 VP8/90000
+m=text 9 UDP/TLS/RTP/SAVPF 98
+c=IN IP4 0.0.0.0
+a=setup:actpass
+a=mid:text
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:98 t140/1000
+m=audio 9 UDP/TLS/RTP/SAVPF 111
+c=IN IP4 0.0.0.0
+a=setup:actpass
+a=mid:audio0
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:111 opus/48000/2
+m=video 9 UDP/TLS/RTP/SAVPF 100
+c=IN IP4 0.0.0.0
+a=setup:actpass
+a=mid:video1
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:100 H264/90000
+m=application 9 DTLS/SCTP 5000
+c=IN IP4 0.0.0.0
+a=setup:actpass
+a=mid:app
+a=sctpmap:5000 webrtc-datachannel 1024
+`
+
+	for _, mirror := range []bool{false, true} {
+		mediaEngine := MediaEngine{}
+		mediaEngine.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+		s := SettingEngine{}
+		s.SetMirrorRejectedMediaSections(mirror)
+
+		pc, err := NewAPI(WithMediaEngine(mediaEngine), WithSettingEngine(s)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: sdpOffer}))
+		answer, err := pc.CreateAnswer(nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		offer := pc.remoteDescription().parsed
+		assert.Equal(t, len(offer.MediaDescriptions), len(answer.parsed.MediaDescriptions))
+		for i, m := range answer.parsed.MediaDescriptions {
+			offered := offer.MediaDescriptions[i]
+			assert.Equal(t, getMidValue(offered), getMidValue(m))
+
+			switch mid := getMidValue(m); mid {
+			case "video0", "app":
+				assert.Equal(t, offered.MediaName.Media, m.MediaName.Media)
+				assert.NotEqual(t, 0, m.MediaName.Port.Value, mid)
+			case "text":
+				// unsupported media types are always kept
+				assert.Equal(t, 0, m.MediaName.Port.Value)
+				assert.Equal(t, offered.MediaName.Media, m.MediaName.Media)
+				assert.Equal(t, offered.MediaName.Formats, m.MediaName.Formats)
+			default:
+				assert.Equal(t, 0, m.MediaName.Port.Value, mid)
+				assert.Equal(t, offered.MediaName.Media, m.MediaName.Media)
+				if mirror {
+					assert.Equal(t, offered.MediaName.Formats, m.MediaName.Formats)
+				} else {
+					assert.Equal(t, []string{"0"}, m.MediaName.Formats)
+				}
+			}
+		}
+
+		bundle, _ := answer.parsed.Attribute(sdp.AttrKeyGroup)
+		assert.Equal(t, "BUNDLE video0 app", bundle)
+
+		assert.NoError(t, pc.Close())
+	}
+}
+
+func TestPeerConnection_Media_MaxBundle(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, err := api.NewPeerConnection(Configuration{BundlePolicy: BundlePolicyMaxBundle})
+	assert.NoError(t, err)
+	pcAnswer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	onTrackFired, onTrackFiredFunc := context.WithCancel(context.Background())
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		onTrackFiredFunc()
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	offer := pcAnswer.remoteDescription().parsed
+	for i, m := range offer.MediaDescriptions {
+		_, bundleOnly := m.Attribute(attributeBundleOnly)
+		assert.Equal(t, i > 0, bundleOnly)
+		assert.Equal(t, i > 0, m.MediaName.Port.Value == 0)
+	}
+	for _, m := range pcOffer.remoteDescription().parsed.MediaDescriptions {
+		assert.NotEqual(t, 0, m.MediaName.Port.Value)
+	}
+
+	// the media of a bundle-only media section is received
+	sendVideoUntilDone(onTrackFired.Done(), t, []*Track{track})
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestAnswerNoBundle(t *testing.T) {
+	const sdpOffer = `v=0
+o=- 6476616870435111971 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:46:FD:A8:2A:F3:13:94:6E:E6:43:23:CB:D7
+m=audio 9 UDP/TLS/RTP/SAVPF 111
+c=IN IP4 0.0.0.0
+a=ice-ufrag:sRIG
+a=ice-pwd:yZb5ZMsBlPoK577sGhjvEUtT
+a=setup:actpass
+a=mid:0
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:111 opus/48000/2
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=ice-ufrag:xBsu
+a=ice-pwd:qD3jYt6zSFQE7ihFfPtBuHpZ
+a=setup:actpass
+a=mid:1
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:96 VP8/90000
+`
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	answer := func(offer string) *sdp.SessionDescription {
+		pc, err := api.NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		transceiverErr := make(chan error, 1)
+		pc.OnTransceiverError(func(_ *RTPTransceiver, err error) {
+			transceiverErr <- err
+		})
+
+		// the unbundled media section is rejected with an error instead of
+		// being silently dropped
+		assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: offer}))
+		assert.Equal(t, &SDPValidationError{MediaIndex: 1, Mid: "1", Attribute: sdp.AttrKeyGroup, Err: ErrUnbundledMediaSection}, <-transceiverErr)
+		answer, err := pc.CreateAnswer(nil)
+		assert.NoError(t, err)
+		assert.NoError(t, pc.Close())
+		return answer.parsed
+	}
+
+	// a single transport is available for the first media section
+	d := answer(sdpOffer)
+	assert.Equal(t, 2, len(d.MediaDescriptions))
+	assert.NotEqual(t, 0, d.MediaDescriptions[0].MediaName.Port.Value)
+	assert.Equal(t, 0, d.MediaDescriptions[1].MediaName.Port.Value)
+	assert.Equal(t, "1", getMidValue(d.MediaDescriptions[1]))
+	_, haveGroup := d.Attribute(sdp.AttrKeyGroup)
+	assert.False(t, haveGroup)
+
+	// only the media sections of the BUNDLE group share the transport
+	d = answer(strings.Replace(sdpOffer, "m=audio", "a=group:BUNDLE 0\nm=audio", 1))
+	assert.NotEqual(t, 0, d.MediaDescriptions[0].MediaName.Port.Value)
+	assert.Equal(t, 0, d.MediaDescriptions[1].MediaName.Port.Value)
+	group, _ := d.Attribute(sdp.AttrKeyGroup)
+	assert.Equal(t, "BUNDLE 0", group)
+}
+
+func TestAnswerExtMaps(t *testing.T) {
+	const sdpOffer = `v=0
+o=- 6476616870435111971 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:46:FD:A8:2A:F3:13:94:6E:E6:43:23:CB:D7
+a=group:BUNDLE 0
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=ice-ufrag:sRIG
+a=ice-pwd:yZb5ZMsBlPoK577sGhjvEUtT
+a=setup:actpass
+a=mid:0
+a=sendrecv
+a=rtcp-mux
+a=extmap:3 urn:ietf:params:rtp-hdrext:sdes:mid
+a=extmap:5 urn:example:unsupported
+%sa=rtpmap:96 VP8/90000
+`
+	const toffset = "a=extmap:9 urn:ietf:params:rtp-hdrext:toffset\n"
+
+	newURL := func(raw string) *url.URL {
+		u, err := url.Parse(raw)
+		assert.NoError(t, err)
+		return u
+	}
+
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pc.OnMediaNegotiation(func(*RTPTransceiver, bool) *NegotiationData {
+		return &NegotiationData{SupportedExtMaps: []SupportedExtMap{
+			{URI: newURL("urn:ietf:params:rtp-hdrext:toffset")},
+			{URI: newURL(sdesMidURI)},
+		}}
+	})
+
+	// the supported extmaps are answered with the offered values
+	assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: fmt.Sprintf(sdpOffer, "")}))
+	answer, err := pc.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, answer.SDP, "a=extmap:3 "+sdesMidURI+"\r\n")
+	assert.NotContains(t, answer.SDP, "urn:example:unsupported")
+	assert.NotContains(t, answer.SDP, "toffset")
+	assert.NoError(t, pc.SetLocalDescription(answer))
+
+	// the extmaps offered while renegotiating are answered too
+	assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: fmt.Sprintf(sdpOffer, toffset)}))
+	answer, err = pc.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, answer.SDP, "a=extmap:3 "+sdesMidURI+"\r\n")
+	assert.Contains(t, answer.SDP, "a=extmap:9 urn:ietf:params:rtp-hdrext:toffset\r\n")
+	assert.NoError(t, pc.SetLocalDescription(answer))
+	assert.NoError(t, pc.Close())
+
+	// an extmap value can't be used for different uris
+	pc, err = NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	err = pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: fmt.Sprintf(sdpOffer, "a=extmap:3 urn:ietf:params:rtp-hdrext:toffset\n")})
+	assert.Error(t, err)
+	assert.NoError(t, pc.Close())
+}
+
+func TestRecycledMediaSection(t *testing.T) {
+	const sdpOffer = `v=0
+o=- 6476616870435111971 %d IN IP4 127.0.0.1
+s=-
+t=0 0
+a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:46:FD:A8:2A:F3:13:94:6E:E6:43:23:CB:D7
+m=video %d UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=ice-ufrag:sRIG
+a=ice-pwd:yZb5ZMsBlPoK577sGhjvEUtT
+a=setup:actpass
+a=mid:%s
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:96 VP8/90000
+`
+
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	negotiate := func(version, port int, mid string) {
+		assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: fmt.Sprintf(sdpOffer, version, port, mid)}))
+		answer, err := pc.CreateAnswer(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(answer.SDP, "m=video"))
+		assert.Contains(t, answer.SDP, "a=mid:"+mid+"\r\n")
+		assert.NoError(t, pc.SetLocalDescription(answer))
+	}
+
+	negotiate(1, 9, "v1")
+	transceivers := pc.GetTransceivers()
+	assert.Equal(t, 1, len(transceivers))
+
+	// the remote stops the media section and then reuses its m-line
+	negotiate(2, 0, "v1")
+	assert.True(t, transceivers[0].isRejected())
+	negotiate(3, 9, "v2")
+	assert.Equal(t, transceivers, pc.GetTransceivers())
+	assert.Equal(t, "v2", transceivers[0].Mid())
+	assert.False(t, transceivers[0].isRejected())
+	assert.Nil(t, transceivers[0].recycled)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(offer.SDP, "m=video"))
+
+	// a rollback restores the stopped media section
+	negotiate(4, 0, "v2")
+	receiver := transceivers[0].Receiver()
+	close(receiver.received)
+	assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: fmt.Sprintf(sdpOffer, 5, 9, "v3")}))
+	assert.Equal(t, "v3", transceivers[0].Mid())
+	assert.NotEqual(t, receiver, transceivers[0].Receiver())
+	assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeRollback}))
+	assert.Equal(t, "v2", transceivers[0].Mid())
+	assert.True(t, transceivers[0].isRejected())
+	assert.Equal(t, receiver, transceivers[0].Receiver())
+	assert.Nil(t, transceivers[0].recycled)
+	assert.Equal(t, transceivers, pc.GetTransceivers())
+
+	// the replaced receiver is stopped once the answer is applied
+	negotiate(6, 9, "v3")
+	assert.NotEqual(t, receiver, transceivers[0].Receiver())
+	select {
+	case <-receiver.closed:
+	default:
+		t.Error("the replaced receiver has not been stopped")
+	}
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestSimulcastAnswer(t *testing.T) {
+	const sdpOffer = `v=0
+o=- 6476616870435111971 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:46:FD:A8:2A:F3:13:94:6E:E6:43:23:CB:D7
+a=group:BUNDLE 0
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=ice-ufrag:sRIG
+a=ice-pwd:yZb5ZMsBlPoK577sGhjvEUtT
+a=setup:actpass
+a=mid:0
+a=sendonly
+a=rtcp-mux
+a=extmap:3 urn:ietf:params:rtp-hdrext:sdes:mid
+a=extmap:4 urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id
+a=rtpmap:96 VP8/90000
+a=rid:hi send max-width=1280;max-height=720
+a=rid:lo send pt=96;max-width=320
+a=simulcast:send hi;~lo
+`
+
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pc.OnMediaNegotiation(func(*RTPTransceiver, bool) *NegotiationData {
+		u1, _ := url.Parse(sdesMidURI)
+		u2, _ := url.Parse(sdesRTPStreamIDURI)
+		return &NegotiationData{SupportedExtMaps: []SupportedExtMap{{URI: u1}, {URI: u2}}}
+	})
+
+	assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: sdpOffer}))
+	answer, err := pc.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, answer.SDP, "a=rid:hi recv\r\na=rid:lo recv\r\na=simulcast:recv hi;~lo\r\n")
+	assert.NoError(t, pc.Close())
+}
+
+func TestExtMapAllowMixed(t *testing.T) {
+	const sdpOffer = `v=0
+o=- 6476616870435111971 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:46:FD:A8:2A:F3:13:94:6E:E6:43:23:CB:D7
+a=group:BUNDLE 0
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=ice-ufrag:sRIG
+a=ice-pwd:yZb5ZMsBlPoK577sGhjvEUtT
+a=setup:actpass
+a=mid:0
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:96 VP8/90000
+`
+
+	for _, test := range []struct {
+		name               string
+		old, new           string
+		allowMixed         bool
+		sessionMixed       bool
+		mediaMixedInAnswer bool
+	}{
+		{name: "none"},
+		{name: "session level", old: "a=group:", new: "a=extmap-allow-mixed\na=group:", allowMixed: true, sessionMixed: true},
+		{name: "media level", old: "a=rtcp-mux\n", new: "a=rtcp-mux\na=extmap-allow-mixed\n", allowMixed: true, mediaMixedInAnswer: true},
+	} {
+		pc, err := NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: strings.Replace(sdpOffer, test.old, test.new, 1)}))
+		answer, err := pc.CreateAnswer(nil)
+		assert.NoError(t, err)
+
+		sessionLevel := strings.Split(answer.SDP, "m=video")[0]
+		assert.Equal(t, test.sessionMixed, strings.Contains(sessionLevel, "a=extmap-allow-mixed"), test.name)
+		assert.Equal(t, test.mediaMixedInAnswer, strings.Contains(strings.TrimPrefix(answer.SDP, sessionLevel), "a=extmap-allow-mixed"), test.name)
+		assert.Equal(t, test.allowMixed, pc.GetTransceivers()[0].ExtMapAllowMixed(), test.name)
+
+		// the offers always allow it at session level
+		assert.NoError(t, pc.SetLocalDescription(answer))
+		offer, err := pc.CreateOffer(nil)
+		assert.NoError(t, err)
+		assert.Contains(t, strings.Split(offer.SDP, "m=video")[0], "a=extmap-allow-mixed\r\n", test.name)
+
+		assert.NoError(t, pc.Close())
+	}
+}
+
+func TestAnswerDirection(t *testing.T) {
+	const sdpOffer = `v=0
+o=- 6476616870435111971 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:46:FD:A8:2A:F3:13:94:6E:E6:43:23:CB:D7
+a=group:BUNDLE 0
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=ice-ufrag:sRIG
+a=ice-pwd:yZb5ZMsBlPoK577sGhjvEUtT
+a=setup:actpass
+a=mid:0
+a=%s
+a=rtcp-mux
+a=rtpmap:96 VP8/90000
+`
+
+	for _, test := range []struct {
+		offered, answered RTPTransceiverDirection
+		// localDirection adds a local transceiver when set
+		localDirection RTPTransceiverDirection
+	}{
+		{offered: RTPTransceiverDirectionSendrecv, answered: RTPTransceiverDirectionRecvonly},
+		{offered: RTPTransceiverDirectionSendrecv, localDirection: RTPTransceiverDirectionSendrecv, answered: RTPTransceiverDirectionSendrecv},
+		{offered: RTPTransceiverDirectionSendrecv, localDirection: RTPTransceiverDirectionRecvonly, answered: RTPTransceiverDirectionRecvonly},
+		{offered: RTPTransceiverDirectionSendonly, answered: RTPTransceiverDirectionRecvonly},
+		{offered: RTPTransceiverDirectionRecvonly, answered: RTPTransceiverDirectionInactive},
+		{offered: RTPTransceiverDirectionRecvonly, localDirection: RTPTransceiverDirectionSendrecv, answered: RTPTransceiverDirectionSendonly},
+		{offered: RTPTransceiverDirectionInactive, localDirection: RTPTransceiverDirectionSendrecv, answered: RTPTransceiverDirectionInactive},
+	} {
+		pc, err := NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		if test.localDirection != RTPTransceiverDirection(Unknown) {
+			_, err = pc.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: test.localDirection})
+			assert.NoError(t, err)
+		}
+
+		assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: fmt.Sprintf(sdpOffer, test.offered)}))
+		answer, err := pc.CreateAnswer(nil)
+		assert.NoError(t, err)
+		assert.Contains(t, answer.SDP, "a="+test.answered.String()+"\r\n", "offered %s, local %s", test.offered, test.localDirection)
+		assert.NoError(t, pc.Close())
+	}
+}
+
+func TestNonMuxedRTCP(t *testing.T) {
+	const sdpOffer = `v=0
+o=- 6476616870435111971 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:46:FD:A8:2A:F3:13:94:6E:E6:43:23:CB:D7
+a=group:BUNDLE 0 1
+m=audio 9 UDP/TLS/RTP/SAVPF 111
+c=IN IP4 0.0.0.0
+a=rtcp:9 IN IP4 0.0.0.0
+a=ice-ufrag:sRIG
+a=ice-pwd:yZb5ZMsBlPoK577sGhjvEUtT
+a=setup:actpass
+a=mid:0
+a=sendrecv
+a=rtpmap:111 opus/48000/2
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=ice-ufrag:sRIG
+a=ice-pwd:yZb5ZMsBlPoK577sGhjvEUtT
+a=setup:actpass
+a=mid:1
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:96 VP8/90000
+`
+
+	// with every policy only the media section multiplexing RTCP is
+	// accepted, the second one is bundled with the first one that doesn't
+	for _, policy := range []RTCPMuxPolicy{RTCPMuxPolicyRequire, RTCPMuxPolicyNegotiate} {
+		pc, err := NewPeerConnection(Configuration{RTCPMuxPolicy: policy})
+		assert.NoError(t, err)
+
+		transceiverErr := make(chan error, 1)
+		pc.OnTransceiverError(func(_ *RTPTransceiver, err error) {
+			transceiverErr <- err
+		})
+
+		assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: sdpOffer}))
+		assert.Equal(t, &SDPValidationError{MediaIndex: 0, Mid: "0", Attribute: "rtcp-mux", Err: ErrNonMuxedRTCP}, <-transceiverErr)
+		answer, err := pc.CreateAnswer(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, answer.parsed.MediaDescriptions[0].MediaName.Port.Value)
+		assert.NotEqual(t, 0, answer.parsed.MediaDescriptions[1].MediaName.Port.Value)
+		assert.NoError(t, pc.Close())
+	}
+
+	negotiate := func(policy RTCPMuxPolicy, removeRTCPMux func(answer string) string) error {
+		pcOffer, err := NewPeerConnection(Configuration{RTCPMuxPolicy: policy})
+		assert.NoError(t, err)
+		pcAnswer, err := NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		defer func() {
+			assert.NoError(t, pcOffer.Close())
+			assert.NoError(t, pcAnswer.Close())
+		}()
+		_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio)
+		assert.NoError(t, err)
+		_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo)
+		assert.NoError(t, err)
+
+		offer, err := pcOffer.CreateOffer(nil)
+		assert.NoError(t, err)
+		assert.NoError(t, pcOffer.SetLocalDescription(offer))
+		assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+		answer, err := pcAnswer.CreateAnswer(nil)
+		assert.NoError(t, err)
+
+		answer.SDP = removeRTCPMux(answer.SDP)
+		err = pcOffer.SetRemoteDescription(answer)
+		if err != nil {
+			assert.Equal(t, SignalingStateHaveLocalOffer, pcOffer.SignalingState())
+		}
+		return err
+	}
+	removeAll := func(answer string) string {
+		return strings.Replace(answer, "a=rtcp-mux\r\n", "", -1)
+	}
+	// RFC 8843 answers carry rtcp-mux only in the tagged media section
+	removeUntagged := func(answer string) string {
+		sections := strings.SplitAfterN(answer, "m=", 3)
+		sections[2] = removeAll(sections[2])
+		return strings.Join(sections, "")
+	}
+
+	// an answer can't ask for a separate RTCP transport
+	for _, policy := range []RTCPMuxPolicy{RTCPMuxPolicyRequire, RTCPMuxPolicyNegotiate} {
+		assert.Equal(t, &SDPValidationError{MediaIndex: 0, Mid: "0", Attribute: "rtcp-mux", Err: ErrNonMuxedRTCP}, negotiate(policy, removeAll))
+	}
+	assert.NoError(t, negotiate(RTCPMuxPolicyRequire, removeUntagged))
+}
+
+func TestPeerConnection_Media_Generators(t *testing.T) {
+	newPeerConnection := func(mids []string) *PeerConnection {
+		s := SettingEngine{}
+		s.SetMidGenerator(func() string {
+			mid := mids[0]
+			mids = mids[1:]
+			return mid
+		})
+		s.SetCNAMEGenerator(func(track *Track) string {
+			return "tenant-1/" + track.Kind().String()
+		})
+		s.SetTrackIDGenerator(func() string {
+			return "tenant-1.track"
+		})
+		m := MediaEngine{}
+		m.RegisterDefaultCodecs()
+
+		pc, err := NewAPI(WithSettingEngine(s), WithMediaEngine(m)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		return pc
+	}
+
+	pc := newPeerConnection([]string{"tenant-1.video", "tenant-1.data"})
+	transceiver, err := pc.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant-1.track", transceiver.Sender().Track().ID())
+	_, err = pc.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant-1.video", getMidValue(offer.parsed.MediaDescriptions[0]))
+	assert.Equal(t, "tenant-1.data", getMidValue(offer.parsed.MediaDescriptions[1]))
+	assert.Contains(t, offer.SDP, "cname:tenant-1/video")
+	assert.Contains(t, offer.SDP, "msid:"+transceiver.Sender().Track().Label()+" tenant-1.track")
+
+	// the data media section mid is kept in the following offers
+	offer, err = pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant-1.data", getMidValue(offer.parsed.MediaDescriptions[1]))
+	assert.NoError(t, pc.Close())
+
+	for _, mids := range [][]string{{"a b"}, {""}, {"a-mid-longer-than-16"}, {"0", "0"}} {
+		pc = newPeerConnection(mids)
+		for range mids {
+			_, err = pc.AddTransceiverFromKind(RTPCodecTypeAudio)
+			assert.NoError(t, err)
+		}
+		_, err = pc.CreateOffer(nil)
+		assert.Equal(t, ErrInvalidMid, err, "%v", mids)
+		assert.NoError(t, pc.Close())
+	}
+}
+
+func TestIsSDPToken(t *testing.T) {
+	for _, test := range []struct {
+		s     string
+		token bool
+	}{
+		{"tenant-1.track_0", true},
+		{"!#$%&'*+-.^_`{|~", true},
+		{"", false},
+		{"with space", false},
+		{"quote\"", false},
+		{"a/b", false},
+		{"0123456789abcdefg", false},
+	} {
+		assert.Equal(t, test.token, isSDPToken(test.s, maxMidLength), test.s)
+	}
+
+	assert.True(t, isSDPValue("tenant-1/+Zm9v", maxCNAMELength))
+	assert.False(t, isSDPValue("tenant 1", maxCNAMELength))
+	assert.False(t, isSDPValue("tenant-1\r\n", maxCNAMELength))
+	assert.False(t, isSDPValue("", maxCNAMELength))
+}
+
 func TestGetRegisteredRTPCodecs(t *testing.T) {
 	mediaEngine := MediaEngine{}
 	expectedCodec := NewRTPH264Codec(DefaultPayloadTypeH264, 90000)
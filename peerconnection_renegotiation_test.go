@@ -306,6 +306,9 @@ func TestPeerConnection_Transceiver_Mid(t *testing.T) {
 
 	assert.True(t, sdpMidHasSsrc(offer, "1", track2.SSRC()), "Expected mid %q with ssrc %d, offer.SDP: %s", "1", track2.SSRC(), offer.SDP)
 
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
 	answer, err = pcAnswer.CreateAnswer(nil)
 	assert.NoError(t, err)
 	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
@@ -479,6 +482,405 @@ func TestPeerConnection_Renegotiation_RemoveTrack(t *testing.T) {
 	assert.NoError(t, pcAnswer.Close())
 }
 
+func TestPeerConnection_Renegotiation_RemoveTrack_Reuse(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "foo", "bar")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// the removed track media section is offered as recvonly, the remote
+	// answers it as inactive since it doesn't send either
+	assert.NoError(t, pcOffer.RemoveTrack(sender))
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(offer.SDP, "m=video"))
+	assert.Contains(t, offer.SDP, "a=recvonly")
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	answer.SDP = strings.Replace(answer.SDP, "a=recvonly", "a=inactive", 1)
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+	// a new track reuses the transceiver and its media section
+	transceivers := pcOffer.GetTransceivers()
+	newTrack, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "foo2", "bar2")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(newTrack)
+	assert.NoError(t, err)
+	assert.Equal(t, transceivers, pcOffer.GetTransceivers())
+	assert.Equal(t, RTPTransceiverDirectionSendrecv, transceivers[0].Direction())
+
+	offer, err = pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(offer.SDP, "m=video"))
+	assert.True(t, sdpMidHasSsrc(offer, transceivers[0].Mid(), newTrack.SSRC()))
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// Assert that tracks can be added and removed by both peers throughout the
+// call: the m-lines keep their order and every media section has a single
+// transceiver on each side
+func TestPeerConnection_Renegotiation_AddRemoveTracks(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcA, pcB, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	received := map[*PeerConnection]chan string{
+		pcA: make(chan string, 10),
+		pcB: make(chan string, 10),
+	}
+	for _, pc := range []*PeerConnection{pcA, pcB} {
+		ch := received[pc]
+		pc.OnTrack(func(track *Track, r *RTPReceiver) {
+			ch <- track.Label()
+		})
+	}
+
+	tracks := map[*PeerConnection]map[*RTPSender]*Track{pcA: {}, pcB: {}}
+	addTrack := func(pc *PeerConnection, label string) {
+		track, err := pc.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), label, label)
+		assert.NoError(t, err)
+		sender, err := pc.AddTrack(track)
+		assert.NoError(t, err)
+		tracks[pc][sender] = track
+	}
+	removeTrack := func(pc *PeerConnection, label string) {
+		for sender, track := range tracks[pc] {
+			if track.Label() == label {
+				assert.NoError(t, pc.RemoveTrack(sender))
+				delete(tracks[pc], sender)
+			}
+		}
+	}
+
+	mids := []string{}
+	negotiate := func(offerer, answerer *PeerConnection, expected map[*PeerConnection][]string) {
+		assert.NoError(t, signalPair(offerer, answerer))
+
+		// the previous media sections keep their position
+		current := []string{}
+		for _, media := range offerer.CurrentLocalDescription().parsed.MediaDescriptions {
+			current = append(current, getMidValue(media))
+		}
+		assert.True(t, len(current) >= len(mids))
+		assert.Equal(t, mids, current[:len(mids)])
+		mids = current
+
+		for _, pc := range []*PeerConnection{pcA, pcB} {
+			seen := map[string]bool{}
+			for _, transceiver := range pc.GetTransceivers() {
+				mid := transceiver.Mid()
+				if mid == "" {
+					// the answerer tracks are offered by its next offer
+					assert.Equal(t, answerer, pc)
+					continue
+				}
+				assert.False(t, seen[mid], "duplicated transceiver for mid %s", mid)
+				seen[mid] = true
+			}
+			// all the media sections but the application one
+			assert.Equal(t, len(mids)-1, len(seen))
+		}
+
+		// the added tracks are received
+		for pc, labels := range expected {
+			sending := []*Track{}
+			for _, remote := range []*PeerConnection{pcA, pcB} {
+				if remote != pc {
+					for _, track := range tracks[remote] {
+						sending = append(sending, track)
+					}
+				}
+			}
+			for _, label := range labels {
+				done := make(chan struct{})
+				go func() {
+					for l := range received[pc] {
+						if l == label {
+							break
+						}
+					}
+					close(done)
+				}()
+				sendVideoUntilDone(done, t, sending)
+			}
+		}
+	}
+
+	addTrack(pcA, "a1")
+	addTrack(pcB, "b1")
+	negotiate(pcA, pcB, map[*PeerConnection][]string{pcA: {"b1"}, pcB: {"a1"}})
+
+	// the answerer adds a track, and removes it once offered
+	addTrack(pcB, "b2")
+	negotiate(pcA, pcB, nil)
+	negotiate(pcB, pcA, map[*PeerConnection][]string{pcA: {"b2"}})
+	removeTrack(pcB, "b2")
+	negotiate(pcB, pcA, nil)
+
+	// the tracks replacing the removed ones reuse their media sections
+	sections := len(mids)
+	removeTrack(pcA, "a1")
+	addTrack(pcA, "a2")
+	addTrack(pcB, "b3")
+	negotiate(pcA, pcB, map[*PeerConnection][]string{pcA: {"b3"}, pcB: {"a2"}})
+	assert.Equal(t, sections, len(mids))
+
+	assert.NoError(t, pcA.Close())
+	assert.NoError(t, pcB.Close())
+}
+
+// Assert that a subsequent offer changes only the updated media sections
+func TestPeerConnection_Renegotiation_IncrementalOffer(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// the origin and the media sections without the candidates
+	splitDescription := func(desc string) (string, []string) {
+		lines := []string{}
+		for _, line := range strings.Split(desc, "\r\n") {
+			if !strings.HasPrefix(line, "a=candidate:") && line != "a=end-of-candidates" {
+				lines = append(lines, line)
+			}
+		}
+		sections := strings.Split(strings.Join(lines, "\r\n"), "\r\nm=")
+		origin := strings.Split(sections[0], "\r\n")[1]
+		return origin, sections[1:]
+	}
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	senders := []*RTPSender{}
+	for i := 0; i < 3; i++ {
+		track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), util.RandSeq(16), util.RandSeq(16))
+		assert.NoError(t, err)
+		sender, err := pcOffer.AddTrack(track)
+		assert.NoError(t, err)
+		senders = append(senders, sender)
+	}
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	origin, sections := splitDescription(pcOffer.CurrentLocalDescription().SDP)
+	assert.Equal(t, 4, len(sections))
+
+	// nothing changed
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	offerOrigin, offerSections := splitDescription(offer.SDP)
+	assert.Equal(t, origin, offerOrigin)
+	assert.Equal(t, sections, offerSections)
+
+	assert.NoError(t, pcOffer.RemoveTrack(senders[1]))
+	offer, err = pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	offerOrigin, offerSections = splitDescription(offer.SDP)
+
+	// same session id and incremented version
+	originFields, offerOriginFields := strings.Fields(origin), strings.Fields(offerOrigin)
+	assert.Equal(t, originFields[1], offerOriginFields[1])
+	version, err := strconv.ParseUint(originFields[2], 10, 64)
+	assert.NoError(t, err)
+	assert.Equal(t, strconv.FormatUint(version+1, 10), offerOriginFields[2])
+
+	assert.Equal(t, 4, len(offerSections))
+	assert.Equal(t, sections[0], offerSections[0])
+	assert.NotEqual(t, sections[1], offerSections[1])
+	assert.Equal(t, sections[2], offerSections[2])
+	assert.Equal(t, sections[3], offerSections[3])
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_Renegotiation_MediaStreams(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "foo", "bar")
+	assert.NoError(t, err)
+
+	rtpSender, err := pcOffer.AddTrack(vp8Track)
+	assert.NoError(t, err)
+
+	streamAdded, streamAddedFunc := context.WithCancel(context.Background())
+	streamRemoved, streamRemovedFunc := context.WithCancel(context.Background())
+
+	pcAnswer.OnStreamAdded(func(s *MediaStream) {
+		assert.Equal(t, "bar", s.ID())
+		assert.Equal(t, 1, len(s.GetTracks()))
+		streamAddedFunc()
+	})
+	pcAnswer.OnStreamRemoved(func(s *MediaStream) {
+		assert.Equal(t, "bar", s.ID())
+		assert.Equal(t, 0, len(s.GetTracks()))
+		streamRemovedFunc()
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(streamAdded.Done(), t, []*Track{vp8Track})
+	assert.NotNil(t, pcAnswer.GetRemoteStream("bar"))
+
+	assert.NoError(t, pcOffer.RemoveTrack(rtpSender))
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	<-streamRemoved.Done()
+	assert.Nil(t, pcAnswer.GetRemoteStream("bar"))
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_Renegotiation_NegotiationNeeded(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	negotiationNeeded := make(chan struct{}, 10)
+	pcOffer.OnNegotiationNeeded(func() {
+		negotiationNeeded <- struct{}{}
+	})
+
+	assertNegotiationNeeded := func(expected bool) {
+		select {
+		case <-negotiationNeeded:
+			assert.True(t, expected, "unexpected negotiation needed event")
+		case <-time.After(500 * time.Millisecond):
+			assert.False(t, expected, "negotiation needed event not fired")
+		}
+	}
+
+	vp8Track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "foo", "bar")
+	assert.NoError(t, err)
+
+	rtpSender, err := pcOffer.AddTrack(vp8Track)
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+
+	// multiple changes in the same task fire a single event
+	assertNegotiationNeeded(true)
+	assertNegotiationNeeded(false)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assertNegotiationNeeded(false)
+
+	assert.NoError(t, pcOffer.RemoveTrack(rtpSender))
+	assertNegotiationNeeded(true)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assertNegotiationNeeded(false)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_Renegotiation_NegotiationNeeded_Answerer(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	negotiationNeeded := make(chan struct{}, 10)
+	pcAnswer.OnNegotiationNeeded(func() {
+		negotiationNeeded <- struct{}{}
+	})
+
+	assertNegotiationNeeded := func(expected bool) {
+		select {
+		case <-negotiationNeeded:
+			assert.True(t, expected, "unexpected negotiation needed event")
+		case <-time.After(500 * time.Millisecond):
+			assert.False(t, expected, "negotiation needed event not fired")
+		}
+	}
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	vp8Track, err := pcAnswer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "foo", "bar")
+	assert.NoError(t, err)
+	rtpSender, err := pcAnswer.AddTrack(vp8Track)
+	assert.NoError(t, err)
+	assertNegotiationNeeded(true)
+
+	// the sendrecv transceiver answers sendonly to a recvonly offer, that's
+	// the intersection with the offered direction
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assertNegotiationNeeded(false)
+
+	// without a track the answer would be inactive
+	assert.NoError(t, pcAnswer.RemoveTrack(rtpSender))
+	assertNegotiationNeeded(true)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assertNegotiationNeeded(false)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
 func TestPeerConnection_RoleSwitch(t *testing.T) {
 	api := NewAPI()
 	lim := test.TimeOut(time.Second * 30)
@@ -514,10 +916,255 @@ func TestPeerConnection_RoleSwitch(t *testing.T) {
 	assert.NoError(t, signalPair(pcSecondOfferer, pcFirstOfferer))
 	sendVideoUntilDone(onTrackFired.Done(), t, []*Track{vp8Track})
 
+	// the answer of the first offerer must keep its DTLS client role
+	assert.Equal(t, DTLSRoleClient, negotiatedDTLSRole(pcFirstOfferer.currentLocalDescription, pcFirstOfferer.currentRemoteDescription))
+	assert.Equal(t, DTLSRoleServer, negotiatedDTLSRole(pcSecondOfferer.currentLocalDescription, pcSecondOfferer.currentRemoteDescription))
+
 	assert.NoError(t, pcFirstOfferer.Close())
 	assert.NoError(t, pcSecondOfferer.Close())
 }
 
+// Assert that an ICE restart generates new credentials keeping the connection
+func TestPeerConnection_Renegotiation_ICERestart(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := make(chan string, 1)
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			messages <- string(msg.Data)
+		})
+	})
+
+	negotiationNeeded := make(chan struct{}, 1)
+	pcOffer.OnNegotiationNeeded(func() {
+		select {
+		case negotiationNeeded <- struct{}{}:
+		default:
+		}
+	})
+
+	var selectedPair atomic.Value
+	selectedPairChanged := make(chan struct{}, 1)
+	pcOffer.iceTransport.OnSelectedCandidatePairChange(func(pair *ICECandidatePair) {
+		selectedPair.Store(pair)
+		select {
+		case selectedPairChanged <- struct{}{}:
+		default:
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// the data channel created by signalPair
+	pcOffer.sctpTransport.lock.RLock()
+	dc := pcOffer.sctpTransport.dataChannels[0]
+	pcOffer.sctpTransport.lock.RUnlock()
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(opened)
+	})
+	<-opened
+
+	ufrag := func(desc *SessionDescription) string {
+		ufrag, _, _, err := extractICEDetails(desc.parsed)
+		assert.NoError(t, err)
+		return ufrag
+	}
+
+	restart := func(options *OfferOptions) {
+		offerUfrag := ufrag(pcOffer.currentLocalDescription)
+		answerUfrag := ufrag(pcAnswer.currentLocalDescription)
+		previousPair := selectedPair.Load().(*ICECandidatePair)
+
+		offer, err := pcOffer.CreateOffer(options)
+		assert.NoError(t, err)
+		assert.NotEqual(t, offerUfrag, ufrag(&offer))
+		assert.NoError(t, pcOffer.SetLocalDescription(offer))
+		assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+		answer, err := pcAnswer.CreateAnswer(nil)
+		assert.NoError(t, err)
+		assert.NotEqual(t, answerUfrag, ufrag(&answer))
+		assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+		assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+		<-pcOffer.ops.Done()
+		<-pcAnswer.ops.Done()
+		assert.False(t, pcOffer.iceGatherer.isRestarting())
+		assert.False(t, pcAnswer.iceGatherer.isRestarting())
+		assert.Equal(t, ICEConnectionStateConnected, pcOffer.ICEConnectionState())
+
+		// the data channel keeps working on the new connection
+		assert.NoError(t, dc.SendText(ufrag(&offer)))
+		assert.Equal(t, ufrag(&offer), <-messages)
+
+		// the pair of the new agent, using new local ports, is notified
+		for selectedPair.Load().(*ICECandidatePair).Local.Port == previousPair.Local.Port {
+			<-selectedPairChanged
+		}
+		assert.Equal(t, selectedPair.Load(), pcOffer.iceTransport.GetSelectedCandidatePair())
+	}
+
+	restart(&OfferOptions{ICERestart: true})
+
+	pcOffer.RestartICE()
+	<-negotiationNeeded
+	restart(nil)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// Assert that a glare can be resolved rolling back the local offer
+func TestPeerConnection_Renegotiation_Rollback(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcImpolite, pcPolite, err := api.newPair(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	onTrackFired, onTrackFiredFunc := context.WithCancel(context.Background())
+	pcPolite.OnTrack(func(track *Track, r *RTPReceiver) {
+		onTrackFiredFunc()
+	})
+
+	assert.NoError(t, signalPair(pcImpolite, pcPolite))
+
+	impoliteTrack, err := pcImpolite.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "foo", "bar")
+	assert.NoError(t, err)
+	_, err = pcImpolite.AddTrack(impoliteTrack)
+	assert.NoError(t, err)
+
+	politeTrack, err := pcPolite.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "baz", "qux")
+	assert.NoError(t, err)
+	_, err = pcPolite.AddTrack(politeTrack)
+	assert.NoError(t, err)
+
+	// both peers send an offer at the same time
+	impoliteOffer, err := pcImpolite.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcImpolite.SetLocalDescription(impoliteOffer))
+
+	politeOffer, err := pcPolite.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcPolite.SetLocalDescription(politeOffer))
+
+	// the polite peer rolls back its offer and accepts the remote one
+	assert.NoError(t, pcPolite.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}))
+	assert.Equal(t, SignalingStateStable, pcPolite.SignalingState())
+	assert.Nil(t, pcPolite.PendingLocalDescription())
+
+	assert.NoError(t, pcPolite.SetRemoteDescription(impoliteOffer))
+	answer, err := pcPolite.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcPolite.SetLocalDescription(answer))
+	assert.NoError(t, pcImpolite.SetRemoteDescription(answer))
+
+	sendVideoUntilDone(onTrackFired.Done(), t, []*Track{impoliteTrack})
+
+	// rolling back a remote offer removes the transceivers it created
+	_, err = pcImpolite.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	offer, err := pcImpolite.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcImpolite.SetLocalDescription(offer))
+
+	transceivers := len(pcPolite.GetTransceivers())
+	assert.NoError(t, pcPolite.SetRemoteDescription(offer))
+	assert.Equal(t, transceivers+1, len(pcPolite.GetTransceivers()))
+	assert.NoError(t, pcPolite.SetRemoteDescription(SessionDescription{Type: SDPTypeRollback}))
+	assert.Equal(t, SignalingStateStable, pcPolite.SignalingState())
+	assert.Equal(t, transceivers, len(pcPolite.GetTransceivers()))
+
+	// rolling back from the stable state isn't allowed
+	assert.Error(t, pcPolite.SetRemoteDescription(SessionDescription{Type: SDPTypeRollback}))
+
+	assert.NoError(t, pcImpolite.Close())
+	assert.NoError(t, pcPolite.Close())
+}
+
+// Assert that the PerfectNegotiator resolves an offer collision
+func TestPeerConnection_Renegotiation_PerfectNegotiator(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcImpolite, pcPolite, err := newPair()
+	assert.NoError(t, err)
+
+	// the signaling channels keep the descriptions order
+	toImpolite, toPolite := make(chan SessionDescription, 10), make(chan SessionDescription, 10)
+	impolite := NewPerfectNegotiator(pcImpolite, false, func(desc SessionDescription) error {
+		toPolite <- desc
+		return nil
+	})
+	polite := NewPerfectNegotiator(pcPolite, true, func(desc SessionDescription) error {
+		toImpolite <- desc
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for _, s := range []struct {
+		n  *PerfectNegotiator
+		ch chan SessionDescription
+	}{{impolite, toImpolite}, {polite, toPolite}} {
+		wg.Add(1)
+		go func(n *PerfectNegotiator, ch chan SessionDescription) {
+			defer wg.Done()
+			for {
+				select {
+				case desc := <-ch:
+					assert.NoError(t, n.HandleDescription(desc))
+				case <-done:
+					return
+				}
+			}
+		}(s.n, s.ch)
+	}
+	impolite.OnError(func(err error) { assert.NoError(t, err) })
+	polite.OnError(func(err error) { assert.NoError(t, err) })
+
+	// both peers need a negotiation at the same time
+	var opened sync.WaitGroup
+	for _, pc := range []*PeerConnection{pcImpolite, pcPolite} {
+		dc, err := pc.CreateDataChannel("data", nil)
+		assert.NoError(t, err)
+
+		opened.Add(1)
+		dc.OnOpen(opened.Done)
+	}
+
+	opened.Wait()
+	close(done)
+	wg.Wait()
+	assert.Equal(t, SignalingStateStable, pcImpolite.SignalingState())
+	assert.Equal(t, SignalingStateStable, pcPolite.SignalingState())
+
+	assert.NoError(t, pcImpolite.Close())
+	assert.NoError(t, pcPolite.Close())
+}
+
 // Assert that renegotiation doesn't attempt to gather ICE twice
 // Before we would attempt to gather multiple times and would put
 // the PeerConnection into a broken state
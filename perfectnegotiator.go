@@ -0,0 +1,143 @@
+package webrtc
+
+import (
+	"sync"
+)
+
+// PerfectNegotiator implements the perfect negotiation pattern
+// (https://w3c.github.io/webrtc-pc/#perfect-negotiation-example) on top of a
+// PeerConnection. The peers of a connection are given different roles: when
+// their offers collide the impolite peer ignores the remote offer while the
+// polite peer rolls back its local offer and answers the remote one.
+//
+// The negotiator takes over the PeerConnection OnNegotiationNeeded handler
+// and creates the offers when a negotiation is needed. The descriptions to
+// send to the remote peer are provided to the signal function while the
+// descriptions and candidates received from the remote peer must be passed to
+// HandleDescription and HandleCandidate.
+type PerfectNegotiator struct {
+	pc     *PeerConnection
+	polite bool
+	signal func(SessionDescription) error
+
+	// mu serializes the negotiation steps
+	mu          sync.Mutex
+	ignoreOffer bool
+
+	onErrorHandler func(error)
+}
+
+// NewPerfectNegotiator creates a new PerfectNegotiator for the provided
+// PeerConnection
+func NewPerfectNegotiator(pc *PeerConnection, polite bool, signal func(SessionDescription) error) *PerfectNegotiator {
+	n := &PerfectNegotiator{
+		pc:     pc,
+		polite: polite,
+		signal: signal,
+	}
+	pc.OnNegotiationNeeded(n.negotiate)
+	return n
+}
+
+// OnError sets an event handler which is called when a negotiation started
+// by the negotiator fails
+func (n *PerfectNegotiator) OnError(f func(error)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.onErrorHandler = f
+}
+
+func (n *PerfectNegotiator) onError(err error) {
+	n.mu.Lock()
+	hdlr := n.onErrorHandler
+	n.mu.Unlock()
+
+	if hdlr != nil {
+		go hdlr(err)
+	}
+}
+
+func (n *PerfectNegotiator) negotiate() {
+	offer, err := func() (*SessionDescription, error) {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		// a remote offer is being handled, a new negotiation will be needed
+		// when it's answered
+		if n.pc.SignalingState() != SignalingStateStable {
+			return nil, nil
+		}
+
+		offer, err := n.pc.CreateOffer(nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := n.pc.SetLocalDescription(offer); err != nil {
+			return nil, err
+		}
+		return n.pc.LocalDescription(), nil
+	}()
+	if err == nil && offer != nil {
+		err = n.signal(*offer)
+	}
+	if err != nil {
+		n.onError(err)
+	}
+}
+
+// HandleDescription handles a description received from the remote peer. A
+// colliding remote offer is ignored when the peer is impolite and accepted,
+// rolling back the local offer, when the peer is polite. Remote offers are
+// answered, providing the answer to the signal function.
+func (n *PerfectNegotiator) HandleDescription(desc SessionDescription) error {
+	answer, err := func() (*SessionDescription, error) {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		offerCollision := desc.Type == SDPTypeOffer && n.pc.SignalingState() != SignalingStateStable
+		n.ignoreOffer = !n.polite && offerCollision
+		if n.ignoreOffer {
+			return nil, nil
+		}
+
+		if offerCollision {
+			if err := n.pc.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := n.pc.SetRemoteDescription(desc); err != nil {
+			return nil, err
+		}
+		if desc.Type != SDPTypeOffer {
+			return nil, nil
+		}
+
+		answer, err := n.pc.CreateAnswer(nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := n.pc.SetLocalDescription(answer); err != nil {
+			return nil, err
+		}
+		return n.pc.LocalDescription(), nil
+	}()
+	if err != nil || answer == nil {
+		return err
+	}
+
+	return n.signal(*answer)
+}
+
+// HandleCandidate handles an ICE candidate received from the remote peer. The
+// errors adding the candidates of an ignored remote offer are discarded.
+func (n *PerfectNegotiator) HandleCandidate(candidate ICECandidateInit) error {
+	err := n.pc.AddICECandidate(candidate)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err != nil && !n.ignoreOffer {
+		return err
+	}
+	return nil
+}
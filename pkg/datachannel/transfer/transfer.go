@@ -0,0 +1,320 @@
+// Package transfer sends a file, or any stream, over a detached DataChannel.
+// The data is sent in chunks, the receiver acknowledges what it has written
+// so the sender never has more than a window of data in flight, and an
+// interrupted transfer is resumed, on a new DataChannel, from what the
+// receiver already has.
+//
+// The DataChannels are detached, see SettingEngine.DetachDataChannels, and
+// the ReadWriteCloser returned by DataChannel.Detach is passed to Send on one
+// side and Receive on the other once the channel is open. They should be
+// closed after the transfer, which also ends a transfer that failed.
+//
+// The messages start with their type. The receiver first sends a request
+// with the offset to start from and its window, then the sender sends the
+// data messages
+// followed by an end message with the total size. The receiver acknowledges
+// the bytes written as the window drains, and echoes the end message once
+// all of them are written.
+package transfer
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+)
+
+const (
+	// DefaultChunkSize is the size of the data messages when the Config
+	// doesn't set it, small enough for all the browsers
+	DefaultChunkSize = 16 * 1024
+	// DefaultWindow is the data in flight when the Config doesn't set it
+	DefaultWindow = 1024 * 1024
+
+	// maxMessageSize is the largest message of a DataChannel
+	maxMessageSize = 65535
+	// maxChunkSize leaves room for the type of the data messages
+	maxChunkSize = maxMessageSize - 1
+)
+
+const (
+	messageTypeRequest byte = iota + 1
+	messageTypeData
+	messageTypeAck
+	messageTypeEnd
+)
+
+var (
+	// ErrUnexpectedMessage indicates a message that isn't part of the
+	// protocol, or not expected at this point of the transfer
+	ErrUnexpectedMessage = errors.New("transfer: unexpected message")
+
+	// ErrSizeMismatch indicates that the size of the end message isn't the
+	// size of the received data
+	ErrSizeMismatch = errors.New("transfer: received size doesn't match the sent size")
+
+	// ErrInvalidOffset indicates a request to resume after the end of the
+	// data to send
+	ErrInvalidOffset = errors.New("transfer: offset after the end of the data")
+
+	// ErrInvalidChunkSize indicates a chunk size larger than the messages of
+	// a DataChannel
+	ErrInvalidChunkSize = errors.New("transfer: chunk size is larger than the maximum message size")
+)
+
+// Config configures a transfer, the zero value uses the defaults
+type Config struct {
+	// ChunkSize is the size of the data messages sent, up to 65534 bytes
+	ChunkSize int
+	// Window is the number of bytes the sender sends before waiting for the
+	// receiver to acknowledge them. It's set by the receiver, which sends it
+	// in its request, the Window of Send is ignored.
+	Window uint64
+	// OnProgress is called, from the goroutine of the transfer, with the
+	// offset reached each time some data is sent or written
+	OnProgress func(offset uint64)
+}
+
+func (c *Config) chunkSize() (int, error) {
+	switch {
+	case c.ChunkSize == 0:
+		return DefaultChunkSize, nil
+	case c.ChunkSize < 0 || c.ChunkSize > maxChunkSize:
+		return 0, ErrInvalidChunkSize
+	default:
+		return c.ChunkSize, nil
+	}
+}
+
+func (c *Config) window() uint64 {
+	if c.Window == 0 {
+		return DefaultWindow
+	}
+	return c.Window
+}
+
+func (c *Config) progress(offset uint64) {
+	if c.OnProgress != nil {
+		c.OnProgress(offset)
+	}
+}
+
+// Send sends the data of r over conn, a detached DataChannel whose Writes and
+// Reads are whole messages. It starts from the offset requested by the
+// receiver, seeking r if it's an io.Seeker or skipping the data otherwise,
+// and returns once the receiver has written all the data.
+func Send(conn io.ReadWriter, r io.Reader, config Config) error {
+	chunkSize, err := config.chunkSize()
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, maxMessageSize)
+	offset, window, err := readRequest(conn, buf)
+	if err != nil {
+		return err
+	}
+	if err = skip(r, offset); err != nil {
+		return err
+	}
+
+	// the acknowledgments are read while the data is sent, until the one of
+	// the end message
+	var acked uint64 = offset
+	ackCh := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			messageType, ack, err := readOffset(conn, buf, messageTypeAck, messageTypeEnd)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			atomic.StoreUint64(&acked, ack)
+			select {
+			case ackCh <- struct{}{}:
+			default:
+			}
+			if messageType == messageTypeEnd {
+				close(errCh)
+				return
+			}
+		}
+	}()
+	waitAck := func(condition func(acked uint64) bool) error {
+		for !condition(atomic.LoadUint64(&acked)) {
+			select {
+			case <-ackCh:
+			case err, ok := <-errCh:
+				if !ok {
+					// the end acknowledged before all the data was sent
+					return ErrUnexpectedMessage
+				}
+				return err
+			}
+		}
+		return nil
+	}
+
+	chunk := make([]byte, 1+chunkSize)
+	chunk[0] = messageTypeData
+	for {
+		n, err := io.ReadFull(r, chunk[1:])
+		if err == io.EOF {
+			break
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		// a chunk larger than the window is sent when nothing is in flight
+		if err = waitAck(func(acked uint64) bool {
+			return acked == offset || offset+uint64(n)-acked <= window
+		}); err != nil {
+			return err
+		}
+		if _, err = conn.Write(chunk[:1+n]); err != nil {
+			return err
+		}
+		offset += uint64(n)
+		config.progress(offset)
+
+		if n < chunkSize {
+			break
+		}
+	}
+
+	if err = writeOffset(conn, messageTypeEnd, offset); err != nil {
+		return err
+	}
+
+	// the receiver echoes the end message once it has written all the data
+	if err, ok := <-errCh; ok {
+		return err
+	} else if atomic.LoadUint64(&acked) != offset {
+		return ErrSizeMismatch
+	}
+	return nil
+}
+
+// Receive writes to w the data received over conn, a detached DataChannel
+// whose Writes and Reads are whole messages. offset is the number of bytes
+// already received by a previous transfer, w must be positioned after them.
+// It returns the total size once all the data has been written.
+func Receive(conn io.ReadWriter, w io.Writer, offset uint64, config Config) (uint64, error) {
+	window := config.window()
+
+	if err := writeRequest(conn, offset, window); err != nil {
+		return offset, err
+	}
+
+	buf := make([]byte, maxMessageSize)
+	acked := offset
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return offset, err
+		} else if n == 0 {
+			return offset, ErrUnexpectedMessage
+		}
+
+		switch buf[0] {
+		case messageTypeData:
+			if _, err = w.Write(buf[1:n]); err != nil {
+				return offset, err
+			}
+			offset += uint64(n - 1)
+			config.progress(offset)
+
+			// acknowledge before the sender runs out of window
+			if offset-acked >= window/2 {
+				if err = writeOffset(conn, messageTypeAck, offset); err != nil {
+					return offset, err
+				}
+				acked = offset
+			}
+		case messageTypeEnd:
+			if n != 9 {
+				return offset, ErrUnexpectedMessage
+			} else if binary.BigEndian.Uint64(buf[1:9]) != offset {
+				return offset, ErrSizeMismatch
+			}
+			return offset, writeOffset(conn, messageTypeEnd, offset)
+		default:
+			return offset, ErrUnexpectedMessage
+		}
+	}
+}
+
+// skip moves r offset bytes forward
+func skip(r io.Reader, offset uint64) error {
+	if offset == 0 {
+		return nil
+	}
+	if seeker, ok := r.(io.Seeker); ok {
+		size, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		} else if uint64(size) < offset {
+			return ErrInvalidOffset
+		}
+		_, err = seeker.Seek(int64(offset), io.SeekStart)
+		return err
+	}
+
+	n, err := io.CopyN(ioutil.Discard, r, int64(offset))
+	if err == io.EOF && uint64(n) < offset {
+		return ErrInvalidOffset
+	}
+	return err
+}
+
+// readRequest reads the request of the receiver, with the offset to start
+// from and the window
+func readRequest(conn io.Reader, buf []byte) (uint64, uint64, error) {
+	n, err := conn.Read(buf)
+	if err != nil {
+		return 0, 0, err
+	} else if n != 17 || buf[0] != messageTypeRequest {
+		return 0, 0, ErrUnexpectedMessage
+	}
+	window := binary.BigEndian.Uint64(buf[9:17])
+	if window == 0 {
+		return 0, 0, ErrUnexpectedMessage
+	}
+	return binary.BigEndian.Uint64(buf[1:9]), window, nil
+}
+
+// writeRequest writes the request of the receiver
+func writeRequest(conn io.Writer, offset, window uint64) error {
+	msg := make([]byte, 17)
+	msg[0] = messageTypeRequest
+	binary.BigEndian.PutUint64(msg[1:9], offset)
+	binary.BigEndian.PutUint64(msg[9:], window)
+	_, err := conn.Write(msg)
+	return err
+}
+
+// readOffset reads a message carrying an offset, of one of the expected types
+func readOffset(conn io.Reader, buf []byte, messageTypes ...byte) (byte, uint64, error) {
+	n, err := conn.Read(buf)
+	if err != nil {
+		return 0, 0, err
+	} else if n != 9 {
+		return 0, 0, ErrUnexpectedMessage
+	}
+	for _, messageType := range messageTypes {
+		if buf[0] == messageType {
+			return messageType, binary.BigEndian.Uint64(buf[1:9]), nil
+		}
+	}
+	return 0, 0, ErrUnexpectedMessage
+}
+
+// writeOffset writes a message carrying an offset
+func writeOffset(conn io.Writer, messageType byte, offset uint64) error {
+	msg := make([]byte, 9)
+	msg[0] = messageType
+	binary.BigEndian.PutUint64(msg[1:], offset)
+	_, err := conn.Write(msg)
+	return err
+}
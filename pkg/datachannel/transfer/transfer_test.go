@@ -0,0 +1,284 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// messageConn is one end of an in-memory pipe where each Write is a message,
+// like a detached DataChannel
+type messageConn struct {
+	read  chan []byte
+	write chan []byte
+	done  chan struct{}
+	once  *sync.Once
+
+	// window records the data in flight, as seen by the sender
+	window *window
+}
+
+type window struct {
+	lock        sync.Mutex
+	sent        uint64
+	acked       uint64
+	maxInFlight uint64
+}
+
+func newMessagePipe() (*messageConn, *messageConn) {
+	a, b := make(chan []byte, 1024), make(chan []byte, 1024)
+	done := make(chan struct{})
+	once, w := &sync.Once{}, &window{}
+	return &messageConn{read: a, write: b, done: done, once: once, window: w},
+		&messageConn{read: b, write: a, done: done, once: once, window: w}
+}
+
+func (c *messageConn) Read(p []byte) (int, error) {
+	select {
+	case msg := <-c.read:
+		if len(msg) > len(p) {
+			return 0, io.ErrShortBuffer
+		}
+		if msg[0] == messageTypeAck && len(msg) == 9 {
+			c.window.lock.Lock()
+			c.window.acked = binary.BigEndian.Uint64(msg[1:])
+			c.window.lock.Unlock()
+		}
+		return copy(p, msg), nil
+	case <-c.done:
+		return 0, io.EOF
+	}
+}
+
+func (c *messageConn) Write(p []byte) (int, error) {
+	if p[0] == messageTypeData {
+		c.window.lock.Lock()
+		c.window.sent += uint64(len(p) - 1)
+		if inFlight := c.window.sent - c.window.acked; inFlight > c.window.maxInFlight {
+			c.window.maxInFlight = inFlight
+		}
+		c.window.lock.Unlock()
+	}
+
+	select {
+	case c.write <- append([]byte{}, p...):
+		return len(p), nil
+	case <-c.done:
+		return 0, io.ErrClosedPipe
+	}
+}
+
+func (c *messageConn) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+	})
+	return nil
+}
+
+func (c *messageConn) maxInFlight() uint64 {
+	c.window.lock.Lock()
+	defer c.window.lock.Unlock()
+	return c.window.maxInFlight
+}
+
+// onlyReader hides the io.Seeker of a reader
+type onlyReader struct {
+	io.Reader
+}
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func transfer(t *testing.T, r io.Reader, w io.Writer, offset uint64, config Config) (uint64, error, error) {
+	sender, receiver := newMessagePipe()
+	defer func() {
+		assert.NoError(t, sender.Close())
+	}()
+
+	sendErr := make(chan error, 1)
+	go func() {
+		sendErr <- Send(sender, r, config)
+	}()
+	size, err := Receive(receiver, w, offset, config)
+	return size, err, <-sendErr
+}
+
+func TestTransfer(t *testing.T) {
+	for _, size := range []int{0, 1, DefaultChunkSize, 3*DefaultChunkSize + 5} {
+		data := testData(size)
+		var out bytes.Buffer
+		progress := []uint64{}
+		var progressLock sync.Mutex
+		config := Config{
+			OnProgress: func(offset uint64) {
+				progressLock.Lock()
+				progress = append(progress, offset)
+				progressLock.Unlock()
+			},
+		}
+
+		received, receiveErr, sendErr := transfer(t, bytes.NewReader(data), &out, 0, config)
+		assert.NoError(t, receiveErr)
+		assert.NoError(t, sendErr)
+		assert.Equal(t, uint64(size), received)
+		assert.True(t, bytes.Equal(data, out.Bytes()))
+
+		// both sides report the progress of each chunk
+		chunks := (size + DefaultChunkSize - 1) / DefaultChunkSize
+		progressLock.Lock()
+		assert.Equal(t, 2*chunks, len(progress))
+		progressLock.Unlock()
+	}
+}
+
+func TestTransfer_Resume(t *testing.T) {
+	data := testData(100000)
+	offset := uint64(40000)
+
+	for name, r := range map[string]io.Reader{
+		"Seeker":    bytes.NewReader(data),
+		"NotSeeker": onlyReader{bytes.NewReader(data)},
+	} {
+		out := bytes.NewBuffer(append([]byte{}, data[:offset]...))
+		received, receiveErr, sendErr := transfer(t, r, out, offset, Config{ChunkSize: 1000})
+		assert.NoError(t, receiveErr, name)
+		assert.NoError(t, sendErr, name)
+		assert.Equal(t, uint64(len(data)), received, name)
+		assert.Equal(t, data, out.Bytes(), name)
+	}
+
+	for name, r := range map[string]io.Reader{
+		"Seeker":    bytes.NewReader(data[:10]),
+		"NotSeeker": onlyReader{bytes.NewReader(data[:10])},
+	} {
+		sender, receiver := newMessagePipe()
+		go func() {
+			_, _ = Receive(receiver, &bytes.Buffer{}, offset, Config{})
+		}()
+		assert.Equal(t, ErrInvalidOffset, Send(sender, r, Config{}), name)
+		assert.NoError(t, sender.Close())
+	}
+}
+
+func TestTransfer_Window(t *testing.T) {
+	data := testData(50000)
+
+	for _, config := range []Config{
+		{ChunkSize: 500, Window: 5000},
+		{ChunkSize: 1000, Window: 1500},
+	} {
+		sender, receiver := newMessagePipe()
+		sendErr := make(chan error, 1)
+		go func() {
+			sendErr <- Send(sender, bytes.NewReader(data), config)
+		}()
+		var out bytes.Buffer
+		received, err := Receive(receiver, &out, 0, config)
+		assert.NoError(t, err)
+		assert.NoError(t, <-sendErr)
+		assert.Equal(t, uint64(len(data)), received)
+		assert.Equal(t, data, out.Bytes())
+		assert.True(t, sender.maxInFlight() <= config.Window, "%d bytes in flight", sender.maxInFlight())
+		assert.NoError(t, sender.Close())
+	}
+
+	// a chunk larger than the window is sent once the previous one is
+	// acknowledged
+	sender, receiver := newMessagePipe()
+	config := Config{ChunkSize: 8000, Window: 1000}
+	sendErr := make(chan error, 1)
+	go func() {
+		sendErr <- Send(sender, bytes.NewReader(data), config)
+	}()
+	var out bytes.Buffer
+	received, err := Receive(receiver, &out, 0, config)
+	assert.NoError(t, err)
+	assert.NoError(t, <-sendErr)
+	assert.Equal(t, uint64(len(data)), received)
+	assert.Equal(t, data, out.Bytes())
+	assert.Equal(t, uint64(config.ChunkSize), sender.maxInFlight())
+	assert.NoError(t, sender.Close())
+}
+
+func TestTransfer_MismatchedWindow(t *testing.T) {
+	data := testData(50000)
+
+	// the sender uses the window of the receiver, which acknowledges the
+	// data as its window drains
+	for _, receiverWindow := range []uint64{1000, 20000} {
+		sender, receiver := newMessagePipe()
+		sendErr := make(chan error, 1)
+		go func() {
+			sendErr <- Send(sender, bytes.NewReader(data), Config{ChunkSize: 500, Window: 5000})
+		}()
+		var out bytes.Buffer
+		received, err := Receive(receiver, &out, 0, Config{Window: receiverWindow})
+		assert.NoError(t, err)
+		assert.NoError(t, <-sendErr)
+		assert.Equal(t, uint64(len(data)), received)
+		assert.Equal(t, data, out.Bytes())
+		assert.True(t, sender.maxInFlight() <= receiverWindow, "%d bytes in flight", sender.maxInFlight())
+		assert.NoError(t, sender.Close())
+	}
+}
+
+func TestTransfer_InvalidChunkSize(t *testing.T) {
+	sender, _ := newMessagePipe()
+	for _, chunkSize := range []int{-1, maxChunkSize + 1} {
+		assert.Equal(t, ErrInvalidChunkSize, Send(sender, bytes.NewReader(nil), Config{ChunkSize: chunkSize}))
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestTransfer_Interrupted(t *testing.T) {
+	data := testData(100000)
+	sender, receiver := newMessagePipe()
+
+	sendErr := make(chan error, 1)
+	go func() {
+		sendErr <- Send(sender, bytes.NewReader(data), Config{ChunkSize: 1000, Window: 10000})
+	}()
+
+	// the receiver stops at the first write, the sender fails once the
+	// connection is closed
+	_, err := Receive(receiver, failingWriter{}, 0, Config{})
+	assert.Error(t, err)
+	assert.NoError(t, receiver.Close())
+	assert.Error(t, <-sendErr)
+}
+
+func TestTransfer_UnexpectedMessage(t *testing.T) {
+	sender, receiver := newMessagePipe()
+	defer func() {
+		assert.NoError(t, sender.Close())
+	}()
+
+	_, err := sender.Write([]byte{messageTypeAck, 0, 0, 0, 0, 0, 0, 0, 0})
+	assert.NoError(t, err)
+	assert.Equal(t, ErrUnexpectedMessage, Send(receiver, bytes.NewReader(nil), Config{}))
+
+	// a request without a window
+	_, err = sender.Write([]byte{messageTypeRequest, 0, 0, 0, 0, 0, 0, 0, 0})
+	assert.NoError(t, err)
+	assert.Equal(t, ErrUnexpectedMessage, Send(receiver, bytes.NewReader(nil), Config{}))
+
+	_, err = receiver.Write([]byte{messageTypeEnd, 0, 0, 0, 0, 0, 0, 0, 1})
+	assert.NoError(t, err)
+	_, err = Receive(sender, &bytes.Buffer{}, 0, Config{})
+	assert.Equal(t, ErrSizeMismatch, err)
+}
@@ -13,6 +13,12 @@ type Sample struct {
 	Samples uint32
 }
 
+// SampleTransform transforms the data of a Sample, like the transforms of the
+// WebRTC insertable streams. It can encrypt the frames end-to-end on the
+// sender side and decrypt them on the receiver side, so an SFU forwarding
+// them can't read the media.
+type SampleTransform func(sample Sample) (Sample, error)
+
 // NSamples calculates the number of samples in media of length d with sampling frequency f.
 // For example, NSamples(20 * time.Millisecond, 48000) will return the number of samples
 // in a 20 millisecond segment of Opus audio recorded at 48000 samples per second.
@@ -3,6 +3,8 @@ package samplebuilder
 
 import (
 	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v2/internal/h264"
 	"github.com/pion/webrtc/v2/pkg/media"
 )
 
@@ -28,6 +30,9 @@ type SampleBuilder struct {
 
 	// Interface that checks whether the packet is the first fragment of the frame or not
 	partitionHeadChecker rtp.PartitionHeadChecker
+
+	// Transform applied to the built samples
+	sampleTransform media.SampleTransform
 }
 
 // New constructs a new SampleBuilder
@@ -103,6 +108,21 @@ func (s *SampleBuilder) Pop() *media.Sample {
 // PopWithTimestamp scans buffer for valid samples and its RTP timestamp,
 // returns nil, 0 when no valid samples have been found
 func (s *SampleBuilder) PopWithTimestamp() (*media.Sample, uint32) {
+	for {
+		sample, timestamp := s.popSample()
+		if sample == nil || s.sampleTransform == nil {
+			return sample, timestamp
+		}
+		transformed, err := s.transformSample(*sample)
+		if err == nil {
+			return &transformed, timestamp
+		}
+		// the samples that can't be transformed are dropped
+	}
+}
+
+// popSample scans buffer for valid samples, before they're transformed
+func (s *SampleBuilder) popSample() (*media.Sample, uint32) {
 	var i uint16
 	if !s.isContiguous {
 		i = s.lastPush - s.maxLate
@@ -142,6 +162,13 @@ func (s *SampleBuilder) PopWithTimestamp() (*media.Sample, uint32) {
 	return nil, 0
 }
 
+func (s *SampleBuilder) transformSample(sample media.Sample) (media.Sample, error) {
+	if _, ok := s.depacketizer.(*codecs.H264Packet); ok {
+		return h264.TransformIncoming(sample, s.sampleTransform)
+	}
+	return s.sampleTransform(sample)
+}
+
 // Option configures SampleBuilder
 type Option func(o *SampleBuilder)
 
@@ -151,3 +178,14 @@ func WithPartitionHeadChecker(checker rtp.PartitionHeadChecker) Option {
 		o.partitionHeadChecker = checker
 	}
 }
+
+// WithSampleTransform sets a transform applied to the built samples, e.g. to
+// decrypt the samples encrypted by the transform of the remote track (see
+// webrtc.Track.SetSampleTransform). With the H.264 depacketizer it's applied
+// to the payload of each NAL unit. The samples it fails to transform are
+// dropped.
+func WithSampleTransform(transform media.SampleTransform) Option {
+	return func(o *SampleBuilder) {
+		o.sampleTransform = transform
+	}
+}
@@ -1,6 +1,7 @@
 package samplebuilder
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/pion/rtp"
@@ -237,3 +238,24 @@ func TestSeqnumDistance(t *testing.T) {
 		}
 	}
 }
+
+func TestSampleBuilderSampleTransform(t *testing.T) {
+	assert := assert.New(t)
+
+	errTransform := errors.New("transform failed")
+	s := New(50, &fakeDepacketizer{}, WithSampleTransform(func(sample media.Sample) (media.Sample, error) {
+		if sample.Data[0] == 0x03 {
+			return sample, errTransform
+		}
+		return media.Sample{Data: []byte{sample.Data[0] ^ 0xFF}, Samples: sample.Samples}, nil
+	}))
+
+	for i := uint16(0); i < 5; i++ {
+		s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 5000 + i, Timestamp: 5 + uint32(i)}, Payload: []byte{byte(i + 1)}})
+	}
+
+	// the sample that can't be transformed is dropped
+	assert.Equal(&media.Sample{Data: []byte{0xFD}, Samples: 1}, s.Pop())
+	assert.Equal(&media.Sample{Data: []byte{0xFB}, Samples: 1}, s.Pop())
+	assert.Nil(s.Pop())
+}
@@ -0,0 +1,325 @@
+// Package ulpfec implements the receive side of the RED (RFC 2198) and
+// ULPFEC (RFC 5109) payload formats, recovering lost RTP packets using
+// the received FEC packets.
+package ulpfec
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	rtpHeaderSize       = 12
+	fecHeaderSize       = 10
+	levelHeaderSize     = 4
+	levelHeaderLongSize = 8
+
+	// Number of media packets kept to recover lost packets. The largest
+	// ULPFEC mask protects 48 packets.
+	maxMediaPackets = 48
+
+	// Number of emitted sequence numbers remembered to avoid emitting a
+	// packet twice after it left the media packets window.
+	maxEmittedPackets = 1024
+)
+
+var (
+	errShortPacket    = errors.New("ulpfec: packet is too short")
+	errInvalidRED     = errors.New("ulpfec: invalid RED packet")
+	errUnsupportedFEC = errors.New("ulpfec: only one protection level is supported")
+)
+
+type fecPacket struct {
+	ssrc uint32
+
+	seqBase          uint16
+	mask             []uint16
+	protectionLength int
+
+	// recovery fields
+	headerRecovery    [2]byte
+	timestampRecovery uint32
+	lengthRecovery    uint16
+	payloadRecovery   []byte
+}
+
+// Decoder unwraps RED packets and recovers lost media packets using the
+// ULPFEC packets carried in the RED stream
+type Decoder struct {
+	redPayloadType    uint8
+	ulpfecPayloadType uint8
+
+	// received or recovered media packets by sequence number
+	mediaPackets map[uint16][]byte
+	// sequence numbers of mediaPackets in arrival order
+	mediaOrder []uint16
+
+	// sequence numbers of the emitted packets, in emission order
+	emitted      map[uint16]struct{}
+	emittedOrder []uint16
+
+	fecPackets []*fecPacket
+}
+
+// NewDecoder creates a new Decoder for a RED stream using the provided
+// RED and ULPFEC payload types
+func NewDecoder(redPayloadType, ulpfecPayloadType uint8) *Decoder {
+	return &Decoder{
+		redPayloadType:    redPayloadType,
+		ulpfecPayloadType: ulpfecPayloadType,
+		mediaPackets:      make(map[uint16][]byte),
+		emitted:           make(map[uint16]struct{}),
+	}
+}
+
+// Push processes a received packet and returns the media packets ready to
+// be consumed: the media packet carried in the RED packet (if any) and any
+// packet recovered thanks to the FEC data received until now. Packets not
+// using the RED payload type are returned untouched. An error is returned
+// when the packet is malformed, the decoder state isn't changed and the
+// following packets can still be pushed.
+func (d *Decoder) Push(p *rtp.Packet) ([]*rtp.Packet, error) {
+	if p.PayloadType != d.redPayloadType {
+		return []*rtp.Packet{p}, nil
+	}
+
+	blockPayloadType, block, err := primaryBlock(p.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	packets := []*rtp.Packet{}
+	if blockPayloadType == d.ulpfecPayloadType {
+		fec, err := parseFECPacket(p.SSRC, block)
+		if err != nil {
+			return nil, err
+		}
+		d.fecPackets = append(d.fecPackets, fec)
+	} else {
+		media := &rtp.Packet{Header: p.Header, Payload: block}
+		media.PayloadType = blockPayloadType
+		raw, err := media.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		if !d.addMediaPacket(media.SequenceNumber, raw) {
+			// duplicated or already recovered packet
+			return packets, nil
+		}
+		packets = append(packets, media)
+	}
+
+	return append(packets, d.recover()...), nil
+}
+
+// primaryBlock returns the payload type and data of the primary block of a
+// RED payload (RFC 2198 Section 3). Redundant blocks are skipped.
+func primaryBlock(payload []byte) (uint8, []byte, error) {
+	offset := 0
+	dataLength := 0
+	for {
+		if offset >= len(payload) {
+			return 0, nil, errInvalidRED
+		}
+		if payload[offset]&0x80 == 0 {
+			// last header, primary block
+			payloadType := payload[offset] & 0x7f
+			offset++
+			if offset+dataLength > len(payload) {
+				return 0, nil, errInvalidRED
+			}
+			return payloadType, payload[offset+dataLength:], nil
+		}
+
+		if offset+4 > len(payload) {
+			return 0, nil, errInvalidRED
+		}
+		dataLength += int(binary.BigEndian.Uint16(payload[offset+2:]) & 0x03ff)
+		offset += 4
+	}
+}
+
+func parseFECPacket(ssrc uint32, b []byte) (*fecPacket, error) {
+	if len(b) < fecHeaderSize+levelHeaderSize {
+		return nil, errShortPacket
+	}
+
+	// E bit set means an header extension that isn't defined
+	if b[0]&0x80 != 0 {
+		return nil, errUnsupportedFEC
+	}
+	longMask := b[0]&0x40 != 0
+
+	fec := &fecPacket{
+		ssrc:              ssrc,
+		headerRecovery:    [2]byte{b[0], b[1]},
+		seqBase:           binary.BigEndian.Uint16(b[2:]),
+		timestampRecovery: binary.BigEndian.Uint32(b[4:]),
+		lengthRecovery:    binary.BigEndian.Uint16(b[8:]),
+	}
+
+	levelHeaderLength := levelHeaderSize
+	if longMask {
+		levelHeaderLength = levelHeaderLongSize
+	}
+	if len(b) < fecHeaderSize+levelHeaderLength {
+		return nil, errShortPacket
+	}
+
+	level := b[fecHeaderSize:]
+	fec.protectionLength = int(binary.BigEndian.Uint16(level))
+	maskBytes := level[2:levelHeaderLength]
+	for i, maskByte := range maskBytes {
+		for bit := 0; bit < 8; bit++ {
+			if maskByte&(0x80>>uint(bit)) != 0 {
+				fec.mask = append(fec.mask, fec.seqBase+uint16(i*8+bit))
+			}
+		}
+	}
+
+	payload := level[levelHeaderLength:]
+	if len(payload) < fec.protectionLength {
+		return nil, errShortPacket
+	}
+	fec.payloadRecovery = payload[:fec.protectionLength]
+
+	return fec, nil
+}
+
+// addMediaPacket stores a media packet, returns false if it was already
+// emitted
+func (d *Decoder) addMediaPacket(seq uint16, raw []byte) bool {
+	if _, ok := d.emitted[seq]; ok {
+		return false
+	}
+
+	d.emitted[seq] = struct{}{}
+	d.emittedOrder = append(d.emittedOrder, seq)
+	if len(d.emittedOrder) > maxEmittedPackets {
+		delete(d.emitted, d.emittedOrder[0])
+		d.emittedOrder = d.emittedOrder[1:]
+	}
+
+	d.mediaPackets[seq] = raw
+	d.mediaOrder = append(d.mediaOrder, seq)
+	if len(d.mediaOrder) > maxMediaPackets {
+		delete(d.mediaPackets, d.mediaOrder[0])
+		d.mediaOrder = d.mediaOrder[1:]
+	}
+	return true
+}
+
+// recover tries to recover the lost media packets using the stored FEC
+// packets. A FEC packet can recover a single packet, so it's used when all
+// the other protected packets have been received. FEC packets that can't
+// recover a packet anymore, or protect invalid data, are dropped.
+func (d *Decoder) recover() []*rtp.Packet {
+	recovered := []*rtp.Packet{}
+
+	for {
+		progress := false
+		fecPackets := d.fecPackets[:0]
+		for _, fec := range d.fecPackets {
+			missing := []uint16{}
+			evicted := false
+			for _, seq := range fec.mask {
+				if _, ok := d.mediaPackets[seq]; ok {
+					continue
+				}
+				if _, ok := d.emitted[seq]; ok {
+					// already emitted but left the window, its data
+					// can't be used anymore
+					evicted = true
+					continue
+				}
+				missing = append(missing, seq)
+			}
+
+			switch {
+			case len(missing) == 0 || evicted:
+				// nothing left to recover with this packet
+				continue
+			case len(missing) == 1:
+				p, raw, err := d.recoverPacket(fec, missing[0])
+				if err != nil {
+					// invalid FEC packet
+					continue
+				}
+				d.addMediaPacket(p.SequenceNumber, raw)
+				recovered = append(recovered, p)
+				progress = true
+				continue
+			case !d.inWindow(fec):
+				// the protected packets are too old
+				continue
+			}
+			fecPackets = append(fecPackets, fec)
+		}
+		d.fecPackets = fecPackets
+
+		if !progress {
+			return recovered
+		}
+	}
+}
+
+// inWindow reports if the FEC packet still protects packets that could be
+// received
+func (d *Decoder) inWindow(fec *fecPacket) bool {
+	if len(d.mediaOrder) < maxMediaPackets {
+		return true
+	}
+	oldest := d.mediaOrder[0]
+	last := fec.mask[len(fec.mask)-1]
+	return int16(last-oldest) >= 0
+}
+
+func (d *Decoder) recoverPacket(fec *fecPacket, seq uint16) (*rtp.Packet, []byte, error) {
+	header := fec.headerRecovery
+	timestamp := fec.timestampRecovery
+	length := fec.lengthRecovery
+	payload := make([]byte, fec.protectionLength)
+	copy(payload, fec.payloadRecovery)
+
+	for _, protected := range fec.mask {
+		if protected == seq {
+			continue
+		}
+		raw := d.mediaPackets[protected]
+		if len(raw) < rtpHeaderSize {
+			return nil, nil, errShortPacket
+		}
+
+		header[0] ^= raw[0]
+		header[1] ^= raw[1]
+		timestamp ^= binary.BigEndian.Uint32(raw[4:])
+		length ^= uint16(len(raw) - rtpHeaderSize)
+		for i, b := range raw[rtpHeaderSize:] {
+			if i >= len(payload) {
+				break
+			}
+			payload[i] ^= b
+		}
+	}
+
+	if int(length) > len(payload) {
+		// the FEC packet doesn't protect the whole packet
+		return nil, nil, errShortPacket
+	}
+
+	raw := make([]byte, rtpHeaderSize+int(length))
+	raw[0] = 0x80 | (header[0] & 0x3f)
+	raw[1] = header[1]
+	binary.BigEndian.PutUint16(raw[2:], seq)
+	binary.BigEndian.PutUint32(raw[4:], timestamp)
+	binary.BigEndian.PutUint32(raw[8:], fec.ssrc)
+	copy(raw[rtpHeaderSize:], payload[:length])
+
+	p := &rtp.Packet{}
+	if err := p.Unmarshal(raw); err != nil {
+		return nil, nil, err
+	}
+	return p, raw, nil
+}
@@ -0,0 +1,210 @@
+package ulpfec
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testREDPayloadType    = 116
+	testULPFECPayloadType = 117
+	testVP8PayloadType    = 96
+	testSSRC              = 5000
+)
+
+// generateFEC generates a ULPFEC payload with a short mask protecting all the
+// provided packets
+func generateFEC(t *testing.T, packets []*rtp.Packet) []byte {
+	var header [2]byte
+	var timestamp uint32
+	var length uint16
+	protectionLength := 0
+	raws := [][]byte{}
+	for _, p := range packets {
+		raw, err := p.Marshal()
+		assert.NoError(t, err)
+		raws = append(raws, raw)
+		if len(raw)-rtpHeaderSize > protectionLength {
+			protectionLength = len(raw) - rtpHeaderSize
+		}
+	}
+
+	payload := make([]byte, protectionLength)
+	var mask uint16
+	for _, raw := range raws {
+		header[0] ^= raw[0]
+		header[1] ^= raw[1]
+		timestamp ^= binary.BigEndian.Uint32(raw[4:])
+		length ^= uint16(len(raw) - rtpHeaderSize)
+		for i, b := range raw[rtpHeaderSize:] {
+			payload[i] ^= b
+		}
+		mask |= 0x8000 >> (binary.BigEndian.Uint16(raw[2:]) - packets[0].SequenceNumber)
+	}
+
+	fec := make([]byte, fecHeaderSize+levelHeaderSize)
+	fec[0] = header[0] & 0x3f
+	fec[1] = header[1]
+	binary.BigEndian.PutUint16(fec[2:], packets[0].SequenceNumber)
+	binary.BigEndian.PutUint32(fec[4:], timestamp)
+	binary.BigEndian.PutUint16(fec[8:], length)
+	binary.BigEndian.PutUint16(fec[10:], uint16(protectionLength))
+	binary.BigEndian.PutUint16(fec[12:], mask)
+	return append(fec, payload...)
+}
+
+func redPacket(header rtp.Header, blockPayloadType uint8, block []byte) *rtp.Packet {
+	header.PayloadType = testREDPayloadType
+	return &rtp.Packet{
+		Header:  header,
+		Payload: append([]byte{blockPayloadType}, block...),
+	}
+}
+
+func assertPacketEqual(t *testing.T, expected, actual *rtp.Packet) {
+	expectedRaw, err := expected.Marshal()
+	assert.NoError(t, err)
+	actualRaw, err := actual.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRaw, actualRaw)
+}
+
+func mediaPackets() []*rtp.Packet {
+	packets := []*rtp.Packet{}
+	for i, payload := range [][]byte{{0x01, 0x02, 0x03}, {0x04, 0x05}, {0x06, 0x07, 0x08, 0x09}} {
+		packets = append(packets, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         i == 2,
+				PayloadType:    testVP8PayloadType,
+				SequenceNumber: uint16(100 + i),
+				Timestamp:      3000,
+				SSRC:           testSSRC,
+			},
+			Payload: payload,
+		})
+	}
+	return packets
+}
+
+func TestDecoderUnwrapRED(t *testing.T) {
+	d := NewDecoder(testREDPayloadType, testULPFECPayloadType)
+
+	media := mediaPackets()[0]
+	packets, err := d.Push(redPacket(media.Header, testVP8PayloadType, media.Payload))
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(packets)) {
+		assertPacketEqual(t, media, packets[0])
+	}
+
+	// a duplicated packet must be ignored
+	packets, err = d.Push(redPacket(media.Header, testVP8PayloadType, media.Payload))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(packets))
+
+	// non RED packets are returned untouched
+	media = mediaPackets()[1]
+	packets, err = d.Push(media)
+	assert.NoError(t, err)
+	assert.Equal(t, []*rtp.Packet{media}, packets)
+}
+
+func TestDecoderRecover(t *testing.T) {
+	for lost := 0; lost < 3; lost++ {
+		d := NewDecoder(testREDPayloadType, testULPFECPayloadType)
+		media := mediaPackets()
+		fec := generateFEC(t, media)
+
+		received := []*rtp.Packet{}
+		for i, p := range media {
+			if i == lost {
+				continue
+			}
+			packets, err := d.Push(redPacket(p.Header, testVP8PayloadType, p.Payload))
+			assert.NoError(t, err)
+			received = append(received, packets...)
+		}
+
+		header := media[2].Header
+		header.SequenceNumber = 103
+		packets, err := d.Push(redPacket(header, testULPFECPayloadType, fec))
+		assert.NoError(t, err)
+		if assert.Equal(t, 1, len(packets), "lost packet %d", lost) {
+			assertPacketEqual(t, media[lost], packets[0])
+		}
+		assert.Equal(t, 2, len(received))
+	}
+}
+
+func TestDecoderInvalidRED(t *testing.T) {
+	d := NewDecoder(testREDPayloadType, testULPFECPayloadType)
+
+	header := mediaPackets()[0].Header
+	p := redPacket(header, testVP8PayloadType, nil)
+	p.Payload = []byte{0x80 | testVP8PayloadType}
+	_, err := d.Push(p)
+	assert.Equal(t, errInvalidRED, err)
+
+	_, err = d.Push(redPacket(header, testULPFECPayloadType, []byte{0x00}))
+	assert.Equal(t, errShortPacket, err)
+}
+
+func TestDecoderInvalidFEC(t *testing.T) {
+	d := NewDecoder(testREDPayloadType, testULPFECPayloadType)
+	media := mediaPackets()
+
+	// the length recovery is larger than the protected payload, the FEC
+	// packet is dropped
+	fec := generateFEC(t, media)
+	binary.BigEndian.PutUint16(fec[8:], 0xffff)
+
+	for _, p := range media[:2] {
+		_, err := d.Push(redPacket(p.Header, testVP8PayloadType, p.Payload))
+		assert.NoError(t, err)
+	}
+	header := media[2].Header
+	header.SequenceNumber = 103
+	packets, err := d.Push(redPacket(header, testULPFECPayloadType, fec))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(packets))
+
+	// the following packets are still decoded
+	packets, err = d.Push(redPacket(media[2].Header, testVP8PayloadType, media[2].Payload))
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(packets)) {
+		assertPacketEqual(t, media[2], packets[0])
+	}
+}
+
+func TestDecoderNoReEmit(t *testing.T) {
+	d := NewDecoder(testREDPayloadType, testULPFECPayloadType)
+	media := mediaPackets()
+	fec := generateFEC(t, media)
+
+	for _, p := range media {
+		_, err := d.Push(redPacket(p.Header, testVP8PayloadType, p.Payload))
+		assert.NoError(t, err)
+	}
+
+	// the first packet leaves the media packets window
+	header := media[2].Header
+	for i := 0; i < maxMediaPackets; i++ {
+		header.SequenceNumber++
+		packets, err := d.Push(redPacket(header, testVP8PayloadType, media[2].Payload))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(packets))
+	}
+
+	// it must not be recovered again nor emitted when duplicated
+	header.SequenceNumber++
+	packets, err := d.Push(redPacket(header, testULPFECPayloadType, fec))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(packets))
+
+	packets, err = d.Push(redPacket(media[0].Header, testVP8PayloadType, media[0].Payload))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(packets))
+}
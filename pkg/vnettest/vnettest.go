@@ -0,0 +1,137 @@
+// +build !js
+
+// Package vnettest connects PeerConnections over a pion/transport virtual
+// network, with configurable latency, jitter and loss, so integration tests
+// run offline and reproducibly.
+package vnettest
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/transport/vnet"
+	"github.com/pion/webrtc/v2"
+)
+
+// Config describes the virtual network
+type Config struct {
+	// CIDR is the subnet of the PeerConnections, 1.2.3.0/24 if empty
+	CIDR string
+	// MinDelay is the latency added to every packet, and MaxJitter the
+	// maximum random delay added on top of it
+	MinDelay  time.Duration
+	MaxJitter time.Duration
+	// LossPercent is the percentage, from 0 to 100, of the packets dropped
+	LossPercent int
+	// Seed seeds the losses, the same seed drops the same packets of the
+	// same traffic
+	Seed int64
+	// LoggerFactory is used by the router, the default one if nil
+	LoggerFactory logging.LoggerFactory
+}
+
+// Network is a virtual network the PeerConnections are attached to
+type Network struct {
+	Router *vnet.Router
+}
+
+// New creates and starts a virtual network
+func New(config Config) (*Network, error) {
+	if config.CIDR == "" {
+		config.CIDR = "1.2.3.0/24"
+	}
+	if config.LoggerFactory == nil {
+		config.LoggerFactory = logging.NewDefaultLoggerFactory()
+	}
+
+	router, err := vnet.NewRouter(&vnet.RouterConfig{
+		CIDR:          config.CIDR,
+		MinDelay:      config.MinDelay,
+		MaxJitter:     config.MaxJitter,
+		LoggerFactory: config.LoggerFactory,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if config.LossPercent > 0 {
+		var lock sync.Mutex
+		random := rand.New(rand.NewSource(config.Seed)) // nolint: gosec
+		router.AddChunkFilter(func(vnet.Chunk) bool {
+			lock.Lock()
+			defer lock.Unlock()
+			return random.Intn(100) >= config.LossPercent
+		})
+	}
+
+	if err := router.Start(); err != nil {
+		return nil, err
+	}
+	return &Network{Router: router}, nil
+}
+
+// NewPeerConnection creates a PeerConnection attached to the network, with
+// a new address. The VNet of the SettingEngine is replaced, the other
+// settings are kept.
+func (n *Network) NewPeerConnection(s webrtc.SettingEngine, m webrtc.MediaEngine, configuration webrtc.Configuration) (*webrtc.PeerConnection, error) {
+	vn := vnet.NewNet(&vnet.NetConfig{})
+	if err := n.Router.AddNet(vn); err != nil {
+		return nil, err
+	}
+
+	s.SetVNet(vn)
+	return webrtc.NewAPI(webrtc.WithSettingEngine(s), webrtc.WithMediaEngine(m)).NewPeerConnection(configuration)
+}
+
+// NewPair creates two PeerConnections attached to the network, with the
+// default codecs and settings
+func (n *Network) NewPair(configuration webrtc.Configuration) (*webrtc.PeerConnection, *webrtc.PeerConnection, error) {
+	m := webrtc.MediaEngine{}
+	m.RegisterDefaultCodecs()
+
+	pcOffer, err := n.NewPeerConnection(webrtc.SettingEngine{}, m, configuration)
+	if err != nil {
+		return nil, nil, err
+	}
+	pcAnswer, err := n.NewPeerConnection(webrtc.SettingEngine{}, m, configuration)
+	if err != nil {
+		_ = pcOffer.Close()
+		return nil, nil, err
+	}
+	return pcOffer, pcAnswer, nil
+}
+
+// Signal negotiates the PeerConnections, exchanging the descriptions once
+// their candidates are gathered
+func Signal(pcOffer, pcAnswer *webrtc.PeerConnection) error {
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		return err
+	}
+	offerGatheringComplete := pcOffer.GatheringCompletePromise()
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		return err
+	}
+	<-offerGatheringComplete
+	if err = pcAnswer.SetRemoteDescription(*pcOffer.LocalDescription()); err != nil {
+		return err
+	}
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+	answerGatheringComplete := pcAnswer.GatheringCompletePromise()
+	if err = pcAnswer.SetLocalDescription(answer); err != nil {
+		return err
+	}
+	<-answerGatheringComplete
+	return pcOffer.SetRemoteDescription(*pcAnswer.LocalDescription())
+}
+
+// Close stops the network
+func (n *Network) Close() error {
+	return n.Router.Stop()
+}
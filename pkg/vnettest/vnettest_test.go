@@ -0,0 +1,51 @@
+// +build !js
+
+package vnettest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetwork(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	n, err := New(Config{MinDelay: 10 * time.Millisecond, MaxJitter: 5 * time.Millisecond, LossPercent: 5})
+	assert.NoError(t, err)
+
+	pcOffer, pcAnswer, err := n.NewPair(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	received := make(chan string)
+	pcAnswer.OnDataChannel(func(d *webrtc.DataChannel) {
+		d.OnMessage(func(msg webrtc.DataChannelMessage) {
+			received <- string(msg.Data)
+		})
+	})
+
+	d, err := pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	d.OnOpen(func() {
+		assert.NoError(t, d.SendText("hello"))
+	})
+
+	assert.NoError(t, Signal(pcOffer, pcAnswer))
+	assert.Equal(t, "hello", <-received)
+
+	// only the virtual addresses are used
+	assert.NotContains(t, pcOffer.LocalDescription().SDP, "127.0.0.1")
+	assert.True(t, strings.Contains(pcAnswer.LocalDescription().SDP, " 1.2.3."))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+	assert.NoError(t, n.Close())
+}
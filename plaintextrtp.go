@@ -0,0 +1,92 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"sync"
+
+	"github.com/pion/srtp"
+)
+
+// plaintextRTPConn carries the packets of a SRTP or SRTCP session in clear.
+// The packets written by the session are decrypted before being sent, with
+// the local keys, and the packets received are encrypted before being read
+// by the session, with the remote keys. The session is unchanged and both
+// endpoints must use it.
+type plaintextRTPConn struct {
+	net.Conn
+
+	isRTCP bool
+
+	// contexts mirror the ones of the session, they aren't safe for
+	// concurrent use
+	lock          sync.Mutex
+	localContext  *srtp.Context
+	remoteContext *srtp.Context
+}
+
+func newPlaintextRTPConn(conn net.Conn, config *srtp.Config, isRTCP bool) (*plaintextRTPConn, error) {
+	localContext, err := srtp.CreateContext(config.Keys.LocalMasterKey, config.Keys.LocalMasterSalt, config.Profile)
+	if err != nil {
+		return nil, err
+	}
+	remoteContext, err := srtp.CreateContext(config.Keys.RemoteMasterKey, config.Keys.RemoteMasterSalt, config.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &plaintextRTPConn{
+		Conn:          conn,
+		isRTCP:        isRTCP,
+		localContext:  localContext,
+		remoteContext: remoteContext,
+	}, nil
+}
+
+// Read reads a packet in clear and returns it encrypted, the packets that
+// can't be parsed are dropped like the session drops the ones it can't
+// decrypt
+func (c *plaintextRTPConn) Read(b []byte) (int, error) {
+	buf := make([]byte, len(b))
+	for {
+		n, err := c.Conn.Read(buf)
+		if err != nil {
+			return 0, err
+		}
+
+		c.lock.Lock()
+		var encrypted []byte
+		if c.isRTCP {
+			encrypted, err = c.remoteContext.EncryptRTCP(nil, buf[:n], nil)
+		} else {
+			encrypted, err = c.remoteContext.EncryptRTP(nil, buf[:n], nil)
+		}
+		c.lock.Unlock()
+		if err != nil || len(encrypted) > len(b) {
+			continue
+		}
+		return copy(b, encrypted), nil
+	}
+}
+
+// Write decrypts a packet and writes it in clear
+func (c *plaintextRTPConn) Write(b []byte) (int, error) {
+	c.lock.Lock()
+	var decrypted []byte
+	var err error
+	if c.isRTCP {
+		decrypted, err = c.localContext.DecryptRTCP(nil, b, nil)
+	} else {
+		decrypted, err = c.localContext.DecryptRTP(nil, b, nil)
+	}
+	c.lock.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = c.Conn.Write(decrypted); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
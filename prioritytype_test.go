@@ -0,0 +1,49 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPriorityType(t *testing.T) {
+	testCases := []struct {
+		priorityString   string
+		expectedPriority PriorityType
+	}{
+		{unknownStr, PriorityType(Unknown)},
+		{"very-low", PriorityTypeVeryLow},
+		{"low", PriorityTypeLow},
+		{"medium", PriorityTypeMedium},
+		{"high", PriorityTypeHigh},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedPriority,
+			newPriorityTypeFromString(testCase.priorityString),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestPriorityType_String(t *testing.T) {
+	testCases := []struct {
+		priority       PriorityType
+		expectedString string
+	}{
+		{PriorityType(Unknown), unknownStr},
+		{PriorityTypeVeryLow, "very-low"},
+		{PriorityTypeLow, "low"},
+		{PriorityTypeMedium, "medium"},
+		{PriorityTypeHigh, "high"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.priority.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
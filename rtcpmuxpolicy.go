@@ -9,6 +9,10 @@ const (
 	// RTP and RTCP candidates. If the remote-endpoint is capable of
 	// multiplexing RTCP, multiplex RTCP on the RTP candidates. If it is not,
 	// use both the RTP and RTCP candidates separately.
+	//
+	// Only multiplexed RTCP is currently supported, since a single ICE
+	// component is gathered: the remote media sections without rtcp-mux are
+	// rejected like with RTCPMuxPolicyRequire.
 	RTCPMuxPolicyNegotiate RTCPMuxPolicy = iota + 1
 
 	// RTCPMuxPolicyRequire indicates to gather ICE candidates only for
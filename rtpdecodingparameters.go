@@ -5,4 +5,12 @@ package webrtc
 // http://draft.ortc.org/#dom-rtcrtpdecodingparameters
 type RTPDecodingParameters struct {
 	RTPCodingParameters
+
+	// Paused reports a simulcast stream initially paused by the sender
+	Paused bool
+	// PayloadTypes restricts the payload types of the stream, if not empty
+	PayloadTypes []uint8
+	// Restrictions are the other restrictions of the stream signaled by its
+	// rid (max-width, max-height, max-fps...)
+	Restrictions map[string]string
 }
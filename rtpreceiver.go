@@ -18,6 +18,9 @@ type RTPReceiver struct {
 
 	track *Track
 
+	// parameters are the parameters provided to Receive
+	parameters RTPReceiveParameters
+
 	closed, received chan interface{}
 	mu               sync.RWMutex
 
@@ -83,6 +86,14 @@ func (r *RTPReceiver) Track() *Track {
 	return r.track
 }
 
+// GetParameters returns the parameters used to receive the track, with the
+// simulcast streams (if any) in the order signaled by the remote peer
+func (r *RTPReceiver) GetParameters() RTPReceiveParameters {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.parameters
+}
+
 // Receive initialize the track and starts all the transports
 func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 	r.mu.Lock()
@@ -98,6 +109,8 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 	}
 	defer close(r.received)
 
+	r.parameters = parameters
+
 	r.track = &Track{
 		kind:        r.kind,
 		streams:     make([]*TrackRTPStream, len(parameters.Encodings)),
@@ -298,6 +311,22 @@ func (r *RTPReceiver) Stop() error {
 	return nil
 }
 
+// writeRTCP sends a RTCP packet to the remote sender
+func (r *RTPReceiver) writeRTCP(raw []byte) error {
+	srtcpSession, err := r.transport.getSRTCPSession()
+	if err != nil {
+		return err
+	}
+
+	writeStream, err := srtcpSession.OpenWriteStream()
+	if err != nil {
+		return err
+	}
+
+	_, err = writeStream.Write(raw)
+	return err
+}
+
 func (r *RTPReceiver) readRTPStreamID(b []byte, streamID string) (n int, err error) {
 	// TODO(sgotti) implement replaceable read streams (when ssrc for a rid changes)
 	idx := r.streamsIndex[streamID]
@@ -4,8 +4,11 @@ package webrtc
 
 import (
 	"fmt"
+	mathRand "math/rand"
 	"sync"
+	"time"
 
+	"github.com/pion/logging"
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 	"github.com/pion/srtp"
@@ -23,9 +26,28 @@ type RTPSender struct {
 	// transceiver negotiation status
 	negotiated bool
 
+	// cname is the generated CNAME signaled for the track, the track label is
+	// used when empty
+	cname string
+
+	// voiceActivityDetection reports if the silence suppression has been
+	// enabled by the local description
+	voiceActivityDetection bool
+
+	// fecSSRC is the ssrc of the FlexFEC repair flow, it's 0 when FlexFEC
+	// isn't supported by the MediaEngine
+	fecSSRC uint32
+
+	// senderReportStreams are the outbound streams state used to generate
+	// the sender reports, keyed by ssrc
+	senderReportStreams   map[uint32]*senderReportStream
+	senderReportStreamsMu sync.Mutex
+
 	// A reference to the associated api object
 	api *API
 
+	log logging.LeveledLogger
+
 	mu                     sync.RWMutex
 	sendCalled, stopCalled chan interface{}
 }
@@ -38,6 +60,13 @@ func (api *API) NewRTPSender(track *Track, transport *DTLSTransport) (*RTPSender
 		return nil, fmt.Errorf("DTLSTransport must not be nil")
 	}
 
+	cname := ""
+	if generate := api.settingEngine.generators.CNAME; generate != nil {
+		if cname = generate(track); !isSDPValue(cname, maxCNAMELength) {
+			return nil, ErrInvalidCNAME
+		}
+	}
+
 	track.mu.Lock()
 	defer track.mu.Unlock()
 	if track.receiver != nil {
@@ -45,13 +74,31 @@ func (api *API) NewRTPSender(track *Track, transport *DTLSTransport) (*RTPSender
 	}
 	track.totalSenderCount++
 
-	return &RTPSender{
+	r := &RTPSender{
 		track:      track,
 		transport:  transport,
 		api:        api,
+		log:        api.settingEngine.LoggerFactory.NewLogger("ortc"),
+		cname:      cname,
 		sendCalled: make(chan interface{}),
 		stopCalled: make(chan interface{}),
-	}, nil
+
+		senderReportStreams: map[uint32]*senderReportStream{},
+
+		// the voice activity detection is enabled by default
+		voiceActivityDetection: true,
+	}
+	if api.mediaEngine != nil && api.mediaEngine.getCodecByName(track.kind, FlexFEC) != nil {
+		r.fecSSRC = mathRand.Uint32()
+	}
+
+	return r, nil
+}
+
+func (r *RTPSender) getFECSSRC() uint32 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.fecSSRC
 }
 
 func (r *RTPSender) isNegotiated() bool {
@@ -66,6 +113,23 @@ func (r *RTPSender) setNegotiated() {
 	r.negotiated = true
 }
 
+// VoiceActivityDetection reports if the voice activity detection has been
+// enabled for the sender media section by the last local description, it's
+// enabled unless the description was created with
+// DisableVoiceActivityDetection. The application should then suppress the
+// silence when encoding the samples, i.e. using the opus DTX.
+func (r *RTPSender) VoiceActivityDetection() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.voiceActivityDetection
+}
+
+func (r *RTPSender) setVoiceActivityDetection(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.voiceActivityDetection = enabled
+}
+
 // Transport returns the currently-configured *DTLSTransport or nil
 // if one has not yet been configured
 func (r *RTPSender) Transport() *DTLSTransport {
@@ -105,6 +169,10 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 	r.track.mu.Unlock()
 
 	close(r.sendCalled)
+
+	if interval := r.api.settingEngine.senderReportInterval; interval > 0 {
+		go r.sendReports(interval)
+	}
 	return nil
 }
 
@@ -177,10 +245,96 @@ func (r *RTPSender) SendRTP(header *rtp.Header, payload []byte) (int, error) {
 			return 0, err
 		}
 
+		if r.api.settingEngine.senderReportInterval > 0 {
+			r.updateSenderReportStream(header, len(payload))
+		}
+
 		return writeStream.WriteRTP(header, payload)
 	}
 }
 
+// updateSenderReportStream updates the state of the outbound stream of the
+// packet
+func (r *RTPSender) updateSenderReportStream(header *rtp.Header, payloadLen int) {
+	r.senderReportStreamsMu.Lock()
+	defer r.senderReportStreamsMu.Unlock()
+
+	stream, ok := r.senderReportStreams[header.SSRC]
+	if !ok {
+		clockRate := r.streamClockRate(header.SSRC)
+		if clockRate == 0 {
+			// not a track stream (i.e. a repair flow)
+			return
+		}
+		stream = &senderReportStream{clockRate: clockRate}
+		r.senderReportStreams[header.SSRC] = stream
+	}
+
+	stream.update(header, payloadLen, time.Now())
+}
+
+// streamClockRate returns the clock rate of the track stream with the
+// provided ssrc, 0 if there isn't such a stream
+func (r *RTPSender) streamClockRate(ssrc uint32) uint32 {
+	r.track.mu.RLock()
+	defer r.track.mu.RUnlock()
+
+	for _, stream := range r.track.streams {
+		if stream.ssrc == ssrc && stream.codec != nil {
+			return stream.codec.ClockRate
+		}
+	}
+	return 0
+}
+
+// sendReports periodically sends the sender reports of the outbound streams
+// until the RTPSender is stopped, the failed reports are logged and dropped
+func (r *RTPSender) sendReports(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopCalled:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		reports := []rtcp.Packet{}
+		r.senderReportStreamsMu.Lock()
+		for ssrc, stream := range r.senderReportStreams {
+			if report, ok := stream.report(ssrc, now); ok {
+				reports = append(reports, report)
+			}
+		}
+		r.senderReportStreamsMu.Unlock()
+		if len(reports) == 0 {
+			continue
+		}
+
+		raw, err := rtcp.Marshal(reports)
+		if err != nil {
+			r.log.Warnf("Failed to marshal sender reports: %s", err)
+			continue
+		}
+
+		srtcpSession, err := r.transport.getSRTCPSession()
+		if err != nil {
+			r.log.Warnf("Failed to send sender reports: %s", err)
+			continue
+		}
+		writeStream, err := srtcpSession.OpenWriteStream()
+		if err != nil {
+			r.log.Warnf("Failed to send sender reports: %s", err)
+			continue
+		}
+		if _, err := writeStream.Write(raw); err != nil {
+			r.log.Warnf("Failed to send sender reports: %s", err)
+		}
+	}
+}
+
 // hasSent tells if data has been ever sent for this instance
 func (r *RTPSender) hasSent() bool {
 	select {
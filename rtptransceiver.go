@@ -16,6 +16,7 @@ type RTPTransceiver struct {
 	receiver        atomic.Value // *RTPReceiver
 	direction       atomic.Value // RTPTransceiverDirection
 	remoteDirection atomic.Value // RTPTransceiverDirection
+	codec           atomic.Value // *RTPCodec
 
 	negotiationData atomic.Value
 
@@ -24,10 +25,51 @@ type RTPTransceiver struct {
 	// extMaps are the negotiated extmaps by media section
 	extMaps map[int]*sdp.ExtMap
 
+	// rejected reports if the transceiver media section was rejected in
+	// the last negotiation
+	rejected atomicBool
+
+	// extMapAllowMixed reports if the negotiated media section allows mixing
+	// one-byte and two-byte RTP header extensions
+	extMapAllowMixed atomicBool
+
+	// createdByRemote reports if the transceiver was created to receive
+	// a media section of a remote offer
+	createdByRemote bool
+
+	// recycled is the stopped media section whose m-line has been reused by
+	// the remote offer for the transceiver one, restored by a rollback and
+	// released when the answer is applied
+	recycled *recycledMediaSection
+
+	// onStopHandler is called when the transceiver is stopped, it reports
+	// the change to the PeerConnection transceiver feed
+	onStopHandler func()
+
 	stopped bool
 	kind    RTPCodecType
 }
 
+// recycledMediaSection is the state of a recycled transceiver in its stopped
+// media section, the receiver and sender are set if they have been replaced
+type recycledMediaSection struct {
+	mid      string
+	receiver *RTPReceiver
+	sender   *RTPSender
+}
+
+func (r *recycledMediaSection) stop() error {
+	if r.receiver != nil {
+		if err := r.receiver.Stop(); err != nil {
+			return err
+		}
+	}
+	if r.sender != nil {
+		return r.sender.Stop()
+	}
+	return nil
+}
+
 // Sender returns the RTPTransceiver's RTPSender if it has one
 func (t *RTPTransceiver) Sender() *RTPSender {
 	if v := t.sender.Load(); v != nil {
@@ -95,6 +137,39 @@ func (t *RTPTransceiver) getRemoteDirection() RTPTransceiverDirection {
 	return RTPTransceiverDirection(Unknown)
 }
 
+// getCodec returns the codec negotiated for the transceiver media section,
+// nil before the negotiation
+func (t *RTPTransceiver) getCodec() *RTPCodec {
+	if v := t.codec.Load(); v != nil {
+		return v.(*RTPCodec)
+	}
+	return nil
+}
+
+func (t *RTPTransceiver) setCodec(codec *RTPCodec) {
+	t.codec.Store(codec)
+}
+
+// ExtMapAllowMixed reports if the negotiated media section allows mixing
+// one-byte and two-byte RTP header extensions in a RTP stream (RFC 8285).
+// Without it all the packets written by the sender must use the same header
+// extension format.
+func (t *RTPTransceiver) ExtMapAllowMixed() bool {
+	return t.extMapAllowMixed.get()
+}
+
+func (t *RTPTransceiver) setExtMapAllowMixed(allowMixed bool) {
+	t.extMapAllowMixed.set(allowMixed)
+}
+
+func (t *RTPTransceiver) isRejected() bool {
+	return t.rejected.get()
+}
+
+func (t *RTPTransceiver) setRejected(rejected bool) {
+	t.rejected.set(rejected)
+}
+
 // Stop irreversibly stops the RTPTransceiver
 func (t *RTPTransceiver) Stop() error {
 	if t.Sender() != nil {
@@ -109,6 +184,9 @@ func (t *RTPTransceiver) Stop() error {
 	}
 
 	t.setDirection(RTPTransceiverDirectionInactive)
+	if t.onStopHandler != nil {
+		t.onStopHandler()
+	}
 	return nil
 }
 
@@ -155,6 +233,36 @@ func findByMid(mid string, localTransceivers []*RTPTransceiver) (*RTPTransceiver
 	return nil, localTransceivers
 }
 
+// findRecyclable plucks from the passed list a transceiver of the provided
+// kind whose media section has been stopped and removed from the remote
+// description, so its m-line has been reused for another media section
+func findRecyclable(kind RTPCodecType, remoteMids map[string]bool, localTransceivers []*RTPTransceiver) (*RTPTransceiver, []*RTPTransceiver) {
+	for i, t := range localTransceivers {
+		if mid := t.Mid(); mid != "" && !remoteMids[mid] && t.kind == kind && t.isRejected() {
+			return t, append(localTransceivers[:i], localTransceivers[i+1:]...)
+		}
+	}
+
+	return nil, localTransceivers
+}
+
+// answerDirection returns the direction of the media section answering an
+// offered one: it sends only when the remote peer receives and a local track
+// is sent, and it receives only what the remote peer sends (JSEP 5.3.1)
+func answerDirection(transceivers []*RTPTransceiver, offered RTPTransceiverDirection) RTPTransceiverDirection {
+	send, recv := false, false
+	for _, t := range transceivers {
+		direction := t.Direction()
+		if direction.hasSend() && t.Sender() != nil && t.Sender().Track() != nil {
+			send = true
+		}
+		if direction.hasRecv() {
+			recv = true
+		}
+	}
+	return newRTPTransceiverDirection(send && offered.hasRecv(), recv && offered.hasSend())
+}
+
 // Given a direction+type pluck a transceiver from the passed list
 // if no entry satisfies the requested type+direction return a inactive Transceiver
 func satisfyTypeAndDirection(remoteKind RTPCodecType, remoteDirection RTPTransceiverDirection, localTransceivers []*RTPTransceiver) (*RTPTransceiver, []*RTPTransceiver) {
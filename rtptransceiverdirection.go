@@ -60,3 +60,25 @@ func (t RTPTransceiverDirection) String() string {
 		return ErrUnknownType.Error()
 	}
 }
+
+// newRTPTransceiverDirection returns the direction sending and receiving as
+// requested
+func newRTPTransceiverDirection(send, recv bool) RTPTransceiverDirection {
+	switch {
+	case send && recv:
+		return RTPTransceiverDirectionSendrecv
+	case send:
+		return RTPTransceiverDirectionSendonly
+	case recv:
+		return RTPTransceiverDirectionRecvonly
+	}
+	return RTPTransceiverDirectionInactive
+}
+
+func (t RTPTransceiverDirection) hasSend() bool {
+	return t == RTPTransceiverDirectionSendrecv || t == RTPTransceiverDirectionSendonly
+}
+
+func (t RTPTransceiverDirection) hasRecv() bool {
+	return t == RTPTransceiverDirectionSendrecv || t == RTPTransceiverDirectionRecvonly
+}
@@ -17,6 +17,15 @@ import (
 
 const sctpMaxChannels = uint16(65535)
 
+const (
+	// sctpMaxMessageSize is the largest message pion/sctp can send and the
+	// DataChannels can receive, it's advertised with a=max-message-size
+	sctpMaxMessageSize = math.MaxUint16
+	// sctpDefaultMaxMessageSize is the limit of a remote peer that doesn't
+	// advertise a=max-message-size (RFC 8841 6)
+	sctpDefaultMaxMessageSize = 65536
+)
+
 // SCTPTransport provides details about the SCTP transport.
 type SCTPTransport struct {
 	lock sync.RWMutex
@@ -63,7 +72,7 @@ func (api *API) NewSCTPTransport(dtls *DTLSTransport) *SCTPTransport {
 		log:           api.settingEngine.LoggerFactory.NewLogger("ortc"),
 	}
 
-	res.updateMessageSize()
+	res.updateMessageSize(sctpDefaultMaxMessageSize)
 	res.updateMaxChannels()
 
 	return res
@@ -80,21 +89,25 @@ func (r *SCTPTransport) Transport() *DTLSTransport {
 // GetCapabilities returns the SCTPCapabilities of the SCTPTransport.
 func (r *SCTPTransport) GetCapabilities() SCTPCapabilities {
 	return SCTPCapabilities{
-		MaxMessageSize: 0,
+		MaxMessageSize: sctpMaxMessageSize,
 	}
 }
 
 // Start the SCTPTransport. Since both local and remote parties must mutually
 // create an SCTPTransport, SCTP SO (Simultaneous Open) is used to establish
-// a connection over SCTP.
+// a connection over SCTP. A zero remoteCaps.MaxMessageSize means the remote
+// peer doesn't limit the size of the messages.
 func (r *SCTPTransport) Start(remoteCaps SCTPCapabilities) error {
 	if err := r.ensureDTLS(); err != nil {
 		return err
 	}
 
+	r.updateMessageSize(remoteCaps.MaxMessageSize)
+
 	sctpAssociation, err := sctp.Client(sctp.Config{
-		NetConn:       r.Transport().conn,
-		LoggerFactory: r.api.settingEngine.LoggerFactory,
+		NetConn:              r.Transport().conn,
+		MaxReceiveBufferSize: r.api.settingEngine.sctp.MaxReceiveBufferSize,
+		LoggerFactory:        r.api.settingEngine.LoggerFactory,
 	})
 	if err != nil {
 		return err
@@ -151,6 +164,14 @@ func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
 			return
 		}
 
+		if sid := dc.StreamIdentifier(); sid >= r.MaxChannels() {
+			r.log.Warnf("Rejecting data channel %d above the maximum number of channels", sid)
+			if err = dc.Close(); err != nil {
+				r.log.Warnf("Failed to close the rejected data channel: %v", err)
+			}
+			continue
+		}
+
 		var ordered = true
 		var maxRetransmits *uint16
 		var maxPacketLifeTime *uint16
@@ -185,6 +206,7 @@ func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
 			Ordered:           ordered,
 			MaxPacketLifeTime: maxPacketLifeTime,
 			MaxRetransmits:    maxRetransmits,
+			Priority:          newPriorityTypeFromChannelPriority(dc.Config.Priority),
 		}, r.api.settingEngine.LoggerFactory.NewLogger("ortc"))
 
 		if err != nil {
@@ -192,6 +214,7 @@ func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
 			r.onError(err)
 			return
 		}
+		rtcDC.sctpTransport = r
 
 		<-r.onDataChannel(rtcDC)
 		rtcDC.handleOpen(dc)
@@ -264,14 +287,11 @@ func (r *SCTPTransport) onDataChannel(dc *DataChannel) (done chan struct{}) {
 	return
 }
 
-func (r *SCTPTransport) updateMessageSize() {
+func (r *SCTPTransport) updateMessageSize(remoteMaxMessageSize uint32) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	var remoteMaxMessageSize float64 = 65536 // pion/webrtc#758
-	var canSendSize float64 = 65536          // pion/webrtc#758
-
-	r.maxMessageSize = r.calcMessageSize(remoteMaxMessageSize, canSendSize)
+	r.maxMessageSize = r.calcMessageSize(float64(remoteMaxMessageSize), sctpMaxMessageSize)
 }
 
 func (r *SCTPTransport) calcMessageSize(remoteMaxMessageSize, canSendSize float64) float64 {
@@ -294,12 +314,40 @@ func (r *SCTPTransport) calcMessageSize(remoteMaxMessageSize, canSendSize float6
 	}
 }
 
+// updateMaxChannels sets the MaxChannels to the limit of the SettingEngine.
+//
+// TODO advertise the limit in the INIT chunk and use the stream counts
+// negotiated with the remote peer, pion/sctp always offers 65535 streams and
+// doesn't expose the negotiated counts.
 func (r *SCTPTransport) updateMaxChannels() {
 	val := sctpMaxChannels
+	if max := r.api.settingEngine.sctp.MaxChannels; max != 0 && max < val {
+		val = max
+	}
 	r.maxChannels = &val
 }
 
+// MaxMessageSize is the maximum size of the messages that can be sent on the
+// DataChannels, the smallest of the limits of pion/sctp and of the remote
+// peer.
+func (r *SCTPTransport) MaxMessageSize() float64 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.maxMessageSize
+}
+
+// ensureMessageSize returns an error if a message is larger than the
+// MaxMessageSize
+func (r *SCTPTransport) ensureMessageSize(size int) error {
+	if float64(size) > r.MaxMessageSize() {
+		return &rtcerr.TypeError{Err: ErrMessageTooLarge}
+	}
+	return nil
+}
+
 // MaxChannels is the maximum number of RTCDataChannels that can be open simultaneously.
+// It's 65535, or the limit set with SettingEngine.SetSCTPMaxChannels.
 func (r *SCTPTransport) MaxChannels() uint16 {
 	r.lock.Lock()
 	defer r.lock.Unlock()
@@ -321,6 +369,8 @@ func (r *SCTPTransport) State() SCTPTransportState {
 func (r *SCTPTransport) collectStats(collector *statsReportCollector) {
 	r.lock.Lock()
 	association := r.association
+	dtlsTransport := r.dtlsTransport
+	dataChannels := append([]*DataChannel{}, r.dataChannels...)
 	r.lock.Unlock()
 
 	collector.Collecting()
@@ -328,27 +378,62 @@ func (r *SCTPTransport) collectStats(collector *statsReportCollector) {
 	stats := TransportStats{
 		Timestamp: statsTimestampFrom(time.Now()),
 		Type:      StatsTypeTransport,
-		ID:        "sctpTransport",
+		ID:        sctpTransportStatsID,
 	}
 
-	if association != nil {
-		stats.BytesSent = association.BytesSent()
-		stats.BytesReceived = association.BytesReceived()
+	if dtlsTransport != nil {
+		stats.DTLSState = dtlsTransport.State()
 	}
 
+	if association == nil {
+		collector.Collect(stats.ID, stats)
+		return
+	}
+
+	stats.BytesSent = association.BytesSent()
+	stats.BytesReceived = association.BytesReceived()
+
+	associationStats := SCTPTransportStats{
+		Timestamp:     stats.Timestamp,
+		Type:          StatsTypeSCTPTransport,
+		ID:            sctpAssociationStatsID,
+		TransportID:   sctpTransportStatsID,
+		BytesSent:     stats.BytesSent,
+		BytesReceived: stats.BytesReceived,
+	}
+	for _, d := range dataChannels {
+		if d.ReadyState() == DataChannelStateOpen {
+			associationStats.DataChannelsOpen++
+		}
+		associationStats.BufferedAmount += d.BufferedAmount()
+	}
+
+	collector.Collecting()
 	collector.Collect(stats.ID, stats)
+	collector.Collect(associationStats.ID, associationStats)
 }
 
-func (r *SCTPTransport) generateAndSetDataChannelID(dtlsRole DTLSRole, idOut **uint16) error {
-	isChannelWithID := func(id uint16) bool {
-		for _, d := range r.dataChannels {
-			if d.id != nil && *d.id == id {
-				return true
-			}
+const (
+	// sctpTransportStatsID is the ID of the TransportStats of the
+	// SCTPTransport, the transport of the DataChannelStats
+	sctpTransportStatsID = "sctpTransport"
+
+	// sctpAssociationStatsID is the ID of the SCTPTransportStats
+	sctpAssociationStatsID = "sctpAssociation"
+)
+
+// isChannelWithID reports if a DataChannel already uses the stream id.
+// The caller should hold the lock.
+func (r *SCTPTransport) isChannelWithID(id uint16) bool {
+	for _, d := range r.dataChannels {
+		if d.id != nil && *d.id == id {
+			return true
 		}
-		return false
 	}
+	return false
+}
 
+func (r *SCTPTransport) generateAndSetDataChannelID(dtlsRole DTLSRole, idOut **uint16) error {
 	var id uint16
 	if dtlsRole != DTLSRoleClient {
 		id++
@@ -358,13 +443,34 @@ func (r *SCTPTransport) generateAndSetDataChannelID(dtlsRole DTLSRole, idOut **u
 
 	r.lock.Lock()
 	defer r.lock.Unlock()
-	for ; id < max-1; id += 2 {
-		if isChannelWithID(id) {
+	for ; id < max; id += 2 {
+		if r.isChannelWithID(id) {
 			continue
 		}
 		*idOut = &id
 		return nil
 	}
 
-	return &rtcerr.OperationError{Err: ErrMaxDataChannelID}
+	return &rtcerr.OperationError{Err: ErrMaxDataChannels}
+}
+
+// ensureChannelCount returns an error if the DataChannels not closed reach
+// the MaxChannels
+func (r *SCTPTransport) ensureChannelCount() error {
+	max := r.MaxChannels()
+
+	r.lock.RLock()
+	dataChannels := append([]*DataChannel{}, r.dataChannels...)
+	r.lock.RUnlock()
+
+	var count uint16
+	for _, d := range dataChannels {
+		if d.ReadyState() != DataChannelStateClosed {
+			count++
+		}
+	}
+	if count >= max {
+		return &rtcerr.OperationError{Err: ErrMaxDataChannels}
+	}
+	return nil
 }
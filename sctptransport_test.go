@@ -2,7 +2,12 @@
 
 package webrtc
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
 
 func TestGenerateDataChannelID(t *testing.T) {
 	sctpTransportWithChannels := func(ids []uint16) *SCTPTransport {
@@ -44,3 +49,66 @@ func TestGenerateDataChannelID(t *testing.T) {
 		}
 	}
 }
+
+func TestSCTPTransport_MaxMessageSize(t *testing.T) {
+	r := &SCTPTransport{}
+
+	for _, testCase := range []struct {
+		remoteMaxMessageSize uint32
+		maxMessageSize       float64
+	}{
+		{0, sctpMaxMessageSize},
+		{1024, 1024},
+		{sctpDefaultMaxMessageSize, sctpMaxMessageSize},
+	} {
+		r.updateMessageSize(testCase.remoteMaxMessageSize)
+		if r.MaxMessageSize() != testCase.maxMessageSize {
+			t.Errorf("Wrong max message size: %v expected %v", r.MaxMessageSize(), testCase.maxMessageSize)
+		}
+	}
+
+	if err := r.ensureMessageSize(1024); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := r.ensureMessageSize(sctpMaxMessageSize + 1); err == nil {
+		t.Errorf("Expected an error for the message larger than %v", r.MaxMessageSize())
+	}
+}
+
+func TestSCTPTransport_MaxChannels(t *testing.T) {
+	s := SettingEngine{}
+	s.SetSCTPMaxChannels(4)
+	r := &SCTPTransport{api: NewAPI(WithSettingEngine(s))}
+	r.updateMaxChannels()
+
+	if r.MaxChannels() != 4 {
+		t.Fatalf("Wrong max channels: %d expected 4", r.MaxChannels())
+	}
+
+	for _, testCase := range []struct {
+		role DTLSRole
+		ids  []uint16
+		id   uint16
+	}{
+		{DTLSRoleClient, []uint16{0}, 2},
+		{DTLSRoleServer, []uint16{1}, 3},
+	} {
+		id := testCase.ids[0]
+		r.dataChannels = []*DataChannel{{id: &id}}
+		idPtr := new(uint16)
+		if err := r.generateAndSetDataChannelID(testCase.role, &idPtr); err != nil {
+			t.Fatalf("failed to generate id: %v", err)
+		}
+		if *idPtr != testCase.id {
+			t.Errorf("Wrong id: %d expected %d", *idPtr, testCase.id)
+		}
+
+		// the next id would be above the limit
+		id2 := testCase.id
+		r.dataChannels = append(r.dataChannels, &DataChannel{id: &id2})
+		err := r.generateAndSetDataChannelID(testCase.role, &idPtr)
+		if !reflect.DeepEqual(err, &rtcerr.OperationError{Err: ErrMaxDataChannels}) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+}
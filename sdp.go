@@ -5,6 +5,7 @@ package webrtc
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -17,15 +18,28 @@ const (
 
 	sdesMidURI         = "urn:ietf:params:rtp-hdrext:sdes:mid"
 	sdesRTPStreamIDURI = "urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id"
+
+	// semanticTokenFECFR is the ssrc-group semantic used by FlexFEC to
+	// associate a FEC repair flow to its source flow (RFC 5956)
+	semanticTokenFECFR = "FEC-FR"
 )
 
 type streamDetails struct {
 	rid  string
 	ssrc uint32
 
+	// fecSSRC is the ssrc of the FlexFEC repair flow protecting this stream
+	fecSSRC uint32
+
 	trackID string
 	msid    string
 	mstid   string
+
+	// paused, payloadTypes and restrictions are the simulcast stream
+	// parameters provided by its a=simulcast and a=rid attributes
+	paused       bool
+	payloadTypes []uint8
+	restrictions map[string]string
 }
 
 type trackDetails struct {
@@ -38,12 +52,15 @@ type trackDetails struct {
 
 	ridStreams  map[string]*streamDetails
 	ssrcStreams map[uint32]*streamDetails
+	// rids are the ridStreams keys in the simulcast order
+	rids []string
 }
 
 // extract all trackDetails from an SDP.
 func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription, isPlanB bool) map[string]trackDetails {
 	incomingTracks := map[string]trackDetails{}
 	rtxRepairFlows := map[uint32]bool{}
+	fecRepairFlows := map[uint32]bool{}
 
 	for _, media := range s.MediaDescriptions {
 		// Plan B can have multiple tracks in a single media section
@@ -51,7 +68,9 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription, i
 		mstid := ""
 		extMaps := map[int]*sdp.ExtMap{}
 		ridStreams := map[string]*streamDetails{}
+		rids := []string{}
 		ssrcStreams := map[uint32]*streamDetails{}
+		fecSSRCs := map[uint32]uint32{}
 
 		// If media section is recvonly or inactive skip
 		if _, ok := media.Attribute(sdp.AttrKeyRecvOnly); ok {
@@ -68,7 +87,13 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription, i
 				continue
 			}
 
-			_, useRid = media.Attribute("rid")
+			ridDescriptions, simulcast, err := getSimulcast(media)
+			if err != nil {
+				log.Warnf("Failed to parse simulcast: %v", err)
+				continue
+			}
+			rids, ridStreams = simulcastStreamDetails(ridDescriptions, simulcast)
+			useRid = len(rids) > 0
 		}
 
 		codecType := NewRTPCodecType(media.MediaName.Media)
@@ -100,6 +125,24 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription, i
 						//delete(incomingTracks, uint32(rtxRepairFlow)) // Remove if rtx was added as track before
 					}
 				}
+				if split[0] == semanticTokenFECFR {
+					// Lines like `a=ssrc-group:FEC-FR 2231627014 632943048` declare that the second SSRC
+					// (632943048) is a FlexFEC repair flow for the first (2231627014) as specified in RFC5956
+					if len(split) == 3 {
+						sourceFlow, err := strconv.ParseUint(split[1], 10, 32)
+						if err != nil {
+							log.Warnf("Failed to parse SSRC: %v", err)
+							continue
+						}
+						fecRepairFlow, err := strconv.ParseUint(split[2], 10, 32)
+						if err != nil {
+							log.Warnf("Failed to parse SSRC: %v", err)
+							continue
+						}
+						fecRepairFlows[uint32(fecRepairFlow)] = true
+						fecSSRCs[uint32(sourceFlow)] = uint32(fecRepairFlow)
+					}
+				}
 
 			// Handle `a=msid:<stream_id> <track_label>` for Unified plan. The first value is the same as MediaStream.id
 			// in the browser and can be used to figure out which tracks belong to the same stream. The browser should
@@ -111,13 +154,6 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription, i
 					mstid = split[1]
 				}
 
-			// TODO(sgotti) define this in pion/sdp
-			case "rid":
-				split := strings.Split(attr.Value, " ")
-				rid := split[0]
-
-				ridStreams[rid] = &streamDetails{rid: rid}
-
 			case sdp.AttrKeySSRC:
 				if useRid {
 					log.Warnf("ignoring provided SSRC since we're using rid attributes")
@@ -160,6 +196,15 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription, i
 				// This ssrc is a RTX repair flow, ignore
 				delete(ssrcStreams, ssrc)
 			}
+			if fecRepairFlow := fecRepairFlows[ssrc]; fecRepairFlow {
+				// This ssrc is a FEC repair flow, ignore
+				delete(ssrcStreams, ssrc)
+			}
+		}
+		for ssrc, fecSSRC := range fecSSRCs {
+			if stream, ok := ssrcStreams[ssrc]; ok {
+				stream.fecSSRC = fecSSRC
+			}
 		}
 
 		if isPlanB {
@@ -191,6 +236,7 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription, i
 				useRid:      useRid,
 				ridStreams:  ridStreams,
 				ssrcStreams: ssrcStreams,
+				rids:        rids,
 			}
 		}
 	}
@@ -198,6 +244,39 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription, i
 	return incomingTracks
 }
 
+// simulcastStreamDetails returns the details of the streams sent by the
+// remote peer, and their rids in the simulcast order
+func simulcastStreamDetails(ridDescriptions map[string]*ridDescription, simulcast *simulcastDescription) ([]string, map[string]*streamDetails) {
+	rids := []string{}
+	ridStreams := map[string]*streamDetails{}
+	add := func(rid *ridDescription, paused bool) {
+		if rid.direction != ridDirectionSend {
+			return
+		}
+		if _, ok := ridStreams[rid.id]; ok {
+			return
+		}
+		rids = append(rids, rid.id)
+		ridStreams[rid.id] = &streamDetails{
+			rid:          rid.id,
+			paused:       paused,
+			payloadTypes: rid.payloadTypes,
+			restrictions: rid.restrictions,
+		}
+	}
+
+	// the rids not listed by the simulcast description are ignored
+	// (RFC 8853 section 5.1)
+	if simulcast != nil {
+		for _, alternatives := range simulcast.send {
+			for _, stream := range alternatives {
+				add(ridDescriptions[stream.rid], stream.paused)
+			}
+		}
+	}
+	return rids, ridStreams
+}
+
 func addCandidatesToMediaDescriptions(candidates []ICECandidate, m *sdp.MediaDescription, iceGatheringState ICEGatheringState) {
 	appendCandidateIfNew := func(c sdp.ICECandidate, attributes []sdp.Attribute) {
 		marshaled := c.Marshal()
@@ -232,7 +311,99 @@ func addCandidatesToMediaDescriptions(candidates []ICECandidate, m *sdp.MediaDes
 	m.WithPropertyAttribute("end-of-candidates")
 }
 
-func addDataMediaSection(d *sdp.SessionDescription, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, iceGatheringState ICEGatheringState) {
+// isCandidateAttribute reports if the attribute is added with the gathered
+// candidates
+func isCandidateAttribute(attr sdp.Attribute) bool {
+	return attr.Key == "candidate" || attr.Key == "end-of-candidates"
+}
+
+// sameMediaSection reports if two media sections are equal, apart from the
+// candidates and the attributes order
+func sameMediaSection(a, b *sdp.MediaDescription) bool {
+	content := func(media *sdp.MediaDescription) string {
+		m := *media
+		m.Attributes = []sdp.Attribute{}
+		for _, attr := range media.Attributes {
+			if !isCandidateAttribute(attr) {
+				m.Attributes = append(m.Attributes, attr)
+			}
+		}
+		sort.Slice(m.Attributes, func(i, j int) bool {
+			return m.Attributes[i].Key+":"+m.Attributes[i].Value < m.Attributes[j].Key+":"+m.Attributes[j].Value
+		})
+
+		marshaled, err := (&sdp.SessionDescription{MediaDescriptions: []*sdp.MediaDescription{&m}}).Marshal()
+		if err != nil {
+			return ""
+		}
+		return string(marshaled)
+	}
+
+	contentA := content(a)
+	return contentA != "" && contentA == content(b)
+}
+
+// reusePreviousDescription updates a subsequent local description to be
+// minimally different from the previous one: the media sections whose
+// content didn't change keep the previous attributes order, and the origin
+// keeps the session id with the version incremented only when something
+// changed (JSEP 5.2.2)
+func reusePreviousDescription(d, previousDescription *sdp.SessionDescription) {
+	previous := cloneSessionDescription(previousDescription)
+
+	previousMedias := map[string]*sdp.MediaDescription{}
+	for _, media := range previous.MediaDescriptions {
+		if mid := getMidValue(media); mid != "" {
+			previousMedias[mid] = media
+		}
+	}
+
+	changed := len(d.MediaDescriptions) != len(previous.MediaDescriptions)
+	for i, media := range d.MediaDescriptions {
+		previousMedia, ok := previousMedias[getMidValue(media)]
+		if !ok || !sameMediaSection(media, previousMedia) {
+			changed = true
+			continue
+		}
+
+		// keep the current candidates, they could have been gathered after
+		// the previous description
+		attributes := []sdp.Attribute{}
+		for _, attr := range previousMedia.Attributes {
+			if !isCandidateAttribute(attr) {
+				attributes = append(attributes, attr)
+			}
+		}
+		for _, attr := range media.Attributes {
+			if isCandidateAttribute(attr) {
+				attributes = append(attributes, attr)
+			}
+		}
+		previousMedia.Attributes = attributes
+		d.MediaDescriptions[i] = previousMedia
+	}
+
+	d.Origin = previous.Origin
+	if changed || !sameSessionAttributes(d, previous) {
+		d.Origin.SessionVersion++
+	}
+}
+
+// sameSessionAttributes reports if two descriptions have the same session
+// level attributes
+func sameSessionAttributes(a, b *sdp.SessionDescription) bool {
+	if len(a.Attributes) != len(b.Attributes) {
+		return false
+	}
+	for i := range a.Attributes {
+		if a.Attributes[i] != b.Attributes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func addDataMediaSection(d *sdp.SessionDescription, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, iceGatheringState ICEGatheringState, bundleOnly bool) {
 	media := (&sdp.MediaDescription{
 		MediaName: sdp.MediaName{
 			Media:   mediaSectionApplication,
@@ -252,12 +423,145 @@ func addDataMediaSection(d *sdp.SessionDescription, midValue string, iceParams I
 		WithValueAttribute(sdp.AttrKeyMID, midValue).
 		WithPropertyAttribute(RTPTransceiverDirectionSendrecv.String()).
 		WithPropertyAttribute("sctpmap:5000 webrtc-datachannel 1024").
+		WithValueAttribute(attributeMaxMessageSize, strconv.Itoa(sctpMaxMessageSize)).
 		WithICECredentials(iceParams.UsernameFragment, iceParams.Password)
 
-	addCandidatesToMediaDescriptions(candidates, media, iceGatheringState)
+	if bundleOnly {
+		setBundleOnly(media)
+	} else {
+		addCandidatesToMediaDescriptions(candidates, media, iceGatheringState)
+	}
 	d.WithMedia(media)
 }
 
+// setBundleOnly makes the media section usable only when bundled, it's
+// offered with port 0 and without candidates (RFC 8843 7.2.1)
+func setBundleOnly(media *sdp.MediaDescription) {
+	media.MediaName.Port = sdp.RangedPort{Value: 0}
+	media.WithPropertyAttribute(attributeBundleOnly)
+}
+
+// isMediaSectionRejected reports if the media section has been rejected, a
+// media section with port 0 is rejected unless it's bundle-only
+func isMediaSectionRejected(media *sdp.MediaDescription) bool {
+	if media.MediaName.Port.Value != 0 {
+		return false
+	}
+	_, bundleOnly := media.Attribute(attributeBundleOnly)
+	return !bundleOnly
+}
+
+// haveExtMapAllowMixed reports if the media section allows mixing one-byte and
+// two-byte RTP header extensions, at session or media level
+func haveExtMapAllowMixed(d *sdp.SessionDescription, media *sdp.MediaDescription) bool {
+	if _, ok := d.Attribute(attributeExtMapAllowMixed); ok {
+		return true
+	}
+	_, ok := media.Attribute(attributeExtMapAllowMixed)
+	return ok
+}
+
+// haveRTCPMux reports if the media section multiplexes RTCP on the RTP
+// transport. A bundled media section uses the RTCP multiplexing of the tagged
+// one, the first of its BUNDLE group, which is the only one required to carry
+// the rtcp-mux attribute (RFC 8843 7.1.3), the application media sections
+// don't carry RTCP.
+func haveRTCPMux(desc *sdp.SessionDescription, media *sdp.MediaDescription) bool {
+	if media.MediaName.Media == mediaSectionApplication || isMediaSectionRejected(media) {
+		return true
+	}
+	midValue := getMidValue(media)
+	if group := getBundleGroup(desc, midValue); group != nil && group[0] != midValue {
+		return true
+	}
+	_, ok := media.Attribute(sdp.AttrKeyRTCPMux)
+	return ok
+}
+
+// isSDPToken reports if s is a token (RFC 4566 9) of at most maxLength
+// characters
+func isSDPToken(s string, maxLength int) bool {
+	if len(s) == 0 || len(s) > maxLength {
+		return false
+	}
+	for _, c := range []byte(s) {
+		switch {
+		case c == 0x21, c >= 0x23 && c <= 0x27, c == 0x2A, c == 0x2B, c == 0x2D, c == 0x2E,
+			c >= 0x30 && c <= 0x39, c >= 0x41 && c <= 0x5A, c >= 0x5E && c <= 0x7E:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isSDPValue reports if s is a non empty attribute value with no whitespaces
+// and control characters, of at most maxLength bytes
+func isSDPValue(s string, maxLength int) bool {
+	if len(s) == 0 || len(s) > maxLength {
+		return false
+	}
+	for _, c := range []byte(s) {
+		if c <= 0x20 || c == 0x7F {
+			return false
+		}
+	}
+	return true
+}
+
+// haveBundleGroup reports if the session description has a BUNDLE group
+func haveBundleGroup(desc *sdp.SessionDescription) bool {
+	for _, a := range desc.Attributes {
+		if a.Key == sdp.AttrKeyGroup && strings.HasPrefix(a.Value, "BUNDLE ") {
+			return true
+		}
+	}
+	return false
+}
+
+// useSharedTransport reports if the media section at the index uses the
+// single transport: the media sections of the first BUNDLE group do, or only
+// the first media section without BUNDLE
+func useSharedTransport(desc *sdp.SessionDescription, index int) bool {
+	for _, a := range desc.Attributes {
+		if a.Key != sdp.AttrKeyGroup {
+			continue
+		}
+		fields := strings.Fields(a.Value)
+		if len(fields) < 2 || fields[0] != "BUNDLE" {
+			continue
+		}
+		midValue := getMidValue(desc.MediaDescriptions[index])
+		for _, mid := range fields[1:] {
+			if mid == midValue {
+				return true
+			}
+		}
+		return false
+	}
+	return index == 0
+}
+
+// getBundleGroup returns the mids of the BUNDLE group of the media section
+// with the mid, nil if it isn't bundled
+func getBundleGroup(desc *sdp.SessionDescription, midValue string) []string {
+	for _, a := range desc.Attributes {
+		if a.Key != sdp.AttrKeyGroup {
+			continue
+		}
+		fields := strings.Fields(a.Value)
+		if len(fields) < 2 || fields[0] != "BUNDLE" {
+			continue
+		}
+		for _, mid := range fields[1:] {
+			if mid == midValue {
+				return fields[1:]
+			}
+		}
+	}
+	return nil
+}
+
 func addFingerprints(d *sdp.SessionDescription, c Certificate) error {
 	fingerprints, err := c.GetFingerprints()
 	if err != nil {
@@ -269,6 +573,18 @@ func addFingerprints(d *sdp.SessionDescription, c Certificate) error {
 	return nil
 }
 
+// copySessionDescription returns a copy of the description that can be
+// handed to the application without exposing the parsed one
+func copySessionDescription(sessionDescription *SessionDescription) *SessionDescription {
+	if sessionDescription == nil {
+		return nil
+	}
+	return &SessionDescription{
+		Type: sessionDescription.Type,
+		SDP:  sessionDescription.SDP,
+	}
+}
+
 func populateLocalCandidates(sessionDescription *SessionDescription, i *ICEGatherer, iceGatheringState ICEGatheringState) *SessionDescription {
 	if sessionDescription == nil || i == nil {
 		return sessionDescription
@@ -279,19 +595,35 @@ func populateLocalCandidates(sessionDescription *SessionDescription, i *ICEGathe
 		return sessionDescription
 	}
 
-	parsed := sessionDescription.parsed
+	current, err := sessionDescription.parse()
+	if err != nil {
+		return sessionDescription
+	}
+
+	// don't add the candidates to the stored description
+	parsed := cloneSessionDescription(current)
 	for _, m := range parsed.MediaDescriptions {
 		addCandidatesToMediaDescriptions(candidates, m, iceGatheringState)
 	}
-	sdp, err := parsed.Marshal()
+	populated, err := newSessionDescription(sessionDescription.Type, parsed)
 	if err != nil {
 		return sessionDescription
 	}
+	return populated
+}
 
-	return &SessionDescription{
-		SDP:  string(sdp),
-		Type: sessionDescription.Type,
+// cloneSessionDescription returns a copy of the description whose attributes
+// and media sections can be modified without changing the original one
+func cloneSessionDescription(d *sdp.SessionDescription) *sdp.SessionDescription {
+	clone := *d
+	clone.Attributes = append([]sdp.Attribute{}, d.Attributes...)
+	clone.MediaDescriptions = make([]*sdp.MediaDescription, len(d.MediaDescriptions))
+	for i, media := range d.MediaDescriptions {
+		m := *media
+		m.Attributes = append([]sdp.Attribute{}, media.Attributes...)
+		clone.MediaDescriptions[i] = &m
 	}
+	return &clone
 }
 
 func addTransceiverSDP(d *sdp.SessionDescription, isPlanB bool, mediaEngine *MediaEngine, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, iceGatheringState ICEGatheringState, mediaSection mediaSection) (bool, error) {
@@ -308,19 +640,36 @@ func addTransceiverSDP(d *sdp.SessionDescription, isPlanB bool, mediaEngine *Med
 		WithPropertyAttribute(sdp.AttrKeyRTCPMux).
 		WithPropertyAttribute(sdp.AttrKeyRTCPRsize)
 
-	for _, extMap := range mediaSection.extMaps {
-		media.WithExtMap(*extMap)
+	// sorted to generate the same media section when nothing changed
+	extMapValues := []int{}
+	for value := range mediaSection.extMaps {
+		extMapValues = append(extMapValues, value)
+	}
+	sort.Ints(extMapValues)
+	for _, value := range extMapValues {
+		media.WithExtMap(*mediaSection.extMaps[value])
+	}
+	if mediaSection.extMapAllowMixed {
+		media.WithPropertyAttribute(attributeExtMapAllowMixed)
 	}
 
-	if mediaSection.recvSimulcast {
-		for _, rid := range mediaSection.recvRids {
-			media.WithValueAttribute("rid", rid+" recv")
+	if len(mediaSection.recvSimulcast) > 0 {
+		for _, alternatives := range mediaSection.recvSimulcast {
+			for _, stream := range alternatives {
+				media.WithValueAttribute(sdpAttributeRid, stream.rid+" "+ridDirectionRecv)
+			}
 		}
 
-		media.WithValueAttribute("simulcast", "recv "+strings.Join(mediaSection.recvRids, ";"))
+		media.WithValueAttribute(sdpAttributeSimulcast, ridDirectionRecv+" "+formatSimulcastList(mediaSection.recvSimulcast))
 	}
 
 	codecs := mediaEngine.GetCodecsByKind(t.kind)
+	if mediaSection.rejected || len(codecs) == 0 {
+		// Explicitly reject track if we don't have the codec
+		addRejectedMediaSection(d, midValue, t.kind.String(), mediaSection.remoteMedia)
+		return false, nil
+	}
+
 	for _, codec := range codecs {
 		media.WithCodec(codec.PayloadType, codec.Name, codec.ClockRate, codec.Channels, codec.SDPFmtpLine)
 
@@ -328,36 +677,33 @@ func addTransceiverSDP(d *sdp.SessionDescription, isPlanB bool, mediaEngine *Med
 			media.WithValueAttribute("rtcp-fb", fmt.Sprintf("%d %s %s", codec.PayloadType, feedback.Type, feedback.Parameter))
 		}
 	}
-	if len(codecs) == 0 {
-		// Explicitly reject track if we don't have the codec
-		d.WithMedia(&sdp.MediaDescription{
-			MediaName: sdp.MediaName{
-				Media:   t.kind.String(),
-				Port:    sdp.RangedPort{Value: 0},
-				Protos:  []string{"UDP", "TLS", "RTP", "SAVPF"},
-				Formats: []string{"0"},
-			},
-		})
-		return false, nil
-	}
-
 	for _, mt := range transceivers {
 		if mt.Sender() != nil && mt.Sender().track != nil {
 			track := mt.Sender().track
+			cname := track.Label()
+			if mt.Sender().cname != "" {
+				cname = mt.Sender().cname
+			}
 			if len(track.streams) > 1 {
-				rids := []string{}
+				streams := [][]simulcastStream{}
 				for _, stream := range track.streams {
-					media.WithValueAttribute("rid", fmt.Sprintf("%s send", stream.RID()))
-					rids = append(rids, stream.RID())
+					media.WithValueAttribute(sdpAttributeRid, stream.RID()+" "+ridDirectionSend)
+					streams = append(streams, []simulcastStream{{rid: stream.RID()}})
 				}
-				media.WithValueAttribute("simulcast", fmt.Sprintf("send %s", strings.Join(rids, ";")))
+				media.WithValueAttribute(sdpAttributeSimulcast, ridDirectionSend+" "+formatSimulcastList(streams))
 			} else {
 				// currently we support only one stream when not using simulcast. TODO(sgotti) support also an additional repair stream
 				if len(track.streams) > 1 {
 					return false, fmt.Errorf("only one stream is supported when not using simulcast")
 				}
 				for _, stream := range track.streams {
-					media = media.WithMediaSource(stream.SSRC(), track.Label() /* cname */, track.Label() /* streamLabel */, track.ID())
+					if fecSSRC := mt.Sender().getFECSSRC(); fecSSRC != 0 {
+						media = media.WithValueAttribute(sdp.AttrKeySSRCGroup, fmt.Sprintf("%s %d %d", semanticTokenFECFR, stream.SSRC(), fecSSRC))
+						media = media.WithMediaSource(stream.SSRC(), cname, track.Label() /* streamLabel */, track.ID())
+						media = media.WithMediaSource(fecSSRC, cname, track.Label() /* streamLabel */, track.ID())
+						continue
+					}
+					media = media.WithMediaSource(stream.SSRC(), cname, track.Label() /* streamLabel */, track.ID())
 				}
 			}
 			if !isPlanB {
@@ -367,25 +713,73 @@ func addTransceiverSDP(d *sdp.SessionDescription, isPlanB bool, mediaEngine *Med
 		}
 	}
 
-	media = media.WithPropertyAttribute(t.Direction().String())
+	direction := t.Direction()
+	if mediaSection.offeredDirection != RTPTransceiverDirection(Unknown) {
+		direction = answerDirection(transceivers, mediaSection.offeredDirection)
+	}
+	media = media.WithPropertyAttribute(direction.String())
 
-	addCandidatesToMediaDescriptions(candidates, media, iceGatheringState)
+	if mediaSection.bundleOnly {
+		setBundleOnly(media)
+	} else {
+		addCandidatesToMediaDescriptions(candidates, media, iceGatheringState)
+	}
 	d.WithMedia(media)
 
 	return true, nil
 }
 
+// addRejectedMediaSection adds a rejected media section. When the remote
+// media section is provided its media type, protocol and formats are kept.
+func addRejectedMediaSection(d *sdp.SessionDescription, midValue string, kind string, remoteMedia *sdp.MediaDescription) {
+	mediaName := sdp.MediaName{
+		Media:   kind,
+		Port:    sdp.RangedPort{Value: 0},
+		Protos:  []string{"UDP", "TLS", "RTP", "SAVPF"},
+		Formats: []string{"0"},
+	}
+	if remoteMedia != nil {
+		mediaName.Media = remoteMedia.MediaName.Media
+		mediaName.Protos = remoteMedia.MediaName.Protos
+		if len(remoteMedia.MediaName.Formats) != 0 {
+			mediaName.Formats = remoteMedia.MediaName.Formats
+		}
+	}
+
+	d.WithMedia(&sdp.MediaDescription{
+		MediaName: mediaName,
+		Attributes: []sdp.Attribute{
+			{Key: sdp.AttrKeyMID, Value: midValue},
+			{Key: RTPTransceiverDirectionInactive.String()},
+		},
+	})
+}
+
 type mediaSection struct {
-	id            string
-	transceivers  []*RTPTransceiver
-	recvSimulcast bool
-	recvRids      []string
+	id           string
+	transceivers []*RTPTransceiver
+	// recvSimulcast are the simulcast streams offered by the remote peer
+	recvSimulcast [][]simulcastStream
 	extMaps       map[int]*sdp.ExtMap
-	data          bool
+	// extMapAllowMixed adds a media level extmap-allow-mixed attribute
+	extMapAllowMixed bool
+	data             bool
+	rejected         bool
+	// bundleOnly is set for the offered media sections usable only when
+	// bundled
+	bundleOnly bool
+	// offeredDirection is the direction of the remote media section when
+	// answering
+	offeredDirection RTPTransceiverDirection
+	// remoteMedia is the remote media section mirrored if the section is
+	// rejected, a rejected section without transceivers is a remote media
+	// section of an unsupported kind
+	remoteMedia *sdp.MediaDescription
 }
 
-// populateSDP serializes a PeerConnections state into an SDP
-func populateSDP(d *sdp.SessionDescription, isPlanB bool, isICELite bool, mediaEngine *MediaEngine, connectionRole sdp.ConnectionRole, candidates []ICECandidate, iceParams ICEParameters, mediaSections []mediaSection, iceGatheringState ICEGatheringState) (*sdp.SessionDescription, error) {
+// populateSDP serializes a PeerConnections state into an SDP, the accepted
+// media sections are added to a BUNDLE group when bundle is set
+func populateSDP(d *sdp.SessionDescription, isPlanB bool, isICELite bool, bundle bool, mediaEngine *MediaEngine, connectionRole sdp.ConnectionRole, candidates []ICECandidate, iceParams ICEParameters, mediaSections []mediaSection, iceGatheringState ICEGatheringState) (*sdp.SessionDescription, error) {
 	var err error
 
 	bundleValue := "BUNDLE"
@@ -403,8 +797,11 @@ func populateSDP(d *sdp.SessionDescription, isPlanB bool, isICELite bool, mediaE
 		}
 
 		shouldAddID := true
-		if m.data {
-			addDataMediaSection(d, m.id, iceParams, candidates, connectionRole, iceGatheringState)
+		if m.rejected && len(m.transceivers) == 0 {
+			addRejectedMediaSection(d, m.id, m.remoteMedia.MediaName.Media, m.remoteMedia)
+			shouldAddID = false
+		} else if m.data {
+			addDataMediaSection(d, m.id, iceParams, candidates, connectionRole, iceGatheringState, m.bundleOnly)
 		} else if shouldAddID, err = addTransceiverSDP(d, isPlanB, mediaEngine, m.id, iceParams, candidates, connectionRole, iceGatheringState, m); err != nil {
 			return nil, err
 		}
@@ -416,7 +813,10 @@ func populateSDP(d *sdp.SessionDescription, isPlanB bool, isICELite bool, mediaE
 
 	if isICELite {
 		// RFC 5245 S15.3
-		d = d.WithValueAttribute(sdp.AttrKeyICELite, sdp.AttrKeyICELite)
+		d = d.WithPropertyAttribute(sdp.AttrKeyICELite)
+	}
+	if !bundle {
+		return d, nil
 	}
 	return d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue), nil
 }
@@ -430,26 +830,82 @@ func getMidValue(media *sdp.MediaDescription) string {
 	return ""
 }
 
-// TODO(sgotti) add rid parsing
-func getRids(media *sdp.MediaDescription) []string {
-	rids := []string{}
-	for _, attr := range media.Attributes {
-		if attr.Key == "rid" {
-			split := strings.Split(attr.Value, " ")
-			rids = append(rids, split[0])
+// getMatchingCodec returns the local codec matching the most preferred codec
+// of the media section, nil if none is supported
+func getMatchingCodec(media *sdp.MediaDescription, kind RTPCodecType, mediaEngine *MediaEngine) *RTPCodec {
+	codecs := mediaEngine.GetCodecsByKind(kind)
+	for _, format := range media.MediaName.Formats {
+		pt, err := strconv.Atoi(format)
+		if err != nil {
+			continue
+		}
+
+		remoteCodec, err := getMediaCodecForPayloadType(media, uint8(pt))
+		for _, codec := range codecs {
+			if err != nil {
+				// static payload types could be used without a rtpmap
+				if pt < dynamicPayloadTypeMin && codec.PayloadType == uint8(pt) {
+					return codec
+				}
+				continue
+			}
+			if strings.EqualFold(codec.Name, remoteCodec.Name) && codec.ClockRate == remoteCodec.ClockRate {
+				return codec
+			}
 		}
 	}
-	return rids
+	return nil
 }
 
-// TODO(sgotti) add simulcast parsing
-func hasSimulcast(media *sdp.MediaDescription) bool {
-	for _, attr := range media.Attributes {
-		if attr.Key == "simulcast" {
-			return true
+// getMediaCodecForPayloadType returns the codec described by the rtpmap and
+// fmtp attributes of the media section for the payload type. Payload types
+// are scoped to the media section, so the other sections aren't considered.
+func getMediaCodecForPayloadType(media *sdp.MediaDescription, payloadType uint8) (sdp.Codec, error) {
+	desc := &sdp.SessionDescription{MediaDescriptions: []*sdp.MediaDescription{media}}
+	return desc.GetCodecForPayloadType(payloadType)
+}
+
+func getMediaSectionByMid(desc *sdp.SessionDescription, mid string) *sdp.MediaDescription {
+	for _, media := range desc.MediaDescriptions {
+		if getMidValue(media) == mid {
+			return media
 		}
 	}
-	return false
+	return nil
+}
+
+// sameSendingTracks reports if the tracks described by the plan-b ssrc
+// attributes of desc are the ones currently sent by the transceivers
+func sameSendingTracks(desc *sdp.SessionDescription, transceivers []*RTPTransceiver) bool {
+	described := map[string]struct{}{}
+	for _, media := range desc.MediaDescriptions {
+		for _, attr := range media.Attributes {
+			if attr.Key != sdp.AttrKeySSRC {
+				continue
+			}
+			split := strings.Split(attr.Value, " ")
+			if len(split) == 3 && strings.HasPrefix(split[1], "msid:") {
+				described[split[2]] = struct{}{}
+			}
+		}
+	}
+
+	sending := map[string]struct{}{}
+	for _, t := range transceivers {
+		if sender := t.Sender(); sender != nil && sender.track != nil {
+			sending[sender.track.ID()] = struct{}{}
+		}
+	}
+
+	if len(described) != len(sending) {
+		return false
+	}
+	for id := range sending {
+		if _, ok := described[id]; !ok {
+			return false
+		}
+	}
+	return true
 }
 
 func descriptionIsPlanB(desc *SessionDescription) bool {
@@ -475,34 +931,79 @@ func getPeerDirection(media *sdp.MediaDescription) RTPTransceiverDirection {
 	return RTPTransceiverDirection(Unknown)
 }
 
-func extractFingerprint(desc *sdp.SessionDescription) (string, string, error) {
-	fingerprints := []string{}
-
-	if fingerprint, haveFingerprint := desc.Attribute("fingerprint"); haveFingerprint {
-		fingerprints = append(fingerprints, fingerprint)
+// getFingerprints returns the fingerprints of a list of session or media
+// attributes
+func getFingerprints(attributes []sdp.Attribute) ([]DTLSFingerprint, error) {
+	fingerprints := []DTLSFingerprint{}
+	for _, attr := range attributes {
+		if attr.Key != "fingerprint" {
+			continue
+		}
+		parts := strings.Split(attr.Value, " ")
+		if len(parts) != 2 {
+			return nil, ErrSessionDescriptionInvalidFingerprint
+		}
+		fingerprints = append(fingerprints, DTLSFingerprint{Algorithm: strings.ToLower(parts[0]), Value: parts[1]})
 	}
+	return fingerprints, nil
+}
 
-	for _, m := range desc.MediaDescriptions {
-		if fingerprint, haveFingerprint := m.Attribute("fingerprint"); haveFingerprint {
-			fingerprints = append(fingerprints, fingerprint)
+// sameFingerprints reports if two lists contain the same fingerprints, in any
+// order
+func sameFingerprints(a, b []DTLSFingerprint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, fa := range a {
+		found := false
+		for _, fb := range b {
+			if fa.Algorithm == fb.Algorithm && strings.EqualFold(fa.Value, fb.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
 		}
 	}
+	return true
+}
 
-	if len(fingerprints) < 1 {
-		return "", "", ErrSessionDescriptionNoFingerprint
+// extractFingerprints returns the remote fingerprints. The media level
+// fingerprints override the session level ones (RFC 8122 5), and since the
+// media sections share the DTLS transport they must have the same ones.
+func extractFingerprints(desc *sdp.SessionDescription) ([]DTLSFingerprint, error) {
+	sessionFingerprints, err := getFingerprints(desc.Attributes)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, m := range fingerprints {
-		if m != fingerprints[0] {
-			return "", "", ErrSessionDescriptionConflictingFingerprints
+	var fingerprints []DTLSFingerprint
+	for _, m := range desc.MediaDescriptions {
+		mediaFingerprints, err := getFingerprints(m.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		if len(mediaFingerprints) == 0 {
+			mediaFingerprints = sessionFingerprints
+		}
+
+		switch {
+		case len(mediaFingerprints) == 0:
+		case fingerprints == nil:
+			fingerprints = mediaFingerprints
+		case !sameFingerprints(fingerprints, mediaFingerprints):
+			return nil, ErrSessionDescriptionConflictingFingerprints
 		}
 	}
 
-	parts := strings.Split(fingerprints[0], " ")
-	if len(parts) != 2 {
-		return "", "", ErrSessionDescriptionInvalidFingerprint
+	if fingerprints == nil {
+		fingerprints = sessionFingerprints
+	}
+	if len(fingerprints) == 0 {
+		return nil, ErrSessionDescriptionNoFingerprint
 	}
-	return parts[1], parts[0], nil
+	return fingerprints, nil
 }
 
 func extractICEDetails(desc *sdp.SessionDescription) (string, string, []ICECandidate, error) {
@@ -542,6 +1043,27 @@ func extractICEDetails(desc *sdp.SessionDescription) (string, string, []ICECandi
 	return remoteUfrag, remotePwd, candidates, nil
 }
 
+// getMaxMessageSize returns the size of the largest message the remote peer
+// can receive on the DataChannels, zero if it's unlimited
+func getMaxMessageSize(desc *sdp.SessionDescription) uint32 {
+	for _, m := range desc.MediaDescriptions {
+		if m.MediaName.Media != mediaSectionApplication {
+			continue
+		}
+		value, ok := m.Attribute(attributeMaxMessageSize)
+		if !ok {
+			break
+		}
+		size, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			break
+		}
+		return uint32(size)
+	}
+
+	return sctpDefaultMaxMessageSize
+}
+
 func haveApplicationMediaSection(desc *sdp.SessionDescription) bool {
 	for _, m := range desc.MediaDescriptions {
 		if m.MediaName.Media == mediaSectionApplication {
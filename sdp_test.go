@@ -9,16 +9,15 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestExtractFingerprint(t *testing.T) {
+func TestExtractFingerprints(t *testing.T) {
 	t.Run("Good Session Fingerprint", func(t *testing.T) {
 		s := &sdp.SessionDescription{
 			Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "foo bar"}},
 		}
 
-		fingerprint, hash, err := extractFingerprint(s)
+		fingerprints, err := extractFingerprints(s)
 		assert.NoError(t, err)
-		assert.Equal(t, fingerprint, "bar")
-		assert.Equal(t, hash, "foo")
+		assert.Equal(t, []DTLSFingerprint{{Algorithm: "foo", Value: "bar"}}, fingerprints)
 	})
 
 	t.Run("Good Media Fingerprint", func(t *testing.T) {
@@ -28,16 +27,42 @@ func TestExtractFingerprint(t *testing.T) {
 			},
 		}
 
-		fingerprint, hash, err := extractFingerprint(s)
+		fingerprints, err := extractFingerprints(s)
 		assert.NoError(t, err)
-		assert.Equal(t, fingerprint, "bar")
-		assert.Equal(t, hash, "foo")
+		assert.Equal(t, []DTLSFingerprint{{Algorithm: "foo", Value: "bar"}}, fingerprints)
+	})
+
+	t.Run("Multiple Fingerprints", func(t *testing.T) {
+		s := &sdp.SessionDescription{
+			Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "sha-256 AA"}, {Key: "fingerprint", Value: "SHA-512 BB"}},
+			MediaDescriptions: []*sdp.MediaDescription{
+				{},
+				{Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "sha-512 bb"}, {Key: "fingerprint", Value: "sha-256 aa"}}},
+			},
+		}
+
+		fingerprints, err := extractFingerprints(s)
+		assert.NoError(t, err)
+		assert.Equal(t, []DTLSFingerprint{{Algorithm: "sha-256", Value: "AA"}, {Algorithm: "sha-512", Value: "BB"}}, fingerprints)
+	})
+
+	t.Run("Overridden Session Fingerprint", func(t *testing.T) {
+		s := &sdp.SessionDescription{
+			Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "foo bar"}},
+			MediaDescriptions: []*sdp.MediaDescription{
+				{Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "foo blah"}}},
+			},
+		}
+
+		fingerprints, err := extractFingerprints(s)
+		assert.NoError(t, err)
+		assert.Equal(t, []DTLSFingerprint{{Algorithm: "foo", Value: "blah"}}, fingerprints)
 	})
 
 	t.Run("No Fingerprint", func(t *testing.T) {
 		s := &sdp.SessionDescription{}
 
-		_, _, err := extractFingerprint(s)
+		_, err := extractFingerprints(s)
 		assert.Equal(t, ErrSessionDescriptionNoFingerprint, err)
 	})
 
@@ -46,19 +71,20 @@ func TestExtractFingerprint(t *testing.T) {
 			Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "foo"}},
 		}
 
-		_, _, err := extractFingerprint(s)
+		_, err := extractFingerprints(s)
 		assert.Equal(t, ErrSessionDescriptionInvalidFingerprint, err)
 	})
 
 	t.Run("Conflicting Fingerprint", func(t *testing.T) {
 		s := &sdp.SessionDescription{
-			Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "foo"}},
+			Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "foo bar"}},
 			MediaDescriptions: []*sdp.MediaDescription{
+				{},
 				{Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "foo blah"}}},
 			},
 		}
 
-		_, _, err := extractFingerprint(s)
+		_, err := extractFingerprints(s)
 		assert.Equal(t, ErrSessionDescriptionConflictingFingerprints, err)
 	})
 }
@@ -172,6 +198,34 @@ func TestTrackDetailsFromSDP(t *testing.T) {
 		}
 	})
 
+	t.Run("FlexFEC repair flow", func(t *testing.T) {
+		s := &sdp.SessionDescription{
+			MediaDescriptions: []*sdp.MediaDescription{
+				{
+					MediaName: sdp.MediaName{
+						Media: "video",
+					},
+					Attributes: []sdp.Attribute{
+						{Key: "mid", Value: "0"},
+						{Key: "sendonly"},
+						{Key: "msid", Value: "video_stream_id video_trk_id"},
+						{Key: "ssrc-group", Value: "FEC-FR 5000 6000"},
+						{Key: "ssrc", Value: "5000"},
+						{Key: "ssrc", Value: "6000"},
+					},
+				},
+			},
+		}
+
+		tracks := trackDetailsFromSDP(nil, s, false)
+		if track, ok := tracks["0"]; !ok {
+			assert.Fail(t, "missing video track with ssrc:5000")
+		} else {
+			assert.Equal(t, 1, len(track.ssrcStreams))
+			assert.Equal(t, uint32(6000), track.ssrcStreams[5000].fecSSRC)
+		}
+	})
+
 	t.Run("inactive and recvonly tracks ignored", func(t *testing.T) {
 		s := &sdp.SessionDescription{
 			MediaDescriptions: []*sdp.MediaDescription{
@@ -198,6 +252,85 @@ func TestTrackDetailsFromSDP(t *testing.T) {
 
 		assert.Equal(t, 0, len(trackDetailsFromSDP(nil, s, true)))
 	})
+
+	t.Run("simulcast streams", func(t *testing.T) {
+		s := &sdp.SessionDescription{
+			MediaDescriptions: []*sdp.MediaDescription{
+				{
+					MediaName: sdp.MediaName{
+						Media: "video",
+					},
+					Attributes: []sdp.Attribute{
+						{Key: "mid", Value: "0"},
+						{Key: "sendonly"},
+						{Key: "rid", Value: "c send pt=96,97;max-width=320"},
+						{Key: "rid", Value: "b send"},
+						{Key: "rid", Value: "a send max-width=1280;max-height=720"},
+						{Key: "rid", Value: "r recv"},
+						{Key: "rid", Value: "d send"},
+						{Key: "simulcast", Value: "send a;~b,c recv r"},
+					},
+				},
+			},
+		}
+
+		track, ok := trackDetailsFromSDP(nil, s, false)["0"]
+		assert.True(t, ok)
+		assert.True(t, track.useRid)
+		assert.Equal(t, []string{"a", "b", "c"}, track.rids)
+		assert.Equal(t, map[string]string{"max-width": "1280", "max-height": "720"}, track.ridStreams["a"].restrictions)
+		assert.False(t, track.ridStreams["a"].paused)
+		assert.True(t, track.ridStreams["b"].paused)
+		assert.Equal(t, []uint8{96, 97}, track.ridStreams["c"].payloadTypes)
+		// the rids not listed by a=simulcast are ignored
+		_, ok = track.ridStreams["d"]
+		assert.False(t, ok)
+	})
+}
+
+func TestParseRid(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		rid   *ridDescription
+		err   error
+	}{
+		{"1 send", &ridDescription{id: "1", direction: "send", restrictions: map[string]string{}}, nil},
+		{"hi recv pt=96,97;max-fps=30;depend", &ridDescription{id: "hi", direction: "recv", payloadTypes: []uint8{96, 97}, restrictions: map[string]string{"max-fps": "30", "depend": ""}}, nil},
+		{"1", nil, ErrSessionDescriptionInvalidRid},
+		{"1 sendrecv", nil, ErrSessionDescriptionInvalidRid},
+		{"a.b send", nil, ErrSessionDescriptionInvalidRid},
+		{"1 send pt=x", nil, ErrSessionDescriptionInvalidRid},
+		{"1 send pt=128", nil, ErrSessionDescriptionInvalidRid},
+		{"1 send ;max-fps=30", nil, ErrSessionDescriptionInvalidRid},
+	} {
+		rid, err := parseRid(test.value)
+		assert.Equal(t, test.err, err, test.value)
+		assert.Equal(t, test.rid, rid, test.value)
+	}
+}
+
+func TestParseSimulcast(t *testing.T) {
+	for _, test := range []struct {
+		value     string
+		simulcast *simulcastDescription
+		err       error
+	}{
+		{"send 1;2", &simulcastDescription{send: [][]simulcastStream{{{rid: "1"}}, {{rid: "2"}}}}, nil},
+		{"recv ~1,2 send 3", &simulcastDescription{
+			send: [][]simulcastStream{{{rid: "3"}}},
+			recv: [][]simulcastStream{{{rid: "1", paused: true}, {rid: "2"}}},
+		}, nil},
+		{"send", nil, ErrSessionDescriptionInvalidSimulcast},
+		{"send 1 send 2", nil, ErrSessionDescriptionInvalidSimulcast},
+		{"sendrecv 1", nil, ErrSessionDescriptionInvalidSimulcast},
+		{"send 1;;2", nil, ErrSessionDescriptionInvalidSimulcast},
+	} {
+		simulcast, err := parseSimulcast(test.value)
+		assert.Equal(t, test.err, err, test.value)
+		assert.Equal(t, test.simulcast, simulcast, test.value)
+	}
+
+	assert.Equal(t, "a;~b,c", formatSimulcastList([][]simulcastStream{{{rid: "a"}}, {{rid: "b", paused: true}, {rid: "c"}}}))
 }
 
 func TestHaveApplicationMediaSection(t *testing.T) {
@@ -233,3 +366,69 @@ func TestHaveApplicationMediaSection(t *testing.T) {
 		assert.True(t, haveApplicationMediaSection(s))
 	})
 }
+
+func TestGetMatchingCodec(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+
+	// the payload types are scoped to their media section
+	video := func(mid, rtpmap string) *sdp.MediaDescription {
+		return &sdp.MediaDescription{
+			MediaName: sdp.MediaName{
+				Media:   "video",
+				Formats: []string{"96"},
+			},
+			Attributes: []sdp.Attribute{
+				{Key: "mid", Value: mid},
+				{Key: "rtpmap", Value: "96 " + rtpmap},
+			},
+		}
+	}
+	s := &sdp.SessionDescription{
+		MediaDescriptions: []*sdp.MediaDescription{video("0", "H264/90000"), video("1", "VP8/90000")},
+	}
+
+	assert.Nil(t, getMatchingCodec(s.MediaDescriptions[0], RTPCodecTypeVideo, &m))
+	if codec := getMatchingCodec(s.MediaDescriptions[1], RTPCodecTypeVideo, &m); assert.NotNil(t, codec) {
+		assert.Equal(t, VP8, codec.Name)
+	}
+}
+
+func TestGetMaxMessageSize(t *testing.T) {
+	application := func(attributes ...sdp.Attribute) *sdp.SessionDescription {
+		return &sdp.SessionDescription{
+			MediaDescriptions: []*sdp.MediaDescription{
+				{
+					MediaName:  sdp.MediaName{Media: "audio"},
+					Attributes: []sdp.Attribute{{Key: attributeMaxMessageSize, Value: "10"}},
+				},
+				{
+					MediaName:  sdp.MediaName{Media: mediaSectionApplication},
+					Attributes: attributes,
+				},
+			},
+		}
+	}
+
+	assert.Equal(t, uint32(sctpDefaultMaxMessageSize), getMaxMessageSize(application()))
+	assert.Equal(t, uint32(sctpDefaultMaxMessageSize), getMaxMessageSize(application(sdp.Attribute{Key: attributeMaxMessageSize, Value: "-1"})))
+	assert.Equal(t, uint32(1024), getMaxMessageSize(application(sdp.Attribute{Key: attributeMaxMessageSize, Value: "1024"})))
+	assert.Equal(t, uint32(0), getMaxMessageSize(application(sdp.Attribute{Key: attributeMaxMessageSize, Value: "0"})))
+}
+
+func TestCloneSessionDescription(t *testing.T) {
+	d := &sdp.SessionDescription{
+		Attributes: []sdp.Attribute{{Key: "group", Value: "BUNDLE 0"}},
+		MediaDescriptions: []*sdp.MediaDescription{
+			{Attributes: []sdp.Attribute{{Key: "mid", Value: "0"}}},
+		},
+	}
+
+	clone := cloneSessionDescription(d)
+	clone.WithPropertyAttribute(attributeExtMapAllowMixed)
+	clone.MediaDescriptions[0].Attributes[0].Value = "1"
+	clone.MediaDescriptions[0].WithCandidate("candidate")
+
+	assert.Equal(t, []sdp.Attribute{{Key: "group", Value: "BUNDLE 0"}}, d.Attributes)
+	assert.Equal(t, []sdp.Attribute{{Key: "mid", Value: "0"}}, d.MediaDescriptions[0].Attributes)
+}
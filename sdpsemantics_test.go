@@ -92,7 +92,7 @@ func TestSDPSemantics_PlanBOfferTransceivers(t *testing.T) {
 	}
 
 	mdNames := getMdNames(offer.parsed)
-	assert.ObjectsAreEqual(mdNames, []string{"video", "audio", "data"})
+	assert.Equal(t, []string{"video", "audio", "application"}, mdNames)
 
 	// Verify that each section has 2 SSRCs (one for each transceiver)
 	for _, section := range []string{"video", "audio"} {
@@ -120,7 +120,7 @@ func TestSDPSemantics_PlanBOfferTransceivers(t *testing.T) {
 	}
 
 	mdNames = getMdNames(answer.parsed)
-	assert.ObjectsAreEqual(mdNames, []string{"video", "audio", "data"})
+	assert.Equal(t, []string{"video", "audio", "application"}, mdNames)
 }
 
 func TestSDPSemantics_PlanBAnswerSenders(t *testing.T) {
@@ -148,7 +148,7 @@ func TestSDPSemantics_PlanBAnswerSenders(t *testing.T) {
 	}
 
 	mdNames := getMdNames(offer.parsed)
-	assert.ObjectsAreEqual(mdNames, []string{"video", "audio", "data"})
+	assert.Equal(t, []string{"video", "audio", "application"}, mdNames)
 
 	apc, err := NewPeerConnection(Configuration{
 		SDPSemantics: SDPSemanticsPlanB,
@@ -196,7 +196,7 @@ func TestSDPSemantics_PlanBAnswerSenders(t *testing.T) {
 	}
 
 	mdNames = getMdNames(answer.parsed)
-	assert.ObjectsAreEqual(mdNames, []string{"video", "audio", "data"})
+	assert.Equal(t, []string{"video", "audio", "application"}, mdNames)
 
 	// Verify that each section has 2 SSRCs (one for each sender)
 	for _, section := range []string{"video", "audio"} {
@@ -233,7 +233,7 @@ func TestSDPSemantics_UnifiedPlanWithFallback(t *testing.T) {
 	}
 
 	mdNames := getMdNames(offer.parsed)
-	assert.ObjectsAreEqual(mdNames, []string{"video", "audio", "data"})
+	assert.Equal(t, []string{"video", "audio", "application"}, mdNames)
 
 	apc, err := NewPeerConnection(Configuration{
 		SDPSemantics: SDPSemanticsUnifiedPlanWithFallback,
@@ -281,7 +281,7 @@ func TestSDPSemantics_UnifiedPlanWithFallback(t *testing.T) {
 	}
 
 	mdNames = getMdNames(answer.parsed)
-	assert.ObjectsAreEqual(mdNames, []string{"video", "audio", "data"})
+	assert.Equal(t, []string{"video", "audio", "application"}, mdNames)
 
 	extractSsrcList := func(md *sdp.MediaDescription) []string {
 		ssrcMap := map[string]struct{}{}
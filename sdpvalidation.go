@@ -0,0 +1,146 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+
+	"github.com/pion/sdp/v2"
+)
+
+// SDPValidationError reports the part of a remote description that is not
+// valid or not supported. It's returned by SetRemoteDescription, or provided
+// to the OnTransceiverError handler when only a media section is rejected.
+type SDPValidationError struct {
+	// MediaIndex is the index of the media section, -1 for the session
+	// level
+	MediaIndex int
+	// Mid is the mid of the media section, if any
+	Mid string
+	// Attribute is the key of the attribute causing the failure, if any
+	Attribute string
+	// Err is the reason of the failure
+	Err error
+}
+
+func (e *SDPValidationError) Error() string {
+	location := "session"
+	if e.MediaIndex >= 0 {
+		location = fmt.Sprintf("media section %d", e.MediaIndex)
+		if e.Mid != "" {
+			location += fmt.Sprintf(" (mid %s)", e.Mid)
+		}
+	}
+	if e.Attribute != "" {
+		location += fmt.Sprintf(" attribute %s", e.Attribute)
+	}
+	return fmt.Sprintf("invalid remote description, %s: %v", location, e.Err)
+}
+
+// Unwrap returns the reason of the failure
+func (e *SDPValidationError) Unwrap() error {
+	return e.Err
+}
+
+func newSDPValidationError(index int, media *sdp.MediaDescription, attribute string, err error) *SDPValidationError {
+	e := &SDPValidationError{MediaIndex: index, Attribute: attribute, Err: err}
+	if media != nil {
+		e.Mid = getMidValue(media)
+	}
+	return e
+}
+
+// validateRemoteDescription checks the remote description before it's
+// applied, in the FIPS mode the fingerprints must use an approved hash
+// function
+func validateRemoteDescription(desc *SessionDescription, fipsMode bool) error {
+	d := desc.parsed
+	isAnswer := desc.Type == SDPTypeAnswer || desc.Type == SDPTypePranswer
+
+	sessionFingerprints, err := getFingerprints(d.Attributes)
+	if err != nil {
+		return newSDPValidationError(-1, nil, "fingerprint", err)
+	}
+	// the fingerprints of the DTLS transport shared by the media sections
+	var fingerprints []DTLSFingerprint
+
+	haveUfrag, havePwd := false, false
+	for i, media := range d.MediaDescriptions {
+		// the ICE credentials are taken from any media section, like
+		// extractICEDetails does
+		if _, ok := media.Attribute("ice-ufrag"); ok {
+			haveUfrag = true
+		}
+		if _, ok := media.Attribute("ice-pwd"); ok {
+			havePwd = true
+		}
+
+		// the rejected media sections aren't negotiated
+		if isMediaSectionRejected(media) {
+			continue
+		}
+
+		if getMidValue(media) == "" {
+			return newSDPValidationError(i, media, sdp.AttrKeyMID, ErrSessionDescriptionMissingMid)
+		}
+
+		// the media level fingerprints override the session level ones
+		mediaFingerprints, err := getFingerprints(media.Attributes)
+		if err != nil {
+			return newSDPValidationError(i, media, "fingerprint", err)
+		}
+		if len(mediaFingerprints) == 0 {
+			mediaFingerprints = sessionFingerprints
+		}
+		switch {
+		case len(mediaFingerprints) == 0:
+		case fingerprints == nil:
+			fingerprints = mediaFingerprints
+		case !sameFingerprints(fingerprints, mediaFingerprints):
+			return newSDPValidationError(i, media, "fingerprint", ErrSessionDescriptionConflictingFingerprints)
+		}
+
+		if setup, ok := media.Attribute(sdp.AttrKeyConnectionSetup); ok {
+			switch setup {
+			case sdp.ConnectionRoleActive.String(), sdp.ConnectionRolePassive.String():
+			case sdp.ConnectionRoleActpass.String():
+				// the answerer must choose the role
+				if isAnswer {
+					return newSDPValidationError(i, media, sdp.AttrKeyConnectionSetup, ErrSessionDescriptionInvalidSetup)
+				}
+			default:
+				return newSDPValidationError(i, media, sdp.AttrKeyConnectionSetup, ErrSessionDescriptionInvalidSetup)
+			}
+		}
+
+		if _, _, err := getSimulcast(media); err != nil {
+			attribute := sdpAttributeSimulcast
+			if err == ErrSessionDescriptionInvalidRid {
+				attribute = sdpAttributeRid
+			}
+			return newSDPValidationError(i, media, attribute, err)
+		}
+
+		// the offered media sections are always multiplexed, a remote answer
+		// can't ask for a separate RTCP transport
+		if isAnswer && !haveRTCPMux(d, media) {
+			return newSDPValidationError(i, media, sdp.AttrKeyRTCPMux, ErrNonMuxedRTCP)
+		}
+	}
+
+	if fingerprints == nil {
+		fingerprints = sessionFingerprints
+	}
+
+	switch {
+	case len(fingerprints) == 0:
+		return newSDPValidationError(-1, nil, "fingerprint", ErrSessionDescriptionNoFingerprint)
+	case len(strongestFingerprints(fingerprints)) == 0, fipsMode && !fipsApprovedFingerprints(fingerprints):
+		return newSDPValidationError(-1, nil, "fingerprint", ErrSessionDescriptionUnsupportedFingerprint)
+	case !haveUfrag:
+		return newSDPValidationError(-1, nil, "ice-ufrag", ErrSessionDescriptionMissingIceUfrag)
+	case !havePwd:
+		return newSDPValidationError(-1, nil, "ice-pwd", ErrSessionDescriptionMissingIcePwd)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRemoteDescription(t *testing.T) {
+	const sdpDescription = `v=0
+o=- 6476616870435111971 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:46:FD:A8:2A:F3:13:94:6E:E6:43:23:CB:D7
+a=group:BUNDLE 0 1
+m=audio 9 UDP/TLS/RTP/SAVPF 111
+c=IN IP4 0.0.0.0
+a=ice-ufrag:sRIG
+a=ice-pwd:yZb5ZMsBlPoK577sGhjvEUtT
+a=setup:actpass
+a=mid:0
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:111 opus/48000/2
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=setup:actpass
+a=mid:1
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:96 VP8/90000
+`
+
+	for _, test := range []struct {
+		name     string
+		sdpType  SDPType
+		old, new string
+		err      *SDPValidationError
+	}{
+		{
+			name:    "valid",
+			sdpType: SDPTypeOffer,
+		},
+		{
+			name:    "missing mid",
+			sdpType: SDPTypeOffer,
+			old:     "a=mid:1\n", new: "",
+			err: &SDPValidationError{MediaIndex: 1, Attribute: "mid", Err: ErrSessionDescriptionMissingMid},
+		},
+		{
+			name:    "missing fingerprint",
+			sdpType: SDPTypeOffer,
+			old:     "a=fingerprint:", new: "a=nofingerprint:",
+			err: &SDPValidationError{MediaIndex: -1, Attribute: "fingerprint", Err: ErrSessionDescriptionNoFingerprint},
+		},
+		{
+			name:    "conflicting fingerprint",
+			sdpType: SDPTypeOffer,
+			old:     "a=mid:1\n", new: "a=mid:1\na=fingerprint:sha-256 AA:BB\n",
+			err: &SDPValidationError{MediaIndex: 1, Mid: "1", Attribute: "fingerprint", Err: ErrSessionDescriptionConflictingFingerprints},
+		},
+		{
+			name:    "unsupported fingerprint",
+			sdpType: SDPTypeOffer,
+			old:     "a=fingerprint:sha-256", new: "a=fingerprint:sha-123",
+			err: &SDPValidationError{MediaIndex: -1, Attribute: "fingerprint", Err: ErrSessionDescriptionUnsupportedFingerprint},
+		},
+		{
+			name:    "multiple fingerprints",
+			sdpType: SDPTypeOffer,
+			old:     "a=fingerprint:sha-256", new: "a=fingerprint:sha-123 AA:BB\na=fingerprint:sha-256",
+		},
+		{
+			name:    "invalid setup",
+			sdpType: SDPTypeOffer,
+			old:     "a=setup:actpass\na=mid:1", new: "a=setup:holdconn\na=mid:1",
+			err: &SDPValidationError{MediaIndex: 1, Mid: "1", Attribute: "setup", Err: ErrSessionDescriptionInvalidSetup},
+		},
+		{
+			name:    "answer with actpass",
+			sdpType: SDPTypeAnswer,
+			err:     &SDPValidationError{MediaIndex: 0, Mid: "0", Attribute: "setup", Err: ErrSessionDescriptionInvalidSetup},
+		},
+		{
+			name:    "undeclared simulcast rid",
+			sdpType: SDPTypeOffer,
+			old:     "a=rtpmap:96 VP8/90000\n", new: "a=rtpmap:96 VP8/90000\na=rid:hi send\na=simulcast:send hi;lo\n",
+			err: &SDPValidationError{MediaIndex: 1, Mid: "1", Attribute: "simulcast", Err: ErrSessionDescriptionInvalidSimulcast},
+		},
+		{
+			name:    "missing ice-pwd",
+			sdpType: SDPTypeOffer,
+			old:     "a=ice-pwd:yZb5ZMsBlPoK577sGhjvEUtT\n", new: "",
+			err: &SDPValidationError{MediaIndex: -1, Attribute: "ice-pwd", Err: ErrSessionDescriptionMissingIcePwd},
+		},
+	} {
+		pc, err := NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		desc := SessionDescription{Type: test.sdpType, SDP: strings.Replace(sdpDescription, test.old, test.new, 1)}
+		if test.sdpType == SDPTypeAnswer {
+			_, err = pc.CreateDataChannel("data", nil)
+			assert.NoError(t, err)
+			offer, err := pc.CreateOffer(nil)
+			assert.NoError(t, err)
+			assert.NoError(t, pc.SetLocalDescription(offer))
+		}
+
+		err = pc.SetRemoteDescription(desc)
+		if test.err == nil {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.Equal(t, test.err, err, test.name)
+			// the invalid description isn't applied
+			assert.Nil(t, pc.RemoteDescription(), test.name)
+		}
+		assert.NoError(t, pc.Close())
+	}
+}
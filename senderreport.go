@@ -0,0 +1,69 @@
+// +build !js
+
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+const (
+	// senderReportPauseThreshold is the minimum time without sent packets
+	// after which an outbound stream is considered paused
+	senderReportPauseThreshold = 500 * time.Millisecond
+
+	// ntpEpochOffset are the seconds between the NTP epoch (1900) and the
+	// unix epoch (1970)
+	ntpEpochOffset = 2208988800
+)
+
+// senderReportStream keeps the state of an outbound rtp stream needed to
+// generate its sender reports
+type senderReportStream struct {
+	clockRate   uint32
+	packetCount uint32
+	octetCount  uint32
+
+	// lastTimestamp is the timestamp of the last sent packet and
+	// lastSentTime is when it was sent
+	lastTimestamp uint32
+	lastSentTime  time.Time
+}
+
+// update accounts a packet that is going to be sent, its header isn't
+// changed
+func (s *senderReportStream) update(header *rtp.Header, payloadLen int, now time.Time) {
+	s.lastTimestamp = header.Timestamp
+	s.lastSentTime = now
+	s.packetCount++
+	s.octetCount += uint32(payloadLen)
+}
+
+// report generates a sender report extrapolating the rtp timestamp from the
+// last sent packet. No report is generated while the stream is paused: the
+// timestamps of the resumed stream are set by the application, so an
+// extrapolated timestamp could be ahead of them and the receivers would see
+// a jump. The reports resume with the packets, derived from their timestamps.
+func (s *senderReportStream) report(ssrc uint32, now time.Time) (*rtcp.SenderReport, bool) {
+	if now.Sub(s.lastSentTime) > senderReportPauseThreshold {
+		return nil, false
+	}
+
+	return &rtcp.SenderReport{
+		SSRC:        ssrc,
+		NTPTime:     ntpTime(now),
+		RTPTime:     s.lastTimestamp + uint32(now.Sub(s.lastSentTime).Seconds()*float64(s.clockRate)),
+		PacketCount: s.packetCount,
+		OctetCount:  s.octetCount,
+	}, true
+}
+
+// ntpTime converts a time to the 64 bit NTP timestamp format
+func ntpTime(t time.Time) uint64 {
+	nsec := uint64(t.UnixNano())
+	sec := nsec/uint64(time.Second) + ntpEpochOffset
+	frac := (nsec % uint64(time.Second)) << 32 / uint64(time.Second)
+	return sec<<32 | frac
+}
@@ -0,0 +1,54 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSenderReportStream(t *testing.T) {
+	start := time.Now()
+	s := &senderReportStream{clockRate: 90000}
+
+	h := &rtp.Header{SSRC: 5000, Timestamp: 1000}
+	s.update(h, 10, start)
+	assert.Equal(t, uint32(1000), h.Timestamp)
+
+	h = &rtp.Header{SSRC: 5000, Timestamp: 1000 + 3000}
+	s.update(h, 10, start.Add(33*time.Millisecond))
+	assert.Equal(t, uint32(4000), h.Timestamp)
+
+	// reports extrapolate the timestamp from the wall clock
+	now := start.Add(133 * time.Millisecond)
+	sr, ok := s.report(5000, now)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(5000), sr.SSRC)
+	assert.Equal(t, uint32(4000+9000), sr.RTPTime)
+	assert.Equal(t, uint32(2), sr.PacketCount)
+	assert.Equal(t, uint32(20), sr.OctetCount)
+	assert.Equal(t, ntpTime(now), sr.NTPTime)
+
+	// no reports while the stream is paused
+	_, ok = s.report(5000, start.Add(1033*time.Millisecond))
+	assert.False(t, ok)
+
+	// the resumed stream keeps its timestamps and the reports are derived
+	// from them
+	h = &rtp.Header{SSRC: 5000, Timestamp: 4000 + 3000}
+	s.update(h, 10, start.Add(2033*time.Millisecond))
+	assert.Equal(t, uint32(7000), h.Timestamp)
+
+	sr, ok = s.report(5000, start.Add(2133*time.Millisecond))
+	assert.True(t, ok)
+	assert.Equal(t, uint32(7000+9000), sr.RTPTime)
+	assert.Equal(t, uint32(3), sr.PacketCount)
+}
+
+func TestNTPTime(t *testing.T) {
+	assert.Equal(t, uint64(ntpEpochOffset)<<32, ntpTime(time.Unix(0, 0)))
+	assert.Equal(t, uint64(ntpEpochOffset+1)<<32|1<<31, ntpTime(time.Unix(1, int64(500*time.Millisecond))))
+}
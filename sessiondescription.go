@@ -11,4 +11,51 @@ type SessionDescription struct {
 
 	// This will never be initialized by callers, internal use only
 	parsed *sdp.SessionDescription
+	// parsedSDP is the SDP the parsed description has been built from, it
+	// differs from SDP when the application modifies it
+	parsedSDP string
+	// sdpStale is set when the parsed description has been updated, like
+	// with the remote candidates, and SDP must be marshaled again before
+	// being exposed
+	sdpStale bool
+}
+
+// newSessionDescription returns a description caching the description it has
+// been marshaled from, that must be a parsed description or a copy of one
+func newSessionDescription(sdpType SDPType, parsed *sdp.SessionDescription) (*SessionDescription, error) {
+	raw, err := parsed.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return &SessionDescription{Type: sdpType, SDP: string(raw), parsed: parsed, parsedSDP: string(raw)}, nil
+}
+
+// parse returns the parsed description, the SDP is parsed again only when it
+// changed since the last time. The returned description is shared and must
+// not be modified.
+func (sd *SessionDescription) parse() (*sdp.SessionDescription, error) {
+	if sd.parsed != nil && (sd.sdpStale || sd.parsedSDP == sd.SDP) {
+		return sd.parsed, nil
+	}
+
+	parsed := &sdp.SessionDescription{}
+	if err := parsed.Unmarshal([]byte(sd.SDP)); err != nil {
+		return nil, err
+	}
+	sd.parsed, sd.parsedSDP = parsed, sd.SDP
+	return parsed, nil
+}
+
+// updateSDP marshals the parsed description when SDP is stale, the
+// description must not be shared with other goroutines meanwhile
+func (sd *SessionDescription) updateSDP() {
+	if sd == nil || !sd.sdpStale {
+		return
+	}
+
+	raw, err := sd.parsed.Marshal()
+	if err != nil {
+		return
+	}
+	sd.SDP, sd.parsedSDP, sd.sdpStale = string(raw), string(raw), false
 }
@@ -57,3 +57,25 @@ func TestSessionDescription_JSON(t *testing.T) {
 		)
 	}
 }
+
+func TestSessionDescription_Parse(t *testing.T) {
+	desc := SessionDescription{Type: SDPTypeOffer, SDP: "v=0\r\no=- 1 2 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\n"}
+
+	parsed, err := desc.parse()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), parsed.Origin.SessionID)
+
+	// the SDP isn't parsed again while it doesn't change
+	cached, err := desc.parse()
+	assert.NoError(t, err)
+	assert.True(t, parsed == cached)
+
+	desc.SDP = "v=0\r\no=- 3 4 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\n"
+	parsed, err = desc.parse()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), parsed.Origin.SessionID)
+
+	desc.SDP = "invalid"
+	_, err = desc.parse()
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@
 package webrtc
 
 import (
+	"crypto/x509"
 	"errors"
 	"time"
 
@@ -25,33 +26,60 @@ type SettingEngine struct {
 	timeout struct {
 		ICEConnection                *time.Duration
 		ICEKeepalive                 *time.Duration
+		ICEFailed                    time.Duration
 		ICECandidateSelectionTimeout *time.Duration
 		ICEHostAcceptanceMinWait     *time.Duration
 		ICESrflxAcceptanceMinWait    *time.Duration
 		ICEPrflxAcceptanceMinWait    *time.Duration
 		ICERelayAcceptanceMinWait    *time.Duration
+		DTLSFlightInterval           time.Duration
+		DTLSHandshake                time.Duration
 	}
 	candidates struct {
-		ICELite                        bool
-		ICETrickle                     bool
-		ICENetworkTypes                []NetworkType
-		InterfaceFilter                func(string) bool
-		NAT1To1IPs                     []string
-		NAT1To1IPCandidateType         ICECandidateType
-		GenerateMulticastDNSCandidates bool
-		MulticastDNSHostName           string
-		UsernameFragment               string
-		Password                       string
+		ICELite                bool
+		ICETrickle             bool
+		ICENetworkTypes        []NetworkType
+		InterfaceFilter        func(string) bool
+		NAT1To1IPs             []string
+		NAT1To1IPCandidateType ICECandidateType
+		MulticastDNSMode       ice.MulticastDNSMode
+		MulticastDNSHostName   string
+		UsernameFragment       string
+		Password               string
+		TURNInsecureSkipVerify bool
+		ICEServerResolver      ICEServerResolver
+		PrivacyPolicy          ICECandidatePrivacyPolicy
 	}
 	replayProtection struct {
 		DTLS  *uint
 		SRTP  *uint
 		SRTCP *uint
 	}
+	sctp struct {
+		MaxReceiveBufferSize uint32
+		MaxSendBufferSize    uint64
+		MaxChannels          uint16
+	}
+	generators struct {
+		Mid     func() string
+		CNAME   func(track *Track) string
+		TrackID func() string
+	}
+	senderReportInterval                      time.Duration
+	freezeRecoveryInterval                    time.Duration
+	networkMonitorInterval                    time.Duration
+	iceServerHealthCheckInterval              time.Duration
 	answeringDTLSRole                         DTLSRole
+	dtlsRole                                  DTLSRole
+	iceRole                                   ICERole
+	mirrorRejectedMediaSections               bool
 	disableCertificateFingerprintVerification bool
+	verifyDTLSPeerCertificate                 func(*x509.Certificate, error) error
+	fipsMode                                  bool
 	disableSRTPReplayProtection               bool
 	disableSRTCPReplayProtection              bool
+	disableSRTPOverVNet                       bool
+	dataChannelOnly                           bool
 	vnet                                      *vnet.Net
 	LoggerFactory                             logging.LoggerFactory
 }
@@ -63,18 +91,86 @@ func (e *SettingEngine) DetachDataChannels() {
 	e.detach.DataChannels = true
 }
 
+// SetSCTPMaxReceiveBufferSize sets the size of the SCTP receive buffer, in
+// bytes, 1 MiB by default. It's the receiver window (a_rwnd) advertised to the
+// remote peer, so it bounds the data in flight of all the DataChannels: a
+// larger buffer raises the throughput on the links with a high latency, a
+// smaller one bounds the memory of a PeerConnection. 0 keeps the default.
+func (e *SettingEngine) SetSCTPMaxReceiveBufferSize(size uint32) {
+	e.sctp.MaxReceiveBufferSize = size
+}
+
+// SetSCTPMaxSendBufferSize limits the data queued to be sent on each
+// DataChannel, in bytes. Send fails with an OperationError when the message
+// would raise the BufferedAmount over the limit. 0, the default, doesn't
+// limit it.
+func (e *SettingEngine) SetSCTPMaxSendBufferSize(size uint64) {
+	e.sctp.MaxSendBufferSize = size
+}
+
+// SetSCTPMaxChannels limits the number of DataChannels of a PeerConnection,
+// and their IDs, to the first max SCTP streams, 65535 by default.
+// CreateDataChannel fails with an OperationError when the DataChannels not
+// closed reach the limit or when no ID is left, and the remote DataChannels
+// with a larger ID are rejected. 0 keeps the default.
+func (e *SettingEngine) SetSCTPMaxChannels(max uint16) {
+	e.sctp.MaxChannels = max
+}
+
 // SetConnectionTimeout sets the amount of silence needed on a given candidate pair
-// before the ICE agent considers the pair timed out.
+// before the ICE agent considers the pair timed out. keepAlive sets the same
+// interval as SetICETimeouts and SetICEKeepaliveInterval, the last call wins.
 func (e *SettingEngine) SetConnectionTimeout(connectionTimeout, keepAlive time.Duration) {
 	e.timeout.ICEConnection = &connectionTimeout
 	e.timeout.ICEKeepalive = &keepAlive
 }
 
+// SetICETimeouts sets the ICE timers. disconnectedTimeout is the amount of
+// silence on the selected candidate pair before the connection is
+// disconnected, failedTimeout how long the connection can stay disconnected
+// before it's failed, 0 (the default) never fails it, and keepAliveInterval
+// how often a STUN binding request is sent on an idle selected pair. A failed
+// connection is recovered only by an ICE restart. The interval of the
+// connectivity checks isn't configurable in pion/ice. Like
+// SetConnectionTimeout, it overrides the interval set by a previous
+// SetICEKeepaliveInterval call.
+func (e *SettingEngine) SetICETimeouts(disconnectedTimeout, failedTimeout, keepAliveInterval time.Duration) {
+	e.timeout.ICEConnection = &disconnectedTimeout
+	e.timeout.ICEFailed = failedTimeout
+	e.timeout.ICEKeepalive = &keepAliveInterval
+}
+
+// SetICEKeepaliveInterval sets how often a STUN binding request is sent on
+// the selected candidate pair when nothing else was sent, 10 seconds by
+// default. Battery-sensitive clients can lengthen it, clients behind NATs
+// with short binding lifetimes shorten it. 0 disables the keepalives, the
+// remote agent could then disconnect an idle connection. It overrides the
+// keepAlive of a previous SetConnectionTimeout or SetICETimeouts call, and a
+// later call of either overrides it.
+func (e *SettingEngine) SetICEKeepaliveInterval(interval time.Duration) {
+	e.timeout.ICEKeepalive = &interval
+}
+
 // SetCandidateSelectionTimeout sets the max ICECandidateSelectionTimeout
 func (e *SettingEngine) SetCandidateSelectionTimeout(t time.Duration) {
 	e.timeout.ICECandidateSelectionTimeout = &t
 }
 
+// SetICENominationSettlingPeriod sets how long the controlling agent keeps
+// checking the candidate pairs before nominating the best valid one, for
+// every candidate type (the Set*AcceptanceMinWait setters set a period per
+// type). pion/ice uses the regular nomination: a 0 period nominates the
+// first valid pair, like the aggressive nomination, while a longer one lets
+// a better pair succeed first on lossy networks. The defaults wait 0 for the
+// host, 500ms for the srflx, 1s for the prflx and 2s for the relay
+// candidates.
+func (e *SettingEngine) SetICENominationSettlingPeriod(period time.Duration) {
+	e.SetHostAcceptanceMinWait(period)
+	e.SetSrflxAcceptanceMinWait(period)
+	e.SetPrflxAcceptanceMinWait(period)
+	e.SetRelayAcceptanceMinWait(period)
+}
+
 // SetHostAcceptanceMinWait sets the ICEHostAcceptanceMinWait
 func (e *SettingEngine) SetHostAcceptanceMinWait(t time.Duration) {
 	e.timeout.ICEHostAcceptanceMinWait = &t
@@ -98,6 +194,11 @@ func (e *SettingEngine) SetRelayAcceptanceMinWait(t time.Duration) {
 // SetEphemeralUDPPortRange limits the pool of ephemeral ports that
 // ICE UDP connections can allocate from. This affects both host candidates,
 // and the local address of server reflexive candidates.
+//
+// Every PeerConnection binds a port for each local address and network type,
+// the range must be large enough for all the concurrent PeerConnections,
+// when it's exhausted the candidates that can't bind a port are skipped. The
+// connections to the TURN servers aren't constrained.
 func (e *SettingEngine) SetEphemeralUDPPortRange(portMin, portMax uint16) error {
 	if portMax < portMin {
 		return ice.ErrPort
@@ -109,6 +210,10 @@ func (e *SettingEngine) SetEphemeralUDPPortRange(portMin, portMax uint16) error
 }
 
 // SetLite configures whether or not the ice agent should be a lite agent
+// (RFC 8445 S2.5), meant for the servers with a public IP address. A lite
+// agent gathers only host candidates, advertises a=ice-lite and doesn't
+// initiate the connectivity checks. It takes the controlled role, unless the
+// remote agent is lite too and the local one is the offerer.
 func (e *SettingEngine) SetLite(lite bool) {
 	e.candidates.ICELite = lite
 }
@@ -120,7 +225,10 @@ func (e *SettingEngine) SetTrickle(trickle bool) {
 }
 
 // SetNetworkTypes configures what types of candidate networks are supported
-// during local and server reflexive gathering.
+// during local and server reflexive gathering. For example it can be limited
+// to NetworkTypeUDP4 on the hosts with a broken IPv6 connectivity, to avoid
+// the connectivity checks of the IPv6 candidates. The TCP network types
+// aren't supported yet, no candidates are gathered for them.
 func (e *SettingEngine) SetNetworkTypes(candidateTypes []NetworkType) {
 	e.candidates.ICENetworkTypes = candidateTypes
 }
@@ -129,10 +237,24 @@ func (e *SettingEngine) SetNetworkTypes(candidateTypes []NetworkType) {
 // This can be used to exclude certain network interfaces from ICE. Which may be
 // useful if you know a certain interface will never succeed, or if you wish to reduce
 // the amount of information you wish to expose to the remote peer
+//
+// The filter is called with the name of every network interface that is up,
+// the host candidates are gathered only for the interfaces it returns true
+// for. The server reflexive and relay candidates aren't filtered.
 func (e *SettingEngine) SetInterfaceFilter(filter func(string) bool) {
 	e.candidates.InterfaceFilter = filter
 }
 
+// SetICECandidatePrivacyPolicy sets the local addresses revealed by the
+// signaled candidates, for the applications that must not leak the topology
+// of their local network. With ICECandidatePrivacyPolicyNoHost a STUN or TURN
+// server is needed to have any candidate, and ICE lite can't be used. The
+// remote peer still learns the addresses used by the connectivity checks as
+// peer reflexive candidates.
+func (e *SettingEngine) SetICECandidatePrivacyPolicy(policy ICECandidatePrivacyPolicy) {
+	e.candidates.PrivacyPolicy = policy
+}
+
 // SetNAT1To1IPs sets a list of external IP addresses of 1:1 (D)NAT
 // and a candidate type for which the external IP address is used.
 // This is useful when you are host a server using Pion on an AWS EC2 instance
@@ -156,11 +278,69 @@ func (e *SettingEngine) SetInterfaceFilter(filter func(string) bool) {
 // with the public IP. The host candidate is still available along with mDNS
 // capabilities unaffected. Also, you cannot give STUN server URL at the same time.
 // It will result in an error otherwise.
+//
+// The other candidate types aren't supported, the gathering fails with
+// ice.ErrUnsupportedNAT1To1IPCandidateType.
 func (e *SettingEngine) SetNAT1To1IPs(ips []string, candidateType ICECandidateType) {
 	e.candidates.NAT1To1IPs = ips
 	e.candidates.NAT1To1IPCandidateType = candidateType
 }
 
+// SetSenderReportInterval enables the generation of RTCP sender reports for
+// the outbound streams, sent every interval. Sender reports are disabled when
+// interval is 0 (the default).
+//
+// The rtp timestamps of the sent packets are never changed, the reports are
+// derived from them. No report is sent for a paused stream, they resume with
+// its packets, so the remote jitter buffers don't see a timestamp jump.
+func (e *SettingEngine) SetSenderReportInterval(interval time.Duration) {
+	e.senderReportInterval = interval
+}
+
+// SetFreezeRecoveryInterval enables the freeze detection of the remote video
+// streams. When a stream stops producing decodable frames (a packet loss not
+// followed by the retransmission of the lost packets or by a keyframe) a
+// recovery sequence is run: first the lost packets are requested with a
+// NACK, then a keyframe is requested with a PLI and then with a FIR, every
+// step waiting interval before the next one. If the stream is still broken
+// TrackRTPStream.OnFrozen is fired. Freeze detection is disabled when interval
+// is 0 (the default).
+func (e *SettingEngine) SetFreezeRecoveryInterval(interval time.Duration) {
+	e.freezeRecoveryInterval = interval
+}
+
+// SetICERestartOnNetworkChange keeps checking the addresses of the local
+// interfaces every checkInterval, and requests an ICE restart (see
+// PeerConnection.RestartICE) when they change, e.g. when moving from Wi-Fi to
+// LTE. This isn't continual gathering: pion/ice can't gather new candidates
+// for a running agent, they're gathered by the restart and emitted by
+// OnICECandidate once the restart offer is set. 0 (the default) disables it.
+func (e *SettingEngine) SetICERestartOnNetworkChange(checkInterval time.Duration) {
+	e.networkMonitorInterval = checkInterval
+}
+
+// SetICEServerHealthChecks sends a STUN binding request to every ICE server
+// when the candidates are first gathered and then every interval, until the
+// ICEGatherer is closed. The reachability and the round trip time of every
+// server are reported by GetStats as ICEServerStats, and the stats of the
+// local relay candidates have the URL of the TURN server whose address is
+// the relayed one. Only the servers reached over UDP (stun: and turn: with
+// the udp transport) are checked. 0 (the default) disables the checks.
+func (e *SettingEngine) SetICEServerHealthChecks(interval time.Duration) {
+	e.iceServerHealthCheckInterval = interval
+}
+
+// SetMirrorRejectedMediaSections controls how the media sections rejected by
+// an answer are generated. Answers always keep the offered media sections
+// order and mids, the rejected ones have port 0. By default a rejected media
+// section is a minimal inactive section, when mirroring is enabled it keeps
+// the media type, transport protocol and formats of the offered section. This
+// may be useful with endpoints that require the answered m-lines to match the
+// offered ones.
+func (e *SettingEngine) SetMirrorRejectedMediaSections(mirror bool) {
+	e.mirrorRejectedMediaSections = mirror
+}
+
 // SetAnsweringDTLSRole sets the DTLS role that is selected when offering
 // The DTLS role controls if the WebRTC Client as a client or server. This
 // may be useful when interacting with non-compliant clients or debugging issues.
@@ -178,18 +358,97 @@ func (e *SettingEngine) SetAnsweringDTLSRole(role DTLSRole) error {
 	return nil
 }
 
+// SetDTLSRole forces the local DTLS role, instead of negotiating it with the
+// a=setup attributes (RFC 5763 S5). The local descriptions offer and answer
+// the forced role, and the role announced by the remote description is
+// ignored. This may be useful when interacting with endpoints that mishandle
+// actpass, the remote endpoint must take the other role.
+func (e *SettingEngine) SetDTLSRole(role DTLSRole) error {
+	if role != DTLSRoleClient && role != DTLSRoleServer {
+		return errors.New("SetDTLSRole must DTLSRoleClient or DTLSRoleServer")
+	}
+
+	e.dtlsRole = role
+	return nil
+}
+
+// SetDTLSTimeouts sets the DTLS handshake timers. flightInterval is how often
+// an unacknowledged flight of handshake messages is retransmitted, 1 second
+// by default, and handshakeTimeout how long the handshake can last before
+// the DTLS transport fails, 30 seconds by default. A lossy link fails over
+// faster with shorter timers, a link with a long round trip time, like a
+// satellite one, needs a flightInterval longer than it. 0 keeps a default.
+func (e *SettingEngine) SetDTLSTimeouts(flightInterval, handshakeTimeout time.Duration) {
+	e.timeout.DTLSFlightInterval = flightInterval
+	e.timeout.DTLSHandshake = handshakeTimeout
+}
+
+// SetICERole forces the ICE role of the agent, instead of selecting it from
+// the offerer and the lite agents (RFC 8445 S6.1.1). The remote agent must use
+// the other role, as when interacting with non-compliant ICE lite peers or for
+// deterministic tests, otherwise the role conflicts are resolved by the
+// tie-breakers.
+func (e *SettingEngine) SetICERole(role ICERole) error {
+	if role != ICERoleControlling && role != ICERoleControlled {
+		return errors.New("SetICERole must ICERoleControlling or ICERoleControlled")
+	}
+
+	e.iceRole = role
+	return nil
+}
+
+// DisableSRTPOverVNet sends the RTP and RTCP packets in clear, so the tests
+// and the fuzzers can inspect them on the virtual network. It's meant for
+// testing only, both endpoints must disable SRTP and SRTP fails to start
+// unless a virtual VNet is set with SetVNet. The DTLS handshake and the SRTP
+// key derivation are unchanged.
+func (e *SettingEngine) DisableSRTPOverVNet(isDisabled bool) {
+	e.disableSRTPOverVNet = isDisabled
+}
+
+// SetDataChannelOnly makes the PeerConnections only support data channels,
+// for the applications creating a lot of them. The SRTP endpoints, sessions
+// and their goroutines are never started and the MediaEngine isn't needed,
+// the SRTP protection profiles are still negotiated by DTLS. Adding a track or a
+// transceiver fails with ErrDataChannelOnly and the remote audio and video
+// media sections are rejected.
+func (e *SettingEngine) SetDataChannelOnly(dataChannelOnly bool) {
+	e.dataChannelOnly = dataChannelOnly
+}
+
 // SetVNet sets the VNet instance that is passed to pion/ice
 //
 // VNet is a virtual network layer for Pion, allowing users to simulate
 // different topologies, latency, loss and jitter. This can be useful for
 // learning WebRTC concepts or testing your application in a lab environment
+//
+// The interfaces, the UDP sockets of the candidates and the STUN and
+// TURN/UDP servers go through the VNet. The mDNS queries are disabled with a
+// virtual VNet unless a mode is set with SetICEMulticastDNSMode, and the
+// TURN servers over TCP, TLS and DTLS are still reached through the real
+// network.
 func (e *SettingEngine) SetVNet(vnet *vnet.Net) {
 	e.vnet = vnet
 }
 
 // GenerateMulticastDNSCandidates instructs pion/ice to generate host candidates with mDNS hostnames instead of IP Addresses
+// Deprecated: use SetICEMulticastDNSMode with ice.MulticastDNSModeQueryAndGather instead
 func (e *SettingEngine) GenerateMulticastDNSCandidates(generateMulticastDNSCandidates bool) {
-	e.candidates.GenerateMulticastDNSCandidates = generateMulticastDNSCandidates
+	e.candidates.MulticastDNSMode = ice.MulticastDNSModeQueryOnly
+	if generateMulticastDNSCandidates {
+		e.candidates.MulticastDNSMode = ice.MulticastDNSModeQueryAndGather
+	}
+}
+
+// SetICEMulticastDNSMode controls the mDNS (RFC 6762) usage of pion/ice.
+// Browsers obfuscate their host candidates with .local hostnames, by default
+// (ice.MulticastDNSModeQueryOnly) these remote candidates are resolved with
+// multicast DNS queries while the local host candidates keep their IP
+// addresses. With ice.MulticastDNSModeQueryAndGather the local host
+// candidates use an mDNS hostname too, and with ice.MulticastDNSModeDisabled
+// the remote mDNS candidates are discarded.
+func (e *SettingEngine) SetICEMulticastDNSMode(multicastDNSMode ice.MulticastDNSMode) {
+	e.candidates.MulticastDNSMode = multicastDNSMode
 }
 
 // SetMulticastDNSHostName sets a static HostName to be used by pion/ice instead of generating one on startup
@@ -200,6 +459,52 @@ func (e *SettingEngine) SetMulticastDNSHostName(hostName string) {
 	e.candidates.MulticastDNSHostName = hostName
 }
 
+// SetTURNInsecureSkipVerify disables the verification of the certificate of
+// the TURN servers reached over TLS (turns:?transport=tcp) or DTLS
+// (turns:?transport=udp), accepting self-signed certificates. The
+// certificates are otherwise verified against the system roots. Over TLS the
+// certificate must be valid for the host of the URL. Over DTLS the hostname
+// is never verified: pion/ice dials the resolved address, so the certificate
+// must be valid for the server IP address and one issued only for the
+// hostname is rejected.
+func (e *SettingEngine) SetTURNInsecureSkipVerify(insecureSkipVerify bool) {
+	e.candidates.TURNInsecureSkipVerify = insecureSkipVerify
+}
+
+// SetICEServerResolver sets the resolver of the hostnames of the ICE servers,
+// for split-horizon DNS, DNS over HTTPS or hermetic tests, instead of the
+// system one. The hostnames are resolved to their first IPv4 address each
+// time the candidates are gathered, a server that can't be resolved is
+// skipped. The turns servers keep their hostname, since it's needed to
+// verify their certificate.
+func (e *SettingEngine) SetICEServerResolver(resolver ICEServerResolver) {
+	e.candidates.ICEServerResolver = resolver
+}
+
+// SetMidGenerator sets the function generating the mids of the media sections
+// offered for new transceivers and data channels, instead of the numeric ones.
+// A mid must be an SDP token of at most 16 characters, since it's sent in an
+// RTP header extension, not already used in the session or CreateOffer fails.
+func (e *SettingEngine) SetMidGenerator(f func() string) {
+	e.generators.Mid = f
+}
+
+// SetCNAMEGenerator sets the function generating the CNAME signaled for a track
+// when it's added to an RTPSender, instead of the track label. A CNAME must
+// have at most 255 bytes, no whitespaces and control characters or the
+// RTPSender creation fails.
+func (e *SettingEngine) SetCNAMEGenerator(f func(track *Track) string) {
+	e.generators.CNAME = f
+}
+
+// SetTrackIDGenerator sets the function generating the ids of the tracks
+// created by AddTransceiverFromKind, instead of random ones. A track id must be
+// an SDP token of at most 64 characters (RFC 8830 2) or the transceiver
+// creation fails.
+func (e *SettingEngine) SetTrackIDGenerator(f func() string) {
+	e.generators.TrackID = f
+}
+
 // SetICECredentials sets a staic uFrag/uPwd to be used by pion/ice
 //
 // This is useful if you want to do signalless WebRTC session, or having a reproducible environment with static credentials
@@ -213,18 +518,60 @@ func (e *SettingEngine) DisableCertificateFingerprintVerification(isDisabled boo
 	e.disableCertificateFingerprintVerification = isDisabled
 }
 
+// SetDTLSVerifyPeerCertificate sets a function called after the DTLS
+// handshake, before the SRTP and SCTP transports are started, with the
+// certificate of the remote peer and the result of its comparison with the
+// fingerprints of the remote description (nil when they match, or when the
+// verification is disabled). It can pin the certificate or validate the
+// identity of the remote peer, the DTLS transport fails when it returns an
+// error. Returning fingerprintErr keeps the default verification.
+func (e *SettingEngine) SetDTLSVerifyPeerCertificate(verify func(certificate *x509.Certificate, fingerprintErr error) error) {
+	e.verifyDTLSPeerCertificate = verify
+}
+
+// SetFIPSMode limits the cryptography to the algorithms approved by FIPS
+// 140, except the DTLS key exchange:
+//
+// Certificates:
+//		ECDSA keys on the P-256, P-384 and P-521 curves, RSA keys of 2048 bits
+//		or more, for the local and the remote certificates
+// DTLS:
+//		the ECDHE AES-GCM cipher suites, the ECDSA and RSA PKCS#1 signatures
+//		with the SHA-2 hashes, and the extended master secret
+// SRTP:
+//		the SRTP_AES128_CM_HMAC_SHA1_80 protection profile
+// Fingerprints:
+//		SHA-256 or stronger
+//
+// The PeerConnections fail to be created with other certificates, the remote
+// descriptions without an approved fingerprint are rejected, and the DTLS
+// transport fails when the remote peer can't negotiate the approved
+// algorithms.
+//
+// It doesn't make a deployment FIPS compliant: pion/dtls always uses X25519,
+// which isn't approved, for the ECDHE key exchange and can't be limited to
+// the NIST curves, and the Go cryptography isn't a validated module.
+func (e *SettingEngine) SetFIPSMode(enabled bool) {
+	e.fipsMode = enabled
+}
+
 // SetDTLSReplayProtectionWindow sets a replay attack protection window size of DTLS connection.
 func (e *SettingEngine) SetDTLSReplayProtectionWindow(n uint) {
 	e.replayProtection.DTLS = &n
 }
 
 // SetSRTPReplayProtectionWindow sets a replay attack protection window size of SRTP session.
+// The received packets with a sequence number older than the window, 64
+// packets by default, compared to the latest accepted one are dropped. It can
+// be enlarged on the networks with heavy reordering, a window larger than the
+// packets received during the jitter buffer delay isn't useful.
 func (e *SettingEngine) SetSRTPReplayProtectionWindow(n uint) {
 	e.disableSRTPReplayProtection = false
 	e.replayProtection.SRTP = &n
 }
 
 // SetSRTCPReplayProtectionWindow sets a replay attack protection window size of SRTCP session.
+// Like the SRTP one it's 64 packets by default, counted with the SRTCP index.
 func (e *SettingEngine) SetSRTCPReplayProtectionWindow(n uint) {
 	e.disableSRTCPReplayProtection = false
 	e.replayProtection.SRTCP = &n
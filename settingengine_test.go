@@ -50,6 +50,33 @@ func TestSetConnectionTimeout(t *testing.T) {
 	}
 }
 
+func TestSetICEKeepaliveInterval(t *testing.T) {
+	s := SettingEngine{}
+	s.SetICEKeepaliveInterval(20 * time.Second)
+	assert.Nil(t, s.timeout.ICEConnection)
+	assert.Equal(t, 20*time.Second, *s.timeout.ICEKeepalive)
+
+	// The last call wins
+	s.SetICETimeouts(5*time.Second, 0, 2*time.Second)
+	assert.Equal(t, 2*time.Second, *s.timeout.ICEKeepalive)
+	s.SetICEKeepaliveInterval(30 * time.Second)
+	assert.Equal(t, 5*time.Second, *s.timeout.ICEConnection)
+	assert.Equal(t, 30*time.Second, *s.timeout.ICEKeepalive)
+}
+
+func TestSetICENominationSettlingPeriod(t *testing.T) {
+	s := SettingEngine{}
+	s.SetICENominationSettlingPeriod(time.Second)
+	for _, wait := range []*time.Duration{
+		s.timeout.ICEHostAcceptanceMinWait,
+		s.timeout.ICESrflxAcceptanceMinWait,
+		s.timeout.ICEPrflxAcceptanceMinWait,
+		s.timeout.ICERelayAcceptanceMinWait,
+	} {
+		assert.Equal(t, time.Second, *wait)
+	}
+}
+
 func TestDetachDataChannels(t *testing.T) {
 	s := SettingEngine{}
 
@@ -90,6 +117,20 @@ func TestSetAnsweringDTLSRole(t *testing.T) {
 	assert.Error(t, s.SetAnsweringDTLSRole(DTLSRole(0)), "SetAnsweringDTLSRole can only be called with DTLSRoleClient or DTLSRoleServer")
 }
 
+func TestSetDTLSRole(t *testing.T) {
+	s := SettingEngine{}
+	assert.Error(t, s.SetDTLSRole(DTLSRoleAuto), "SetDTLSRole can only be called with DTLSRoleClient or DTLSRoleServer")
+	assert.NoError(t, s.SetDTLSRole(DTLSRoleServer))
+	assert.Equal(t, DTLSRoleServer, s.dtlsRole)
+}
+
+func TestSetICERole(t *testing.T) {
+	s := SettingEngine{}
+	assert.Error(t, s.SetICERole(ICERole(0)), "SetICERole can only be called with ICERoleControlling or ICERoleControlled")
+	assert.NoError(t, s.SetICERole(ICERoleControlled))
+	assert.Equal(t, ICERoleControlled, s.iceRole)
+}
+
 func TestSetReplayProtection(t *testing.T) {
 	s := SettingEngine{}
 
@@ -116,3 +157,46 @@ func TestSetReplayProtection(t *testing.T) {
 		t.Errorf("Failed to set SRTCP replay protection window")
 	}
 }
+
+func TestSetSCTPBufferSizes(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.sctp.MaxReceiveBufferSize != 0 ||
+		s.sctp.MaxSendBufferSize != 0 {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	s.SetSCTPMaxReceiveBufferSize(64 * 1024)
+	s.SetSCTPMaxSendBufferSize(4 * 1024)
+
+	if s.sctp.MaxReceiveBufferSize != 64*1024 {
+		t.Errorf("Failed to set SCTP max receive buffer size")
+	}
+	if s.sctp.MaxSendBufferSize != 4*1024 {
+		t.Errorf("Failed to set SCTP max send buffer size")
+	}
+}
+
+func TestSetDataChannelOnly(t *testing.T) {
+	s := SettingEngine{}
+	if s.dataChannelOnly {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	s.SetDataChannelOnly(true)
+	if !s.dataChannelOnly {
+		t.Errorf("Failed to enable the data channel only mode")
+	}
+}
+
+func TestSetSCTPMaxChannels(t *testing.T) {
+	s := SettingEngine{}
+	if s.sctp.MaxChannels != 0 {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	s.SetSCTPMaxChannels(16)
+	if s.sctp.MaxChannels != 16 {
+		t.Errorf("Failed to set SCTP max channels")
+	}
+}
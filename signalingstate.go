@@ -103,12 +103,51 @@ func (t SignalingState) String() string {
 	}
 }
 
+// SignalingStateTransitionError reports a description set in a signaling
+// state that doesn't allow it, i.e. an answer set without a pending offer.
+// It's returned by SetLocalDescription and SetRemoteDescription wrapped in a
+// rtcerr.InvalidModificationError.
+type SignalingStateTransitionError struct {
+	// Current is the signaling state when the description was set
+	Current SignalingState
+	// Next is the signaling state proposed by the description
+	Next SignalingState
+	// Remote reports if the description was set by SetRemoteDescription
+	Remote bool
+	// Type is the type of the description
+	Type SDPType
+	// Err is ErrSignalingStateCannotRollback or
+	// ErrSignalingStateProposedTransitionInvalid
+	Err error
+}
+
+func (e *SignalingStateTransitionError) Error() string {
+	op := stateChangeOpSetLocal
+	if e.Remote {
+		op = stateChangeOpSetRemote
+	}
+	return fmt.Sprintf("%v %s->%s(%s)->%s", e.Err, e.Current, op, e.Type, e.Next)
+}
+
+// Unwrap returns the reason of the failure
+func (e *SignalingStateTransitionError) Unwrap() error {
+	return e.Err
+}
+
+func newSignalingStateTransitionError(cur, next SignalingState, op stateChangeOp, sdpType SDPType, err error) error {
+	return &rtcerr.InvalidModificationError{Err: &SignalingStateTransitionError{
+		Current: cur,
+		Next:    next,
+		Remote:  op == stateChangeOpSetRemote,
+		Type:    sdpType,
+		Err:     err,
+	}}
+}
+
 func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType SDPType) (SignalingState, error) {
 	// Special case for rollbacks
 	if sdpType == SDPTypeRollback && cur == SignalingStateStable {
-		return cur, &rtcerr.InvalidModificationError{
-			Err: fmt.Errorf("can't rollback from stable state"),
-		}
+		return cur, newSignalingStateTransitionError(cur, next, op, sdpType, ErrSignalingStateCannotRollback)
 	}
 
 	// 4.3.1 valid state transitions
@@ -127,6 +166,10 @@ func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType
 			}
 		}
 	case SignalingStateHaveLocalOffer:
+		// have-local-offer->SetLocal(rollback)->stable
+		if op == stateChangeOpSetLocal && sdpType == SDPTypeRollback && next == SignalingStateStable {
+			return next, nil
+		}
 		if op == stateChangeOpSetRemote {
 			switch sdpType {
 			// have-local-offer->SetRemote(answer)->stable
@@ -142,6 +185,10 @@ func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType
 			}
 		}
 	case SignalingStateHaveRemotePranswer:
+		// have-remote-pranswer->SetLocal(rollback)->stable
+		if op == stateChangeOpSetLocal && sdpType == SDPTypeRollback && next == SignalingStateStable {
+			return next, nil
+		}
 		if op == stateChangeOpSetRemote && sdpType == SDPTypeAnswer {
 			// have-remote-pranswer->SetRemote(answer)->stable
 			if next == SignalingStateStable {
@@ -149,6 +196,10 @@ func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType
 			}
 		}
 	case SignalingStateHaveRemoteOffer:
+		// have-remote-offer->SetRemote(rollback)->stable
+		if op == stateChangeOpSetRemote && sdpType == SDPTypeRollback && next == SignalingStateStable {
+			return next, nil
+		}
 		if op == stateChangeOpSetLocal {
 			switch sdpType {
 			// have-remote-offer->SetLocal(answer)->stable
@@ -164,6 +215,10 @@ func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType
 			}
 		}
 	case SignalingStateHaveLocalPranswer:
+		// have-local-pranswer->SetRemote(rollback)->stable
+		if op == stateChangeOpSetRemote && sdpType == SDPTypeRollback && next == SignalingStateStable {
+			return next, nil
+		}
 		if op == stateChangeOpSetLocal && sdpType == SDPTypeAnswer {
 			// have-local-pranswer->SetLocal(answer)->stable
 			if next == SignalingStateStable {
@@ -172,7 +227,5 @@ func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType
 		}
 	}
 
-	return cur, &rtcerr.InvalidModificationError{
-		Err: fmt.Errorf("invalid proposed signaling state transition %s->%s(%s)->%s", cur, op, sdpType, next),
-	}
+	return cur, newSignalingStateTransitionError(cur, next, op, sdpType, ErrSignalingStateProposedTransitionInvalid)
 }
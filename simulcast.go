@@ -0,0 +1,211 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pion/sdp/v2"
+)
+
+const (
+	// TODO(sgotti) define these in pion/sdp
+	sdpAttributeRid       = "rid"
+	sdpAttributeSimulcast = "simulcast"
+
+	ridDirectionSend = "send"
+	ridDirectionRecv = "recv"
+
+	simulcastPausedPrefix = "~"
+)
+
+// ridDescription is the content of an a=rid attribute (RFC 8851)
+type ridDescription struct {
+	id        string
+	direction string
+	// payloadTypes are the payload types allowed for the stream, all the
+	// media section ones when empty
+	payloadTypes []uint8
+	// restrictions are the other restrictions of the stream (max-width,
+	// max-fps...) keyed by name
+	restrictions map[string]string
+}
+
+// simulcastStream is a stream listed by an a=simulcast attribute
+type simulcastStream struct {
+	rid    string
+	paused bool
+}
+
+// simulcastDescription is the content of an a=simulcast attribute
+// (RFC 8853). Every entry of the lists contains the alternative streams of a
+// simulcast stream, in preference order.
+type simulcastDescription struct {
+	send [][]simulcastStream
+	recv [][]simulcastStream
+}
+
+// isRidID reports if s is a valid rid-id, the RtpStreamId header extension
+// limits it to 16 bytes
+func isRidID(s string) bool {
+	if s == "" || len(s) > maxRidLength {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// parseRid parses the value of an a=rid attribute:
+// <rid-id> <direction> [pt=<fmt-list>;<restriction>=<value>...]
+func parseRid(value string) (*ridDescription, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 2 || len(fields) > 3 || !isRidID(fields[0]) {
+		return nil, ErrSessionDescriptionInvalidRid
+	}
+	if fields[1] != ridDirectionSend && fields[1] != ridDirectionRecv {
+		return nil, ErrSessionDescriptionInvalidRid
+	}
+
+	rid := &ridDescription{id: fields[0], direction: fields[1], restrictions: map[string]string{}}
+	if len(fields) == 2 {
+		return rid, nil
+	}
+	for _, param := range strings.Split(fields[2], ";") {
+		kv := strings.SplitN(param, "=", 2)
+		if kv[0] == "" {
+			return nil, ErrSessionDescriptionInvalidRid
+		}
+		if kv[0] != "pt" {
+			rid.restrictions[kv[0]] = ""
+			if len(kv) == 2 {
+				rid.restrictions[kv[0]] = kv[1]
+			}
+			continue
+		}
+
+		if len(kv) != 2 {
+			return nil, ErrSessionDescriptionInvalidRid
+		}
+		for _, pt := range strings.Split(kv[1], ",") {
+			payloadType, err := strconv.ParseUint(pt, 10, 7)
+			if err != nil {
+				return nil, ErrSessionDescriptionInvalidRid
+			}
+			rid.payloadTypes = append(rid.payloadTypes, uint8(payloadType))
+		}
+	}
+	return rid, nil
+}
+
+// parseSimulcast parses the value of an a=simulcast attribute:
+// send <streams> [recv <streams>], or the other way around
+func parseSimulcast(value string) (*simulcastDescription, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 && len(fields) != 4 {
+		return nil, ErrSessionDescriptionInvalidSimulcast
+	}
+
+	s := &simulcastDescription{}
+	for i := 0; i < len(fields); i += 2 {
+		list, err := parseSimulcastList(fields[i+1])
+		switch {
+		case err != nil:
+			return nil, err
+		case fields[i] == ridDirectionSend && s.send == nil:
+			s.send = list
+		case fields[i] == ridDirectionRecv && s.recv == nil:
+			s.recv = list
+		default:
+			return nil, ErrSessionDescriptionInvalidSimulcast
+		}
+	}
+	return s, nil
+}
+
+// parseSimulcastList parses a list of simulcast streams separated by ";",
+// every one made by alternative rids separated by ",", the paused ones
+// prefixed with "~"
+func parseSimulcastList(value string) ([][]simulcastStream, error) {
+	list := [][]simulcastStream{}
+	for _, alternatives := range strings.Split(value, ";") {
+		streams := []simulcastStream{}
+		for _, id := range strings.Split(alternatives, ",") {
+			stream := simulcastStream{
+				rid:    strings.TrimPrefix(id, simulcastPausedPrefix),
+				paused: strings.HasPrefix(id, simulcastPausedPrefix),
+			}
+			if !isRidID(stream.rid) {
+				return nil, ErrSessionDescriptionInvalidSimulcast
+			}
+			streams = append(streams, stream)
+		}
+		list = append(list, streams)
+	}
+	return list, nil
+}
+
+func formatSimulcastList(list [][]simulcastStream) string {
+	streams := []string{}
+	for _, alternatives := range list {
+		ids := []string{}
+		for _, stream := range alternatives {
+			id := stream.rid
+			if stream.paused {
+				id = simulcastPausedPrefix + id
+			}
+			ids = append(ids, id)
+		}
+		streams = append(streams, strings.Join(ids, ","))
+	}
+	return strings.Join(streams, ";")
+}
+
+// getSimulcast returns the rids and the simulcast description, nil if
+// missing, of a media section. Every stream listed by the simulcast
+// description must be declared by a rid with the same direction.
+func getSimulcast(media *sdp.MediaDescription) (map[string]*ridDescription, *simulcastDescription, error) {
+	rids := map[string]*ridDescription{}
+	var simulcast *simulcastDescription
+	for _, attr := range media.Attributes {
+		switch attr.Key {
+		case sdpAttributeRid:
+			rid, err := parseRid(attr.Value)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, ok := rids[rid.id]; ok {
+				return nil, nil, ErrSessionDescriptionInvalidRid
+			}
+			rids[rid.id] = rid
+		case sdpAttributeSimulcast:
+			if simulcast != nil {
+				return nil, nil, ErrSessionDescriptionInvalidSimulcast
+			}
+			var err error
+			if simulcast, err = parseSimulcast(attr.Value); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if simulcast == nil {
+		return rids, nil, nil
+	}
+
+	for direction, list := range map[string][][]simulcastStream{ridDirectionSend: simulcast.send, ridDirectionRecv: simulcast.recv} {
+		for _, alternatives := range list {
+			for _, stream := range alternatives {
+				if rid, ok := rids[stream.rid]; !ok || rid.direction != direction {
+					return nil, nil, ErrSessionDescriptionInvalidSimulcast
+				}
+			}
+		}
+	}
+	return rids, simulcast, nil
+}
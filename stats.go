@@ -66,6 +66,12 @@ const (
 
 	// StatsTypeCertificate is used by CertificateStats.
 	StatsTypeCertificate StatsType = "certificate"
+
+	// StatsTypeICEServer is used by ICEServerStats.
+	StatsTypeICEServer StatsType = "ice-server"
+
+	// StatsTypeSCTPTransport is used by SCTPTransportStats.
+	StatsTypeSCTPTransport StatsType = "sctp-transport"
 )
 
 // StatsTimestamp is a timestamp represented by the floating point number of
@@ -1195,7 +1201,7 @@ func toStatsICECandidatePairState(state ice.CandidatePairState) (StatsICECandida
 		err := fmt.Errorf(
 			"cannot convert to StatsICECandidatePairStateSucceeded invalid ice candidate state: %s",
 			state.String())
-		return StatsICECandidatePairState(Unknown), err
+		return StatsICECandidatePairState(unknownStr), err
 	}
 }
 
@@ -1452,3 +1458,77 @@ type CertificateStats struct {
 	// (i.e. a self-signed certificate), this will not be set.
 	IssuerCertificateID string `json:"issuerCertificateId"`
 }
+
+// ICEServerStats contains the results of the health checks of an ICE server.
+type ICEServerStats struct {
+	// Timestamp is the timestamp associated with this object.
+	Timestamp StatsTimestamp `json:"timestamp"`
+
+	// Type is the object's StatsType
+	Type StatsType `json:"type"`
+
+	// ID is a unique id that is associated with the component inspected to produce
+	// this Stats object. Two Stats objects will have the same ID if they were produced
+	// by inspecting the same underlying object.
+	ID string `json:"id"`
+
+	// URL is the URL of the ICE server.
+	URL string `json:"url"`
+
+	// Port is the port number of the server.
+	Port int32 `json:"port"`
+
+	// RelayProtocol is the protocol used to reach the server, valid values
+	// are udp, tcp and tls.
+	RelayProtocol string `json:"relayProtocol"`
+
+	// TotalRequestsSent is the total amount of requests that have been sent to
+	// this server.
+	TotalRequestsSent uint32 `json:"totalRequestsSent"`
+
+	// TotalResponsesReceived is the total amount of responses received from
+	// this server.
+	TotalResponsesReceived uint32 `json:"totalResponsesReceived"`
+
+	// TotalRoundTripTime is the sum of RTTs, in seconds, for all requests that
+	// have been sent where a response has been received.
+	TotalRoundTripTime float64 `json:"totalRoundTripTime"`
+
+	// Reachable is true if the server answered the last health check.
+	Reachable bool `json:"reachable"`
+}
+
+// SCTPTransportStats contains the statistics of the SCTP association of a
+// SCTPTransport. The congestion control state of the association, like its
+// congestion window and smoothed RTT, isn't exposed by pion/sctp.
+type SCTPTransportStats struct {
+	// Timestamp is the timestamp associated with this object.
+	Timestamp StatsTimestamp `json:"timestamp"`
+
+	// Type is the object's StatsType
+	Type StatsType `json:"type"`
+
+	// ID is a unique id that is associated with the component inspected to produce
+	// this Stats object. Two Stats objects will have the same ID if they were produced
+	// by inspecting the same underlying object.
+	ID string `json:"id"`
+
+	// TransportID is the ID of the TransportStats of the SCTPTransport.
+	TransportID string `json:"transportId"`
+
+	// DataChannelsOpen is the number of DataChannels currently open over the
+	// association.
+	DataChannelsOpen uint32 `json:"dataChannelsOpen"`
+
+	// BufferedAmount is the number of bytes of the messages of all the
+	// DataChannels which are queued or sent and not yet acknowledged.
+	BufferedAmount uint64 `json:"bufferedAmount"`
+
+	// BytesSent represents the total number of bytes of the SCTP packets sent
+	// on the association, including the headers and the control chunks.
+	BytesSent uint64 `json:"bytesSent"`
+
+	// BytesReceived represents the total number of bytes of the SCTP packets received
+	// on the association, including the headers and the control chunks.
+	BytesReceived uint64 `json:"bytesReceived"`
+}
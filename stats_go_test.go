@@ -237,6 +237,14 @@ func TestPeerConnection_GetStats(t *testing.T) {
 	assert.Equal(t, uint32(0), connStatsOffer.DataChannelsAccepted)
 	dcStatsOffer := getDataChannelStats(t, reportPCOffer, offerDC)
 	assert.Equal(t, DataChannelStateOpen, dcStatsOffer.State)
+	assert.Equal(t, offerDC.Label(), dcStatsOffer.Label)
+	assert.Equal(t, int32(*offerDC.ID()), dcStatsOffer.DataChannelIdentifier)
+	assert.Equal(t, "sctpTransport", dcStatsOffer.TransportID)
+	associationStats, ok := reportPCOffer["sctpAssociation"].(SCTPTransportStats)
+	assert.True(t, ok)
+	assert.Equal(t, StatsTypeSCTPTransport, associationStats.Type)
+	assert.Equal(t, "sctpTransport", associationStats.TransportID)
+	assert.Equal(t, uint32(1), associationStats.DataChannelsOpen)
 	assert.Equal(t, uint32(1), dcStatsOffer.MessagesSent)
 	assert.Equal(t, uint64(len(msg)), dcStatsOffer.BytesSent)
 	assert.NotEmpty(t, findLocalCandidateStats(reportPCOffer))
@@ -250,6 +258,9 @@ func TestPeerConnection_GetStats(t *testing.T) {
 	assert.Equal(t, uint32(1), connStatsAnswer.DataChannelsAccepted)
 	dcStatsAnswer := getDataChannelStats(t, reportPCAnswer, answerDC)
 	assert.Equal(t, DataChannelStateOpen, dcStatsAnswer.State)
+	assert.Equal(t, offerDC.Label(), dcStatsAnswer.Label)
+	assert.Equal(t, int32(*offerDC.ID()), dcStatsAnswer.DataChannelIdentifier)
+	assert.Equal(t, "sctpTransport", dcStatsAnswer.TransportID)
 	assert.Equal(t, uint32(1), dcStatsAnswer.MessagesReceived)
 	assert.Equal(t, uint64(len(msg)), dcStatsAnswer.BytesReceived)
 	assert.NotEmpty(t, findLocalCandidateStats(reportPCAnswer))
@@ -289,11 +300,32 @@ func TestPeerConnection_GetStats(t *testing.T) {
 	offerICETransportStats := getTransportStats(t, reportPCOffer, "iceTransport")
 	assert.GreaterOrEqual(t, offerICETransportStats.BytesSent, answerICETransportStats.BytesReceived)
 	assert.GreaterOrEqual(t, answerICETransportStats.BytesSent, offerICETransportStats.BytesReceived)
+	assert.Equal(t, ICERoleControlling, offerICETransportStats.ICERole)
+
+	// the traffic goes through the selected candidate pair
+	selectedPairStats, ok := reportPCOffer[offerICETransportStats.SelectedCandidatePairID].(ICECandidatePairStats)
+	assert.True(t, ok)
+	assert.Equal(t, "iceTransport", selectedPairStats.TransportID)
+	assert.True(t, selectedPairStats.Nominated)
+	assert.Equal(t, offerICETransportStats.BytesSent, selectedPairStats.BytesSent)
+	assert.Equal(t, offerICETransportStats.BytesReceived, selectedPairStats.BytesReceived)
+	localCandidateStats, ok := reportPCOffer[selectedPairStats.LocalCandidateID].(ICECandidateStats)
+	assert.True(t, ok)
+	assert.Equal(t, "iceTransport", localCandidateStats.TransportID)
+	_, ok = reportPCOffer[selectedPairStats.RemoteCandidateID].(ICECandidateStats)
+	assert.True(t, ok)
 
 	answerSCTPTransportStats := getTransportStats(t, reportPCAnswer, "sctpTransport")
 	offerSCTPTransportStats := getTransportStats(t, reportPCOffer, "sctpTransport")
 	assert.GreaterOrEqual(t, offerSCTPTransportStats.BytesSent, answerSCTPTransportStats.BytesReceived)
 	assert.GreaterOrEqual(t, answerSCTPTransportStats.BytesSent, offerSCTPTransportStats.BytesReceived)
+	assert.Equal(t, DTLSTransportStateConnected, offerSCTPTransportStats.DTLSState)
+	assert.Equal(t, DTLSTransportStateConnected, answerSCTPTransportStats.DTLSState)
+
+	offerAssociationStats, ok := reportPCOffer["sctpAssociation"].(SCTPTransportStats)
+	assert.True(t, ok)
+	assert.Equal(t, offerSCTPTransportStats.BytesSent, offerAssociationStats.BytesSent)
+	assert.Equal(t, uint32(0), offerAssociationStats.DataChannelsOpen)
 
 	assert.NoError(t, offerPC.Close())
 	assert.NoError(t, answerPC.Close())
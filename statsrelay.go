@@ -0,0 +1,282 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// StatsRelayLabel is the label of the DataChannel used by the StatsRelay
+const StatsRelayLabel = "pion-stats-relay"
+
+// statsRelayMessage is a message sent by the StatsRelay. To keep the messages
+// compact only the stats members changed since the previous message are
+// sent, a member never sent has the zero value. The stats timestamps are
+// replaced by the message one.
+type statsRelayMessage struct {
+	Timestamp StatsTimestamp                    `json:"t"`
+	Stats     map[string]map[string]interface{} `json:"s,omitempty"`
+	Removed   []string                          `json:"r,omitempty"`
+}
+
+// StatsRelay periodically sends the PeerConnection stats to the remote peer
+// over a dedicated DataChannel. The remote peer receives them with a
+// StatsRelayReceiver.
+type StatsRelay struct {
+	pc       *PeerConnection
+	dc       *DataChannel
+	interval time.Duration
+
+	encoder *statsRelayEncoder
+
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+// NewStatsRelay creates the DataChannel used to relay the stats of the
+// PeerConnection and starts sending them every interval once it's open
+func NewStatsRelay(pc *PeerConnection, interval time.Duration) (*StatsRelay, error) {
+	dc, err := pc.CreateDataChannel(StatsRelayLabel, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &StatsRelay{
+		pc:       pc,
+		dc:       dc,
+		interval: interval,
+		encoder:  newStatsRelayEncoder(),
+		stopped:  make(chan struct{}),
+	}
+	dc.OnOpen(func() {
+		go r.run()
+	})
+	dc.OnClose(r.stop)
+	return r, nil
+}
+
+// DataChannel returns the DataChannel used to relay the stats
+func (r *StatsRelay) DataChannel() *DataChannel {
+	return r.dc
+}
+
+// Stop stops relaying the stats and closes the DataChannel
+func (r *StatsRelay) Stop() error {
+	r.stop()
+	return r.dc.Close()
+}
+
+func (r *StatsRelay) stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopped)
+	})
+}
+
+func (r *StatsRelay) run() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopped:
+			return
+		case <-ticker.C:
+		}
+
+		if r.dc.ReadyState() != DataChannelStateOpen {
+			return
+		}
+
+		msg, err := r.encoder.encode(r.pc.GetStats(), statsTimestampNow())
+		if err != nil {
+			r.pc.log.Warnf("Failed to encode the relayed stats: %s", err)
+			continue
+		}
+		if msg == nil {
+			continue
+		}
+		if err := r.dc.Send(msg); err != nil {
+			return
+		}
+	}
+}
+
+// statsRelayEncoder encodes the stats reports in messages containing the
+// changes from the previous ones
+type statsRelayEncoder struct {
+	sent map[string]map[string]interface{}
+}
+
+func newStatsRelayEncoder() *statsRelayEncoder {
+	return &statsRelayEncoder{sent: map[string]map[string]interface{}{}}
+}
+
+// encode returns the message with the changes of the report, nil when
+// nothing changed
+func (e *statsRelayEncoder) encode(report StatsReport, timestamp StatsTimestamp) ([]byte, error) {
+	msg := statsRelayMessage{
+		Timestamp: timestamp,
+		Stats:     map[string]map[string]interface{}{},
+	}
+
+	for id, stats := range report {
+		members, err := statsMembers(stats)
+		if err != nil {
+			return nil, err
+		}
+
+		sent, ok := e.sent[id]
+		if !ok {
+			sent = map[string]interface{}{}
+			e.sent[id] = sent
+		}
+
+		changed := map[string]interface{}{}
+		for k, v := range members {
+			prev, ok := sent[k]
+			if (!ok && isZeroStatsMember(v)) || (ok && reflect.DeepEqual(prev, v)) {
+				continue
+			}
+			changed[k] = v
+			sent[k] = v
+		}
+		if len(changed) != 0 {
+			msg.Stats[id] = changed
+		}
+	}
+
+	for id := range e.sent {
+		if _, ok := report[id]; !ok {
+			msg.Removed = append(msg.Removed, id)
+			delete(e.sent, id)
+		}
+	}
+
+	if len(msg.Stats) == 0 && len(msg.Removed) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(msg)
+}
+
+// statsMembers returns the members of the stats as decoded from JSON, without
+// the timestamp
+func statsMembers(stats Stats) (map[string]interface{}, error) {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return nil, err
+	}
+
+	members := map[string]interface{}{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	delete(members, "timestamp")
+	return members, nil
+}
+
+func isZeroStatsMember(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// StatsRelayReceiver rebuilds the stats sent by the remote StatsRelay
+type StatsRelayReceiver struct {
+	mu        sync.RWMutex
+	timestamp StatsTimestamp
+	stats     map[string]map[string]interface{}
+
+	onReportHandler func(map[string]map[string]interface{})
+}
+
+// NewStatsRelayReceiver creates a StatsRelayReceiver handling the messages
+// received on the provided DataChannel, it must be the DataChannel with the
+// StatsRelayLabel label announced by the remote peer
+func NewStatsRelayReceiver(dc *DataChannel) *StatsRelayReceiver {
+	r := &StatsRelayReceiver{stats: map[string]map[string]interface{}{}}
+	dc.OnMessage(func(msg DataChannelMessage) {
+		if err := r.handleMessage(msg.Data); err != nil {
+			dc.log.Warnf("Failed to handle the relayed stats: %s", err)
+		}
+	})
+	return r
+}
+
+// OnReport sets an event handler which is invoked when new stats are
+// received, it's called with the updated remote stats keyed by id
+func (r *StatsRelayReceiver) OnReport(f func(map[string]map[string]interface{})) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onReportHandler = f
+}
+
+// Report returns the last received remote stats, keyed by id, and the time
+// they were collected. The stats members are the ones of the JSON encoding of
+// the Stats.
+func (r *StatsRelayReceiver) Report() (map[string]map[string]interface{}, time.Time) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.report(), r.timestamp.Time()
+}
+
+func (r *StatsRelayReceiver) report() map[string]map[string]interface{} {
+	report := make(map[string]map[string]interface{}, len(r.stats))
+	for id, stats := range r.stats {
+		s := make(map[string]interface{}, len(stats)+1)
+		for k, v := range stats {
+			s[k] = v
+		}
+		s["timestamp"] = float64(r.timestamp)
+		report[id] = s
+	}
+	return report
+}
+
+func (r *StatsRelayReceiver) handleMessage(data []byte) error {
+	msg := statsRelayMessage{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.timestamp = msg.Timestamp
+	for id, changed := range msg.Stats {
+		stats, ok := r.stats[id]
+		if !ok {
+			stats = map[string]interface{}{}
+			r.stats[id] = stats
+		}
+		for k, v := range changed {
+			stats[k] = v
+		}
+	}
+	for _, id := range msg.Removed {
+		delete(r.stats, id)
+	}
+	hdlr := r.onReportHandler
+	var report map[string]map[string]interface{}
+	if hdlr != nil {
+		report = r.report()
+	}
+	r.mu.Unlock()
+
+	if hdlr != nil {
+		go hdlr(report)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsRelayEncoder(t *testing.T) {
+	e := newStatsRelayEncoder()
+	r := &StatsRelayReceiver{stats: map[string]map[string]interface{}{}}
+
+	report := StatsReport{
+		"PeerConnection-1": PeerConnectionStats{
+			ID:                 "PeerConnection-1",
+			Type:               StatsTypePeerConnection,
+			DataChannelsOpened: 1,
+		},
+	}
+
+	msg, err := e.encode(report, 1000)
+	assert.NoError(t, err)
+	decoded := statsRelayMessage{}
+	assert.NoError(t, json.Unmarshal(msg, &decoded))
+	// the zero members are not sent
+	assert.Equal(t, map[string]map[string]interface{}{
+		"PeerConnection-1": {
+			"id":                 "PeerConnection-1",
+			"type":               string(StatsTypePeerConnection),
+			"dataChannelsOpened": float64(1),
+		},
+	}, decoded.Stats)
+	assert.NoError(t, r.handleMessage(msg))
+
+	// unchanged stats are not sent
+	msg, err = e.encode(report, 2000)
+	assert.NoError(t, err)
+	assert.Nil(t, msg)
+
+	// only the changed members are sent
+	report["PeerConnection-1"] = PeerConnectionStats{
+		ID:                 "PeerConnection-1",
+		Type:               StatsTypePeerConnection,
+		DataChannelsOpened: 2,
+		DataChannelsClosed: 1,
+	}
+	msg, err = e.encode(report, 3000)
+	assert.NoError(t, err)
+	decoded = statsRelayMessage{}
+	assert.NoError(t, json.Unmarshal(msg, &decoded))
+	assert.Equal(t, map[string]map[string]interface{}{
+		"PeerConnection-1": {
+			"dataChannelsOpened": float64(2),
+			"dataChannelsClosed": float64(1),
+		},
+	}, decoded.Stats)
+	assert.NoError(t, r.handleMessage(msg))
+
+	received, timestamp := r.Report()
+	assert.Equal(t, StatsTimestamp(3000).Time(), timestamp)
+	assert.Equal(t, "PeerConnection-1", received["PeerConnection-1"]["id"])
+	assert.Equal(t, float64(2), received["PeerConnection-1"]["dataChannelsOpened"])
+	assert.Equal(t, float64(1), received["PeerConnection-1"]["dataChannelsClosed"])
+	assert.Equal(t, float64(3000), received["PeerConnection-1"]["timestamp"])
+
+	// removed stats are reported
+	msg, err = e.encode(StatsReport{}, 4000)
+	assert.NoError(t, err)
+	assert.NoError(t, r.handleMessage(msg))
+	received, _ = r.Report()
+	assert.Empty(t, received)
+}
+
+func TestStatsRelay(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reported := make(chan map[string]map[string]interface{}, 1)
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		if d.Label() != StatsRelayLabel {
+			return
+		}
+		NewStatsRelayReceiver(d).OnReport(func(report map[string]map[string]interface{}) {
+			select {
+			case reported <- report:
+			default:
+			}
+		})
+	})
+
+	relay, err := NewStatsRelay(pcOffer, 50*time.Millisecond)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	report := <-reported
+	pcStats, ok := report[pcOffer.getStatsID()]
+	assert.True(t, ok)
+	assert.Equal(t, string(StatsTypePeerConnection), pcStats["type"])
+
+	assert.NoError(t, relay.Stop())
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"time"
 
+	"github.com/pion/logging"
+	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/ulpfec"
 )
 
 const (
@@ -31,6 +35,17 @@ type TrackRTPStream struct {
 
 	packetizer rtp.Packetizer
 
+	// fecDecoder unwraps the received RED packets and recovers the lost
+	// ones using ULPFEC. pendingPackets are the packets ready to be read.
+	fecDecoder     *ulpfec.Decoder
+	fecLog         logging.LeveledLogger
+	pendingPackets []*rtp.Packet
+
+	// freezeDetector detects when the remote video stream stops producing
+	// decodable frames
+	freezeDetector  *freezeDetector
+	onFrozenHandler func()
+
 	track *Track
 }
 
@@ -86,22 +101,211 @@ func (s *TrackRTPStream) Packetizer() rtp.Packetizer {
 
 // Read reads data from the stream. If this is a local stream this will error
 func (s *TrackRTPStream) Read(b []byte) (n int, err error) {
-	return s.track.read(b, s.rid)
+	n, err = s.track.read(b, s.rid)
+	if err == nil {
+		s.observeFreezeRaw(b[:n])
+	}
+	return n, err
 }
 
 // ReadRTP is a convenience method that wraps Read and unmarshals for you
 func (s *TrackRTPStream) ReadRTP() (*rtp.Packet, error) {
-	b := make([]byte, receiveMTU)
-	i, err := s.Read(b)
-	if err != nil {
-		return nil, err
+	return s.readRTP(s.Read)
+}
+
+// readRTP reads a packet using the provided read function and unmarshals it.
+// When the stream uses the RED codec the packets are unwrapped and the lost
+// ones recovered using ULPFEC before being returned. Malformed RED or FEC
+// packets are logged and dropped.
+func (s *TrackRTPStream) readRTP(read func([]byte) (int, error)) (*rtp.Packet, error) {
+	for {
+		s.mu.Lock()
+		if len(s.pendingPackets) > 0 {
+			p := s.pendingPackets[0]
+			s.pendingPackets = s.pendingPackets[1:]
+			s.mu.Unlock()
+			s.observeFreeze(p)
+			return p, nil
+		}
+		s.mu.Unlock()
+
+		b := make([]byte, receiveMTU)
+		i, err := read(b)
+		if err != nil {
+			return nil, err
+		}
+
+		r := &rtp.Packet{}
+		if err := r.Unmarshal(b[:i]); err != nil {
+			return nil, err
+		}
+
+		// the packets read without the FEC decoder are already observed by
+		// read
+		decoder := s.getFECDecoder()
+		if decoder == nil {
+			return r, nil
+		}
+
+		packets, err := decoder.Push(r)
+		if err != nil {
+			s.mu.RLock()
+			log := s.fecLog
+			s.mu.RUnlock()
+			log.Warnf("Dropping RED packet %d: %v", r.SequenceNumber, err)
+			continue
+		}
+
+		s.mu.Lock()
+		s.pendingPackets = append(s.pendingPackets, packets...)
+		s.mu.Unlock()
+	}
+}
+
+// getFECDecoder returns the stream FEC decoder, it's created when the remote
+// stream uses the RED codec and an ULPFEC codec is registered
+func (s *TrackRTPStream) getFECDecoder() *ulpfec.Decoder {
+	s.mu.RLock()
+	decoder, codec, track := s.fecDecoder, s.codec, s.track
+	s.mu.RUnlock()
+
+	if decoder != nil || codec == nil || codec.Name != RED || track == nil {
+		return decoder
+	}
+
+	track.mu.RLock()
+	receiver := track.receiver
+	track.mu.RUnlock()
+	if receiver == nil || receiver.api == nil {
+		return nil
+	}
+
+	ulpfecCodec := receiver.api.mediaEngine.getCodecByName(codec.Type, ULPFEC)
+	if ulpfecCodec == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fecDecoder == nil {
+		s.fecDecoder = ulpfec.NewDecoder(codec.PayloadType, ulpfecCodec.PayloadType)
+		s.fecLog = receiver.api.settingEngine.LoggerFactory.NewLogger("ulpfec")
+	}
+	return s.fecDecoder
+}
+
+// OnFrozen sets an event handler which is called when the remote stream
+// stopped producing decodable frames and the recovery sequence (NACK, PLI
+// and FIR requests) failed. Freeze detection is enabled using
+// SettingEngine.SetFreezeRecoveryInterval.
+func (s *TrackRTPStream) OnFrozen(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onFrozenHandler = f
+}
+
+func (s *TrackRTPStream) onFrozen() {
+	s.mu.RLock()
+	hdlr := s.onFrozenHandler
+	s.mu.RUnlock()
+
+	if hdlr != nil {
+		go hdlr()
+	}
+}
+
+func (s *TrackRTPStream) observeFreeze(p *rtp.Packet) {
+	if detector := s.getFreezeDetector(); detector != nil {
+		detector.observe(p, time.Now())
+	}
+}
+
+// observeFreezeRaw observes a packet read from the stream. The RED packets
+// are observed by readRTP once unwrapped by the FEC decoder.
+func (s *TrackRTPStream) observeFreezeRaw(b []byte) {
+	detector := s.getFreezeDetector()
+	if detector == nil || s.getFECDecoder() != nil {
+		return
+	}
+
+	p := &rtp.Packet{}
+	if err := p.Unmarshal(b); err != nil {
+		return
+	}
+	detector.observe(p, time.Now())
+}
+
+// getFreezeDetector returns the stream freeze detector, it's created for
+// remote video streams when freeze detection is enabled
+func (s *TrackRTPStream) getFreezeDetector() *freezeDetector {
+	s.mu.RLock()
+	detector, codec, track := s.freezeDetector, s.codec, s.track
+	s.mu.RUnlock()
+
+	if detector != nil || codec == nil || codec.Type != RTPCodecTypeVideo || track == nil {
+		return detector
+	}
+
+	track.mu.RLock()
+	receiver := track.receiver
+	track.mu.RUnlock()
+	if receiver == nil || receiver.api == nil || receiver.api.settingEngine.freezeRecoveryInterval <= 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.freezeDetector == nil {
+		s.freezeDetector = newFreezeDetector(codec.Name, receiver.api.settingEngine.freezeRecoveryInterval)
+		go s.runFreezeRecovery(receiver, s.freezeDetector)
 	}
+	return s.freezeDetector
+}
+
+// runFreezeRecovery runs the recovery sequence of the stream when it's broken
+// until the receiver is stopped, the steps that failed to be sent are retried
+func (s *TrackRTPStream) runFreezeRecovery(receiver *RTPReceiver, detector *freezeDetector) {
+	ticker := time.NewTicker(detector.interval / 2)
+	defer ticker.Stop()
+
+	var firSeq uint8
+	for {
+		select {
+		case <-receiver.closed:
+			return
+		case <-ticker.C:
+		}
+
+		step, missing := detector.next(time.Now())
+		ssrc := s.SSRC()
+
+		var raw []byte
+		var err error
+		switch step {
+		case freezeRecoveryNACK:
+			if len(missing) == 0 {
+				continue
+			}
+			raw, err = rtcp.Marshal([]rtcp.Packet{&rtcp.TransportLayerNack{MediaSSRC: ssrc, Nacks: nackPairs(missing)}})
+		case freezeRecoveryPLI:
+			raw, err = rtcp.Marshal([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: ssrc}})
+		case freezeRecoveryFIR:
+			raw, err = marshalFullIntraRequest(ssrc, firSeq)
+			firSeq++
+		case freezeRecoveryFrozen:
+			s.onFrozen()
+			continue
+		default:
+			continue
+		}
+		if err != nil {
+			continue
+		}
 
-	r := &rtp.Packet{}
-	if err := r.Unmarshal(b[:i]); err != nil {
-		return nil, err
+		if err := receiver.writeRTCP(raw); err != nil {
+			detector.retry(step)
+		}
 	}
-	return r, nil
 }
 
 // Write writes data to the stream. If this is a remote stream this will error
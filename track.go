@@ -5,9 +5,11 @@ package webrtc
 import (
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 
 	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2/internal/h264"
 	"github.com/pion/webrtc/v2/pkg/media"
 )
 
@@ -31,6 +33,8 @@ type Track struct {
 	receiver         *RTPReceiver
 	activeSenders    []*RTPSender
 	totalSenderCount int // count of all senders (accounts for senders that have not been started yet)
+
+	sampleTransform media.SampleTransform
 }
 
 // ID gets the ID of the track
@@ -130,6 +134,12 @@ func (t *Track) WriteSample(s media.Sample) error {
 	if t.multiStream {
 		return fmt.Errorf("track is multistream")
 	}
+
+	s, err := t.transformSample(s)
+	if err != nil {
+		return err
+	}
+
 	packets := t.streams[0].packetizer.Packetize(s.Data, s.Samples)
 	for _, p := range packets {
 		err := t.WriteRTP(p)
@@ -141,6 +151,35 @@ func (t *Track) WriteSample(s media.Sample) error {
 	return nil
 }
 
+// SetSampleTransform sets a transform applied by WriteSample to the codec
+// payload of the samples before they're packetized, e.g. to encrypt them
+// end-to-end. With H.264 the transform is applied to the payload of each NAL
+// unit, the NAL unit headers are kept in the clear and the transformed
+// payloads are escaped so they can't contain start codes. The receiver
+// applies the inverse transform with a samplebuilder built with
+// samplebuilder.WithSampleTransform.
+func (t *Track) SetSampleTransform(transform media.SampleTransform) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sampleTransform = transform
+}
+
+func (t *Track) transformSample(s media.Sample) (media.Sample, error) {
+	t.mu.RLock()
+	transform := t.sampleTransform
+	codec := t.streams[0].codec
+	t.mu.RUnlock()
+
+	switch {
+	case transform == nil:
+		return s, nil
+	case codec != nil && strings.EqualFold(codec.Name, H264):
+		return h264.TransformOutgoing(s, transform)
+	default:
+		return transform(s)
+	}
+}
+
 // WriteRTP writes RTP packets to the track
 func (t *Track) WriteRTP(p *rtp.Packet) error {
 	t.mu.RLock()
@@ -214,21 +253,18 @@ func (t *Track) Read(b []byte) (n int, err error) {
 	if t.multiStream {
 		return 0, fmt.Errorf("track is multistream")
 	}
-	return t.read(b, t.streams[0].id)
+	n, err = t.read(b, t.streams[0].id)
+	if err == nil {
+		t.streams[0].observeFreezeRaw(b[:n])
+	}
+	return n, err
 }
 
 // ReadRTP is a convenience method that wraps Read and unmarshals for
 // you. If a track is multistream it'll return an error (use TrackStream.ReadRTP())
 func (t *Track) ReadRTP() (*rtp.Packet, error) {
-	b := make([]byte, receiveMTU)
-	i, err := t.Read(b)
-	if err != nil {
-		return nil, err
-	}
-
-	r := &rtp.Packet{}
-	if err := r.Unmarshal(b[:i]); err != nil {
-		return nil, err
+	if t.multiStream {
+		return nil, fmt.Errorf("track is multistream")
 	}
-	return r, nil
+	return t.streams[0].readRTP(t.Read)
 }
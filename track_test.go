@@ -3,8 +3,14 @@
 package webrtc
 
 import (
+	"errors"
 	"math/rand"
 	"testing"
+
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/media/samplebuilder"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNewVideoTrack(t *testing.T) {
@@ -115,3 +121,70 @@ func TestNewTracksWrite(t *testing.T) {
 		t.Error("Failed to write to audio track")
 	}
 }
+
+func TestTrackSampleTransform(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	pc, err := NewAPI(WithMediaEngine(m)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pc.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	_, err = pc.AddTrack(track)
+	assert.NoError(t, err)
+
+	errTransform := errors.New("transform failed")
+	transformed := []media.Sample{}
+	track.SetSampleTransform(func(sample media.Sample) (media.Sample, error) {
+		transformed = append(transformed, sample)
+		if len(sample.Data) == 0 {
+			return sample, errTransform
+		}
+		return media.Sample{Data: append([]byte{0xFF}, sample.Data...), Samples: sample.Samples}, nil
+	})
+
+	assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x01, 0x02}, Samples: 1}))
+	assert.Equal(t, errTransform, track.WriteSample(media.Sample{Samples: 1}))
+	assert.Equal(t, []media.Sample{{Data: []byte{0x01, 0x02}, Samples: 1}, {Samples: 1}}, transformed)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestTrackSampleTransform_H264(t *testing.T) {
+	track, err := NewTrack(DefaultPayloadTypeH264, rand.Uint32(), "video", "pion", NewRTPH264Codec(DefaultPayloadTypeH264, 90000))
+	assert.NoError(t, err)
+
+	// the transformed payloads contain start codes
+	xor := func(sample media.Sample) (media.Sample, error) {
+		data := make([]byte, len(sample.Data))
+		for i, b := range sample.Data {
+			data[i] = b ^ 0x42
+		}
+		return media.Sample{Data: data, Samples: sample.Samples}, nil
+	}
+	track.SetSampleTransform(xor)
+
+	payload := make([]byte, 3000)
+	for i := range payload {
+		payload[i] = []byte{0x42, 0x42, 0x43, 0x42}[i%4]
+	}
+	samples := []media.Sample{}
+	for i := 0; i < 3; i++ {
+		data := []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x42, 0x41, 0x00, 0x00, 0x00, 0x01, 0x65}
+		samples = append(samples, media.Sample{Data: append(append(data, byte(i)), payload...), Samples: 3000})
+	}
+
+	// the samples go through the H.264 payloader and depacketizer
+	builder := samplebuilder.New(50, &codecs.H264Packet{}, samplebuilder.WithSampleTransform(xor))
+	for _, sample := range samples {
+		transformed, err := track.transformSample(sample)
+		assert.NoError(t, err)
+		for _, p := range track.Packetizer().Packetize(transformed.Data, transformed.Samples) {
+			builder.Push(p)
+		}
+	}
+
+	// the first sample can't be built, it isn't known where it starts
+	assert.Equal(t, &samples[1], builder.Pop())
+	assert.Nil(t, builder.Pop())
+}
@@ -0,0 +1,310 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+)
+
+// TransceiverEventType is the type of a TransceiverEvent
+type TransceiverEventType int
+
+const (
+	// TransceiverEventTypeAdded reports a transceiver added to the
+	// PeerConnection, locally or by a remote offer
+	TransceiverEventTypeAdded TransceiverEventType = iota + 1
+
+	// TransceiverEventTypeDirectionChanged reports a change of the
+	// transceiver direction
+	TransceiverEventTypeDirectionChanged
+
+	// TransceiverEventTypeCodecNegotiated reports the codec negotiated for
+	// the transceiver media section with a remote description
+	TransceiverEventTypeCodecNegotiated
+
+	// TransceiverEventTypeTrackBound reports a track attached to the
+	// transceiver sender
+	TransceiverEventTypeTrackBound
+
+	// TransceiverEventTypeTrackUnbound reports a track detached from the
+	// transceiver sender
+	TransceiverEventTypeTrackUnbound
+
+	// TransceiverEventTypeRemoved reports a transceiver removed from the
+	// PeerConnection by a rollback
+	TransceiverEventTypeRemoved
+)
+
+func (t TransceiverEventType) String() string {
+	switch t {
+	case TransceiverEventTypeAdded:
+		return "added"
+	case TransceiverEventTypeDirectionChanged:
+		return "direction-changed"
+	case TransceiverEventTypeCodecNegotiated:
+		return "codec-negotiated"
+	case TransceiverEventTypeTrackBound:
+		return "track-bound"
+	case TransceiverEventTypeTrackUnbound:
+		return "track-unbound"
+	case TransceiverEventTypeRemoved:
+		return "removed"
+	default:
+		return unknownStr
+	}
+}
+
+// TransceiverEvent is a change of the state of a RTPTransceiver. Other than
+// the changed value it carries the whole transceiver state after the change.
+type TransceiverEvent struct {
+	Type        TransceiverEventType
+	Transceiver *RTPTransceiver
+
+	Mid       string
+	Kind      RTPCodecType
+	Direction RTPTransceiverDirection
+	// Codec is the negotiated codec, nil before the negotiation
+	Codec *RTPCodec
+	// Track is the track sent by the transceiver, for a
+	// TransceiverEventTypeTrackUnbound the detached one
+	Track *Track
+}
+
+// transceiverState is the last state of a transceiver reported to the
+// subscribers
+type transceiverState struct {
+	direction RTPTransceiverDirection
+	codec     *RTPCodec
+	track     *Track
+}
+
+// transceiverFeed reports the changes of the PeerConnection transceivers to
+// the subscribers
+type transceiverFeed struct {
+	mu          sync.Mutex
+	states      map[*RTPTransceiver]*transceiverState
+	order       []*RTPTransceiver
+	subscribers map[*transceiverSubscription]struct{}
+	closed      bool
+}
+
+func newTransceiverFeed() *transceiverFeed {
+	return &transceiverFeed{
+		states:      map[*RTPTransceiver]*transceiverState{},
+		subscribers: map[*transceiverSubscription]struct{}{},
+	}
+}
+
+func currentTransceiverState(t *RTPTransceiver) *transceiverState {
+	s := &transceiverState{
+		direction: t.Direction(),
+		codec:     t.getCodec(),
+	}
+	if sender := t.Sender(); sender != nil {
+		s.track = sender.Track()
+	}
+	return s
+}
+
+func newTransceiverEvent(typ TransceiverEventType, t *RTPTransceiver, s *transceiverState) TransceiverEvent {
+	return TransceiverEvent{
+		Type:        typ,
+		Transceiver: t,
+		Mid:         t.Mid(),
+		Kind:        t.Kind(),
+		Direction:   s.direction,
+		Codec:       s.codec,
+		Track:       s.track,
+	}
+}
+
+// snapshotEvents returns the events describing the current state of the
+// transceivers
+func (f *transceiverFeed) snapshotEvents() []TransceiverEvent {
+	events := []TransceiverEvent{}
+	for _, t := range f.order {
+		s := f.states[t]
+		events = append(events, newTransceiverEvent(TransceiverEventTypeAdded, t, s))
+		if s.codec != nil {
+			events = append(events, newTransceiverEvent(TransceiverEventTypeCodecNegotiated, t, s))
+		}
+		if s.track != nil {
+			events = append(events, newTransceiverEvent(TransceiverEventTypeTrackBound, t, s))
+		}
+	}
+	return events
+}
+
+// update compares the transceivers with their last reported state and
+// reports the changes to the subscribers
+func (f *transceiverFeed) update(transceivers []*RTPTransceiver) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+
+	events := []TransceiverEvent{}
+	current := map[*RTPTransceiver]bool{}
+	for _, t := range transceivers {
+		current[t] = true
+	}
+	order := []*RTPTransceiver{}
+	for _, t := range f.order {
+		if !current[t] {
+			events = append(events, newTransceiverEvent(TransceiverEventTypeRemoved, t, f.states[t]))
+			delete(f.states, t)
+			continue
+		}
+		order = append(order, t)
+	}
+	f.order = order
+
+	for _, t := range transceivers {
+		cur := currentTransceiverState(t)
+		prev, ok := f.states[t]
+		f.states[t] = cur
+		if !ok {
+			f.order = append(f.order, t)
+			events = append(events, newTransceiverEvent(TransceiverEventTypeAdded, t, cur))
+			prev = &transceiverState{direction: cur.direction}
+		}
+
+		if prev.direction != cur.direction {
+			events = append(events, newTransceiverEvent(TransceiverEventTypeDirectionChanged, t, cur))
+		}
+		if prev.codec != cur.codec && cur.codec != nil {
+			events = append(events, newTransceiverEvent(TransceiverEventTypeCodecNegotiated, t, cur))
+		}
+		if prev.track != cur.track {
+			if prev.track != nil {
+				unbound := *cur
+				unbound.track = prev.track
+				events = append(events, newTransceiverEvent(TransceiverEventTypeTrackUnbound, t, &unbound))
+			}
+			if cur.track != nil {
+				events = append(events, newTransceiverEvent(TransceiverEventTypeTrackBound, t, cur))
+			}
+		}
+	}
+
+	if len(events) == 0 {
+		return
+	}
+	for s := range f.subscribers {
+		s.push(events)
+	}
+}
+
+func (f *transceiverFeed) subscribe() (<-chan TransceiverEvent, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := newTransceiverSubscription()
+	s.push(f.snapshotEvents())
+	if f.closed {
+		s.close()
+	} else {
+		f.subscribers[s] = struct{}{}
+	}
+	go s.run()
+
+	return s.ch, func() {
+		f.mu.Lock()
+		delete(f.subscribers, s)
+		f.mu.Unlock()
+		s.cancel()
+	}
+}
+
+// close closes the subscriptions once the pending events are delivered
+func (f *transceiverFeed) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	for s := range f.subscribers {
+		s.close()
+	}
+	f.subscribers = map[*transceiverSubscription]struct{}{}
+}
+
+// transceiverSubscription queues the events of a subscriber, so a slow
+// subscriber doesn't block the PeerConnection and doesn't lose events
+type transceiverSubscription struct {
+	ch chan TransceiverEvent
+
+	mu     sync.Mutex
+	queue  []TransceiverEvent
+	closed bool
+
+	notify     chan struct{}
+	cancelOnce sync.Once
+	canceled   chan struct{}
+}
+
+func newTransceiverSubscription() *transceiverSubscription {
+	return &transceiverSubscription{
+		ch:       make(chan TransceiverEvent),
+		notify:   make(chan struct{}, 1),
+		canceled: make(chan struct{}),
+	}
+}
+
+func (s *transceiverSubscription) push(events []TransceiverEvent) {
+	s.mu.Lock()
+	s.queue = append(s.queue, events...)
+	s.mu.Unlock()
+	s.wakeUp()
+}
+
+func (s *transceiverSubscription) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.wakeUp()
+}
+
+func (s *transceiverSubscription) cancel() {
+	s.cancelOnce.Do(func() {
+		close(s.canceled)
+	})
+}
+
+func (s *transceiverSubscription) wakeUp() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *transceiverSubscription) run() {
+	defer close(s.ch)
+
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return
+			}
+
+			select {
+			case <-s.notify:
+			case <-s.canceled:
+				return
+			}
+			continue
+		}
+		e := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		select {
+		case s.ch <- e:
+		case <-s.canceled:
+			return
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribeTransceiverEvents(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	events, cancel := pcOffer.SubscribeTransceiverEvents()
+	defer cancel()
+
+	expectEvent := func(typ TransceiverEventType) TransceiverEvent {
+		e := <-events
+		assert.Equal(t, typ, e.Type)
+		return e
+	}
+
+	transceiver, err := pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	track := transceiver.Sender().Track()
+
+	e := expectEvent(TransceiverEventTypeAdded)
+	assert.Equal(t, transceiver, e.Transceiver)
+	assert.Equal(t, RTPCodecTypeVideo, e.Kind)
+	assert.Equal(t, RTPTransceiverDirectionSendrecv, e.Direction)
+	e = expectEvent(TransceiverEventTypeTrackBound)
+	assert.Equal(t, track, e.Track)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+	e = expectEvent(TransceiverEventTypeCodecNegotiated)
+	assert.Equal(t, transceiver.Mid(), e.Mid)
+	assert.Equal(t, VP8, e.Codec.Name)
+
+	// a late subscriber receives the current state
+	lateEvents, lateCancel := pcOffer.SubscribeTransceiverEvents()
+	for _, typ := range []TransceiverEventType{TransceiverEventTypeAdded, TransceiverEventTypeCodecNegotiated, TransceiverEventTypeTrackBound} {
+		late := <-lateEvents
+		assert.Equal(t, typ, late.Type)
+		assert.Equal(t, transceiver, late.Transceiver)
+	}
+	lateCancel()
+	for range lateEvents {
+	}
+
+	assert.NoError(t, pcOffer.RemoveTrack(transceiver.Sender()))
+	e = expectEvent(TransceiverEventTypeDirectionChanged)
+	assert.Equal(t, RTPTransceiverDirectionRecvonly, e.Direction)
+	e = expectEvent(TransceiverEventTypeTrackUnbound)
+	assert.Equal(t, track, e.Track)
+
+	// stopping the transceiver is reported right away
+	assert.NoError(t, transceiver.Stop())
+	e = expectEvent(TransceiverEventTypeDirectionChanged)
+	assert.Equal(t, RTPTransceiverDirectionInactive, e.Direction)
+
+	assert.NoError(t, pcOffer.Close())
+	_, ok := <-events
+	assert.False(t, ok)
+
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -0,0 +1,160 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pion/sdp/v2"
+)
+
+const (
+	// opusUseDTXParameter is the opus fmtp parameter requesting the
+	// discontinuous transmission (RFC 7587)
+	opusUseDTXParameter = "usedtx"
+	// g729AnnexBParameter is the G.729 fmtp parameter enabling the annex B
+	// silence suppression (RFC 4856)
+	g729AnnexBParameter = "annexb"
+)
+
+// disableVoiceActivityDetection disables the voice activity detection in the
+// audio media sections of a local description: the comfort noise codecs
+// (RFC 3389) are removed, and the codecs supporting silence suppression have
+// it disabled in their fmtp.
+func disableVoiceActivityDetection(d *sdp.SessionDescription) {
+	for _, media := range d.MediaDescriptions {
+		if media.MediaName.Media != RTPCodecTypeAudio.String() || isMediaSectionRejected(media) {
+			continue
+		}
+
+		codecs := map[uint8]string{}
+		fmtps := map[uint8]string{}
+		for _, attr := range media.Attributes {
+			payloadType, value, ok := splitPayloadTypeAttribute(attr.Value)
+			if !ok {
+				continue
+			}
+			switch attr.Key {
+			case "rtpmap":
+				codecs[payloadType] = strings.Split(value, "/")[0]
+			case "fmtp":
+				fmtps[payloadType] = value
+			}
+		}
+
+		removed := map[string]bool{}
+		for payloadType, name := range codecs {
+			if strings.EqualFold(name, CN) {
+				removed[strconv.Itoa(int(payloadType))] = true
+			}
+		}
+		// don't remove all the formats
+		if len(removed) == len(media.MediaName.Formats) {
+			removed = map[string]bool{}
+		}
+
+		formats := []string{}
+		for _, format := range media.MediaName.Formats {
+			if !removed[format] {
+				formats = append(formats, format)
+			}
+		}
+		media.MediaName.Formats = formats
+
+		attributes := []sdp.Attribute{}
+		for _, attr := range media.Attributes {
+			if attr.Key == "rtpmap" || attr.Key == "fmtp" || attr.Key == "rtcp-fb" {
+				if format := strings.SplitN(attr.Value, " ", 2)[0]; removed[format] {
+					continue
+				}
+			}
+			attributes = append(attributes, attr)
+		}
+		media.Attributes = attributes
+
+		for payloadType, name := range codecs {
+			fmtp, haveFmtp := fmtps[payloadType]
+			var updated string
+			switch {
+			case strings.EqualFold(name, Opus):
+				updated = setFmtpParameter(fmtp, opusUseDTXParameter, "")
+			case strings.EqualFold(name, G729):
+				updated = setFmtpParameter(fmtp, g729AnnexBParameter, "no")
+			default:
+				continue
+			}
+			setFmtpAttribute(media, payloadType, updated, haveFmtp)
+		}
+	}
+}
+
+// splitPayloadTypeAttribute splits the value of a rtpmap or fmtp attribute
+// in the payload type and the rest of the value
+func splitPayloadTypeAttribute(value string) (uint8, string, bool) {
+	split := strings.SplitN(value, " ", 2)
+	if len(split) != 2 {
+		return 0, "", false
+	}
+	payloadType, err := strconv.ParseUint(split[0], 10, 8)
+	if err != nil {
+		return 0, "", false
+	}
+	return uint8(payloadType), split[1], true
+}
+
+func getFmtpParameter(fmtp, key string) string {
+	for _, param := range strings.Split(fmtp, ";") {
+		if kv := strings.SplitN(strings.TrimSpace(param), "=", 2); len(kv) == 2 && kv[0] == key {
+			return kv[1]
+		}
+	}
+	return ""
+}
+
+// setFmtpParameter sets a parameter of a fmtp value, an empty value removes
+// it
+func setFmtpParameter(fmtp, key, value string) string {
+	params := []string{}
+	found := false
+	for _, param := range strings.Split(fmtp, ";") {
+		param = strings.TrimSpace(param)
+		if param == "" {
+			continue
+		}
+		if strings.SplitN(param, "=", 2)[0] == key {
+			found = true
+			if value == "" {
+				continue
+			}
+			param = key + "=" + value
+		}
+		params = append(params, param)
+	}
+	if !found && value != "" {
+		params = append(params, key+"="+value)
+	}
+	return strings.Join(params, ";")
+}
+
+// setFmtpAttribute replaces the fmtp of a payload type, adding it after its
+// rtpmap when missing
+func setFmtpAttribute(media *sdp.MediaDescription, payloadType uint8, fmtp string, haveFmtp bool) {
+	prefix := strconv.Itoa(int(payloadType)) + " "
+
+	attributes := []sdp.Attribute{}
+	for _, attr := range media.Attributes {
+		switch {
+		case attr.Key == "fmtp" && strings.HasPrefix(attr.Value, prefix):
+			if fmtp == "" {
+				continue
+			}
+			attr.Value = prefix + fmtp
+		case attr.Key == "rtpmap" && strings.HasPrefix(attr.Value, prefix) && !haveFmtp && fmtp != "":
+			attributes = append(attributes, attr, sdp.NewAttribute("fmtp", prefix+fmtp))
+			continue
+		}
+		attributes = append(attributes, attr)
+	}
+	media.Attributes = attributes
+}
@@ -0,0 +1,86 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/sdp/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisableVoiceActivityDetection(t *testing.T) {
+	d := &sdp.SessionDescription{
+		MediaDescriptions: []*sdp.MediaDescription{
+			{
+				MediaName: sdp.MediaName{Media: "audio", Port: sdp.RangedPort{Value: 9}, Formats: []string{"111", "18", "13"}},
+				Attributes: []sdp.Attribute{
+					{Key: "rtpmap", Value: "111 opus/48000/2"},
+					{Key: "fmtp", Value: "111 minptime=10;usedtx=1"},
+					{Key: "rtcp-fb", Value: "111 transport-cc"},
+					{Key: "rtpmap", Value: "18 G729/8000"},
+					{Key: "rtpmap", Value: "13 CN/8000"},
+					{Key: "rtcp-fb", Value: "13 transport-cc"},
+				},
+			},
+		},
+	}
+
+	disableVoiceActivityDetection(d)
+	media := d.MediaDescriptions[0]
+	assert.Equal(t, []string{"111", "18"}, media.MediaName.Formats)
+	assert.Equal(t, []sdp.Attribute{
+		{Key: "rtpmap", Value: "111 opus/48000/2"},
+		{Key: "fmtp", Value: "111 minptime=10"},
+		{Key: "rtcp-fb", Value: "111 transport-cc"},
+		{Key: "rtpmap", Value: "18 G729/8000"},
+		{Key: "fmtp", Value: "18 annexb=no"},
+	}, media.Attributes)
+}
+
+func TestPeerConnection_VoiceActivityDetection(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	m.RegisterCodec(NewRTPCNCodec(DefaultPayloadTypeCN, 8000))
+
+	pc, err := NewAPI(WithMediaEngine(m)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	transceiver, err := pc.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+
+	// enabled by default
+	assert.True(t, transceiver.Sender().VoiceActivityDetection())
+
+	offer, err := pc.CreateOffer(&OfferOptions{OfferAnswerOptions: OfferAnswerOptions{DisableVoiceActivityDetection: true}})
+	assert.NoError(t, err)
+	assert.NotContains(t, offer.SDP, "CN/8000")
+	assert.NotContains(t, offer.SDP, "usedtx")
+	assert.NoError(t, pc.SetLocalDescription(offer))
+	assert.False(t, transceiver.Sender().VoiceActivityDetection())
+
+	// without the option the registered codecs are used as they are, the
+	// legacy VoiceActivityDetection field is ignored
+	for _, options := range []*OfferOptions{
+		nil,
+		{ICERestart: true},
+		{OfferAnswerOptions: OfferAnswerOptions{VoiceActivityDetection: false}},
+	} {
+		offer, err = pc.CreateOffer(options)
+		assert.NoError(t, err)
+		assert.Contains(t, offer.SDP, "a=rtpmap:13 CN/8000")
+		assert.NotContains(t, offer.SDP, "usedtx")
+	}
+
+	assert.NoError(t, pc.Close())
+
+	// the default codecs, without comfort noise nor DTX, keep it enabled
+	pc, err = NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	transceiver, err = pc.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	offer, err = pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pc.SetLocalDescription(offer))
+	assert.True(t, transceiver.Sender().VoiceActivityDetection())
+	assert.NoError(t, pc.Close())
+}